@@ -984,11 +984,11 @@ func (rs *Res) PermuteFitCats() {
 	}
 }
 
-// AvgTickDist computes average within-tick distance (7 ticks in a row)
-func (rs *Res) AvgTickDist(insm *simat.SimMat) float64 {
+// AvgTickDist computes average within-object distance across ticks,
+// for a simat with ntick rows in a row for each object
+func (rs *Res) AvgTickDist(insm *simat.SimMat, ntick int) float64 {
 	no := len(insm.Rows)
 	smatv := insm.Mat.(*etensor.Float64).Values
-	ntick := 7
 	avgd := 0.0
 	navg := 0
 	nobj := no / ntick
@@ -1020,7 +1020,7 @@ func (rs *Res) Analyze() {
 	rs.ClustPlots()
 	rs.PermuteFitCats()
 	rs.DoPredNetSims()
-	// atd := rs.AvgTickDist(&rs.LbaTickSimMat)
+	// atd := rs.AvgTickDist(&rs.LbaTickSimMat, 7)
 	// fmt.Printf("avg within-tick distance: %v\n", atd)
 }
 
@@ -13,6 +13,7 @@ import (
 	"log"
 	"math/rand"
 	"os"
+	"path/filepath"
 	"strconv"
 	"strings"
 	"time"
@@ -108,6 +109,7 @@ type Sim struct {
 	MaxRuns          int             `desc:"maximum number of model runs to perform (starting from StartRun)"`
 	MaxEpcs          int             `desc:"maximum number of epochs to run per model run"`
 	MaxTrls          int             `desc:"maximum number of training trials per epoch (each trial is MaxTicks ticks)"`
+	MaxTstTrls       int             `desc:"maximum number of testing trials per epoch"`
 	MaxTicks         int             `desc:"max number of ticks, for logs, stats"`
 	NZeroStop        int             `desc:"if a positive number, training will stop after this many epochs with zero SSE"`
 	RepsInterval     int             `desc:"how often to analyze the representations"`
@@ -150,6 +152,7 @@ type Sim struct {
 	TrnTrlFile   *os.File                      `view:"-" desc:"log file"`
 	RunFile      *os.File                      `view:"-" desc:"log file"`
 	ValsTsrs     map[string]*etensor.Float32   `view:"-" desc:"for holding layer values"`
+	OutDir       string                        `view:"-" desc:"directory for all the files saved by the sim: logs, weights -- current directory if empty"`
 	SaveWts      bool                          `view:"-" desc:"for command-line run only, auto-save final weights after each run"`
 	NoGui        bool                          `view:"-" desc:"if true, runing in no GUI mode"`
 	LogSetParams bool                          `view:"-" desc:"if true, print message for all params that are set"`
@@ -299,6 +302,9 @@ func (ss *Sim) ConfigEnv() {
 		ss.MaxEpcs = 50
 		ss.NZeroStop = -1
 	}
+	if ss.MaxTstTrls == 0 { // allow user override
+		ss.MaxTstTrls = 500
+	}
 	if ss.MaxTrls == 0 { // allow user override
 		ss.MaxTrls = 64
 		ss.MaxTicks = 8
@@ -313,7 +319,7 @@ func (ss *Sim) ConfigEnv() {
 	ss.TestEnv.Nm = "TestEnv"
 	ss.TestEnv.Dsc = "testing params and state"
 	ss.TestEnv.Defaults()
-	ss.TestEnv.Trial.Max = 500
+	ss.TestEnv.Trial.Max = ss.MaxTstTrls
 
 	ss.TrainEnv.Init(0)
 	ss.TestEnv.Init(0)
@@ -1043,7 +1049,7 @@ func (ss *Sim) RunEpochName(run, epc int) string {
 
 // WeightsFileName returns default current weights file name
 func (ss *Sim) WeightsFileName() string {
-	return ss.Net.Nm + "_" + ss.RunName() + "_" + ss.RunEpochName(ss.TrainEnv.Run.Cur, ss.TrainEnv.Epoch.Cur) + ".wts.gz"
+	return filepath.Join(ss.OutDir, ss.Net.Nm+"_"+ss.RunName()+"_"+ss.RunEpochName(ss.TrainEnv.Run.Cur, ss.TrainEnv.Epoch.Cur)+".wts.gz")
 }
 
 // LogFileName returns default log file name
@@ -1053,7 +1059,7 @@ func (ss *Sim) LogFileName(lognm string) string {
 		nm += fmt.Sprintf("_%d", mpi.WorldRank())
 	}
 	nm += ".tsv"
-	return nm
+	return filepath.Join(ss.OutDir, nm)
 }
 
 //////////////////////////////////////////////
@@ -1221,9 +1227,15 @@ func (ss *Sim) CopyCenterPools(ly *axon.Layer, vl *etensor.Float32) {
 
 // LogTrnRepTrl adds data from current trial to the TrnTrlRepLog table.
 func (ss *Sim) LogTrnRepTrl(dt *etable.Table) {
-	epc := ss.TrainEnv.Epoch.Cur
-	trl := ss.TrainEnv.Trial.Cur
-	tick := ss.TrainEnv.Tick.Cur
+	ss.LogRepTrl(dt, &ss.TrainEnv)
+}
+
+// LogRepTrl adds the layer representations of the current trial of given env
+// to dt, configured by ConfigTrnTrlRepLog -- resets dt at a new epoch.
+func (ss *Sim) LogRepTrl(dt *etable.Table, ev *SacEnv) {
+	epc := ev.Epoch.Cur
+	trl := ev.Trial.Cur
+	tick := ev.Tick.Cur
 	row := dt.Rows
 
 	if row > 1 { // reset at new epoch
@@ -1237,13 +1249,13 @@ func (ss *Sim) LogTrnRepTrl(dt *etable.Table) {
 		dt.SetNumRows(row + 1)
 	}
 
-	dt.SetCellFloat("Run", row, float64(ss.TrainEnv.Run.Cur))
+	dt.SetCellFloat("Run", row, float64(ev.Run.Cur))
 	dt.SetCellFloat("Epoch", row, float64(epc))
 	dt.SetCellFloat("Trial", row, float64(trl))
 	dt.SetCellFloat("Tick", row, float64(tick))
 	dt.SetCellFloat("Idx", row, float64(row))
 	dt.SetCellString("Obj", row, "na")
-	dt.SetCellString("TrialName", row, ss.TrainEnv.String())
+	dt.SetCellString("TrialName", row, ev.String())
 
 	for _, lnm := range ss.HidLays {
 		ly := ss.Net.LayerByName(lnm).(axon.AxonLayer).AsAxon()
@@ -1258,7 +1270,7 @@ func (ss *Sim) LogTrnRepTrl(dt *etable.Table) {
 	}
 
 	// if ss.TrnTrlFile != nil && (!ss.UseMPI || ss.SaveProcLog) { // otherwise written at end of epoch, integrated
-	// 	if ss.TrainEnv.Run.Cur == ss.StartRun && epc == 0 && row == 0 {
+	// 	if ev.Run.Cur == ss.StartRun && epc == 0 && row == 0 {
 	// 		dt.WriteCSVHeaders(ss.TrnTrlFile, etable.Tab)
 	// 	}
 	// 	dt.WriteCSVRow(ss.TrnTrlFile, row, etable.Tab)
@@ -2088,12 +2100,19 @@ var SimProps = ki.Props{
 }
 
 func (ss *Sim) CmdArgs() {
+	cmd, args, err := ParseSubCmd(os.Args[1:], SimSubCmds)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	flag.Usage = func() { SubCmdUsage(SimSubCmds) }
 	ss.NoGui = true
 	var nogui bool
 	var saveEpcLog bool
 	var saveTrlLog bool
 	var saveRunLog bool
 	var note string
+	var wts string
 	flag.StringVar(&ss.ParamSet, "params", "", "ParamSet name to use -- must be valid name as listed in compiled-in params or loaded params")
 	flag.StringVar(&ss.Tag, "tag", "", "extra tag to add to file names saved from this run")
 	flag.StringVar(&note, "note", "", "user note -- describe the run params etc")
@@ -2107,7 +2126,16 @@ func (ss *Sim) CmdArgs() {
 	flag.BoolVar(&ss.SaveProcLog, "proclog", false, "if true, save log files separately for each processor (for debugging)")
 	flag.BoolVar(&nogui, "nogui", true, "if not passing any other args and want to run nogui, use nogui")
 	flag.BoolVar(&ss.UseMPI, "mpi", false, "if set, use MPI for distributed computation")
-	flag.Parse()
+	flag.StringVar(&ss.OutDir, "out", "", "output directory for all the files saved: logs, weights -- current directory if empty")
+	flag.IntVar(&ss.MaxTstTrls, "trials", 0, "number of test items for test and export-acts -- 500 if 0")
+	flag.StringVar(&wts, "weights", "", "trained weights file to open for test and export-acts, e.g., as saved with -wts")
+	flag.CommandLine.Parse(args)
+	if ss.OutDir != "" {
+		if err := os.MkdirAll(ss.OutDir, 0755); err != nil {
+			log.Println(err)
+			return
+		}
+	}
 
 	if ss.UseMPI {
 		ss.MPIInit()
@@ -2123,6 +2151,15 @@ func (ss *Sim) CmdArgs() {
 	if ss.ParamSet != "" {
 		mpi.Printf("Using ParamSet: %s\n", ss.ParamSet)
 	}
+	if cmd != "train" {
+		err := ss.RunSubCmd(cmd, wts)
+		ss.MPIFinalize()
+		if err != nil {
+			log.Println(err)
+			os.Exit(1)
+		}
+		return
+	}
 
 	if saveEpcLog && (ss.SaveProcLog || mpi.WorldRank() == 0) {
 		var err error
@@ -2174,6 +2211,79 @@ func (ss *Sim) CmdArgs() {
 	ss.MPIFinalize()
 }
 
+////////////////////////////////////////////////////////////////////
+//  Subcommands
+
+// SimSubCmds are the subcommands supported by this sim -- see SubCmds.
+// This sim has no RSA stats, V1 reconstruction, lesions or weight analysis,
+// so rsa, reconstruct, lesion and wtanal are only in the wwi3d sims.
+var SimSubCmds = []string{"train", "test", "export-acts"}
+
+// RunSubCmd runs given subcommand other than train, after Config and Init,
+// on rank 0 only under MPI.
+func (ss *Sim) RunSubCmd(cmd, wts string) error {
+	if mpi.WorldRank() != 0 {
+		return nil
+	}
+	if wts == "" {
+		return fmt.Errorf("%s needs the trained -weights", cmd)
+	}
+	ss.TrainEnv.Run.Set(ss.StartRun)
+	ss.NewRun()
+	mpi.Printf("Opening weights: %s\n", wts)
+	if err := ss.Net.OpenWtsJSON(gi.FileName(wts)); err != nil {
+		return err
+	}
+	switch cmd {
+	case "test":
+		return ss.CmdTest()
+	case "export-acts":
+		return ss.CmdExportActs()
+	}
+	return fmt.Errorf("subcommand %s is not supported", cmd)
+}
+
+// CmdTest runs the test items and saves the test trial and epoch logs and the ActRFs
+func (ss *Sim) CmdTest() error {
+	ss.TestAll()
+	fnm := ss.LogFileName("tsttrl")
+	mpi.Printf("Saving test trial log to: %s\n", fnm)
+	if err := ss.TstTrlLog.SaveCSV(gi.FileName(fnm), etable.Tab, etable.Headers); err != nil {
+		return err
+	}
+	if err := ss.TstEpcLog.SaveCSV(gi.FileName(ss.LogFileName("tstepc")), etable.Tab, etable.Headers); err != nil {
+		return err
+	}
+	for _, rf := range ss.ActRFs.RFs {
+		fnm := ss.LogFileName("actrf_" + strings.Replace(rf.Name, ":", "_", -1))
+		if err := etensor.SaveCSV(&rf.NormRF, gi.FileName(fnm), etable.Tab.Rune()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// CmdExportActs runs the test items, saving the layer representations of every
+// trial (as in the TrnTrlRepLog) to the tstacts log
+func (ss *Sim) CmdExportActs() error {
+	dt := &etable.Table{}
+	ss.ConfigTrnTrlRepLog(dt)
+	dt.SetMetaData("name", "TstTrlRepLog")
+	dt.SetMetaData("desc", "Record of layer representations per testing trial")
+	ss.TestEnv.Init(ss.TrainEnv.Run.Cur)
+	for {
+		ss.TestTrial(true) // return on chg, don't present
+		_, _, chg := ss.TestEnv.Counter(env.Epoch)
+		if chg || ss.StopNow {
+			break
+		}
+		ss.LogRepTrl(dt, &ss.TestEnv)
+	}
+	fnm := ss.LogFileName("tstacts")
+	mpi.Printf("Saving %d trials of layer activations to: %s\n", dt.Rows, fnm)
+	return dt.SaveCSV(gi.FileName(fnm), etable.Tab, etable.Headers)
+}
+
 ////////////////////////////////////////////////////////////////////
 //  MPI code
 
@@ -0,0 +1,90 @@
+// Copyright (c) 2020, The CCNLab Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// SubCmd is a subcommand of the headless command-line interface, given as
+// the first arg, e.g., wwi3d test -weights trained.wts.gz -out tst --
+// if the first arg is a flag, the subcommand is train, as before.
+type SubCmd struct {
+	Name string `desc:"name of the subcommand, as given on the command line"`
+	Desc string `desc:"description of what it does and the main flags it uses"`
+}
+
+// SubCmds are all the subcommands -- each sim supports those that apply to it (SimSubCmds)
+var SubCmds = []SubCmd{
+	{"train", "train the network, saving logs (and weights with -wts) to -out -- the default"},
+	{"test", "run the test items on the trained -weights, saving the test trial and epoch logs and the ActRFs to -out"},
+	{"rsa", "run the RSA analyses on activations saved by a training run (-acts catact log), and on a saved TE similarity matrix (-simat), saving the results to -out"},
+	{"export-acts", "run the test items on the trained -weights, saving the layer activations of every trial to -out (as .npz with -npzacts)"},
+	{"reconstruct", "run the test items on the trained -weights, saving the V1 images reconstructed from the -recon layers in the minus (prediction) and plus (actual) phases to -out"},
+	{"lesion", "run the test items on the trained -weights intact and with each of the -lesions, saving the changes in pulvinar error, TE RSA and decoding accuracy to -out"},
+	{"wtanal", "analyze the trained -weights: stats of the weights of each projection, how much of their topography survived learning, and weight-based receptive fields in V1 (-wtrf), saving tables and images to -out"},
+}
+
+// SubCmdByName returns the subcommand of given name, or nil if none
+func SubCmdByName(nm string) *SubCmd {
+	for i := range SubCmds {
+		if SubCmds[i].Name == nm {
+			return &SubCmds[i]
+		}
+	}
+	return nil
+}
+
+// ParseSubCmd returns the subcommand given as the first of args (os.Args[1:]),
+// and the remaining args to parse as flags -- train if args start with a flag.
+// Returns an error if the subcommand is not one of those supported.
+func ParseSubCmd(args []string, supported []string) (string, []string, error) {
+	if len(args) == 0 || strings.HasPrefix(args[0], "-") {
+		return "train", args, nil
+	}
+	cmd := args[0]
+	for _, sc := range supported {
+		if sc == cmd {
+			return cmd, args[1:], nil
+		}
+	}
+	if SubCmdByName(cmd) != nil {
+		return cmd, nil, fmt.Errorf("subcommand %s is not supported by %s -- use one of: %s", cmd, os.Args[0], strings.Join(supported, ", "))
+	}
+	return cmd, nil, fmt.Errorf("unknown subcommand: %s -- use one of: %s", cmd, strings.Join(supported, ", "))
+}
+
+// SubCmdUsage prints the usage of given supported subcommands, the names of the
+// other SubCmds that this sim does not support, and all the flags
+func SubCmdUsage(supported []string) {
+	out := flag.CommandLine.Output()
+	fmt.Fprintf(out, "Usage: %s [subcommand] [flags]\n\nSubcommands:\n", os.Args[0])
+	for _, nm := range supported {
+		if sc := SubCmdByName(nm); sc != nil {
+			fmt.Fprintf(out, "  %-12s %s\n", sc.Name, sc.Desc)
+		}
+	}
+	var unsup []string
+	for _, sc := range SubCmds {
+		has := false
+		for _, nm := range supported {
+			if nm == sc.Name {
+				has = true
+				break
+			}
+		}
+		if !has {
+			unsup = append(unsup, sc.Name)
+		}
+	}
+	if len(unsup) > 0 {
+		fmt.Fprintf(out, "\nNot supported by this sim (see the wwi3d sims): %s\n", strings.Join(unsup, ", "))
+	}
+	fmt.Fprintf(out, "\nFlags:\n")
+	flag.PrintDefaults()
+}
@@ -0,0 +1,180 @@
+// Copyright (c) 2020, The CCNLab Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"log"
+	"path/filepath"
+	"strings"
+
+	"github.com/emer/etable/etable"
+	"github.com/emer/etable/etensor"
+	"github.com/goki/gi/gi"
+)
+
+// Collate holds the comparison table of all the runs of a sweep: one row per
+// sim Run (of each Job), with the Tag, status and Dim values of the Job,
+// all the columns of the RunLog, and the final values of the epoch log
+// columns matching EpcCols (by default TE_, which includes the TE RSA metrics).
+type Collate struct {
+	EpcCols []string      `desc:"prefixes of epoch log columns to include, at the last epoch of each run"`
+	Table   *etable.Table `desc:"the comparison table"`
+	cols    []string
+	types   map[string]etensor.Type
+	rows    []map[string]interface{}
+}
+
+// FindLog returns the log file of given type (run, epc) saved by the sim
+// run with given tag in dir, or "" if not found
+func FindLog(dir, tag, lognm string) string {
+	fs, _ := filepath.Glob(filepath.Join(dir, "*_"+tag+"_*_"+lognm+".tsv"))
+	if len(fs) == 0 {
+		return ""
+	}
+	return fs[0]
+}
+
+// OpenLog opens a log table, returning nil if fname is empty or has an error
+func OpenLog(fname string) *etable.Table {
+	if fname == "" {
+		return nil
+	}
+	dt := &etable.Table{}
+	if err := dt.OpenCSV(gi.FileName(fname), etable.Tab); err != nil {
+		log.Println(err)
+		return nil
+	}
+	return dt
+}
+
+// addCol adds a column of given type if not yet present
+func (cl *Collate) addCol(nm string, tp etensor.Type) {
+	if _, has := cl.types[nm]; has {
+		return
+	}
+	cl.cols = append(cl.cols, nm)
+	cl.types[nm] = tp
+}
+
+// cellVal returns the value of given cell as a string or float64
+func cellVal(dt *etable.Table, ci, row int) interface{} {
+	if dt.Cols[ci].DataType() == etensor.STRING {
+		return dt.Cols[ci].StringVal1D(row)
+	}
+	return dt.Cols[ci].FloatVal1D(row)
+}
+
+// epcMatch returns true if the epoch log column matches EpcCols
+func (cl *Collate) epcMatch(nm string) bool {
+	for _, pfx := range cl.EpcCols {
+		if strings.HasPrefix(nm, pfx) {
+			return true
+		}
+	}
+	return false
+}
+
+// Collate collates the logs of all the jobs of the sweep into the Table,
+// looking for the logs in the sim LogDir -- returns an error for the jobs that
+// are Done but have no run log, which still get a row with no results
+func (cl *Collate) Collate(sw *Sweep) error {
+	dir := sw.LogDir()
+	var nolog []string
+	cl.cols = nil
+	cl.types = make(map[string]etensor.Type)
+	cl.rows = nil
+	cl.addCol("Tag", etensor.STRING)
+	cl.addCol("Status", etensor.STRING)
+	for di := range sw.Spec.Dims {
+		cl.addCol(sw.Spec.Dims[di].Name(), etensor.STRING)
+	}
+	for _, jb := range sw.Jobs {
+		base := map[string]interface{}{"Tag": jb.Tag, "Status": jb.Status}
+		for di := range sw.Spec.Dims {
+			base[sw.Spec.Dims[di].Name()] = jb.Vals[di]
+		}
+		runs := OpenLog(FindLog(dir, jb.Tag, "run"))
+		if runs == nil || runs.Rows == 0 {
+			if jb.Status == "Done" {
+				nolog = append(nolog, jb.Tag)
+			}
+			cl.rows = append(cl.rows, base)
+			continue
+		}
+		epcs := OpenLog(FindLog(dir, jb.Tag, "epc"))
+		lastEpc := map[int]int{} // run -> last row
+		if epcs != nil && epcs.ColByName("Run") != nil {
+			for row := 0; row < epcs.Rows; row++ {
+				lastEpc[int(epcs.CellFloat("Run", row))] = row
+			}
+		}
+		for row := 0; row < runs.Rows; row++ {
+			rw := make(map[string]interface{}, len(base))
+			for k, v := range base {
+				rw[k] = v
+			}
+			for ci, cn := range runs.ColNames {
+				cl.addCol(cn, runs.Cols[ci].DataType())
+				rw[cn] = cellVal(runs, ci, row)
+			}
+			erow, ok := -1, false
+			if runs.ColByName("Run") != nil {
+				erow, ok = lastEpc[int(runs.CellFloat("Run", row))]
+			}
+			if ok {
+				cl.addCol("Epoch", etensor.INT64)
+				rw["Epoch"] = epcs.CellFloat("Epoch", erow)
+				for ci, cn := range epcs.ColNames {
+					if cl.epcMatch(cn) {
+						cl.addCol(cn, epcs.Cols[ci].DataType())
+						rw[cn] = cellVal(epcs, ci, erow)
+					}
+				}
+			}
+			cl.rows = append(cl.rows, rw)
+		}
+	}
+	cl.ConfigTable()
+	if len(nolog) > 0 {
+		return fmt.Errorf("sweep Collate: no run log in %s for Done runs: %s", dir, strings.Join(nolog, " "))
+	}
+	return nil
+}
+
+// ConfigTable makes the Table from the collated rows
+func (cl *Collate) ConfigTable() {
+	sch := make(etable.Schema, len(cl.cols))
+	for i, cn := range cl.cols {
+		tp := cl.types[cn]
+		if tp != etensor.STRING {
+			tp = etensor.FLOAT64
+		}
+		sch[i] = etable.Column{cn, tp, nil, nil}
+	}
+	dt := &etable.Table{}
+	dt.SetMetaData("name", "SweepResults")
+	dt.SetMetaData("desc", "RunLog and final epoch log values of each run of the sweep")
+	dt.SetFromSchema(sch, len(cl.rows))
+	for row, rw := range cl.rows {
+		for cn, v := range rw {
+			switch vv := v.(type) {
+			case string:
+				dt.SetCellString(cn, row, vv)
+			case float64:
+				dt.SetCellFloat(cn, row, vv)
+			}
+		}
+	}
+	cl.Table = dt
+}
+
+// Save saves the Table to given file
+func (cl *Collate) Save(fname string) error {
+	if cl.Table == nil {
+		return fmt.Errorf("sweep Collate: nothing collated")
+	}
+	return cl.Table.SaveCSV(gi.FileName(fname), etable.Tab, etable.Headers)
+}
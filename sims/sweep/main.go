@@ -0,0 +1,88 @@
+// Copyright (c) 2020, The CCNLab Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+/*
+sweep runs a parameter sweep of a sim (e.g., wwi3d, wwi3d_axon): it takes a spec
+of a base ParamSet and a grid or random search space over param paths, launches
+the runs as local worker processes (passing each its params with -xparams),
+tracks their status with retries, and collates every RunLog and the final
+epoch log metrics (TE RSA etc) into one comparison table.
+*/
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+func main() {
+	var spec, sim, dir, out, epcCols string
+	var nprocs, retries int
+	var collateOnly bool
+	flag.StringVar(&spec, "spec", "", "JSON file with the sweep Spec: Name, Base ParamSet, Args, Mode (Grid, Random), N, Seed and Dims")
+	flag.StringVar(&sim, "sim", "", "path to the sim executable, e.g., ../wwi3d/wwi3d")
+	flag.StringVar(&dir, "dir", "", "working directory for the sim runs, where they find their files and save their logs -- defaults to the directory of -sim")
+	flag.StringVar(&out, "out", "sweep", "output directory for the param files, run output, status and results tables")
+	flag.IntVar(&nprocs, "j", 2, "maximum number of runs at the same time")
+	flag.IntVar(&retries, "retries", 1, "number of times a failed run is retried")
+	flag.StringVar(&epcCols, "epccols", "TE_", "comma-separated prefixes of epoch log columns to include in the results, at the last epoch of each run")
+	flag.BoolVar(&collateOnly, "collate", false, "if true, only collate the logs of a previous sweep with the same spec, without running")
+	flag.Parse()
+
+	if spec == "" || (sim == "" && !collateOnly) {
+		fmt.Fprintln(os.Stderr, "sweep: -spec and -sim are required")
+		flag.Usage()
+		os.Exit(2)
+	}
+	sw := &Sweep{Out: out, NProcs: nprocs, Retries: retries}
+	if err := sw.Spec.OpenJSON(spec); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	if sim != "" {
+		abs, err := filepath.Abs(sim)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		sw.Sim = abs
+	}
+	sw.Dir = dir
+	if sw.Dir == "" {
+		sw.Dir = filepath.Dir(sw.Sim)
+	}
+	if err := sw.ConfigJobs(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	sw.OpenStatus()
+	if !collateOnly {
+		fmt.Printf("sweep: %s %d runs, %d at a time\n", sw.Spec.Name, len(sw.Jobs), sw.NProcs)
+		sw.Run()
+	}
+
+	cl := &Collate{EpcCols: strings.Split(epcCols, ",")}
+	cerr := cl.Collate(sw)
+	fnm := sw.OutFile("results.tsv")
+	if err := cl.Save(fnm); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	nfail := 0
+	for _, jb := range sw.Jobs {
+		if jb.Status == "Failed" {
+			nfail++
+		}
+	}
+	fmt.Printf("sweep: %s results in: %s -- %d failed\n", sw.Spec.Name, fnm, nfail)
+	if cerr != nil {
+		fmt.Fprintln(os.Stderr, cerr)
+	}
+	if nfail > 0 || cerr != nil {
+		os.Exit(1)
+	}
+}
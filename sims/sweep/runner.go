@@ -0,0 +1,269 @@
+// Copyright (c) 2020, The CCNLab Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/emer/etable/etable"
+	"github.com/emer/etable/etensor"
+	"github.com/goki/gi/gi"
+)
+
+// Job is one run of the sweep, for one point in the search space
+type Job struct {
+	Tag    string    `desc:"tag of the run, passed as -tag: sweep name and index"`
+	Vals   []string  `desc:"values of the spec Dims for this run"`
+	Status string    `desc:"Pending, Running, Done or Failed"`
+	Tries  int       `desc:"number of times the run has been started"`
+	Err    string    `desc:"error from the last failed try"`
+	Start  time.Time `desc:"start time of the last try"`
+	Secs   float64   `desc:"duration of the last try, in seconds"`
+}
+
+// Sweep launches the runs of a Spec as local worker processes, and
+// tracks their status in a status table, which is saved to a file in
+// Out after every change, so an interrupted sweep can be restarted
+// without redoing the runs that are already Done.
+type Sweep struct {
+	Spec    Spec       `desc:"the sweep spec"`
+	Sim     string     `desc:"path to the sim executable"`
+	Dir     string     `desc:"working directory for the sim runs, where they find their files and save their logs"`
+	Out     string     `desc:"output directory for the param files, run output and sweep tables"`
+	NProcs  int        `desc:"maximum number of runs at the same time"`
+	Retries int        `desc:"number of times a failed run is retried"`
+	Jobs    []*Job     `desc:"the jobs, one per point of the search space"`
+	Mu      sync.Mutex `view:"-" desc:"mutex for the job status"`
+}
+
+// OutFile returns the name of a file in the Out dir for given suffix
+func (sw *Sweep) OutFile(suffix string) string {
+	return filepath.Join(sw.Out, sw.Spec.Name+"_"+suffix)
+}
+
+// ConfigJobs makes the jobs for the points of the search space, and
+// saves the param file for each
+func (sw *Sweep) ConfigJobs() error {
+	if err := os.MkdirAll(sw.Out, 0755); err != nil {
+		return err
+	}
+	pts := sw.Spec.Points()
+	sw.Jobs = make([]*Job, len(pts))
+	for i, pt := range pts {
+		jb := &Job{Tag: fmt.Sprintf("%s_%03d", sw.Spec.Name, i), Vals: pt, Status: "Pending"}
+		sw.Jobs[i] = jb
+		if err := sw.Spec.SaveParams(sw.ParamsFile(jb), jb.Tag, pt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ParamsFile returns the name of the param file for given job
+func (sw *Sweep) ParamsFile(jb *Job) string {
+	return filepath.Join(sw.Out, jb.Tag+"_params.json")
+}
+
+// OpenStatus marks the jobs that are Done in a previously saved status table,
+// so they are not run again -- the values must match
+func (sw *Sweep) OpenStatus() {
+	fnm := sw.OutFile("status.tsv")
+	if _, err := os.Stat(fnm); err != nil {
+		return
+	}
+	dt := &etable.Table{}
+	if err := dt.OpenCSV(gi.FileName(fnm), etable.Tab); err != nil {
+		log.Println(err)
+		return
+	}
+	rows := make(map[string]int, dt.Rows)
+	for row := 0; row < dt.Rows; row++ {
+		rows[dt.CellString("Tag", row)] = row
+	}
+	done := 0
+	for _, jb := range sw.Jobs {
+		row, ok := rows[jb.Tag]
+		if !ok || dt.CellString("Status", row) != "Done" {
+			continue
+		}
+		same := true
+		for di := range sw.Spec.Dims {
+			if dt.CellString(sw.Spec.Dims[di].Name(), row) != jb.Vals[di] {
+				same = false
+				break
+			}
+		}
+		if same {
+			jb.Status = "Done"
+			jb.Tries = int(dt.CellFloat("Tries", row))
+			jb.Secs = dt.CellFloat("Secs", row)
+			done++
+		}
+	}
+	if done > 0 {
+		fmt.Printf("%d runs already Done in: %s\n", done, fnm)
+	}
+}
+
+// ConfigStatus configures the status table
+func (sw *Sweep) ConfigStatus(dt *etable.Table) {
+	dt.SetMetaData("name", "SweepStatus")
+	dt.SetMetaData("desc", "status of each run of the sweep")
+	sch := etable.Schema{
+		{"Tag", etensor.STRING, nil, nil},
+		{"Status", etensor.STRING, nil, nil},
+		{"Tries", etensor.INT64, nil, nil},
+		{"Secs", etensor.FLOAT64, nil, nil},
+		{"Err", etensor.STRING, nil, nil},
+	}
+	for di := range sw.Spec.Dims {
+		sch = append(sch, etable.Column{sw.Spec.Dims[di].Name(), etensor.STRING, nil, nil})
+	}
+	dt.SetFromSchema(sch, len(sw.Jobs))
+}
+
+// SaveStatus saves the status table -- must be called under Mu
+func (sw *Sweep) SaveStatus() {
+	dt := &etable.Table{}
+	sw.ConfigStatus(dt)
+	for row, jb := range sw.Jobs {
+		dt.SetCellString("Tag", row, jb.Tag)
+		dt.SetCellString("Status", row, jb.Status)
+		dt.SetCellFloat("Tries", row, float64(jb.Tries))
+		dt.SetCellFloat("Secs", row, jb.Secs)
+		dt.SetCellString("Err", row, jb.Err)
+		for di := range sw.Spec.Dims {
+			dt.SetCellString(sw.Spec.Dims[di].Name(), row, jb.Vals[di])
+		}
+	}
+	if err := dt.SaveCSV(gi.FileName(sw.OutFile("status.tsv")), etable.Tab, etable.Headers); err != nil {
+		log.Println(err)
+	}
+}
+
+// SetStatus sets the status of given job and saves the status table
+func (sw *Sweep) SetStatus(jb *Job, status string) {
+	sw.Mu.Lock()
+	jb.Status = status
+	switch status {
+	case "Running":
+		jb.Tries++
+		jb.Start = time.Now()
+		jb.Err = ""
+	case "Done", "Failed", "Pending":
+		jb.Secs = time.Now().Sub(jb.Start).Seconds()
+	}
+	sw.SaveStatus()
+	sw.Mu.Unlock()
+}
+
+// Args returns the args for running given job
+func (sw *Sweep) Args(jb *Job) []string {
+	pf, _ := filepath.Abs(sw.ParamsFile(jb))
+	args := []string{"-nogui", "-tag", jb.Tag, "-xparams", pf}
+	if sw.Spec.Base != "" {
+		args = append(args, "-params", sw.Spec.Base)
+	}
+	return append(args, sw.Spec.Args...)
+}
+
+// LogDir returns the directory where the sim runs save their logs: the -out
+// directory of the Spec Args, relative to Dir, or Dir if there is none
+func (sw *Sweep) LogDir() string {
+	out := ""
+	for i, arg := range sw.Spec.Args {
+		nm := strings.TrimLeft(arg, "-")
+		switch {
+		case nm == "out" && i+1 < len(sw.Spec.Args):
+			out = sw.Spec.Args[i+1]
+		case strings.HasPrefix(nm, "out="):
+			out = strings.TrimPrefix(nm, "out=")
+		}
+	}
+	if filepath.IsAbs(out) {
+		return out
+	}
+	return filepath.Join(sw.Dir, out)
+}
+
+// RunJob runs given job once, with its output going to a file in Out
+func (sw *Sweep) RunJob(jb *Job) error {
+	sw.SetStatus(jb, "Running")
+	fmt.Printf("start: %s try: %d vals: %v\n", jb.Tag, jb.Tries, jb.Vals)
+	of, err := os.Create(filepath.Join(sw.Out, fmt.Sprintf("%s_try%d.out", jb.Tag, jb.Tries)))
+	if err != nil {
+		return err
+	}
+	defer of.Close()
+	cmd := exec.Command(sw.Sim, sw.Args(jb)...)
+	cmd.Dir = sw.Dir
+	cmd.Stdout = of
+	cmd.Stderr = of
+	return cmd.Run()
+}
+
+// Run runs all the jobs that are not Done, with up to NProcs at the
+// same time, retrying failed runs up to Retries times
+func (sw *Sweep) Run() {
+	var pend []*Job
+	for _, jb := range sw.Jobs {
+		if jb.Status != "Done" {
+			jb.Status = "Pending"
+			jb.Tries = 0
+			pend = append(pend, jb)
+		}
+	}
+	sw.Mu.Lock()
+	sw.SaveStatus()
+	sw.Mu.Unlock()
+	if len(pend) == 0 {
+		return
+	}
+	// capacity for all tries, so requeueing a failed job never blocks
+	jobs := make(chan *Job, len(pend)*(sw.Retries+1))
+	for _, jb := range pend {
+		jobs <- jb
+	}
+	var wg sync.WaitGroup
+	wg.Add(len(pend))
+	np := sw.NProcs
+	if np < 1 {
+		np = 1
+	}
+	for wi := 0; wi < np; wi++ {
+		go func() {
+			for jb := range jobs {
+				err := sw.RunJob(jb)
+				if err == nil {
+					sw.SetStatus(jb, "Done")
+					fmt.Printf("done: %s secs: %.0f\n", jb.Tag, jb.Secs)
+					wg.Done()
+					continue
+				}
+				sw.Mu.Lock()
+				jb.Err = err.Error()
+				sw.Mu.Unlock()
+				if jb.Tries <= sw.Retries {
+					sw.SetStatus(jb, "Pending")
+					fmt.Printf("retry: %s err: %v\n", jb.Tag, err)
+					jobs <- jb
+					continue
+				}
+				sw.SetStatus(jb, "Failed")
+				fmt.Printf("failed: %s err: %v\n", jb.Tag, err)
+				wg.Done()
+			}
+		}()
+	}
+	wg.Wait()
+	close(jobs)
+}
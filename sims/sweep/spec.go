@@ -0,0 +1,206 @@
+// Copyright (c) 2020, The CCNLab Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"math/rand"
+	"strconv"
+
+	"github.com/emer/emergent/params"
+	"github.com/goki/gi/gi"
+)
+
+// Dim is one dimension of the search space: a param path on a selector,
+// with either an explicit list of values, or a Min..Max range that is
+// divided into Steps values for a Grid search, or sampled uniformly
+// (log-uniformly if Log) for a Random search.
+type Dim struct {
+	Sheet string   `desc:"params sheet -- Network if empty"`
+	Sel   string   `desc:"params selector, e.g., .V1 or Layer"`
+	Path  string   `desc:"param path, e.g., Layer.Inhib.Layer.Gi"`
+	Vals  []string `desc:"explicit list of values -- used instead of Min..Max if set"`
+	Min   float64  `desc:"minimum of the range of values"`
+	Max   float64  `desc:"maximum of the range of values"`
+	Steps int      `desc:"number of values in Min..Max for a Grid search (inclusive, min 2)"`
+	Log   bool     `desc:"if true, values are spaced / sampled logarithmically in Min..Max"`
+}
+
+// Name returns the name of the dim as used for columns: Sel:Path
+func (dm *Dim) Name() string {
+	return dm.Sel + ":" + dm.Path
+}
+
+// Validate returns an error if the dim is not well specified
+func (dm *Dim) Validate() error {
+	if dm.Sel == "" || dm.Path == "" {
+		return fmt.Errorf("sweep Dim needs Sel and Path: %+v", *dm)
+	}
+	if len(dm.Vals) > 0 {
+		return nil
+	}
+	if dm.Max < dm.Min {
+		return fmt.Errorf("sweep Dim %s: Max < Min", dm.Name())
+	}
+	if dm.Log && dm.Min <= 0 {
+		return fmt.Errorf("sweep Dim %s: Log requires Min > 0", dm.Name())
+	}
+	return nil
+}
+
+// interp returns the value at proportion p (0..1) along Min..Max
+func (dm *Dim) interp(p float64) float64 {
+	if dm.Log {
+		return math.Exp(math.Log(dm.Min) + p*(math.Log(dm.Max)-math.Log(dm.Min)))
+	}
+	return dm.Min + p*(dm.Max-dm.Min)
+}
+
+// GridVals returns the values of the dim for a Grid search
+func (dm *Dim) GridVals() []string {
+	if len(dm.Vals) > 0 {
+		return dm.Vals
+	}
+	n := dm.Steps
+	if n < 2 {
+		n = 2
+	}
+	vals := make([]string, n)
+	for i := range vals {
+		vals[i] = FmtVal(dm.interp(float64(i) / float64(n-1)))
+	}
+	return vals
+}
+
+// RandVal returns a random value of the dim for a Random search
+func (dm *Dim) RandVal(rnd *rand.Rand) string {
+	if len(dm.Vals) > 0 {
+		return dm.Vals[rnd.Intn(len(dm.Vals))]
+	}
+	return FmtVal(dm.interp(rnd.Float64()))
+}
+
+// FmtVal formats a param value with 4 significant digits, which keeps
+// the values (and param messages) readable
+func FmtVal(v float64) string {
+	return strconv.FormatFloat(v, 'g', 4, 64)
+}
+
+// Spec specifies a sweep: a base ParamSet and a search space of param Dims,
+// searched by Grid (all combinations) or Random (N samples).
+type Spec struct {
+	Name string   `desc:"name of the sweep -- used as the prefix of the run tags and output files"`
+	Base string   `desc:"base ParamSet applied (after Base) in all runs -- empty for Base only"`
+	Args []string `desc:"additional args passed to all runs, e.g., -runs, -epochs"`
+	Mode string   `desc:"Grid or Random"`
+	N    int      `desc:"number of runs for a Random search"`
+	Seed int64    `desc:"random seed for a Random search"`
+	Dims []Dim    `desc:"the dimensions of the search space"`
+}
+
+// OpenJSON opens the spec from a JSON file, and validates it
+func (sp *Spec) OpenJSON(fname string) error {
+	b, err := ioutil.ReadFile(fname)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(b, sp); err != nil {
+		return fmt.Errorf("sweep spec %s: %v", fname, err)
+	}
+	if sp.Name == "" {
+		sp.Name = "sweep"
+	}
+	if sp.Mode == "" {
+		sp.Mode = "Grid"
+	}
+	return sp.Validate()
+}
+
+// Validate returns an error if the spec is not well specified
+func (sp *Spec) Validate() error {
+	if len(sp.Dims) == 0 {
+		return fmt.Errorf("sweep %s: no Dims", sp.Name)
+	}
+	for i := range sp.Dims {
+		if err := sp.Dims[i].Validate(); err != nil {
+			return err
+		}
+	}
+	switch sp.Mode {
+	case "Grid":
+	case "Random":
+		if sp.N <= 0 {
+			return fmt.Errorf("sweep %s: Random needs N > 0", sp.Name)
+		}
+	default:
+		return fmt.Errorf("sweep %s: Mode must be Grid or Random, not: %s", sp.Name, sp.Mode)
+	}
+	return nil
+}
+
+// Points returns the points of the search space, each with one value per Dim
+func (sp *Spec) Points() [][]string {
+	nd := len(sp.Dims)
+	if sp.Mode == "Random" {
+		rnd := rand.New(rand.NewSource(sp.Seed))
+		pts := make([][]string, sp.N)
+		for i := range pts {
+			pt := make([]string, nd)
+			for di := range sp.Dims {
+				pt[di] = sp.Dims[di].RandVal(rnd)
+			}
+			pts[i] = pt
+		}
+		return pts
+	}
+	gv := make([][]string, nd)
+	n := 1
+	for di := range sp.Dims {
+		gv[di] = sp.Dims[di].GridVals()
+		n *= len(gv[di])
+	}
+	pts := make([][]string, n)
+	for i := range pts {
+		pt := make([]string, nd)
+		ix := i
+		for di := nd - 1; di >= 0; di-- { // last dim varies fastest
+			nv := len(gv[di])
+			pt[di] = gv[di][ix%nv]
+			ix /= nv
+		}
+		pts[i] = pt
+	}
+	return pts
+}
+
+// ParamSets returns the param sets for given point, as one set named nm
+// with a sheet for each Sheet used in the Dims -- this is applied after
+// the Base and base ParamSet by the -xparams arg of the sims.
+func (sp *Spec) ParamSets(nm string, pt []string) params.Sets {
+	ps := &params.Set{Name: nm, Desc: "sweep " + sp.Name, Sheets: params.Sheets{}}
+	for di := range sp.Dims {
+		dm := &sp.Dims[di]
+		shnm := dm.Sheet
+		if shnm == "" {
+			shnm = "Network"
+		}
+		sh, ok := ps.Sheets[shnm]
+		if !ok {
+			sh = &params.Sheet{}
+			ps.Sheets[shnm] = sh
+		}
+		*sh = append(*sh, &params.Sel{Sel: dm.Sel, Desc: "sweep", Params: params.Params{dm.Path: pt[di]}})
+	}
+	return params.Sets{ps}
+}
+
+// SaveParams saves the param sets for given point to a JSON file
+func (sp *Spec) SaveParams(fname, nm string, pt []string) error {
+	pss := sp.ParamSets(nm, pt)
+	return pss.SaveJSON(gi.FileName(fname))
+}
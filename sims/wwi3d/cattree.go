@@ -0,0 +1,370 @@
+// Copyright (c) 2020, The CCNLab Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"math"
+	"math/rand"
+	"sort"
+	"strings"
+
+	"github.com/emer/etable/etable"
+	"github.com/emer/etable/etensor"
+	"github.com/emer/etable/simat"
+)
+
+// CatLevel is one level of a CatTree
+type CatLevel struct {
+	Name string            `desc:"name of the level, e.g., Shape, Basic, Instance"`
+	Map  map[string]string `desc:"maps the basic-level object name (first part of the cat/objfile item name) to the category at this level -- if nil, the first Part parts of the item name are used"`
+	Part int               `desc:"for levels with a nil Map: number of /-separated parts of the cat/objfile item name to use as the category: 1 = basic-level object, 2 = object instance"`
+}
+
+// CatTree is a hierarchical category structure over items, with Levels from the most
+// superordinate (e.g., shape class) to the most specific (e.g., object instance).
+// Category labels are nested by prefixing them with the labels of all the levels above,
+// so each category has exactly one parent in the level above.
+type CatTree struct {
+	Levels []CatLevel `desc:"levels of the tree, from most superordinate to most specific"`
+}
+
+// NewCatTree returns a category tree with given superordinate levels, each with
+// a name and a flat map from basic-level object to category (e.g., LbaCats5),
+// from most to least superordinate, followed by the Basic (object) and Instance levels
+func NewCatTree(names []string, maps []map[string]string) CatTree {
+	var ct CatTree
+	for i, nm := range names {
+		ct.Levels = append(ct.Levels, CatLevel{Name: nm, Map: maps[i]})
+	}
+	ct.Levels = append(ct.Levels, CatLevel{Name: "Basic", Part: 1}, CatLevel{Name: "Instance", Part: 2})
+	return ct
+}
+
+// LbaCatTree returns the standard category tree: LbaCats5 shape classes,
+// basic-level objects (Objs) and object instances
+func LbaCatTree() CatTree {
+	return NewCatTree([]string{"Shape"}, []map[string]string{LbaCats5})
+}
+
+// Labels returns the nested category labels [level][item] for given cat/objfile item names
+func (ct *CatTree) Labels(items []string) [][]string {
+	nl := len(ct.Levels)
+	lbls := make([][]string, nl)
+	for li := range ct.Levels {
+		lv := &ct.Levels[li]
+		lbls[li] = make([]string, len(items))
+		for i, it := range items {
+			prts := strings.Split(it, "/")
+			var cat string
+			if lv.Map != nil {
+				cat = lv.Map[prts[0]]
+			} else {
+				np := lv.Part
+				if np < 1 {
+					np = 1
+				}
+				if np > len(prts) {
+					np = len(prts)
+				}
+				cat = strings.Join(prts[:np], "/")
+			}
+			if li > 0 {
+				cat = lbls[li-1][i] + ":" + cat
+			}
+			lbls[li][i] = cat
+		}
+	}
+	return lbls
+}
+
+// TreeStats are the stats columns in the tables computed by StatsFmSimMat,
+// for each level of the CatTree:
+// FlatDst = average contrast distance using the categories at this level alone,
+// NestDst = nested contrast: distance to items in other categories with the same parent category,
+// minus distance to items in the same category but a different child category
+// (i.e., the structure at this level beyond that of the levels below),
+// PermMean, PermSD = mean and SD of NestDst over nested permutations, which shuffle the
+// assignment of child categories to categories within each parent category,
+// Z = (NestDst - PermMean) / PermSD, P = proportion of permutations with NestDst >= actual.
+var TreeStats = []string{"FlatDst", "NestDst", "PermMean", "PermSD", "Z", "P"}
+
+// NestGroups holds the distances from each item to each child category group at
+// one level of a CatTree, for computing the nested contrast distance of that level
+// and its nested permutations efficiently.  At the last level, each item is its own group.
+type NestGroups struct {
+	Grp  []int     `desc:"child group index for each item"`
+	Cat  []string  `desc:"category at this level for each group"`
+	Par  []string  `desc:"parent category (level above) for each group -- empty for the top level"`
+	Cnt  []int     `desc:"number of items in each group"`
+	Sums []float64 `desc:"sum of distances from each item (outer) to the items in each group (inner), excluding the item itself"`
+}
+
+// Init initializes the groups for level lev, from given item x item distance matrix
+// values and nested category labels (see CatTree Labels)
+func (ng *NestGroups) Init(smatv []float64, lbls [][]string, lev int) {
+	nl := len(lbls)
+	no := len(lbls[lev])
+	ng.Grp = make([]int, no)
+	ng.Cat = nil
+	ng.Par = nil
+	ng.Cnt = nil
+	gidx := make(map[string]int)
+	for i := 0; i < no; i++ {
+		gi := i
+		if lev < nl-1 {
+			ch := lbls[lev+1][i]
+			g, has := gidx[ch]
+			if !has {
+				g = len(ng.Cat)
+				gidx[ch] = g
+			}
+			gi = g
+		} else {
+			gi = len(ng.Cat)
+		}
+		if gi == len(ng.Cat) {
+			par := ""
+			if lev > 0 {
+				par = lbls[lev-1][i]
+			}
+			ng.Cat = append(ng.Cat, lbls[lev][i])
+			ng.Par = append(ng.Par, par)
+			ng.Cnt = append(ng.Cnt, 0)
+		}
+		ng.Grp[i] = gi
+		ng.Cnt[gi]++
+	}
+	ngp := len(ng.Cat)
+	ng.Sums = make([]float64, no*ngp)
+	for ri := 0; ri < no; ri++ {
+		roff := ri * no
+		soff := ri * ngp
+		for ci := 0; ci < no; ci++ {
+			if ri == ci {
+				continue
+			}
+			ng.Sums[soff+ng.Grp[ci]] += smatv[roff+ci]
+		}
+	}
+}
+
+// Contrast returns the nested contrast distance for given category of each group
+// (ng.Cat or a permutation of it): for each item, the average distance to items in a
+// different category with the same parent category (between), minus the average
+// distance to items in the same category but a different child group (within).
+// The overall average is positive if this level has structure beyond that of the
+// levels below it.  Items without both types of pairs are skipped.
+func (ng *NestGroups) Contrast(cats []string) float64 {
+	ngp := len(cats)
+	avgd := 0.0
+	navg := 0
+	for ri, rg := range ng.Grp {
+		soff := ri * ngp
+		wd, bd := 0.0, 0.0
+		wn, bn := 0, 0
+		for g := 0; g < ngp; g++ {
+			if g == rg || ng.Par[g] != ng.Par[rg] {
+				continue
+			}
+			if cats[g] == cats[rg] {
+				wd += ng.Sums[soff+g]
+				wn += ng.Cnt[g]
+			} else {
+				bd += ng.Sums[soff+g]
+				bn += ng.Cnt[g]
+			}
+		}
+		if wn == 0 || bn == 0 {
+			continue
+		}
+		avgd += bd/float64(bn) - wd/float64(wn)
+		navg++
+	}
+	if navg == 0 {
+		return 0
+	}
+	return avgd / float64(navg)
+}
+
+// Permute returns a nested permutation of the group categories: the categories are
+// shuffled among the groups within each parent category, which preserves the number
+// of groups in each category and all of the structure within the groups and at the
+// levels above.
+func (ng *NestGroups) Permute(rnd *rand.Rand) []string {
+	pgs := make(map[string][]int)
+	for g, par := range ng.Par {
+		pgs[par] = append(pgs[par], g)
+	}
+	pars := make([]string, 0, len(pgs))
+	for par := range pgs {
+		pars = append(pars, par)
+	}
+	sort.Strings(pars) // deterministic given the seed
+	perm := make([]string, len(ng.Cat))
+	for _, par := range pars {
+		gs := pgs[par]
+		cats := make([]string, len(gs))
+		for i, g := range gs {
+			cats[i] = ng.Cat[g]
+		}
+		rnd.Shuffle(len(cats), func(i, j int) { cats[i], cats[j] = cats[j], cats[i] })
+		for i, g := range gs {
+			perm[g] = cats[i]
+		}
+	}
+	return perm
+}
+
+// FlatContrastDist computes the average contrast distance using the given labels
+// alone: average distance to items in other categories minus that to items in
+// the same category (i.e., the same as -AvgContrastDist on the labels)
+func FlatContrastDist(smatv []float64, lbls []string) float64 {
+	no := len(lbls)
+	if no == 0 {
+		return 0
+	}
+	avgd := 0.0
+	for ri := 0; ri < no; ri++ {
+		roff := ri * no
+		wd, bd := 0.0, 0.0
+		wn, bn := 0, 0
+		for ci := 0; ci < no; ci++ {
+			if ri == ci {
+				continue
+			}
+			d := smatv[roff+ci]
+			if lbls[ri] == lbls[ci] {
+				wd += d
+				wn++
+			} else {
+				bd += d
+				bn++
+			}
+		}
+		if wn > 0 {
+			wd /= float64(wn)
+		}
+		if bn > 0 {
+			bd /= float64(bn)
+		}
+		avgd += bd - wd
+	}
+	return avgd / float64(no)
+}
+
+// ConfigTreeTable configures a table for the TreeStats, with one row per level
+func (ct *CatTree) ConfigTreeTable(dt *etable.Table) {
+	sch := etable.Schema{
+		{"Level", etensor.STRING, nil, nil},
+		{"NCats", etensor.INT64, nil, nil},
+	}
+	for _, sn := range TreeStats {
+		sch = append(sch, etable.Column{sn, etensor.FLOAT64, nil, nil})
+	}
+	dt.SetFromSchema(sch, len(ct.Levels))
+	for li := range ct.Levels {
+		dt.SetCellString("Level", li, ct.Levels[li].Name)
+	}
+}
+
+// StatsFmSimMat computes the TreeStats for each level of the tree into given table
+// (see ConfigTreeTable), from given simat with the cat/objfile item name of each row,
+// using nperm nested permutations with given random seed.
+func (ct *CatTree) StatsFmSimMat(dt *etable.Table, sm *simat.SimMat, items []string, nperm int, seed int64) {
+	ct.ConfigTreeTable(dt)
+	smatv := sm.Mat.(*etensor.Float64).Values
+	if len(items) == 0 || len(items)*len(items) != len(smatv) {
+		return
+	}
+	lbls := ct.Labels(items)
+	rnd := rand.New(rand.NewSource(seed))
+	var ng NestGroups
+	for li := range ct.Levels {
+		cats := make(map[string]bool)
+		for _, lb := range lbls[li] {
+			cats[lb] = true
+		}
+		dt.SetCellFloat("NCats", li, float64(len(cats)))
+		dt.SetCellFloat("FlatDst", li, FlatContrastDist(smatv, lbls[li]))
+		ng.Init(smatv, lbls, li)
+		nd := ng.Contrast(ng.Cat)
+		dt.SetCellFloat("NestDst", li, nd)
+		if nperm <= 0 {
+			continue
+		}
+		sum, ssq := 0.0, 0.0
+		nge := 0
+		for pi := 0; pi < nperm; pi++ {
+			pd := ng.Contrast(ng.Permute(rnd))
+			sum += pd
+			ssq += pd * pd
+			if pd >= nd {
+				nge++
+			}
+		}
+		mean := sum / float64(nperm)
+		sd := math.Sqrt(math.Max(ssq/float64(nperm)-mean*mean, 0))
+		z := 0.0
+		if sd > 0 {
+			z = (nd - mean) / sd
+		}
+		dt.SetCellFloat("PermMean", li, mean)
+		dt.SetCellFloat("PermSD", li, sd)
+		dt.SetCellFloat("Z", li, z)
+		dt.SetCellFloat("P", li, float64(nge+1)/float64(nperm+1))
+	}
+}
+
+// TreeStatsFmActs computes the CatTree stats (TreeStats) for each of given layers
+// from given acts table (CatLayActs format, with Cat, Obj and Tick columns) into
+// Trees, using the rows at TreeTick -- with -1 = all ticks, the Instance level
+// measures how similar the views of an object instance at different ticks are
+// relative to other instances of the same object.
+func (rs *RSA) TreeStatsFmActs(acts *etable.Table, lays []string) {
+	if len(rs.Tree.Levels) == 0 {
+		rs.Tree = LbaCatTree()
+	}
+	if rs.Trees == nil {
+		rs.Trees = make(map[string]*etable.Table)
+	}
+	ix := etable.NewIdxView(acts)
+	if rs.TreeTick >= 0 {
+		tick := rs.TreeTick
+		ix.Filter(func(et *etable.Table, row int) bool {
+			return int(et.CellFloat("Tick", row)) == tick
+		})
+	}
+	items := make([]string, ix.Len())
+	for i, row := range ix.Idxs {
+		items[i] = acts.CellString("Cat", row) + "/" + acts.CellString("Obj", row)
+	}
+	for _, lnm := range LaysInActs(acts, lays) {
+		sm := &simat.SimMat{}
+		rs.SimMatFmActs(sm, ix, lnm)
+		dt, ok := rs.Trees[lnm]
+		if !ok {
+			dt = &etable.Table{}
+			dt.SetMetaData("name", lnm+"_CatTree")
+			dt.SetMetaData("desc", "contrast distances and nested permutation tests at each level of the category tree")
+			dt.SetMetaData("read-only", "true")
+			rs.Trees[lnm] = dt
+		}
+		rs.Tree.StatsFmSimMat(dt, sm, items, rs.TreeNPerm, rs.TreeSeed)
+	}
+}
+
+// TreeVal returns the given TreeStats stat for given layer and tree level name
+func (rs *RSA) TreeVal(lay, level, stat string) float64 {
+	dt, ok := rs.Trees[lay]
+	if !ok {
+		return 0
+	}
+	for row := 0; row < dt.Rows; row++ {
+		if dt.CellString("Level", row) == level {
+			return dt.CellFloat(stat, row)
+		}
+	}
+	return 0
+}
@@ -0,0 +1,418 @@
+// Copyright (c) 2020, The CCNLab Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/gob"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/emer/emergent/emer"
+	"github.com/emer/emergent/env"
+	"github.com/emer/etable/etable"
+	"github.com/goki/gi/gi"
+)
+
+// Checkpoints save the full state of a training run at the start of an epoch,
+// so it can be resumed and continue exactly as if it had not been interrupted.
+// A checkpoint is a directory with the following files, where <r> is the MPI rank:
+// ckpt.json = Ckpt run-level state (rank 0),
+// syns.gob = all synapse state including weights (rank 0 -- same on all ranks),
+// dwts.bin = MPI summed weight changes pending at the checkpoint (rank 0, MPI only),
+// state_<r>.gob = all other network, layer and projection state (see WriteNetState),
+// env_<r>.json = training env counters and trial order (CkptEnv),
+// catact_<r>.tsv = CatLayActs running averages, epc_<r>.tsv = TrnEpcLog rows so far,
+// tstepc_<r>.tsv = TstEpcLog rows so far (from periodic testing).
+// The params are not saved: they are set from the ParamSet as usual, and the params
+// actions of the Sched and Conv up to the checkpoint epoch are re-applied (see ReplaySched).
+// The random number generator is re-seeded from the run seed and epoch at the start of
+// every epoch, whether or not a checkpoint is saved, so the results do not depend on
+// the checkpoint interval -- resuming re-seeds it from the saved EpcSeed.
+// The log files of the run are truncated to the checkpoint when resuming (see ResumeLogFile).
+
+// CkptFile is the name of the run-level checkpoint file in a checkpoint directory
+const CkptFile = "ckpt.json"
+
+// Ckpt is the run-level state saved in a checkpoint
+type Ckpt struct {
+	Run       int             `desc:"run number"`
+	Epoch     int             `desc:"epoch at which the checkpoint was saved -- training resumes with the first trial of this epoch"`
+	NProcs    int             `desc:"number of MPI procs -- must be the same when resuming"`
+	Seed      int64           `desc:"random seed for the run"`
+	EpcSeed   int64           `desc:"random seed with which the random number generator was re-seeded at the start of the checkpoint epoch"`
+	LrateMult float32         `desc:"current learning rate multiplier from the learning rate schedule"`
+	ParamSet  string          `desc:"ParamSet used for the run"`
+	Tag       string          `desc:"Tag used for the run"`
+	Saved     string          `desc:"time when the checkpoint was saved"`
+	Time      json.RawMessage `desc:"network timing state (leabra / axon Time)"`
+	Conv      json.RawMessage `desc:"convergence monitor state (ConvMon), if monitoring"`
+}
+
+// CkptRankFile returns the name of a per-rank file in checkpoint dir
+func CkptRankFile(dir, base string, rank int, ext string) string {
+	return filepath.Join(dir, fmt.Sprintf("%s_%d%s", base, rank, ext))
+}
+
+// SaveCkptJSON saves given value as indented JSON to given file
+func SaveCkptJSON(v interface{}, fname string) error {
+	b, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(fname, b, 0644)
+}
+
+// OpenCkptJSON opens given JSON file into given value
+func OpenCkptJSON(v interface{}, fname string) error {
+	b, err := ioutil.ReadFile(fname)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(b, v)
+}
+
+// SaveTableExact saves given table as a tab-separated file with headers, at full
+// float precision regardless of its precision meta data, so it can be restored exactly
+func SaveTableExact(dt *etable.Table, fname string) error {
+	prec, has := dt.MetaData["precision"]
+	dt.SetMetaData("precision", "-1")
+	err := dt.SaveCSV(gi.FileName(fname), etable.Tab, etable.Headers)
+	if has {
+		dt.SetMetaData("precision", prec)
+	} else {
+		delete(dt.MetaData, "precision")
+	}
+	return err
+}
+
+// CkptCtr is the saved state of an env.Ctr counter
+type CkptCtr struct {
+	Cur int  `desc:"current counter value"`
+	Prv int  `desc:"previous counter value"`
+	Chg bool `desc:"did the counter change on last step"`
+	Max int  `desc:"maximum counter value"`
+}
+
+// CkptCtrFm returns the saved state of given counter
+func CkptCtrFm(ct *env.Ctr) CkptCtr {
+	return CkptCtr{Cur: ct.Cur, Prv: ct.Prv, Chg: ct.Chg, Max: ct.Max}
+}
+
+// SetCtr restores given counter to the saved state
+func (cc *CkptCtr) SetCtr(ct *env.Ctr) {
+	ct.Cur = cc.Cur
+	ct.Prv = cc.Prv
+	ct.Chg = cc.Chg
+	ct.Max = cc.Max
+}
+
+// CkptEnv is the saved state of an Obj3DSacEnv
+type CkptEnv struct {
+	Run   CkptCtr `desc:"Run counter"`
+	Epoch CkptCtr `desc:"Epoch counter"`
+	Trial CkptCtr `desc:"Trial counter"`
+	Tick  CkptCtr `desc:"Tick counter"`
+	Row   CkptCtr `desc:"Row counter -- the position in Idxs"`
+	Idxs  []int   `desc:"IdxView indexes into the Table -- the trials and their order for this proc"`
+}
+
+// CkptState returns the saved state of the env
+func (ev *Obj3DSacEnv) CkptState() CkptEnv {
+	ev.DefaultIdxView()
+	return CkptEnv{Run: CkptCtrFm(&ev.Run), Epoch: CkptCtrFm(&ev.Epoch), Trial: CkptCtrFm(&ev.Trial),
+		Tick: CkptCtrFm(&ev.Tick), Row: CkptCtrFm(&ev.Row), Idxs: append([]int{}, ev.IdxView.Idxs...)}
+}
+
+// SetCkptState restores the env to given saved state, including the inputs for the
+// current row, which must be in the same Table as when saved
+func (ev *Obj3DSacEnv) SetCkptState(ce *CkptEnv) error {
+	for _, ix := range ce.Idxs {
+		if ix >= ev.Table.Rows {
+			return fmt.Errorf("Obj3DSacEnv SetCkptState: %v: index %d out of range of table rows: %d", ev.Nm, ix, ev.Table.Rows)
+		}
+	}
+	ev.IdxView = etable.NewIdxView(ev.Table)
+	ev.IdxView.Idxs = append([]int{}, ce.Idxs...)
+	ce.Row.SetCtr(&ev.Row)
+	if ev.Row.Cur >= 0 {
+		ev.SetCtrs()
+		ev.EncodePops()
+		ev.FilterImage()
+	}
+	ce.Run.SetCtr(&ev.Run)
+	ce.Epoch.SetCtr(&ev.Epoch)
+	ce.Trial.SetCtr(&ev.Trial)
+	ce.Tick.SetCtr(&ev.Tick)
+	return nil
+}
+
+// WriteNetState writes the state of the network, all of its layers and all of their
+// receiving projections, as a gob stream, using reflection: all exported fields that
+// are plain data (numbers, bools, strings, and arrays, slices and structs of them),
+// which includes all neuron, pool, synapse and learning state.  Parameters (fields of
+// a struct type named *Params) are skipped, as are pointer, interface, map, func and
+// chan fields.  If syns is true, only the projection Syns synapse slices are written,
+// otherwise everything except them.  Each value is preceded by its type, and slices
+// by their length, so ReadNetState can check that the network has the same configuration.
+func WriteNetState(w io.Writer, net emer.Network, syns bool) error {
+	enc := gob.NewEncoder(w)
+	return walkNetState(net, syns, func(v reflect.Value) error {
+		if err := enc.Encode(v.Type().String()); err != nil {
+			return err
+		}
+		if v.Kind() == reflect.Slice {
+			if err := enc.Encode(v.Len()); err != nil || v.Len() == 0 {
+				return err
+			}
+		}
+		return enc.EncodeValue(v)
+	})
+}
+
+// ReadNetState reads the network state written by WriteNetState with the same syns arg
+// -- returns an error if any value has a different type or slice length in net
+func ReadNetState(r io.Reader, net emer.Network, syns bool) error {
+	dec := gob.NewDecoder(r)
+	return walkNetState(net, syns, func(v reflect.Value) error {
+		typ := ""
+		if err := dec.Decode(&typ); err != nil {
+			return err
+		}
+		if typ != v.Type().String() {
+			return fmt.Errorf("saved type %s does not match type %s", typ, v.Type().String())
+		}
+		// gob does not transmit zero values within structs, so targets must be zeroed first
+		if v.Kind() == reflect.Slice {
+			n := 0
+			if err := dec.Decode(&n); err != nil {
+				return err
+			}
+			if n != v.Len() {
+				return fmt.Errorf("saved length %d does not match length %d", n, v.Len())
+			}
+			if n == 0 {
+				return nil
+			}
+			zero := reflect.Zero(v.Type().Elem())
+			for i := 0; i < v.Len(); i++ {
+				v.Index(i).Set(zero)
+			}
+		} else {
+			v.Set(reflect.Zero(v.Type()))
+		}
+		return dec.DecodeValue(v.Addr())
+	})
+}
+
+// SaveNetState saves the network state to given file -- see WriteNetState
+func SaveNetState(fname string, net emer.Network, syns bool) error {
+	fp, err := os.Create(fname)
+	if err != nil {
+		return err
+	}
+	defer fp.Close()
+	bw := bufio.NewWriter(fp)
+	if err = WriteNetState(bw, net, syns); err != nil {
+		return err
+	}
+	return bw.Flush()
+}
+
+// OpenNetState opens the network state from given file -- see ReadNetState
+func OpenNetState(fname string, net emer.Network, syns bool) error {
+	fp, err := os.Open(fname)
+	if err != nil {
+		return err
+	}
+	defer fp.Close()
+	return ReadNetState(bufio.NewReader(fp), net, syns)
+}
+
+// walkNetState calls fun on each plain data field of the network, its layers and their
+// receiving projections, in a fixed order -- see WriteNetState
+func walkNetState(net emer.Network, syns bool, fun func(v reflect.Value) error) error {
+	if err := walkState(reflect.ValueOf(net).Elem(), syns, fun); err != nil {
+		return fmt.Errorf("network: %v", err)
+	}
+	nl := net.NLayers()
+	for li := 0; li < nl; li++ {
+		ly := net.Layer(li)
+		if err := walkState(reflect.ValueOf(ly).Elem(), syns, fun); err != nil {
+			return fmt.Errorf("layer %s: %v", ly.Name(), err)
+		}
+		np := ly.NRecvPrjns()
+		for pi := 0; pi < np; pi++ {
+			pj := ly.RecvPrjn(pi)
+			if err := walkState(reflect.ValueOf(pj).Elem(), syns, fun); err != nil {
+				return fmt.Errorf("prjn %s: %v", pj.Name(), err)
+			}
+		}
+	}
+	return nil
+}
+
+// walkState calls fun on each plain data field of given struct value, recursing into
+// struct fields other than params -- Syns fields are only included if syns, and only
+// they are included if syns
+func walkState(sv reflect.Value, syns bool, fun func(v reflect.Value) error) error {
+	st := sv.Type()
+	for i := 0; i < st.NumField(); i++ {
+		sf := st.Field(i)
+		if sf.PkgPath != "" || strings.HasSuffix(sf.Type.Name(), "Params") { // unexported or params
+			continue
+		}
+		fv := sv.Field(i)
+		if sf.Type.Kind() == reflect.Struct {
+			if err := walkState(fv, syns, fun); err != nil {
+				return err
+			}
+			continue
+		}
+		if (sf.Name == "Syns") != syns || !plainType(sf.Type) {
+			continue
+		}
+		if err := fun(fv); err != nil {
+			return fmt.Errorf("%s: %v", sf.Name, err)
+		}
+	}
+	return nil
+}
+
+// plainType returns true if given type is plain data that gob can encode and
+// decode exactly: numbers, bools, strings, and arrays, slices and structs of them,
+// with structs having at least one exported field and no unexported ones
+func plainType(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.Bool, reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64, reflect.String:
+		return true
+	case reflect.Array, reflect.Slice:
+		return plainType(t.Elem())
+	case reflect.Struct:
+		if t.NumField() == 0 {
+			return false
+		}
+		for i := 0; i < t.NumField(); i++ {
+			sf := t.Field(i)
+			if sf.PkgPath != "" || !plainType(sf.Type) {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// SaveDWts saves given weight changes as little-endian float32 values to given file
+func SaveDWts(fname string, dwts []float32) error {
+	fp, err := os.Create(fname)
+	if err != nil {
+		return err
+	}
+	defer fp.Close()
+	bw := bufio.NewWriter(fp)
+	if err = binary.Write(bw, binary.LittleEndian, dwts); err != nil {
+		return err
+	}
+	return bw.Flush()
+}
+
+// OpenDWts opens weight changes saved by SaveDWts
+func OpenDWts(fname string) ([]float32, error) {
+	fp, err := os.Open(fname)
+	if err != nil {
+		return nil, err
+	}
+	defer fp.Close()
+	fi, err := fp.Stat()
+	if err != nil {
+		return nil, err
+	}
+	dwts := make([]float32, fi.Size()/4)
+	err = binary.Read(bufio.NewReader(fp), binary.LittleEndian, dwts)
+	return dwts, err
+}
+
+// TruncLogRuns truncates a tab-separated log file with headers, as written by
+// WriteCSVHeaders and WriteCSVRow, to the rows of the runs before given run, and
+// of the epochs before given epc of the run, so the rest of the run can be rewritten
+// when resuming it from a checkpoint at epoch epc (0 = only the earlier runs are kept,
+// and the file needs no Epoch column).  Returns true if the file still has the
+// headers, which are removed too if no rows are kept (false if it is empty or absent).
+func TruncLogRuns(fname string, run, epc int) (bool, error) {
+	b, err := ioutil.ReadFile(fname)
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	lns := strings.SplitAfter(string(b), "\n")
+	if lns[0] == "" {
+		return false, nil
+	}
+	rc, ec := -1, -1
+	for i, hd := range strings.Split(strings.TrimSpace(lns[0]), "\t") {
+		switch strings.TrimLeft(hd, "$%#^") {
+		case "Run":
+			rc = i
+		case "Epoch":
+			ec = i
+		}
+	}
+	if rc < 0 {
+		return true, fmt.Errorf("TruncLogRuns: %s has no Run column", fname)
+	}
+	if epc > 0 && ec < 0 {
+		return true, fmt.Errorf("TruncLogRuns: %s has no Epoch column", fname)
+	}
+	keep := lns[0]
+	nkeep := 0
+	for _, ln := range lns[1:] {
+		flds := strings.Split(strings.TrimSpace(ln), "\t")
+		if len(flds) <= rc || len(flds) <= ec {
+			continue
+		}
+		r, err := strconv.Atoi(flds[rc])
+		if err != nil {
+			return true, fmt.Errorf("TruncLogRuns: %s: bad Run: %v", fname, err)
+		}
+		kp := r < run
+		if r == run && epc > 0 {
+			e, err := strconv.Atoi(flds[ec])
+			if err != nil {
+				return true, fmt.Errorf("TruncLogRuns: %s: bad Epoch: %v", fname, err)
+			}
+			kp = e < epc
+		}
+		if kp {
+			keep += ln
+			nkeep++
+		}
+	}
+	if nkeep == 0 {
+		return false, ioutil.WriteFile(fname, nil, 0644)
+	}
+	return true, ioutil.WriteFile(fname, []byte(keep), 0644)
+}
+
+// CreateLogFile creates a log file, or opens it for appending if app is true,
+// e.g., when resuming from a checkpoint
+func CreateLogFile(fname string, app bool) (*os.File, error) {
+	if app {
+		return os.OpenFile(fname, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	}
+	return os.Create(fname)
+}
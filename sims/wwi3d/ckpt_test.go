@@ -0,0 +1,142 @@
+// Copyright (c) 2020, The CCNLab Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"math/rand"
+	"path/filepath"
+	"testing"
+
+	"github.com/emer/emergent/emer"
+	"github.com/emer/leabra/deep"
+	"github.com/emer/leabra/leabra"
+)
+
+// testNet returns the LIP-only network built from DefNetSpec
+func testNet(t *testing.T) *deep.Network {
+	ss := &Sim{}
+	ss.New()
+	ss.LIPOnly = true
+	net := &deep.Network{}
+	net.InitName(net, DefNetSpec.Name)
+	if err := ss.ConfigNetSpec(net, &DefNetSpec); err != nil {
+		t.Fatal(err)
+	}
+	if err := net.Build(); err != nil {
+		t.Fatal(err)
+	}
+	return net
+}
+
+// TestNetStateRoundTrip tests that ReadNetState restores the neuron and synapse
+// state written by WriteNetState into a network with the same configuration
+func TestNetStateRoundTrip(t *testing.T) {
+	net := testNet(t)
+	rnd := rand.New(rand.NewSource(1))
+	for _, ly := range net.Layers {
+		lly := ly.(leabra.LeabraLayer).AsLeabra()
+		for ni := range lly.Neurons {
+			lly.Neurons[ni].Act = rnd.Float32()
+		}
+		for _, pj := range *ly.RecvPrjns() {
+			lpj := pj.(leabra.LeabraPrjn).AsLeabra()
+			for si := range lpj.Syns {
+				lpj.Syns[si].Wt = rnd.Float32()
+				lpj.Syns[si].DWt = rnd.Float32()
+			}
+		}
+	}
+	for _, syns := range []bool{true, false} {
+		var buf bytes.Buffer
+		if err := WriteNetState(&buf, net, syns); err != nil {
+			t.Fatal(err)
+		}
+		saved := buf.Bytes()
+		rnet := testNet(t)
+		if err := ReadNetState(bytes.NewReader(saved), rnet, syns); err != nil {
+			t.Fatalf("syns: %v: %v", syns, err)
+		}
+		var rbuf bytes.Buffer
+		if err := WriteNetState(&rbuf, rnet, syns); err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(rbuf.Bytes(), saved) {
+			t.Errorf("syns: %v: restored state differs from saved state", syns)
+		}
+		for li, ly := range net.Layers {
+			lly := ly.(leabra.LeabraLayer).AsLeabra()
+			rly := rnet.Layers[li].(leabra.LeabraLayer).AsLeabra()
+			for ni := range lly.Neurons {
+				act, ract := lly.Neurons[ni].Act, rly.Neurons[ni].Act
+				if (ract == act) == syns {
+					t.Errorf("syns: %v: layer %s neuron %d Act: %g, saved %g", syns, ly.Name(), ni, ract, act)
+					break
+				}
+			}
+			for pi, pj := range *ly.RecvPrjns() {
+				lpj := pj.(leabra.LeabraPrjn).AsLeabra()
+				rpj := (*rly.RecvPrjns())[pi].(leabra.LeabraPrjn).AsLeabra()
+				for si := range lpj.Syns {
+					if (rpj.Syns[si] == lpj.Syns[si]) != syns {
+						t.Errorf("syns: %v: prjn %s syn %d: %v, saved %v", syns, pj.Name(), si, rpj.Syns[si], lpj.Syns[si])
+						break
+					}
+				}
+			}
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := WriteNetState(&buf, net, false); err != nil {
+		t.Fatal(err)
+	}
+	onet := &deep.Network{} // a different configuration
+	onet.InitName(onet, "Other")
+	onet.AddLayer2D("In", 2, 2, emer.Input)
+	if err := onet.Build(); err != nil {
+		t.Fatal(err)
+	}
+	if err := ReadNetState(&buf, onet, false); err == nil {
+		t.Errorf("no error reading state into a network with a different configuration")
+	}
+}
+
+// TestTruncLogRuns tests that a log is truncated to the rows of the earlier runs,
+// and of the epochs before the checkpoint epoch of the resumed run
+func TestTruncLogRuns(t *testing.T) {
+	tsv := "$Run\t#Epoch\t#Val\n0\t0\t1\n0\t1\t2\n1\t0\t3\n1\t1\t4\n1\t2\t5\n2\t0\t6\n"
+	cases := []struct {
+		run, epc int
+		hdr      bool
+		want     string
+	}{
+		{1, 0, true, "$Run\t#Epoch\t#Val\n0\t0\t1\n0\t1\t2\n"},
+		{1, 2, true, "$Run\t#Epoch\t#Val\n0\t0\t1\n0\t1\t2\n1\t0\t3\n1\t1\t4\n"},
+		{3, 0, true, tsv},
+		{0, 0, false, ""},
+	}
+	for _, c := range cases {
+		fnm := filepath.Join(t.TempDir(), "log.tsv")
+		if err := ioutil.WriteFile(fnm, []byte(tsv), 0644); err != nil {
+			t.Fatal(err)
+		}
+		hdr, err := TruncLogRuns(fnm, c.run, c.epc)
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, err := ioutil.ReadFile(fnm)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if hdr != c.hdr || string(b) != c.want {
+			t.Errorf("run %d epc %d: %v %q, want %v %q", c.run, c.epc, hdr, b, c.hdr, c.want)
+		}
+	}
+	if hdr, err := TruncLogRuns(filepath.Join(t.TempDir(), "none.tsv"), 1, 0); hdr || err != nil {
+		t.Errorf("absent log: %v %v", hdr, err)
+	}
+}
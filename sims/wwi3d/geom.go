@@ -0,0 +1,269 @@
+// Copyright (c) 2020, The CCNLab Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"math"
+
+	"github.com/emer/etable/etable"
+	"github.com/emer/etable/etensor"
+)
+
+// GeomStats are the names of the representational geometry stats computed by Geom,
+// which are used as the column names in the Geom tables (and as <lay>_<stat> in the epoch log):
+// PR = participation ratio of the covariance eigenvalues (effective dimensionality),
+// PRNorm = PR / max possible (min of number of items, units),
+// LifeSprs = lifetime sparseness of each unit across items (avg over active units),
+// PopSprs = population sparseness across units for each item (avg over items),
+// CatSel = category (LbaCats5) selectivity index (avg over active units),
+// ObjSel = object (Objs) selectivity index (avg over active units),
+// CatSelFrac = fraction of active units whose CatSel is over SelThr.
+var GeomStats = []string{"PR", "PRNorm", "LifeSprs", "PopSprs", "CatSel", "ObjSel", "CatSelFrac"}
+
+// Geom computes representational geometry metrics of layer activations:
+// dimensionality, sparseness, and category and object selectivity.
+// Sparseness is the Vinje & Gallant (2000) measure, which is 0 for uniform
+// activity and 1 for activity in only one item (or unit).  The selectivity index
+// of a unit is (pref - other) / (pref + other) where pref is its mean activity for
+// its preferred category (object) and other is its mean over the other categories.
+type Geom struct {
+	Tick   int                           `desc:"tick of CatLayActs rows to use -- -1 = all ticks"`
+	ActThr float64                       `desc:"threshold on max mean activity across categories for a unit to count as active, for the unit-wise stats"`
+	SelThr float64                       `desc:"threshold on the category selectivity index for a unit to count as category selective -- 0.33 = preferred category has twice the activity of the others"`
+	Stats  map[string]map[string]float64 `desc:"most recent stats for each layer, by stat name (GeomStats)"`
+	Hist   *etable.Table                 `view:"no-inline" desc:"history of the stats for each layer over epochs"`
+}
+
+func (gm *Geom) Defaults() {
+	gm.Tick = 2
+	gm.ActThr = 0.01
+	gm.SelThr = 0.33
+}
+
+// Val returns the most recent value of given stat for given layer
+func (gm *Geom) Val(lay, stat string) float64 {
+	if gm.Stats == nil {
+		return 0
+	}
+	return gm.Stats[lay][stat]
+}
+
+// ConfigHist configures the Hist table, with an Epoch and Lay column and
+// a column for each of the GeomStats
+func (gm *Geom) ConfigHist() {
+	if gm.Hist != nil {
+		return
+	}
+	dt := &etable.Table{}
+	dt.SetMetaData("name", "Geom")
+	dt.SetMetaData("desc", "representational geometry of each layer over epochs")
+	dt.SetMetaData("read-only", "true")
+	sch := etable.Schema{
+		{"Epoch", etensor.INT64, nil, nil},
+		{"Lay", etensor.STRING, nil, nil},
+	}
+	for _, st := range GeomStats {
+		sch = append(sch, etable.Column{st, etensor.FLOAT64, nil, nil})
+	}
+	dt.SetFromSchema(sch, 0)
+	gm.Hist = dt
+}
+
+// FmActs computes the geometry stats for given layers in acts table (CatLayActs format,
+// with Cat, Obj and Tick columns), at the Tick, and adds them to the Hist with given epoch.
+func (gm *Geom) FmActs(acts *etable.Table, lays []string, epc int) {
+	gm.ConfigHist()
+	if gm.Stats == nil {
+		gm.Stats = make(map[string]map[string]float64)
+	}
+	var cats, objs []string
+	for row := 0; row < acts.Rows; row++ {
+		if gm.Tick >= 0 && int(acts.CellFloat("Tick", row)) != gm.Tick {
+			continue
+		}
+		obj := acts.CellString("Cat", row)
+		objs = append(objs, obj)
+		cats = append(cats, LbaCats5[obj])
+	}
+	dt := gm.Hist
+	for _, lnm := range LaysInActs(acts, lays) {
+		var vecs [][]float64
+		if gm.Tick >= 0 {
+			vecs = TickActs(acts, lnm, gm.Tick)
+		} else {
+			vecs = AllActs(acts, lnm)
+		}
+		st := gm.FmVecs(vecs, cats, objs)
+		gm.Stats[lnm] = st
+		row := dt.Rows
+		dt.SetNumRows(row + 1)
+		dt.SetCellFloat("Epoch", row, float64(epc))
+		dt.SetCellString("Lay", row, lnm)
+		for _, sn := range GeomStats {
+			dt.SetCellFloat(sn, row, st[sn])
+		}
+	}
+}
+
+// AllActs returns the activation vectors for given column, for all rows in acts
+func AllActs(acts *etable.Table, colnm string) [][]float64 {
+	vecs := make([][]float64, acts.Rows)
+	for row := range vecs {
+		tsr := acts.CellTensor(colnm, row)
+		v := make([]float64, tsr.Len())
+		for i := range v {
+			v[i] = tsr.FloatVal1D(i)
+		}
+		vecs[row] = v
+	}
+	return vecs
+}
+
+// FmVecs computes the geometry stats (GeomStats) for given activation vectors,
+// one per item, with given category and object label for each item.
+func (gm *Geom) FmVecs(vecs [][]float64, cats, objs []string) map[string]float64 {
+	st := make(map[string]float64, len(GeomStats))
+	n := len(vecs)
+	if n == 0 {
+		return st
+	}
+	nu := len(vecs[0])
+	pr := PartRatio(vecs)
+	st["PR"] = pr
+	mxd := n
+	if nu < mxd {
+		mxd = nu
+	}
+	st["PRNorm"] = pr / float64(mxd)
+
+	psum := 0.0
+	for _, v := range vecs {
+		psum += Sparseness(v)
+	}
+	st["PopSprs"] = psum / float64(n)
+
+	catm := GroupMeans(vecs, cats)
+	objm := GroupMeans(vecs, objs)
+	col := make([]float64, n)
+	nact := 0
+	lsum, csum, osum, cfrac := 0.0, 0.0, 0.0, 0.0
+	for ui := 0; ui < nu; ui++ {
+		mx := 0.0
+		for _, m := range catm {
+			mx = math.Max(mx, m[ui])
+		}
+		if mx < gm.ActThr {
+			continue
+		}
+		nact++
+		for i, v := range vecs {
+			col[i] = v[ui]
+		}
+		lsum += Sparseness(col)
+		cs := SelIdx(catm, ui)
+		csum += cs
+		osum += SelIdx(objm, ui)
+		if cs > gm.SelThr {
+			cfrac++
+		}
+	}
+	if nact > 0 {
+		st["LifeSprs"] = lsum / float64(nact)
+		st["CatSel"] = csum / float64(nact)
+		st["ObjSel"] = osum / float64(nact)
+		st["CatSelFrac"] = cfrac / float64(nact)
+	}
+	return st
+}
+
+// PartRatio returns the participation ratio (sum l)^2 / sum l^2 of the eigenvalues l
+// of the covariance matrix of given vectors (one per item), which is the effective
+// number of dimensions of the representation.  It is computed from the centered
+// item x item gram matrix K as trace(K)^2 / ||K||^2, without eigen decomposition.
+func PartRatio(vecs [][]float64) float64 {
+	n := len(vecs)
+	if n == 0 {
+		return 0
+	}
+	k := GramMatrix(vecs)
+	CenterGram(k, n)
+	tr := 0.0
+	for i := 0; i < n; i++ {
+		tr += k[i*n+i]
+	}
+	fs := 0.0
+	for _, v := range k {
+		fs += v * v
+	}
+	if fs == 0 {
+		return 0
+	}
+	return tr * tr / fs
+}
+
+// Sparseness returns the Vinje & Gallant (2000) sparseness of given non-negative
+// activities: (1 - (sum r / n)^2 / (sum r^2 / n)) / (1 - 1/n), which is 0 for
+// uniform activity and 1 if only one is active.
+func Sparseness(r []float64) float64 {
+	n := float64(len(r))
+	if n < 2 {
+		return 0
+	}
+	sum := 0.0
+	ssq := 0.0
+	for _, a := range r {
+		sum += a
+		ssq += a * a
+	}
+	if ssq == 0 {
+		return 0
+	}
+	return (1 - (sum*sum/n)/ssq) / (1 - 1/n)
+}
+
+// GroupMeans returns the mean vector for each group of vectors with the same label
+func GroupMeans(vecs [][]float64, lbls []string) map[string][]float64 {
+	ms := make(map[string][]float64)
+	ns := make(map[string]int)
+	for i, v := range vecs {
+		lb := lbls[i]
+		m, ok := ms[lb]
+		if !ok {
+			m = make([]float64, len(v))
+			ms[lb] = m
+		}
+		ns[lb]++
+		for ui, a := range v {
+			m[ui] += a
+		}
+	}
+	for lb, m := range ms {
+		nf := float64(ns[lb])
+		for ui := range m {
+			m[ui] /= nf
+		}
+	}
+	return ms
+}
+
+// SelIdx returns the selectivity index of unit ui given the group means:
+// (pref - other) / (pref + other), where pref is the mean for the preferred
+// (max) group and other is the average mean of the other groups
+func SelIdx(means map[string][]float64, ui int) float64 {
+	if len(means) < 2 {
+		return 0
+	}
+	pref := 0.0
+	sum := 0.0
+	for _, m := range means {
+		pref = math.Max(pref, m[ui])
+		sum += m[ui]
+	}
+	other := (sum - pref) / float64(len(means)-1)
+	if pref+other == 0 {
+		return 0
+	}
+	return (pref - other) / (pref + other)
+}
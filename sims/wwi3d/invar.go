@@ -0,0 +1,243 @@
+// Copyright (c) 2020, The CCNLab Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"math"
+	"math/rand"
+	"sort"
+
+	"github.com/emer/etable/etable"
+	"github.com/emer/etable/etensor"
+)
+
+// InvarStats are the names of the invariance stats computed by Invar, which are
+// used as the column names in the Invar Hist table (and as <lay>_<stat> in the epoch log).
+// All similarities are correlations between trial-level activation patterns:
+// ObjWithin = avg similarity between rows of the same object (across ticks, views and trials),
+// ObjBetween = avg similarity between rows of different objects,
+// ObjInvar = (ObjWithin - ObjBetween) / (1 - ObjBetween): 1 = fully view-invariant object code,
+// PosInvar = avg similarity of the same object at different eye positions minus
+// that of different objects at the same eye position: > 0 = object identity dominates position,
+// SacSim = avg similarity between successive ticks of a trial with a saccade in between,
+// NoSacSim = avg similarity between successive ticks of a trial without a saccade,
+// SacInvar = SacSim / NoSacSim: 1 = fully invariant to saccades.
+var InvarStats = []string{"ObjWithin", "ObjBetween", "ObjInvar", "PosInvar", "SacSim", "NoSacSim", "SacInvar"}
+
+// Invar computes invariance metrics for layer representations recorded
+// at the trial level (TrnTrlRepLog): invariance of object codes across
+// views (ticks), eye positions and saccades.
+type Invar struct {
+	EyeBins int                           `desc:"number of bins along each dimension of the EyePos (-1..1) for grouping rows by eye position"`
+	SacThr  float64                       `desc:"threshold on the length of the Saccade vector for a tick to count as following a saccade"`
+	MaxRows int                           `desc:"maximum number of rows used for the all-pairs stats (Obj, Pos) -- a random subset is used if there are more"`
+	Seed    int64                         `desc:"random seed for selecting the subset of rows"`
+	Stats   map[string]map[string]float64 `desc:"most recent stats for each layer, by stat name (InvarStats)"`
+	Hist    *etable.Table                 `view:"no-inline" desc:"history of the stats for each layer over epochs"`
+}
+
+func (iv *Invar) Defaults() {
+	iv.EyeBins = 3
+	iv.SacThr = 0.05
+	iv.MaxRows = 1024
+	iv.Seed = 1
+}
+
+// Val returns the most recent value of given stat for given layer
+func (iv *Invar) Val(lay, stat string) float64 {
+	if iv.Stats == nil {
+		return 0
+	}
+	return iv.Stats[lay][stat]
+}
+
+// ConfigHist configures the Hist table, with an Epoch and Lay column and
+// a column for each of the InvarStats
+func (iv *Invar) ConfigHist() {
+	if iv.Hist != nil {
+		return
+	}
+	dt := &etable.Table{}
+	dt.SetMetaData("name", "Invar")
+	dt.SetMetaData("desc", "invariance of each layer's representations over epochs")
+	dt.SetMetaData("read-only", "true")
+	sch := etable.Schema{
+		{"Epoch", etensor.INT64, nil, nil},
+		{"Lay", etensor.STRING, nil, nil},
+	}
+	for _, st := range InvarStats {
+		sch = append(sch, etable.Column{st, etensor.FLOAT64, nil, nil})
+	}
+	dt.SetFromSchema(sch, 0)
+	iv.Hist = dt
+}
+
+// EyeBin returns the eye position bin for given EyePos x, y values
+func (iv *Invar) EyeBin(x, y float64) int {
+	nb := iv.EyeBins
+	bin := func(v float64) int {
+		b := int(math.Floor((v + 1) * 0.5 * float64(nb)))
+		if b < 0 {
+			b = 0
+		}
+		if b >= nb {
+			b = nb - 1
+		}
+		return b
+	}
+	return bin(y)*nb + bin(x)
+}
+
+// Run computes the invariance stats for each of given layer columns in ix (TrnTrlRepLog
+// format, with Trial, Tick, Obj, EyePos and Saccade columns), and adds them to the Hist
+// with given epoch.  Rows of the same trial must be successive in ix.
+func (iv *Invar) Run(ix *etable.IdxView, lays []string, epc int) {
+	iv.ConfigHist()
+	if iv.Stats == nil {
+		iv.Stats = make(map[string]map[string]float64)
+	}
+	n := ix.Len()
+	if n < 2 {
+		return
+	}
+	dt := ix.Table
+	objs := make([]string, n)
+	bins := make([]int, n)
+	sacs := make([]bool, n)
+	succ := make([]bool, n) // row is the tick after the previous row in the same trial
+	for i, row := range ix.Idxs {
+		objs[i] = dt.CellString("Obj", row)
+		bins[i] = iv.EyeBin(dt.CellTensorFloat1D("EyePos", row, 0), dt.CellTensorFloat1D("EyePos", row, 1))
+		sx := dt.CellTensorFloat1D("Saccade", row, 0)
+		sy := dt.CellTensorFloat1D("Saccade", row, 1)
+		sacs[i] = math.Sqrt(sx*sx+sy*sy) > iv.SacThr
+		if i > 0 {
+			prv := ix.Idxs[i-1]
+			succ[i] = dt.CellFloat("Trial", row) == dt.CellFloat("Trial", prv) &&
+				dt.CellFloat("Tick", row) == dt.CellFloat("Tick", prv)+1 && objs[i] == objs[i-1]
+		}
+	}
+	sub := iv.SubsetIdxs(n)
+	hdt := iv.Hist
+	for _, lnm := range lays {
+		if dt.ColIdx(lnm) < 0 {
+			continue
+		}
+		vecs := make([][]float64, n)
+		for i, row := range ix.Idxs {
+			tsr := dt.CellTensor(lnm, row)
+			v := make([]float64, tsr.Len())
+			for j := range v {
+				v[j] = tsr.FloatVal1D(j)
+			}
+			vecs[i] = v
+		}
+		NormVecs(vecs)
+		st := iv.FmNormVecs(vecs, objs, bins, sacs, succ, sub)
+		iv.Stats[lnm] = st
+		row := hdt.Rows
+		hdt.SetNumRows(row + 1)
+		hdt.SetCellFloat("Epoch", row, float64(epc))
+		hdt.SetCellString("Lay", row, lnm)
+		for _, sn := range InvarStats {
+			hdt.SetCellFloat(sn, row, st[sn])
+		}
+	}
+}
+
+// SubsetIdxs returns the sorted indexes of a random subset of MaxRows of n rows,
+// or all rows if n <= MaxRows
+func (iv *Invar) SubsetIdxs(n int) []int {
+	if iv.MaxRows <= 0 || n <= iv.MaxRows {
+		idxs := make([]int, n)
+		for i := range idxs {
+			idxs[i] = i
+		}
+		return idxs
+	}
+	rnd := rand.New(rand.NewSource(iv.Seed))
+	idxs := rnd.Perm(n)[:iv.MaxRows]
+	sort.Ints(idxs)
+	return idxs
+}
+
+// FmNormVecs computes the InvarStats from given normalized (NormVecs) activation
+// vectors, with the object, eye position bin and saccade status for each, and whether
+// each is the successor tick of the previous one in the same trial.  The all-pairs
+// stats are computed over the sub subset of rows.
+func (iv *Invar) FmNormVecs(vecs [][]float64, objs []string, bins []int, sacs, succ []bool, sub []int) map[string]float64 {
+	st := make(map[string]float64, len(InvarStats))
+	dot := func(a, b []float64) float64 {
+		dp := 0.0
+		for k, av := range a {
+			dp += av * b[k]
+		}
+		return dp
+	}
+	var win, btw, swp, dsp float64 // within, between obj, same-obj diff-pos, diff-obj same-pos
+	var nwin, nbtw, nswp, ndsp int
+	for si, i := range sub {
+		for _, j := range sub[si+1:] {
+			r := dot(vecs[i], vecs[j])
+			sobj := objs[i] == objs[j]
+			spos := bins[i] == bins[j]
+			if sobj {
+				win += r
+				nwin++
+				if !spos {
+					swp += r
+					nswp++
+				}
+			} else {
+				btw += r
+				nbtw++
+				if spos {
+					dsp += r
+					ndsp++
+				}
+			}
+		}
+	}
+	avg := func(s float64, n int) float64 {
+		if n == 0 {
+			return 0
+		}
+		return s / float64(n)
+	}
+	win = avg(win, nwin)
+	btw = avg(btw, nbtw)
+	st["ObjWithin"] = win
+	st["ObjBetween"] = btw
+	if btw < 1 {
+		st["ObjInvar"] = (win - btw) / (1 - btw)
+	}
+	if nswp > 0 && ndsp > 0 {
+		st["PosInvar"] = avg(swp, nswp) - avg(dsp, ndsp)
+	}
+
+	var sac, nsac float64
+	var nsc, nnsc int
+	for i := 1; i < len(vecs); i++ {
+		if !succ[i] {
+			continue
+		}
+		r := dot(vecs[i], vecs[i-1])
+		if sacs[i] {
+			sac += r
+			nsc++
+		} else {
+			nsac += r
+			nnsc++
+		}
+	}
+	sac = avg(sac, nsc)
+	nsac = avg(nsac, nnsc)
+	st["SacSim"] = sac
+	st["NoSacSim"] = nsac
+	if nsac > 0 {
+		st["SacInvar"] = sac / nsac
+	}
+	return st
+}
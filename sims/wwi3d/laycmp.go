@@ -0,0 +1,269 @@
+// Copyright (c) 2020, The CCNLab Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"log"
+	"math"
+	"sort"
+
+	"github.com/emer/etable/etable"
+	"github.com/emer/etable/etensor"
+	"github.com/emer/etable/metric"
+	"github.com/emer/etable/simat"
+)
+
+// LayCmp holds a full layer-by-layer representational comparison,
+// with rows and columns labeled by layer name.
+type LayCmp struct {
+	RDMCor *simat.SimMat `desc:"correlation between object similarity matricies (RDMs) of each pair of layers"`
+	LinCKA *simat.SimMat `desc:"linear centered kernel alignment (CKA) between activations of each pair of layers"`
+	RBFCKA *simat.SimMat `desc:"RBF-kernel centered kernel alignment (CKA) between activations of each pair of layers"`
+}
+
+// Init initializes the sim mats, with given row and col layer names
+func (lc *LayCmp) Init(rows, cols []string) {
+	if lc.RDMCor == nil {
+		lc.RDMCor = &simat.SimMat{}
+		lc.LinCKA = &simat.SimMat{}
+		lc.RBFCKA = &simat.SimMat{}
+	}
+	for _, sm := range []*simat.SimMat{lc.RDMCor, lc.LinCKA, lc.RBFCKA} {
+		sm.Init()
+		smat := sm.Mat.(*etensor.Float64)
+		smat.SetShape([]int{len(rows), len(cols)}, nil, nil)
+		smat.SetMetaData("max", "1")
+		smat.SetMetaData("min", "0")
+		smat.SetMetaData("colormap", "Viridis")
+		smat.SetMetaData("grid-fill", "1")
+		sm.Rows = rows
+		sm.Cols = cols
+	}
+	lc.RDMCor.Mat.SetMetaData("min", "-1")
+}
+
+// LayReps holds the representations of one layer used in the LayCmp comparisons
+type LayReps struct {
+	RDM []float64 `desc:"n x n InvCorrelation distance matrix between objects"`
+	Lin []float64 `desc:"n x n centered linear kernel (gram) matrix"`
+	RBF []float64 `desc:"n x n centered RBF kernel matrix"`
+}
+
+// LayRepsFmActs computes the LayReps for given column (layer) at given tick
+// in acts table, using given RBF sigma as a multiple of median distance.
+// returns nil if there are no rows at that tick.
+func LayRepsFmActs(acts *etable.Table, colnm string, tick int, sigma float64) *LayReps {
+	return LayRepsFmVecs(TickActs(acts, colnm, tick), sigma)
+}
+
+// LayRepsFmVecs computes the LayReps for given activation vectors (one per object),
+// using given RBF sigma as a multiple of median distance.  vecs are modified.
+// returns nil if there are no vectors.
+func LayRepsFmVecs(vecs [][]float64, sigma float64) *LayReps {
+	n := len(vecs)
+	if n == 0 {
+		return nil
+	}
+	lr := &LayReps{}
+	nv := make([][]float64, n)
+	for i, v := range vecs {
+		nv[i] = append([]float64(nil), v...)
+	}
+	NormVecs(nv)
+	lr.RDM = CrossTickDists(nv, nv)
+
+	// center each unit (column) across objects
+	nu := len(vecs[0])
+	for ui := 0; ui < nu; ui++ {
+		mean := 0.0
+		for _, v := range vecs {
+			mean += v[ui]
+		}
+		mean /= float64(n)
+		for _, v := range vecs {
+			v[ui] -= mean
+		}
+	}
+	lr.Lin = make([]float64, n*n)
+	d2 := make([]float64, n*n)
+	for i := 0; i < n; i++ {
+		for j := i; j < n; j++ {
+			dp := 0.0
+			ds := 0.0
+			for ui, a := range vecs[i] {
+				b := vecs[j][ui]
+				dp += a * b
+				ds += (a - b) * (a - b)
+			}
+			lr.Lin[i*n+j] = dp
+			lr.Lin[j*n+i] = dp
+			d2[i*n+j] = ds
+			d2[j*n+i] = ds
+		}
+	}
+	var dists []float64
+	for i := 0; i < n; i++ {
+		for j := i + 1; j < n; j++ {
+			dists = append(dists, math.Sqrt(d2[i*n+j]))
+		}
+	}
+	med := 0.0
+	if len(dists) > 0 {
+		sort.Float64s(dists)
+		med = dists[len(dists)/2]
+	}
+	sig := sigma * med
+	lr.RBF = make([]float64, n*n)
+	for i, ds := range d2 {
+		if sig > 0 {
+			lr.RBF[i] = math.Exp(-ds / (2 * sig * sig))
+		} else if ds == 0 {
+			lr.RBF[i] = 1
+		}
+	}
+	CenterGram(lr.Lin, n)
+	CenterGram(lr.RBF, n)
+	return lr
+}
+
+// CenterGram double-centers the n x n gram matrix k in place: H k H
+// where H = I - 1/n
+func CenterGram(k []float64, n int) {
+	rm := make([]float64, n)
+	tm := 0.0
+	for i := 0; i < n; i++ {
+		for j := 0; j < n; j++ {
+			rm[i] += k[i*n+j]
+		}
+		tm += rm[i]
+		rm[i] /= float64(n)
+	}
+	tm /= float64(n * n)
+	for i := 0; i < n; i++ {
+		for j := 0; j < n; j++ {
+			k[i*n+j] += tm - rm[i] - rm[j]
+		}
+	}
+}
+
+// GramAlign returns the normalized Frobenius inner product between two
+// centered gram matricies, which is the CKA similarity
+func GramAlign(a, b []float64) float64 {
+	ab := 0.0
+	aa := 0.0
+	bb := 0.0
+	for i, av := range a {
+		bv := b[i]
+		ab += av * bv
+		aa += av * av
+		bb += bv * bv
+	}
+	nrm := math.Sqrt(aa * bb)
+	if nrm == 0 {
+		return 0
+	}
+	return ab / nrm
+}
+
+// LaysInActs returns those of given layer names that have a column in acts table
+func LaysInActs(acts *etable.Table, lays []string) []string {
+	var has []string
+	for _, lnm := range lays {
+		if acts.ColIdx(lnm) >= 0 {
+			has = append(has, lnm)
+		}
+	}
+	return has
+}
+
+// ActsObjs returns the Cat/Obj names for the rows at given tick in acts table
+func ActsObjs(acts *etable.Table, tick int) []string {
+	var nms []string
+	for row := 0; row < acts.Rows; row++ {
+		if int(acts.CellFloat("Tick", row)) != tick {
+			continue
+		}
+		nms = append(nms, acts.CellString("Cat", row)+"/"+acts.CellString("Obj", row))
+	}
+	return nms
+}
+
+// SameObjs returns true if the Cat, Obj rows at given tick match between two acts tables,
+// so that they can be directly compared
+func SameObjs(aacts, bacts *etable.Table, tick int) bool {
+	an := ActsObjs(aacts, tick)
+	bn := ActsObjs(bacts, tick)
+	if len(an) != len(bn) {
+		return false
+	}
+	for i := range an {
+		if an[i] != bn[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// LayCmpFmActs computes the layer-by-layer comparison of given layers (rows) in aacts
+// against given layers (cols) in bacts, at the RSA Tick.  aacts and bacts can be the
+// same table, or tables recorded from different models (e.g., wwi3d vs. wwi3d_axon)
+// using the same objects.
+func (rs *RSA) LayCmpFmActs(lc *LayCmp, aacts *etable.Table, alays []string, bacts *etable.Table, blays []string) {
+	if !SameObjs(aacts, bacts, rs.Tick) {
+		log.Println("LayCmpFmActs: the two acts tables do not have the same objects -- cannot compare")
+		return
+	}
+	alays = LaysInActs(aacts, alays)
+	blays = LaysInActs(bacts, blays)
+	lc.Init(alays, blays)
+	areps := make([]*LayReps, len(alays))
+	for i, lnm := range alays {
+		areps[i] = LayRepsFmActs(aacts, lnm, rs.Tick, rs.RBFSigma)
+	}
+	breps := areps
+	if aacts != bacts {
+		breps = make([]*LayReps, len(blays))
+		for i, lnm := range blays {
+			breps[i] = LayRepsFmActs(bacts, lnm, rs.Tick, rs.RBFSigma)
+		}
+	}
+	rdm := lc.RDMCor.Mat.(*etensor.Float64)
+	lin := lc.LinCKA.Mat.(*etensor.Float64)
+	rbf := lc.RBFCKA.Mat.(*etensor.Float64)
+	nb := len(blays)
+	for ai, ar := range areps {
+		for bi, br := range breps {
+			if ar == nil || br == nil {
+				continue
+			}
+			idx := ai*nb + bi
+			rdm.Values[idx] = metric.Correlation64(ar.RDM, br.RDM)
+			lin.Values[idx] = GramAlign(ar.Lin, br.Lin)
+			rbf.Values[idx] = GramAlign(ar.RBF, br.RBF)
+		}
+	}
+}
+
+// SimMatTable returns a simat-style table for given sim mat, with a Lay column
+// for the row labels and a column for each of the column labels
+func SimMatTable(sm *simat.SimMat) *etable.Table {
+	sch := etable.Schema{
+		{"Lay", etensor.STRING, nil, nil},
+	}
+	for _, cn := range sm.Cols {
+		sch = append(sch, etable.Column{cn, etensor.FLOAT64, nil, nil})
+	}
+	dt := &etable.Table{}
+	dt.SetFromSchema(sch, len(sm.Rows))
+	smat := sm.Mat.(*etensor.Float64)
+	nc := len(sm.Cols)
+	for ri, rn := range sm.Rows {
+		dt.SetCellString("Lay", ri, rn)
+		for ci := range sm.Cols {
+			dt.Cols[ci+1].SetFloat1D(ri, smat.Values[ri*nc+ci])
+		}
+	}
+	return dt
+}
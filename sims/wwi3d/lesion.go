@@ -0,0 +1,90 @@
+// Copyright (c) 2020, The CCNLab Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"strings"
+)
+
+// Lesion is one lesion of the network, applied at runtime to trained weights:
+// layers turned off, projections scaled (0 = removed), and / or a random
+// proportion of the units in layers silenced.  See Sim.Lesion and UnLesion.
+type Lesion struct {
+	Name  string   `desc:"name of the lesion, for the lesion log"`
+	Lays  []string `desc:"layers to turn off entirely"`
+	Prjns []string `desc:"projections to scale, by their standard names (e.g., V4ToTEO)"`
+	Scale float32  `desc:"multiplier on the absolute scaling of the Prjns input -- 0 = zero them"`
+	Units []string `desc:"layers in which to silence Prop of the units"`
+	Prop  float32  `desc:"proportion (0-1) of the units to silence in each of the Units layers, chosen at random"`
+}
+
+// String returns a summary of the lesion
+func (ls *Lesion) String() string {
+	var strs []string
+	if len(ls.Lays) > 0 {
+		strs = append(strs, "off: "+strings.Join(ls.Lays, ","))
+	}
+	if len(ls.Prjns) > 0 {
+		strs = append(strs, fmt.Sprintf("prjns: %s * %g", strings.Join(ls.Prjns, ","), ls.Scale))
+	}
+	if len(ls.Units) > 0 {
+		strs = append(strs, fmt.Sprintf("units: %s %g", strings.Join(ls.Units, ","), ls.Prop))
+	}
+	return ls.Name + ": " + strings.Join(strs, " ")
+}
+
+// Validate returns an error if the lesion is not well specified
+func (ls *Lesion) Validate() error {
+	if ls.Name == "" {
+		return fmt.Errorf("Lesion needs a Name")
+	}
+	if len(ls.Lays) == 0 && len(ls.Prjns) == 0 && len(ls.Units) == 0 {
+		return fmt.Errorf("Lesion %s: needs Lays, Prjns or Units", ls.Name)
+	}
+	if ls.Scale < 0 {
+		return fmt.Errorf("Lesion %s: Scale must be >= 0", ls.Name)
+	}
+	if ls.Prop < 0 || ls.Prop > 1 {
+		return fmt.Errorf("Lesion %s: Prop must be a proportion 0-1", ls.Name)
+	}
+	return nil
+}
+
+// LesionState records what the current lesion changed, so UnLesion can undo it
+type LesionState struct {
+	Cur   *Lesion            `desc:"current lesion, nil if intact"`
+	Off   []string           `desc:"layers turned off by the lesion -- not those that were already off"`
+	Abs   map[string]float32 `desc:"original absolute scaling of the scaled projections, by name"`
+	Units []string           `desc:"layers with silenced units"`
+}
+
+// Reset resets the state to intact
+func (st *LesionState) Reset() {
+	st.Cur = nil
+	st.Off = nil
+	st.Abs = nil
+	st.Units = nil
+}
+
+// OpenLesionsJSON opens a list of lesions from a JSON file, and validates them
+func OpenLesionsJSON(fname string) ([]Lesion, error) {
+	b, err := ioutil.ReadFile(fname)
+	if err != nil {
+		return nil, err
+	}
+	var lss []Lesion
+	if err := json.Unmarshal(b, &lss); err != nil {
+		return nil, fmt.Errorf("Lesions %s: %v", fname, err)
+	}
+	for i := range lss {
+		if err := lss[i].Validate(); err != nil {
+			return nil, err
+		}
+	}
+	return lss, nil
+}
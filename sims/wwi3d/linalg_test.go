@@ -0,0 +1,170 @@
+// Copyright (c) 2020, The CCNLab Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+// randOrtho returns a random n x n orthonormal matrix
+func randOrtho(rnd *rand.Rand, n int) []float64 {
+	q := make([]float64, n*n)
+	for i := range q {
+		q[i] = rnd.NormFloat64()
+	}
+	Orthonormalize(q, n, n)
+	return q
+}
+
+// symFmEigen returns the symmetric n x n matrix q diag(vals) q^T
+func symFmEigen(q []float64, n int, vals []float64) []float64 {
+	a := make([]float64, n*n)
+	for i := 0; i < n; i++ {
+		for j := 0; j < n; j++ {
+			s := 0.0
+			for k, v := range vals {
+				s += q[i*n+k] * v * q[j*n+k]
+			}
+			a[i*n+j] = s
+		}
+	}
+	return a
+}
+
+// checkOrtho reports an error if the k columns of n x k matrix q are not orthonormal
+func checkOrtho(t *testing.T, nm string, q []float64, n, k int) {
+	t.Helper()
+	for c := 0; c < k; c++ {
+		for p := 0; p <= c; p++ {
+			dp := 0.0
+			for r := 0; r < n; r++ {
+				dp += q[r*k+c] * q[r*k+p]
+			}
+			want := 0.0
+			if p == c {
+				want = 1
+			}
+			if math.Abs(dp-want) > 1.0e-8 {
+				t.Errorf("%s: columns %d . %d = %g, want %g", nm, c, p, dp, want)
+				return
+			}
+		}
+	}
+}
+
+func TestCholSolve(t *testing.T) {
+	cases := []struct {
+		a, x []float64
+		n, m int
+	}{
+		{[]float64{4}, []float64{0.5}, 1, 1},
+		{[]float64{4, 2, 2, 3}, []float64{1, -1, 2, 0.5}, 2, 2},
+		{[]float64{6, 2, 1, 2, 5, 2, 1, 2, 4}, []float64{1, 2, 3}, 3, 1},
+	}
+	for ci, c := range cases {
+		b := make([]float64, c.n*c.m)
+		MatMul(c.a, c.n, c.n, c.x, c.m, b)
+		a := append([]float64(nil), c.a...)
+		if !CholSolve(a, c.n, b, c.m) {
+			t.Errorf("case %d: not positive definite", ci)
+			continue
+		}
+		for i, x := range c.x {
+			if math.Abs(b[i]-x) > 1.0e-10 {
+				t.Errorf("case %d: x[%d] = %g, want %g", ci, i, b[i], x)
+			}
+		}
+	}
+	if CholSolve([]float64{1, 2, 2, 1}, 2, []float64{1, 1}, 1) {
+		t.Errorf("indefinite matrix: solved")
+	}
+}
+
+func TestSymEigen(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	for _, n := range []int{1, 2, 5, 20} {
+		a := make([]float64, n*n)
+		for i := 0; i < n; i++ {
+			for j := 0; j <= i; j++ {
+				v := rnd.NormFloat64()
+				a[i*n+j] = v
+				a[j*n+i] = v
+			}
+		}
+		vals, vecs := SymEigen(a, n)
+		for i := 1; i < n; i++ {
+			if vals[i] > vals[i-1] {
+				t.Errorf("n %d: eigenvalues not in descending order: %v", n, vals)
+				break
+			}
+		}
+		checkOrtho(t, "SymEigen vecs", vecs, n, n)
+		ra := symFmEigen(vecs, n, vals)
+		for i := range a {
+			if math.Abs(ra[i]-a[i]) > 1.0e-8 {
+				t.Errorf("n %d: reconstructed a[%d] = %g, want %g", n, i, ra[i], a[i])
+				break
+			}
+		}
+	}
+}
+
+// TestTopEigen tests the subspace iteration path of TopEigen, for n > 200,
+// against the known spectrum and SymEigen
+func TestTopEigen(t *testing.T) {
+	n := 220
+	k := 5
+	rnd := rand.New(rand.NewSource(1))
+	q := randOrtho(rnd, n)
+	checkOrtho(t, "Orthonormalize", q, n, n)
+	evals := make([]float64, n)
+	for i := range evals {
+		evals[i] = 100 * math.Pow(0.8, float64(i))
+	}
+	a := symFmEigen(q, n, evals)
+	vals, vecs := TopEigen(a, n, k)
+	svals, svecs := SymEigen(a, n)
+	if len(vals) != k || len(vecs) != n*k {
+		t.Fatalf("TopEigen: %d vals, %d vecs, want %d, %d", len(vals), len(vecs), k, n*k)
+	}
+	checkOrtho(t, "TopEigen vecs", vecs, n, k)
+	for c := 0; c < k; c++ {
+		if math.Abs(vals[c]-evals[c]) > 1.0e-6*evals[0] || math.Abs(vals[c]-svals[c]) > 1.0e-6*evals[0] {
+			t.Errorf("eigenvalue %d: %g, SymEigen %g, want %g", c, vals[c], svals[c], evals[c])
+		}
+		dp := 0.0
+		for r := 0; r < n; r++ {
+			dp += vecs[r*k+c] * svecs[r*n+c]
+		}
+		if math.Abs(math.Abs(dp)-1) > 1.0e-6 {
+			t.Errorf("eigenvector %d: |dot| with SymEigen = %g, want 1", c, math.Abs(dp))
+		}
+	}
+}
+
+func TestOrthonormalize(t *testing.T) {
+	n, k := 6, 3
+	q := []float64{ // col 2 = col 0 + col 1, so is set to zero
+		1, 0, 1,
+		1, 1, 2,
+		0, 1, 1,
+		2, 0, 2,
+		0, 3, 3,
+		1, 1, 2,
+	}
+	Orthonormalize(q, n, k)
+	q2 := make([]float64, n*2)
+	for r := 0; r < n; r++ {
+		copy(q2[r*2:r*2+2], q[r*k:r*k+2])
+	}
+	checkOrtho(t, "Orthonormalize", q2, n, 2)
+	for r := 0; r < n; r++ {
+		if math.Abs(q[r*k+2]) > 1.0e-8 {
+			t.Errorf("dependent column not zeroed: row %d = %g", r, q[r*k+2])
+		}
+	}
+}
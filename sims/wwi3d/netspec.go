@@ -0,0 +1,200 @@
+// Copyright (c) 2020, The CCNLab Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"strconv"
+	"strings"
+
+	"github.com/emer/emergent/prjn"
+	"github.com/emer/emergent/relpos"
+)
+
+// NetSpec is a declarative specification of the network architecture: the layers,
+// with their types, shapes and classes, the projections between them, with named
+// patterns (e.g., the PoolTile patterns such as Prjn4x4Skp2), and the layer positions
+// and threads.  It is organized in parts that are built in order, so that e.g.,
+// just the LIP part can be built.  See Sim.ConfigNetSpec, and DefNetSpec for the default.
+type NetSpec struct {
+	Name  string    `desc:"name of the network"`
+	Parts []NetPart `desc:"parts of the network, built in order"`
+}
+
+// NetPart is one part of a NetSpec, e.g., the LIP dorsal path
+type NetPart struct {
+	Name    string         `desc:"name of the part"`
+	Desc    string         `desc:"description of the part"`
+	Lays    []LaySpec      `desc:"layers, added in order, with the projections made by the layer helpers for the Deep and SuperCT types"`
+	Prjns   []PrjnSpec     `desc:"projections, connected (or set) in order after all the Lays"`
+	Pos     []LayPos       `desc:"relative positions of the layers for the display, set in order"`
+	Threads map[string]int `desc:"thread to run each layer on, by layer name"`
+}
+
+// LaySpec specifies a layer, or a group of layers made by one of the deep layer
+// helpers: a superficial layer Name, with a deep CT layer NameCT and / or a TRC
+// pulvinar layer NameP, which all get the same Class.
+type LaySpec struct {
+	Name      string   `desc:"name of the layer -- for the Deep and SuperCT types, of the superficial layer"`
+	Type      string   `desc:"type of layer: Input, Hidden, Deep (super, CT and TRC pulvinar, e.g., AddDeep4D), SuperCT (super and CT), or TRC (pulvinar alone) -- the deep types supported depend on the sim"`
+	Shape     []int    `desc:"shape of the layer: 2D (Y, X) or 4D (pools Y, X, units Y, X) -- 4D for the deep types"`
+	PulvShape []int    `desc:"if set, shape of the TRC pulvinar of a Deep layer, if different from Shape"`
+	PulvName  string   `desc:"if set, name of the TRC pulvinar of a Deep layer, instead of NameP"`
+	Drivers   []string `desc:"driver layers of the TRC pulvinar of a Deep or TRC layer"`
+	Class     string   `desc:"class(es) of the layer(s), for params"`
+}
+
+// PrjnSpec specifies a projection, or modifies one made by a layer helper
+type PrjnSpec struct {
+	Send  string `desc:"name of the sending layer"`
+	Recv  string `desc:"name of the receiving layer"`
+	Pat   string `desc:"name of the pattern of connectivity: Full, OneToOne, PoolOneToOne, PoolSameUnit (without self connections), UnifRnd:pcon, or one of the PoolTile patterns of the sim (e.g., Prjn4x4Skp2) -- optional for Set"`
+	Type  string `desc:"type of projection: Forward, Back, Lateral, CTCtxt (ConnectCtxtToCT), Inhib (lateral inhibitory, where supported), or Set to set the Pat and / or Class of the existing projection from Send, e.g., as made by a layer helper"`
+	Class string `desc:"class(es) of the projection, for params"`
+}
+
+// LayPos is the relative position of a layer in the display
+type LayPos struct {
+	Lay string     `desc:"name of the layer"`
+	Rel relpos.Rel `desc:"position relative to another layer"`
+}
+
+// PrjnTypes are the valid PrjnSpec Types
+var PrjnTypes = []string{"Forward", "Back", "Lateral", "CTCtxt", "Inhib", "Set"}
+
+// String returns a summary of the projection
+func (ps *PrjnSpec) String() string {
+	return fmt.Sprintf("%s -> %s %s %s %s", ps.Send, ps.Recv, ps.Type, ps.Pat, ps.Class)
+}
+
+// Validate returns an error if the layer is not well specified
+func (ls *LaySpec) Validate() error {
+	if ls.Name == "" {
+		return fmt.Errorf("LaySpec needs a Name")
+	}
+	switch ls.Type {
+	case "Input", "Hidden":
+		if len(ls.Shape) != 2 && len(ls.Shape) != 4 {
+			return fmt.Errorf("LaySpec %s: Shape must be 2D or 4D", ls.Name)
+		}
+	case "Deep", "SuperCT", "TRC":
+		if len(ls.Shape) != 4 {
+			return fmt.Errorf("LaySpec %s: Shape must be 4D for Type %s", ls.Name, ls.Type)
+		}
+	default:
+		return fmt.Errorf("LaySpec %s: unknown Type: %s", ls.Name, ls.Type)
+	}
+	if len(ls.PulvShape) > 0 && len(ls.PulvShape) != 4 {
+		return fmt.Errorf("LaySpec %s: PulvShape must be 4D", ls.Name)
+	}
+	return nil
+}
+
+// Validate returns an error if the projection is not well specified
+func (ps *PrjnSpec) Validate() error {
+	if ps.Send == "" || ps.Recv == "" {
+		return fmt.Errorf("PrjnSpec %s: needs Send and Recv", ps)
+	}
+	for _, tp := range PrjnTypes {
+		if tp == ps.Type {
+			if ps.Pat == "" && tp != "Set" {
+				return fmt.Errorf("PrjnSpec %s: needs a Pat", ps)
+			}
+			return nil
+		}
+	}
+	return fmt.Errorf("PrjnSpec %s: Type must be one of: %s", ps, strings.Join(PrjnTypes, ", "))
+}
+
+// Validate returns an error for the first layer or projection that is not well specified
+func (ns *NetSpec) Validate() error {
+	for pi := range ns.Parts {
+		pt := &ns.Parts[pi]
+		for li := range pt.Lays {
+			if err := pt.Lays[li].Validate(); err != nil {
+				return fmt.Errorf("NetSpec part %s: %v", pt.Name, err)
+			}
+		}
+		for pj := range pt.Prjns {
+			if err := pt.Prjns[pj].Validate(); err != nil {
+				return fmt.Errorf("NetSpec part %s: %v", pt.Name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// OpenJSON opens the spec from a JSON file, and validates it
+func (ns *NetSpec) OpenJSON(fname string) error {
+	b, err := ioutil.ReadFile(fname)
+	if err != nil {
+		return err
+	}
+	nn := NetSpec{}
+	if err := json.Unmarshal(b, &nn); err != nil {
+		return fmt.Errorf("NetSpec %s: %v", fname, err)
+	}
+	if err := nn.Validate(); err != nil {
+		return err
+	}
+	*ns = nn
+	return nil
+}
+
+// SaveJSON saves the spec to a JSON file
+func (ns *NetSpec) SaveJSON(fname string) error {
+	b, err := json.MarshalIndent(ns, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(fname, b, 0644)
+}
+
+// NetSpecPats provides the patterns for the PrjnSpecs of a network by name: the
+// Named patterns of the sim, and the standard ones, made once for each name.
+type NetSpecPats struct {
+	Named map[string]prjn.Pattern `desc:"named patterns of the sim, e.g., the PoolTile patterns"`
+	Made  map[string]prjn.Pattern `desc:"standard patterns made so far, by name"`
+}
+
+// Pat returns the pattern of given name
+func (np *NetSpecPats) Pat(nm string) (prjn.Pattern, error) {
+	if pat, ok := np.Named[nm]; ok {
+		return pat, nil
+	}
+	if pat, ok := np.Made[nm]; ok {
+		return pat, nil
+	}
+	var pat prjn.Pattern
+	switch {
+	case nm == "Full":
+		pat = prjn.NewFull()
+	case nm == "OneToOne":
+		pat = prjn.NewOneToOne()
+	case nm == "PoolOneToOne":
+		pat = prjn.NewPoolOneToOne()
+	case nm == "PoolSameUnit":
+		sameu := prjn.NewPoolSameUnit()
+		sameu.SelfCon = false
+		pat = sameu
+	case strings.HasPrefix(nm, "UnifRnd:"):
+		pc, err := strconv.ParseFloat(strings.TrimPrefix(nm, "UnifRnd:"), 32)
+		if err != nil || pc <= 0 || pc > 1 {
+			return nil, fmt.Errorf("NetSpec pattern %s: pcon must be 0-1", nm)
+		}
+		rnd := prjn.NewUnifRnd()
+		rnd.PCon = float32(pc)
+		pat = rnd
+	default:
+		return nil, fmt.Errorf("NetSpec pattern %s not found", nm)
+	}
+	if np.Made == nil {
+		np.Made = make(map[string]prjn.Pattern)
+	}
+	np.Made[nm] = pat
+	return pat, nil
+}
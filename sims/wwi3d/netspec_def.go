@@ -0,0 +1,232 @@
+// Copyright (c) 2020, The CCNLab Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import "github.com/emer/emergent/relpos"
+
+// DefNetSpec is the default network architecture: the V1 and LIP dorsal path,
+// which is all that is built with LIPOnly, and the rest of the visual hierarchy.
+// The order of layers and projections determines the order of the network.
+var DefNetSpec = NetSpec{
+	Name: "WWI3D",
+	Parts: []NetPart{
+		{Name: "LIP", Desc: "V1 and LIP dorsal path",
+			Lays: []LaySpec{
+				{Name: "V1m", Type: "Input", Shape: []int{8, 8, 5, 4}, Class: "V1"},
+				{Name: "V1h", Type: "Input", Shape: []int{16, 16, 5, 4}, Class: "V1"},
+				{Name: "LIP", Type: "Deep", Shape: []int{8, 8, 4, 4}, PulvShape: []int{8, 8, 1, 1}, Drivers: []string{"MTPos"}, Class: "LIP"},
+				{Name: "MTPos", Type: "Hidden", Shape: []int{8, 8, 1, 1}, Class: "LIP"},
+				{Name: "EyePos", Type: "Input", Shape: []int{21, 21}},
+				{Name: "SacPlan", Type: "Input", Shape: []int{11, 11}, Class: "PopIn"},
+				{Name: "Saccade", Type: "Input", Shape: []int{11, 11}, Class: "PopIn"},
+				{Name: "ObjVel", Type: "Input", Shape: []int{11, 11}, Class: "PopIn"},
+			},
+			Prjns: []PrjnSpec{
+				{Send: "V1m", Recv: "MTPos", Pat: "PoolOneToOne", Type: "Forward", Class: "Fixed"},
+				{Send: "MTPos", Recv: "LIP", Pat: "PoolOneToOne", Type: "Forward", Class: "Fixed"}, // has .5 wtscale in Params
+
+				{Send: "LIPCT", Recv: "LIPP", Pat: "Full", Type: "Set"},
+				{Send: "LIPP", Recv: "LIP", Type: "Set", Class: "FmPulv FmLIP"},
+				{Send: "LIPP", Recv: "LIPCT", Type: "Set", Class: "FmPulv FmLIP"},
+				{Send: "LIP", Recv: "LIPCT", Pat: "Prjn3x3Skp1", Type: "Set", Class: "CTCtxtStd"},
+
+				{Send: "EyePos", Recv: "LIP", Pat: "Full", Type: "Forward"},  // InitWts sets ss.PrjnGaussTopo
+				{Send: "SacPlan", Recv: "LIP", Pat: "Full", Type: "Forward"}, // InitWts sets ss.PrjnSigTopo
+				{Send: "ObjVel", Recv: "LIP", Pat: "Full", Type: "Forward"},  // InitWts sets ss.PrjnSigTopo
+
+				{Send: "EyePos", Recv: "LIPCT", Pat: "Full", Type: "Forward"},  // InitWts sets ss.PrjnGaussTopo
+				{Send: "Saccade", Recv: "LIPCT", Pat: "Full", Type: "Forward"}, // InitWts sets ss.PrjnSigTopo
+				{Send: "ObjVel", Recv: "LIPCT", Pat: "Full", Type: "Forward"},  // InitWts sets ss.PrjnSigTopo
+			},
+			Pos: []LayPos{
+				{"V1h", relpos.Rel{Rel: relpos.RightOf, Other: "V1m", YAlign: relpos.Front, Space: 2}},
+				{"LIP", relpos.Rel{Rel: relpos.Above, Other: "V1m", XAlign: relpos.Left, YAlign: relpos.Front}},
+				{"LIPCT", relpos.Rel{Rel: relpos.Behind, Other: "LIP", XAlign: relpos.Left, Space: 10}},
+				{"LIPP", relpos.Rel{Rel: relpos.Behind, Other: "LIPCT", XAlign: relpos.Left, Space: 10}},
+				{"MTPos", relpos.Rel{Rel: relpos.RightOf, Other: "LIPP", YAlign: relpos.Front, Space: 4}},
+				{"EyePos", relpos.Rel{Rel: relpos.RightOf, Other: "LIP", YAlign: relpos.Front, Space: 2}},
+				{"SacPlan", relpos.Rel{Rel: relpos.Behind, Other: "EyePos", XAlign: relpos.Left, Space: 10}},
+				{"Saccade", relpos.Rel{Rel: relpos.Behind, Other: "SacPlan", XAlign: relpos.Left, Space: 10}},
+				{"ObjVel", relpos.Rel{Rel: relpos.Behind, Other: "Saccade", XAlign: relpos.Left, Space: 10}},
+			},
+		},
+		{Name: "Rest", Desc: "ventral what pathway and dorsal V3, DP, integrated with LIP",
+			Lays: []LaySpec{
+				{Name: "V2", Type: "Deep", Shape: []int{8, 8, 10, 10}, PulvShape: []int{8, 8, 10, 4}, Drivers: []string{"V1m", "V1h"}, Class: "V2"},          // y 0..4 = v1m, 5..9 = v1h
+				{Name: "V3", Type: "Deep", Shape: []int{4, 4, 10, 10}, PulvShape: []int{4, 4, 4, 10}, Drivers: []string{"V1m", "V1h"}, Class: "V3"},          // y 0..1 = v1m, 2..3 = v1h, 4..13 = V2 -- todo: v2?
+				{Name: "DP", Type: "Deep", Shape: []int{1, 1, 10, 10}, PulvShape: []int{1, 1, 4, 10}, Drivers: []string{"V1m", "V1h"}, Class: "DP"},          // , should be "V3" -- orig had note about V3p->DP bad..
+				{Name: "V4", Type: "Deep", Shape: []int{4, 4, 10, 10}, PulvShape: []int{4, 4, 4, 10}, Drivers: []string{"V1m", "V1h"}, Class: "V4"},          // y 0..1 = v1m, 2..3 = v1h, 4..13 = V2 -- todo: v2?
+				{Name: "TEO", Type: "Deep", Shape: []int{4, 4, 10, 10}, PulvShape: []int{4, 4, 14, 10}, Drivers: []string{"V1m", "V1h", "V4"}, Class: "TEO"}, // 2x2 doesn't work with big V2 topo prjn; V4 def better clusters; has Layer.TRC.NoTopo in params
+				{Name: "TE", Type: "Deep", Shape: []int{2, 2, 10, 10}, PulvShape: []int{2, 2, 14, 10}, Drivers: []string{"V1m", "V1h", "V4"}, Class: "TE"},   // has Layer.TRC.NoTopo in params
+			},
+			Prjns: []PrjnSpec{
+				// basic super cons
+				{Send: "V1m", Recv: "V2", Pat: "Prjn3x3Skp1", Type: "Forward"}, // todo: uses V1V2 version of prjn?
+				{Send: "V1h", Recv: "V2", Pat: "Prjn4x4Skp2", Type: "Forward"}, // todo: uses V1V2 version of prjn?
+
+				{Send: "V2", Recv: "V4", Pat: "Prjn4x4Skp2", Type: "Forward"},
+				{Send: "V4", Recv: "V2", Pat: "Prjn4x4Skp2Recip", Type: "Back"},
+
+				{Send: "V2", Recv: "V3", Pat: "Prjn4x4Skp2", Type: "Forward"},
+				{Send: "V3", Recv: "V2", Pat: "Prjn4x4Skp2Recip", Type: "Back", Class: "BackMax"}, // this is critical!
+
+				{Send: "V3", Recv: "DP", Pat: "Full", Type: "Forward"},
+				{Send: "DP", Recv: "V3", Pat: "Full", Type: "Back", Class: "BackStrong"}, // likely key (in 233) -- retest
+
+				{Send: "V4", Recv: "TEO", Pat: "Prjn3x3Skp1", Type: "Forward"},                   // 3x3 > full
+				{Send: "TEO", Recv: "V4", Pat: "Prjn3x3Skp1", Type: "Back", Class: "BackStrong"}, // todo: test
+
+				{Send: "TEO", Recv: "TE", Pat: "Prjn4x4Skp2", Type: "Forward"}, // 4x4 > full
+				{Send: "TE", Recv: "TEO", Pat: "Prjn4x4Skp2Recip", Type: "Back"},
+
+				// non-basic cons
+
+				// to LIP -- weak from v2, v3
+				{Send: "V2", Recv: "LIP", Pat: "PoolOneToOne", Type: "Forward", Class: "FwdWeak"},
+				{Send: "V3", Recv: "LIP", Pat: "Prjn2x2Skp2Recip", Type: "Forward", Class: "FwdWeak"},
+
+				{Send: "V2CT", Recv: "LIPCT", Pat: "PoolOneToOne", Type: "Forward", Class: "FwdWeak"},
+				{Send: "V3CT", Recv: "LIPCT", Pat: "Prjn2x2Skp2Recip", Type: "Forward", Class: "FwdWeak"},
+
+				// to V2
+				{Send: "V2", Recv: "V2", Pat: "PoolSameUnit", Type: "Lateral"},
+
+				{Send: "V2CT", Recv: "V2CT", Pat: "Prjn3x3Skp1", Type: "CTCtxt", Class: "CTSelfLower"}, // was pone2one
+				{Send: "V2", Recv: "V2CT", Type: "Set", Class: "CTFmSuperLower"},
+
+				{Send: "LIP", Recv: "V2", Pat: "PoolOneToOne", Type: "Back", Class: "BackMax FmLIP"}, // key top-down attn .5 > .2
+				{Send: "TEOCT", Recv: "V2", Pat: "Prjn4x4Skp2Recip", Type: "Back"},                   // key! .1 def
+
+				// {Send: "TEO", Recv: "V2", Pat: "Prjn4x4Skp2Recip", Type: "Back"}, // too strong of top-down
+
+				{Send: "LIPCT", Recv: "V2CT", Pat: "PoolOneToOne", Type: "Back", Class: "CTBackMax FmLIP"},
+				{Send: "V3CT", Recv: "V2CT", Pat: "Prjn4x4Skp2Recip", Type: "Back", Class: "CTBackMax"},
+				{Send: "V4CT", Recv: "V2CT", Pat: "Prjn4x4Skp2Recip", Type: "Back", Class: "CTBackMax"},
+
+				// {Send: "TEOCT", Recv: "V2CT", Pat: "Prjn4x4Skp2Recip", Type: "Back", Class: "CTBackMax"}, // not beneficial
+
+				{Send: "V3", Recv: "V2CT", Pat: "Prjn2x2Skp2Recip", Type: "Back", Class: "SToCTMax"},  // s -> ct leak
+				{Send: "TEO", Recv: "V2CT", Pat: "Prjn4x4Skp2Recip", Type: "Back", Class: "SToCTMax"}, // s -> ct leak -- key @ max
+
+				// CTBack generically worse, generally important for cosdiff
+				{Send: "V3CT", Recv: "V2P", Pat: "Prjn4x4Skp2Recip", Type: "Back", Class: "BackToPulv"},
+				{Send: "V4CT", Recv: "V2P", Pat: "Prjn4x4Skp2Recip", Type: "Back", Class: "BackToPulv"}, // better without?  not clear
+
+				// to V3
+				{Send: "V3", Recv: "V3", Pat: "PoolSameUnit", Type: "Lateral"},
+
+				{Send: "V3CT", Recv: "V3CT", Pat: "Prjn3x3Skp1", Type: "CTCtxt", Class: "CTSelfLower"}, // was pone2one
+				{Send: "V3", Recv: "V3CT", Type: "Set", Class: "CTFmSuperLower"},
+
+				{Send: "V4", Recv: "V3", Pat: "Prjn3x3Skp1", Type: "Back", Class: "BackStrong"},
+				{Send: "LIP", Recv: "V3", Pat: "Prjn2x2Skp2", Type: "Back", Class: "FmLIP"},
+
+				{Send: "TEO", Recv: "V3", Pat: "Prjn3x3Skp1", Type: "Back"},
+				{Send: "TEOCT", Recv: "V3", Pat: "Prjn3x3Skp1", Type: "Back"},
+
+				{Send: "LIPCT", Recv: "V3CT", Pat: "Prjn2x2Skp2", Type: "Back", Class: "CTBack FmLIP"},
+				{Send: "DPCT", Recv: "V3CT", Pat: "Full", Type: "Back", Class: "CTBack"},
+				{Send: "V4CT", Recv: "V3CT", Pat: "Prjn3x3Skp1", Type: "Back", Class: "CTBack"},
+
+				// todo: retest again:
+				{Send: "DP", Recv: "V3CT", Pat: "Full", Type: "Back", Class: "SToCT"},
+				{Send: "V4", Recv: "V3CT", Pat: "Prjn3x3Skp1", Type: "Back", Class: "SToCT"}, // s -> ct, 3x3 ok
+
+				// {Send: "DPCT", Recv: "V3P", Pat: "Full", Type: "Back", Class: "BackToPulv"}, // not much effect on cosdiff
+				{Send: "V2CT", Recv: "V3P", Pat: "Prjn4x4Skp2", Type: "Forward", Class: "FwdToPulv"}, // has major effect on cosdiff
+
+				// to DP
+				{Send: "DPCT", Recv: "DPCT", Pat: "Full", Type: "CTCtxt", Class: "CTSelfLower"}, // not much effect, but consistent
+
+				// {Send: "V2", Recv: "DP", Pat: "Full", Type: "Forward"}, // no effect, expensive
+
+				{Send: "TEO", Recv: "DP", Pat: "Full", Type: "Back"}, // todo: test again
+
+				{Send: "TEOCT", Recv: "DPCT", Pat: "Full", Type: "Back", Class: "CTBack"},
+
+				// to V4
+				{Send: "V4", Recv: "V4", Pat: "PoolSameUnit", Type: "Lateral"},
+
+				{Send: "V4CT", Recv: "V4CT", Pat: "Prjn3x3Skp1", Type: "CTCtxt", Class: "CTSelfLower"}, // was pone2one
+
+				// {Send: "TEOCT", Recv: "V4", Pat: "Prjn3x3Skp1", Type: "Back", Class: "CTBack"}, // very not beneficial
+
+				// Prjn4x4Skp2Recip is same as full, but has topo scales -- better than full
+				{Send: "TE", Recv: "V4", Pat: "Prjn4x4Skp2Recip", Type: "Back", Class: "BackStrong"},
+
+				{Send: "TEOCT", Recv: "V4CT", Pat: "Prjn3x3Skp1", Type: "Back", Class: "CTBack"},
+				{Send: "TEO", Recv: "V4CT", Pat: "Prjn3x3Skp1", Type: "Back", Class: "SToCT"}, // s -> ct -- important
+
+				// Prjn4x4Skp2Recip is same as full, but has topo scales -- better
+				{Send: "TECT", Recv: "V4CT", Pat: "Prjn4x4Skp2Recip", Type: "Back", Class: "CTBack"},
+
+				// {Send: "V2CT", Recv: "V4CT", Pat: "Prjn4x4Skp2", Type: "Forward", Class: "CTBack"}, // instead of direct to v2p -- not helpful
+
+				// {Send: "TEOCT", Recv: "V4P", Pat: "Prjn3x3Skp1", Type: "Back"}, // not much additional benefit for cosdiff
+
+				{Send: "V2CT", Recv: "V4P", Pat: "Prjn4x4Skp2", Type: "Forward", Class: "FwdToPulv"}, // has major effect on cosdiff
+
+				// to TEO
+
+				// {Send: "TEO", Recv: "TEO", Pat: "PoolSameUnit", Type: "Lateral"},
+
+				{Send: "TEOCT", Recv: "TEOCT", Pat: "PoolOneToOne", Type: "CTCtxt", Class: "CTSelfHigher"}, // pone2one similar to 3x3 -- bit better
+
+				{Send: "TECT", Recv: "TEOCT", Pat: "Prjn4x4Skp2Recip", Type: "Back", Class: "CTBack"}, // CTBack > not
+
+				{Send: "V4CT", Recv: "TEOCT", Pat: "Full", Type: "Forward", Class: "CTBack"}, // instead of direct to v2p
+
+				// todo: test topo on both
+				// {Send: "V4CT", Recv: "TEOP", Pat: "Full", Type: "Forward", Class: "FwdToPulv"}, // sig effect on TEOP cosdiff, but improves TEP
+				{Send: "TECT", Recv: "TEOP", Pat: "Full", Type: "Back", Class: "BackToPulv"}, // no effect on cosdiff, but better Cat without
+
+				// to TE
+
+				// {Send: "TE", Recv: "TE", Pat: "PoolSameUnit", Type: "Lateral"},
+
+				{Send: "TECT", Recv: "TECT", Pat: "PoolOneToOne", Type: "CTCtxt", Class: "CTSelfHigher"}, // pone2one > full
+
+				{Send: "TEOCT", Recv: "TECT", Pat: "Prjn4x4Skp2", Type: "Forward", Class: "CTBack"}, // was FwdWeak
+
+				{Send: "TEOCT", Recv: "TEP", Pat: "Full", Type: "Back", Class: "FwdToPulv"}, // sig effect on cosdiff, not much other eff
+			},
+			Pos: []LayPos{
+				{"V2", relpos.Rel{Rel: relpos.Above, Other: "V1m", XAlign: relpos.Left, YAlign: relpos.Front}},
+				{"LIP", relpos.Rel{Rel: relpos.Above, Other: "V2", XAlign: relpos.Left, YAlign: relpos.Front}},
+				{"V2P", relpos.Rel{Rel: relpos.Behind, Other: "V1m", XAlign: relpos.Left, Space: 10}},
+				{"V2CT", relpos.Rel{Rel: relpos.Behind, Other: "V2", XAlign: relpos.Left, Space: 10}},
+
+				{"V3", relpos.Rel{Rel: relpos.RightOf, Other: "V2", YAlign: relpos.Front, Space: 2}},
+				{"V3CT", relpos.Rel{Rel: relpos.Behind, Other: "V3", XAlign: relpos.Left, Space: 10}},
+				{"V3P", relpos.Rel{Rel: relpos.RightOf, Other: "V3CT", YAlign: relpos.Front, Space: 2}},
+
+				{"DP", relpos.Rel{Rel: relpos.RightOf, Other: "V3", YAlign: relpos.Front, Space: 2}},
+				{"DPCT", relpos.Rel{Rel: relpos.Behind, Other: "DP", XAlign: relpos.Left, Space: 10}},
+				{"DPP", relpos.Rel{Rel: relpos.RightOf, Other: "DPCT", YAlign: relpos.Front, Space: 2}},
+
+				{"V4", relpos.Rel{Rel: relpos.Behind, Other: "V3CT", XAlign: relpos.Left, Space: 10}},
+				{"V4CT", relpos.Rel{Rel: relpos.Behind, Other: "V4", XAlign: relpos.Left, Space: 10}},
+				{"V4P", relpos.Rel{Rel: relpos.RightOf, Other: "V4CT", YAlign: relpos.Back, Space: 2}},
+
+				{"TEO", relpos.Rel{Rel: relpos.RightOf, Other: "EyePos", YAlign: relpos.Front, Space: 2}},
+				{"TEOCT", relpos.Rel{Rel: relpos.Behind, Other: "TEO", XAlign: relpos.Left, Space: 10}},
+				{"TEOP", relpos.Rel{Rel: relpos.Behind, Other: "TEOCT", XAlign: relpos.Left, Space: 10}},
+
+				{"TE", relpos.Rel{Rel: relpos.RightOf, Other: "TEO", YAlign: relpos.Front, Space: 2}},
+				{"TECT", relpos.Rel{Rel: relpos.Behind, Other: "TE", XAlign: relpos.Left, Space: 10}},
+				{"TEP", relpos.Rel{Rel: relpos.Behind, Other: "TECT", XAlign: relpos.Left, Space: 10}},
+			},
+			// 2 threads = only slight advantage over 1 thread -- 4 threads = about 500 msec / trl @8 mpi
+			Threads: map[string]int{
+				"V2": 0, "V2CT": 0, "V2P": 0,
+				"DP": 0, "DPCT": 0, "DPP": 0,
+				"V3": 1, "V3CT": 0, "V3P": 1,
+				"V4": 1, "V4CT": 1, "V4P": 1,
+				"TEO": 1, "TEOCT": 0, "TEOP": 0, // TEO 23 M -- by far biggest, TEOCT 19 M
+				"TE": 1, "TECT": 0, "TEP": 0,
+			},
+		},
+	},
+}
@@ -0,0 +1,413 @@
+// Copyright (c) 2020, The CCNLab Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/emer/emergent/emer"
+	"github.com/emer/emergent/prjn"
+	"github.com/emer/emergent/relpos"
+	"github.com/emer/leabra/deep"
+)
+
+// netDesc returns a description of the layers and projections of the network
+// that matter for params and running: classes, patterns, positions and threads
+func netDesc(net *deep.Network) string {
+	var b strings.Builder
+	for _, ly := range net.Layers {
+		fmt.Fprintf(&b, "%s: %s %s %v thr: %d\n", ly.Name(), ly.Type(), ly.Class(), ly.RelPos(), ly.Thread())
+		for _, pj := range *ly.RecvPrjns() {
+			fmt.Fprintf(&b, "\t%s: %s %s %s\n", pj.SendLay().Name(), pj.Type(), pj.Class(), pj.Pattern().Name())
+		}
+	}
+	return b.String()
+}
+
+// TestNetSpecSizeReport tests that DefNetSpec builds the same network as the
+// hardcoded ConfigNetLIP and ConfigNetRest that it replaced
+func TestNetSpecSizeReport(t *testing.T) {
+	for _, lipOnly := range []bool{true, false} {
+		ss := &Sim{}
+		ss.New()
+		ss.LIPOnly = lipOnly
+
+		ref := &deep.Network{}
+		ref.InitName(ref, "WWI3D")
+		configNetLIPCode(ss, ref)
+		if !lipOnly {
+			configNetRestCode(ss, ref)
+		}
+		if err := ref.Build(); err != nil {
+			t.Fatal(err)
+		}
+
+		net := &deep.Network{}
+		net.InitName(net, DefNetSpec.Name)
+		if err := ss.ConfigNetSpec(net, &DefNetSpec); err != nil {
+			t.Fatal(err)
+		}
+		if err := net.Build(); err != nil {
+			t.Fatal(err)
+		}
+
+		if sr, rsr := net.SizeReport(), ref.SizeReport(); sr != rsr {
+			t.Errorf("LIPOnly: %v: NetSpec SizeReport:\n%s\nhardcoded:\n%s", lipOnly, sr, rsr)
+		}
+		if nd, rnd := netDesc(net), netDesc(ref); nd != rnd {
+			t.Errorf("LIPOnly: %v: NetSpec network:\n%s\nhardcoded:\n%s", lipOnly, nd, rnd)
+		}
+	}
+}
+
+func TestNetSpecJSON(t *testing.T) {
+	fnm := filepath.Join(t.TempDir(), "netspec.json")
+	if err := DefNetSpec.SaveJSON(fnm); err != nil {
+		t.Fatal(err)
+	}
+	ns := NetSpec{}
+	if err := ns.OpenJSON(fnm); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(ns, DefNetSpec) {
+		t.Errorf("NetSpec JSON round trip differs from DefNetSpec")
+	}
+}
+
+////////////////////////////////////////////////////////////////////////////////
+//  Hardcoded network config, as it was before NetSpec
+
+// configNetLIPCode configures the V1 and LIP dorsal path part as ConfigNetLIP did
+func configNetLIPCode(ss *Sim, net *deep.Network) {
+	v1m := net.AddLayer4D("V1m", 8, 8, 5, 4, emer.Input)
+	v1h := net.AddLayer4D("V1h", 16, 16, 5, 4, emer.Input)
+
+	lip, lipct, lipp := net.AddDeep4D("LIP", 8, 8, 4, 4)
+	lipp.Shape().SetShape([]int{8, 8, 1, 1}, nil, nil)
+
+	mtpos := net.AddLayer4D("MTPos", 8, 8, 1, 1, emer.Hidden)
+
+	lipp.(*deep.TRCLayer).Drivers.Add("MTPos")
+
+	eyepos := net.AddLayer2D("EyePos", 21, 21, emer.Input)
+	sacplan := net.AddLayer2D("SacPlan", 11, 11, emer.Input)
+	sac := net.AddLayer2D("Saccade", 11, 11, emer.Input)
+	objvel := net.AddLayer2D("ObjVel", 11, 11, emer.Input)
+
+	v1m.SetClass("V1")
+	v1h.SetClass("V1")
+
+	mtpos.SetClass("LIP")
+	lip.SetClass("LIP")
+	lipct.SetClass("LIP")
+	lipp.SetClass("LIP")
+	sacplan.SetClass("PopIn")
+	sac.SetClass("PopIn")
+	objvel.SetClass("PopIn")
+
+	v1h.SetRelPos(relpos.Rel{Rel: relpos.RightOf, Other: v1m.Name(), YAlign: relpos.Front, Space: 2})
+	lip.SetRelPos(relpos.Rel{Rel: relpos.Above, Other: v1m.Name(), XAlign: relpos.Left, YAlign: relpos.Front})
+	lipct.SetRelPos(relpos.Rel{Rel: relpos.Behind, Other: lip.Name(), XAlign: relpos.Left, Space: 10})
+	lipp.SetRelPos(relpos.Rel{Rel: relpos.Behind, Other: lipct.Name(), XAlign: relpos.Left, Space: 10})
+	mtpos.SetRelPos(relpos.Rel{Rel: relpos.RightOf, Other: lipp.Name(), YAlign: relpos.Front, Space: 4})
+
+	eyepos.SetRelPos(relpos.Rel{Rel: relpos.RightOf, Other: lip.Name(), YAlign: relpos.Front, Space: 2})
+	sacplan.SetRelPos(relpos.Rel{Rel: relpos.Behind, Other: eyepos.Name(), XAlign: relpos.Left, Space: 10})
+	sac.SetRelPos(relpos.Rel{Rel: relpos.Behind, Other: sacplan.Name(), XAlign: relpos.Left, Space: 10})
+	objvel.SetRelPos(relpos.Rel{Rel: relpos.Behind, Other: sac.Name(), XAlign: relpos.Left, Space: 10})
+
+	full := prjn.NewFull()
+	pone2one := prjn.NewPoolOneToOne()
+
+	var pj emer.Prjn
+
+	net.ConnectLayers(v1m, mtpos, pone2one, emer.Forward).SetClass("Fixed")
+	net.ConnectLayers(mtpos, lip, pone2one, emer.Forward).SetClass("Fixed") // has .5 wtscale in Params
+
+	lipp.RecvPrjns().SendName("LIPCT").SetPattern(full)
+	lip.RecvPrjns().SendName("LIPP").SetClass("FmPulv FmLIP")
+	lipct.RecvPrjns().SendName("LIPP").SetClass("FmPulv FmLIP")
+	lipct.RecvPrjns().SendName("LIP").SetClass("CTCtxtStd")
+
+	net.ConnectLayers(eyepos, lip, full, emer.Forward)  // InitWts sets ss.PrjnGaussTopo
+	net.ConnectLayers(sacplan, lip, full, emer.Forward) // InitWts sets ss.PrjnSigTopo
+	net.ConnectLayers(objvel, lip, full, emer.Forward)  // InitWts sets ss.PrjnSigTopo
+
+	pj = lipct.RecvPrjns().SendName("LIP")
+	pj.SetPattern(ss.Prjn3x3Skp1)
+
+	net.ConnectLayers(eyepos, lipct, full, emer.Forward) // InitWts sets ss.PrjnGaussTopo
+	net.ConnectLayers(sac, lipct, full, emer.Forward)    // InitWts sets ss.PrjnSigTopo
+	net.ConnectLayers(objvel, lipct, full, emer.Forward) // InitWts sets ss.PrjnSigTopo
+}
+
+// configNetRestCode configures the rest of the network as ConfigNetRest did
+func configNetRestCode(ss *Sim, net *deep.Network) {
+	// replace with AddDeep4DFakeCT to disable CT
+	v2, v2ct, v2p := net.AddDeep4D("V2", 8, 8, 10, 10)
+	v2p.Shape().SetShape([]int{8, 8, 10, 4}, nil, nil)
+	v2p.(*deep.TRCLayer).Drivers.Add("V1m", "V1h") // y 0..4 = v1m, 5..9 = v1h
+
+	v3, v3ct, v3p := net.AddDeep4D("V3", 4, 4, 10, 10)
+	v3p.Shape().SetShape([]int{4, 4, 4, 10}, nil, nil)
+	v3p.(*deep.TRCLayer).Drivers.Add("V1m", "V1h") // y 0..1 = v1m, 2..3 = v1h, 4..13 = V2 -- todo: v2?
+
+	dp, dpct, dpp := net.AddDeep4D("DP", 1, 1, 10, 10)
+	dpp.Shape().SetShape([]int{1, 1, 4, 10}, nil, nil)
+	dpp.(*deep.TRCLayer).Drivers.Add("V1m", "V1h") // , should be "V3" -- orig had note about V3p->DP bad..
+
+	v4, v4ct, v4p := net.AddDeep4D("V4", 4, 4, 10, 10)
+	v4p.Shape().SetShape([]int{4, 4, 4, 10}, nil, nil)
+	v4p.(*deep.TRCLayer).Drivers.Add("V1m", "V1h") // y 0..1 = v1m, 2..3 = v1h, 4..13 = V2 -- todo: v2?
+
+	teo, teoct, teop := net.AddDeep4D("TEO", 4, 4, 10, 10) // 2x2 doesn't work with big V2 topo prjn
+	teop.Shape().SetShape([]int{4, 4, 14, 10}, nil, nil)
+	teop.(*deep.TRCLayer).Drivers.Add("V1m", "V1h", "V4") // def better clusters with V4
+	// note: has Layer.TRC.NoTopo set to true in params by default
+
+	te, tect, tep := net.AddDeep4D("TE", 2, 2, 10, 10)
+	tep.Shape().SetShape([]int{2, 2, 14, 10}, nil, nil)
+	tep.(*deep.TRCLayer).Drivers.Add("V1m", "V1h", "V4")
+	// note: has Layer.TRC.NoTopo set to true in params by default
+
+	v2.SetClass("V2")
+	v2ct.SetClass("V2")
+	v2p.SetClass("V2")
+
+	v3.SetClass("V3")
+	v3ct.SetClass("V3")
+	v3p.SetClass("V3")
+
+	v4.SetClass("V4")
+	v4ct.SetClass("V4")
+	v4p.SetClass("V4")
+
+	dp.SetClass("DP")
+	dpct.SetClass("DP")
+	dpp.SetClass("DP")
+
+	teo.SetClass("TEO")
+	teoct.SetClass("TEO")
+	teop.SetClass("TEO")
+
+	te.SetClass("TE")
+	tect.SetClass("TE")
+	tep.SetClass("TE")
+
+	v1m := net.LayerByName("V1m")
+	v1h := net.LayerByName("V1h")
+	lip := net.LayerByName("LIP")
+	lipct := net.LayerByName("LIPCT")
+	eyepos := net.LayerByName("EyePos")
+
+	v2.SetRelPos(relpos.Rel{Rel: relpos.Above, Other: v1m.Name(), XAlign: relpos.Left, YAlign: relpos.Front})
+	lip.SetRelPos(relpos.Rel{Rel: relpos.Above, Other: v2.Name(), XAlign: relpos.Left, YAlign: relpos.Front})
+	v2p.SetRelPos(relpos.Rel{Rel: relpos.Behind, Other: v1m.Name(), XAlign: relpos.Left, Space: 10})
+	v2ct.SetRelPos(relpos.Rel{Rel: relpos.Behind, Other: v2.Name(), XAlign: relpos.Left, Space: 10})
+
+	v3.SetRelPos(relpos.Rel{Rel: relpos.RightOf, Other: v2.Name(), YAlign: relpos.Front, Space: 2})
+	v3ct.SetRelPos(relpos.Rel{Rel: relpos.Behind, Other: v3.Name(), XAlign: relpos.Left, Space: 10})
+	v3p.SetRelPos(relpos.Rel{Rel: relpos.RightOf, Other: v3ct.Name(), YAlign: relpos.Front, Space: 2})
+
+	dp.SetRelPos(relpos.Rel{Rel: relpos.RightOf, Other: v3.Name(), YAlign: relpos.Front, Space: 2})
+	dpct.SetRelPos(relpos.Rel{Rel: relpos.Behind, Other: dp.Name(), XAlign: relpos.Left, Space: 10})
+	dpp.SetRelPos(relpos.Rel{Rel: relpos.RightOf, Other: dpct.Name(), YAlign: relpos.Front, Space: 2})
+
+	v4.SetRelPos(relpos.Rel{Rel: relpos.Behind, Other: v3ct.Name(), XAlign: relpos.Left, Space: 10})
+	v4ct.SetRelPos(relpos.Rel{Rel: relpos.Behind, Other: v4.Name(), XAlign: relpos.Left, Space: 10})
+	v4p.SetRelPos(relpos.Rel{Rel: relpos.RightOf, Other: v4ct.Name(), YAlign: relpos.Back, Space: 2})
+
+	teo.SetRelPos(relpos.Rel{Rel: relpos.RightOf, Other: eyepos.Name(), YAlign: relpos.Front, Space: 2})
+	teoct.SetRelPos(relpos.Rel{Rel: relpos.Behind, Other: teo.Name(), XAlign: relpos.Left, Space: 10})
+	teop.SetRelPos(relpos.Rel{Rel: relpos.Behind, Other: teoct.Name(), XAlign: relpos.Left, Space: 10})
+
+	te.SetRelPos(relpos.Rel{Rel: relpos.RightOf, Other: teo.Name(), YAlign: relpos.Front, Space: 2})
+	tect.SetRelPos(relpos.Rel{Rel: relpos.Behind, Other: te.Name(), XAlign: relpos.Left, Space: 10})
+	tep.SetRelPos(relpos.Rel{Rel: relpos.Behind, Other: tect.Name(), XAlign: relpos.Left, Space: 10})
+
+	full := prjn.NewFull()
+	pone2one := prjn.NewPoolOneToOne()
+	one2one := prjn.NewOneToOne()
+	sameu := prjn.NewPoolSameUnit()
+	sameu.SelfCon = false
+	_ = one2one
+
+	// basic super cons
+	net.ConnectLayers(v1m, v2, ss.Prjn3x3Skp1, emer.Forward) // todo: uses V1V2 version of prjn?
+	net.ConnectLayers(v1h, v2, ss.Prjn4x4Skp2, emer.Forward) // todo: uses V1V2 version of prjn?
+
+	_, v4v2 := net.BidirConnectLayers(v2, v4, ss.Prjn4x4Skp2)
+	v4v2.SetPattern(ss.Prjn4x4Skp2Recip)
+
+	_, v3v2 := net.BidirConnectLayers(v2, v3, ss.Prjn4x4Skp2)
+	v3v2.SetClass("BackMax") // "BackMax") // this is critical!
+	v3v2.SetPattern(ss.Prjn4x4Skp2Recip)
+
+	_, dpv3 := net.BidirConnectLayers(v3, dp, full)
+	dpv3.SetClass("BackStrong") // likely key (in 233) -- retest
+
+	_, teov4 := net.BidirConnectLayers(v4, teo, ss.Prjn3x3Skp1) // 3x3 > full
+	teov4.SetClass("BackStrong")                                // todo: test
+
+	_, teteo := net.BidirConnectLayers(teo, te, ss.Prjn4x4Skp2) // 4x4 > full
+	teteo.SetPattern(ss.Prjn4x4Skp2Recip)
+
+	// non-basic cons
+
+	////////////////////
+	// to LIP -- weak from v2, v3
+
+	net.ConnectLayers(v2, lip, pone2one, emer.Forward).SetClass("FwdWeak")
+	net.ConnectLayers(v3, lip, ss.Prjn2x2Skp2Recip, emer.Forward).SetClass("FwdWeak")
+
+	net.ConnectLayers(v2ct, lipct, pone2one, emer.Forward).SetClass("FwdWeak")
+	net.ConnectLayers(v3ct, lipct, ss.Prjn2x2Skp2Recip, emer.Forward).SetClass("FwdWeak")
+
+	////////////////////
+	// to V2
+
+	net.ConnectLayers(v2, v2, sameu, emer.Lateral)
+
+	net.ConnectCtxtToCT(v2ct, v2ct, ss.Prjn3x3Skp1).SetClass("CTSelfLower") // was pone2one
+	v2ct.RecvPrjns().SendName(v2.Name()).SetClass("CTFmSuperLower")
+
+	net.ConnectLayers(lip, v2, pone2one, emer.Back).SetClass("BackMax FmLIP") // key top-down attn .5 > .2
+	net.ConnectLayers(teoct, v2, ss.Prjn4x4Skp2Recip, emer.Back)              // key! .1 def
+
+	// net.ConnectLayers(teo, v2, ss.Prjn4x4Skp2Recip, emer.Back) // too strong of top-down
+
+	net.ConnectLayers(lipct, v2ct, pone2one, emer.Back).SetClass("CTBackMax FmLIP")
+	net.ConnectLayers(v3ct, v2ct, ss.Prjn4x4Skp2Recip, emer.Back).SetClass("CTBackMax")
+	net.ConnectLayers(v4ct, v2ct, ss.Prjn4x4Skp2Recip, emer.Back).SetClass("CTBackMax")
+
+	// net.ConnectLayers(teoct, v2ct, ss.Prjn4x4Skp2Recip, emer.Back).SetClass("CTBackMax") // not beneficial
+
+	net.ConnectLayers(v3, v2ct, ss.Prjn2x2Skp2Recip, emer.Back).SetClass("SToCTMax")  // s -> ct leak
+	net.ConnectLayers(teo, v2ct, ss.Prjn4x4Skp2Recip, emer.Back).SetClass("SToCTMax") // s -> ct leak -- key @ max
+
+	// CTBack generically worse, generally important for cosdiff
+	net.ConnectLayers(v3ct, v2p, ss.Prjn4x4Skp2Recip, emer.Back).SetClass("BackToPulv")
+	net.ConnectLayers(v4ct, v2p, ss.Prjn4x4Skp2Recip, emer.Back).SetClass("BackToPulv") // better without?  not clear
+
+	////////////////////
+	// to V3
+
+	net.ConnectLayers(v3, v3, sameu, emer.Lateral)
+
+	net.ConnectCtxtToCT(v3ct, v3ct, ss.Prjn3x3Skp1).SetClass("CTSelfLower") // was pone2one
+	v3ct.RecvPrjns().SendName(v3.Name()).SetClass("CTFmSuperLower")
+
+	net.ConnectLayers(v4, v3, ss.Prjn3x3Skp1, emer.Back).SetClass("BackStrong")
+	net.ConnectLayers(lip, v3, ss.Prjn2x2Skp2, emer.Back).SetClass("FmLIP")
+
+	net.ConnectLayers(teo, v3, ss.Prjn3x3Skp1, emer.Back)
+	net.ConnectLayers(teoct, v3, ss.Prjn3x3Skp1, emer.Back)
+
+	net.ConnectLayers(lipct, v3ct, ss.Prjn2x2Skp2, emer.Back).SetClass("CTBack FmLIP")
+	net.ConnectLayers(dpct, v3ct, full, emer.Back).SetClass("CTBack")
+	net.ConnectLayers(v4ct, v3ct, ss.Prjn3x3Skp1, emer.Back).SetClass("CTBack")
+
+	// todo: retest again:
+	net.ConnectLayers(dp, v3ct, full, emer.Back).SetClass("SToCT")
+	net.ConnectLayers(v4, v3ct, ss.Prjn3x3Skp1, emer.Back).SetClass("SToCT") // s -> ct, 3x3 ok
+
+	// net.ConnectLayers(dpct, v3p, full, emer.Back).SetClass("BackToPulv") // not much effect on cosdiff
+	net.ConnectLayers(v2ct, v3p, ss.Prjn4x4Skp2, emer.Forward).SetClass("FwdToPulv") // has major effect on cosdiff
+
+	////////////////////
+	// to DP
+
+	net.ConnectCtxtToCT(dpct, dpct, full).SetClass("CTSelfLower") // not much effect, but consistent
+
+	// net.ConnectLayers(v2, dp, full, emer.Forward) // no effect, expensive
+
+	net.ConnectLayers(teo, dp, full, emer.Back) // todo: test again
+
+	net.ConnectLayers(teoct, dpct, full, emer.Back).SetClass("CTBack")
+
+	////////////////////
+	// to V4
+
+	net.ConnectLayers(v4, v4, sameu, emer.Lateral)
+
+	net.ConnectCtxtToCT(v4ct, v4ct, ss.Prjn3x3Skp1).SetClass("CTSelfLower") // was pone2one
+
+	// net.ConnectLayers(teoct, v4, ss.Prjn3x3Skp1, emer.Back).SetClass("CTBack") // very not beneficial
+
+	// Prjn4x4Skp2Recip is same as full, but has topo scales -- better than full
+	net.ConnectLayers(te, v4, ss.Prjn4x4Skp2Recip, emer.Back).SetClass("BackStrong")
+
+	net.ConnectLayers(teoct, v4ct, ss.Prjn3x3Skp1, emer.Back).SetClass("CTBack")
+	net.ConnectLayers(teo, v4ct, ss.Prjn3x3Skp1, emer.Back).SetClass("SToCT") // s -> ct -- important
+
+	// Prjn4x4Skp2Recip is same as full, but has topo scales -- better
+	net.ConnectLayers(tect, v4ct, ss.Prjn4x4Skp2Recip, emer.Back).SetClass("CTBack")
+
+	// net.ConnectLayers(v2ct, v4ct, ss.Prjn4x4Skp2, emer.Forward).SetClass("CTBack") // instead of direct to v2p -- not helpful
+
+	// net.ConnectLayers(teoct, v4p, ss.Prjn3x3Skp1, emer.Back) // not much additional benefit for cosdiff
+
+	net.ConnectLayers(v2ct, v4p, ss.Prjn4x4Skp2, emer.Forward).SetClass("FwdToPulv") // has major effect on cosdiff
+
+	////////////////////
+	// to TEO
+
+	// net.ConnectLayers(teo, teo, sameu, emer.Lateral)
+
+	net.ConnectCtxtToCT(teoct, teoct, pone2one).SetClass("CTSelfHigher") // pone2one similar to 3x3 -- bit better
+
+	net.ConnectLayers(tect, teoct, ss.Prjn4x4Skp2Recip, emer.Back).SetClass("CTBack") // CTBack > not
+
+	net.ConnectLayers(v4ct, teoct, full, emer.Forward).SetClass("CTBack") // instead of direct to v2p
+
+	// todo: test topo on both
+	// net.ConnectLayers(v4ct, teop, full, emer.Forward).SetClass("FwdToPulv") // sig effect on TEOP cosdiff, but improves TEP
+	net.ConnectLayers(tect, teop, full, emer.Back).SetClass("BackToPulv") // no effect on cosdiff, but better Cat without
+
+	////////////////////
+	// to TE
+
+	// net.ConnectLayers(te, te, sameu, emer.Lateral)
+
+	net.ConnectCtxtToCT(tect, tect, pone2one).SetClass("CTSelfHigher") // pone2one > full
+
+	net.ConnectLayers(teoct, tect, ss.Prjn4x4Skp2, emer.Forward).SetClass("CTBack") // was FwdWeak
+
+	net.ConnectLayers(teoct, tep, full, emer.Back).SetClass("FwdToPulv") // sig effect on cosdiff, not much other eff
+
+	////////////////////
+
+	//	2 threads = only slight advantage over 1 thread
+	v2.SetThread(0)
+	v2ct.SetThread(0)
+	v2p.SetThread(0)
+
+	dp.SetThread(0)
+	dpct.SetThread(0)
+	dpp.SetThread(0)
+
+	v3ct.SetThread(0)
+
+	v3p.SetThread(1)
+	v3.SetThread(1)
+
+	v4.SetThread(1)
+	v4ct.SetThread(1)
+	v4p.SetThread(1)
+
+	teo.SetThread(1) // 23 M -- by far biggest
+
+	teoct.SetThread(0) // 19 M
+	teop.SetThread(0)
+
+	te.SetThread(1)
+
+	tect.SetThread(0)
+	tep.SetThread(0)
+}
@@ -0,0 +1,113 @@
+// Copyright (c) 2020, The CCNLab Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestNpyParseHeader(t *testing.T) {
+	cases := []struct {
+		hdr   string
+		descr string
+		fort  bool
+		shape []int
+		err   bool
+	}{
+		{"{'descr': '<f4', 'fortran_order': False, 'shape': (156, 156), }", "<f4", false, []int{156, 156}, false},
+		{"{'descr': '>f8', 'fortran_order': True, 'shape': (2, 3, 4), }", ">f8", true, []int{2, 3, 4}, false},
+		{"{'descr': '|u1', 'fortran_order': False, 'shape': (7,), }", "|u1", false, []int{7}, false},
+		{"{'descr': '<i8', 'fortran_order': False, 'shape': (3L, 4L), }", "<i8", false, []int{3, 4}, false},
+		{"{'shape': (2, 2), 'fortran_order': True, 'descr': '<i4'}", "<i4", true, []int{2, 2}, false},
+		{"{'descr': '<f4', 'fortran_order': False, 'shape': (), }", "<f4", false, nil, false},
+		{"{'fortran_order': False, 'shape': (2,), }", "", false, nil, true},
+		{"{'descr': '<f4', 'fortran_order': False, }", "", false, nil, true},
+		{"{'descr': '<f4', 'fortran_order': False, 'shape': (2, x), }", "", false, nil, true},
+	}
+	for _, c := range cases {
+		descr, fort, shape, err := NpyParseHeader(c.hdr)
+		if c.err {
+			if err == nil {
+				t.Errorf("%s: no error", c.hdr)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %v", c.hdr, err)
+			continue
+		}
+		if descr != c.descr || fort != c.fort || !reflect.DeepEqual(shape, c.shape) {
+			t.Errorf("%s: %s %v %v, want %s %v %v", c.hdr, descr, fort, shape, c.descr, c.fort, c.shape)
+		}
+	}
+}
+
+func TestNpyFortranToC(t *testing.T) {
+	shape := []int{2, 3, 4}
+	n := 2 * 3 * 4
+	fv := make([]float64, n) // value = C index, stored in Fortran order
+	for i := 0; i < 2; i++ {
+		for j := 0; j < 3; j++ {
+			for k := 0; k < 4; k++ {
+				fv[i+2*j+6*k] = float64(i*12 + j*4 + k)
+			}
+		}
+	}
+	cv := NpyFortranToC(fv, shape)
+	for ci, v := range cv {
+		if v != float64(ci) {
+			t.Fatalf("C index %d: %g", ci, v)
+		}
+	}
+}
+
+// npyBytes returns a version 1.0 .npy file with given header and data
+func npyBytes(hdr string, data []byte) []byte {
+	var b bytes.Buffer
+	b.WriteString(NpyMagic)
+	b.Write([]byte{1, 0})
+	binary.Write(&b, binary.LittleEndian, uint16(len(hdr)))
+	b.WriteString(hdr)
+	b.Write(data)
+	return b.Bytes()
+}
+
+// TestReadNpyFortranBigEndian tests reading a big-endian, Fortran-ordered array
+func TestReadNpyFortranBigEndian(t *testing.T) {
+	// C order values 0..5 of a 2 x 3 array, in Fortran order: columns first
+	fv := []float64{0, 3, 1, 4, 2, 5}
+	var data bytes.Buffer
+	for _, v := range fv {
+		binary.Write(&data, binary.BigEndian, math.Float64bits(v))
+	}
+	b := npyBytes("{'descr': '>f8', 'fortran_order': True, 'shape': (2, 3), }\n", data.Bytes())
+	vals, shape, err := ReadNpy(bytes.NewReader(b))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(shape, []int{2, 3}) {
+		t.Errorf("shape: %v, want [2 3]", shape)
+	}
+	if !reflect.DeepEqual(vals, []float64{0, 1, 2, 3, 4, 5}) {
+		t.Errorf("vals: %v, want [0 1 2 3 4 5]", vals)
+	}
+
+	data.Reset()
+	for _, v := range []int16{-2, 300, 7} {
+		binary.Write(&data, binary.BigEndian, v)
+	}
+	b = npyBytes("{'descr': '>i2', 'fortran_order': False, 'shape': (3,), }\n", data.Bytes())
+	vals, _, err = ReadNpy(bytes.NewReader(b))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(vals, []float64{-2, 300, 7}) {
+		t.Errorf("big-endian int16 vals: %v, want [-2 300 7]", vals)
+	}
+}
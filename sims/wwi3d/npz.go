@@ -0,0 +1,208 @@
+// Copyright (c) 2020, The CCNLab Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"archive/zip"
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"os"
+	"strings"
+)
+
+// numpy .npz archives (zip files of .npy arrays) written by streaming the rows
+// of each array to a spill file, so that only a buffer of rows is in memory.
+
+// NpzExport has the parameters of the export of trial-level layer activations
+// and trial metadata into a .npz archive of .npy arrays per epoch.
+type NpzExport struct {
+	Lays       []string `desc:"layer variables to export, as layer[:var[:ctr]] specs as in RSALays, e.g., V4:ActM:ctr, TE:ActP -- each is a float32 array of trials x units, named as the RSALay Col"`
+	Interval   int      `desc:"export the training trials every this many epochs -- 0 = off"`
+	BlockRows  int      `def:"256" desc:"number of trial rows of an array gathered across MPI procs at a time -- bounds the memory used"`
+	ExportActs bool     `desc:"the export-acts subcommand saves the test trials in a tstacts .npz archive of these arrays, instead of a tsv log of the TstTrlRepLog"`
+}
+
+func (ne *NpzExport) Defaults() {
+	ne.Lays = []string{"V4:ActM:ctr", "TEO:ActM:ctr", "TE", "TE:ActP", "LIP"}
+	ne.BlockRows = 256
+}
+
+// NpyStream is a float32 (or int32) .npy array written row-by-row to a spill file,
+// with the number of rows only known at the end, when it is written to a .npz
+type NpyStream struct {
+	Name   string `desc:"name of the array in the .npz"`
+	Shape  []int  `desc:"shape of each row"`
+	Int    bool   `desc:"values are written as int32 instead of float32, e.g., for indexes"`
+	RowLen int    `desc:"number of values per row"`
+	Rows   int    `desc:"number of rows written so far"`
+
+	fp  *os.File
+	bw  *bufio.Writer
+	buf []byte
+}
+
+// NewNpyStream returns a new stream for the array of given name and row shape,
+// spilling to a temporary file in dir (the default temp dir if empty)
+func NewNpyStream(name, dir string, shape []int) (*NpyStream, error) {
+	fp, err := ioutil.TempFile(dir, "npy_"+name+"_")
+	if err != nil {
+		return nil, err
+	}
+	ns := &NpyStream{Name: name, Shape: shape, RowLen: 1, fp: fp}
+	for _, d := range shape {
+		ns.RowLen *= d
+	}
+	ns.bw = bufio.NewWriter(fp)
+	ns.buf = make([]byte, 4*ns.RowLen)
+	return ns, nil
+}
+
+// AddRow adds a row of values, which must have RowLen values
+func (ns *NpyStream) AddRow(vals []float32) error {
+	if len(vals) != ns.RowLen {
+		return fmt.Errorf("NpyStream %s: row has %d values, not %d", ns.Name, len(vals), ns.RowLen)
+	}
+	for i, v := range vals {
+		if ns.Int {
+			binary.LittleEndian.PutUint32(ns.buf[4*i:], uint32(int32(v)))
+		} else {
+			binary.LittleEndian.PutUint32(ns.buf[4*i:], math.Float32bits(v))
+		}
+	}
+	if _, err := ns.bw.Write(ns.buf); err != nil {
+		return err
+	}
+	ns.Rows++
+	return nil
+}
+
+// ReadRows reads the rows starting at row st into vals, which should have
+// a multiple of RowLen values -- returns the number of rows read
+func (ns *NpyStream) ReadRows(st int, vals []float32) (int, error) {
+	if err := ns.bw.Flush(); err != nil {
+		return 0, err
+	}
+	n := len(vals) / ns.RowLen
+	if st+n > ns.Rows {
+		n = ns.Rows - st
+	}
+	if n <= 0 {
+		return 0, nil
+	}
+	b := make([]byte, 4*n*ns.RowLen)
+	if _, err := ns.fp.ReadAt(b, int64(4*st*ns.RowLen)); err != nil {
+		return 0, err
+	}
+	for i := 0; i < n*ns.RowLen; i++ {
+		u := binary.LittleEndian.Uint32(b[4*i:])
+		if ns.Int {
+			vals[i] = float32(int32(u))
+		} else {
+			vals[i] = math.Float32frombits(u)
+		}
+	}
+	return n, nil
+}
+
+// WriteNpy writes the array in .npy format, of shape Rows x Shape, to w --
+// more rows can be added after
+func (ns *NpyStream) WriteNpy(w io.Writer) error {
+	if err := ns.bw.Flush(); err != nil {
+		return err
+	}
+	descr := "<f4"
+	if ns.Int {
+		descr = "<i4"
+	}
+	if err := WriteNpyHeader(w, descr, append([]int{ns.Rows}, ns.Shape...)); err != nil {
+		return err
+	}
+	if _, err := ns.fp.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	_, err := io.Copy(w, ns.fp)
+	if _, serr := ns.fp.Seek(0, io.SeekEnd); err == nil {
+		err = serr
+	}
+	return err
+}
+
+// Close closes and removes the spill file
+func (ns *NpyStream) Close() error {
+	if ns.fp == nil {
+		return nil
+	}
+	fnm := ns.fp.Name()
+	err := ns.fp.Close()
+	ns.fp = nil
+	os.Remove(fnm)
+	return err
+}
+
+// NpzWriter writes .npy arrays into a .npz (zip) archive
+type NpzWriter struct {
+	fp *os.File
+	zw *zip.Writer
+}
+
+// CreateNpz creates a .npz archive file
+func CreateNpz(fname string) (*NpzWriter, error) {
+	fp, err := os.Create(fname)
+	if err != nil {
+		return nil, err
+	}
+	return &NpzWriter{fp: fp, zw: zip.NewWriter(fp)}, nil
+}
+
+// create starts a new .npy entry of given array name
+func (nz *NpzWriter) create(name string) (io.Writer, error) {
+	return nz.zw.CreateHeader(&zip.FileHeader{Name: name + ".npy", Method: zip.Store})
+}
+
+// WriteStream writes the array of given stream to the archive
+func (nz *NpzWriter) WriteStream(ns *NpyStream) error {
+	w, err := nz.create(ns.Name)
+	if err != nil {
+		return err
+	}
+	return ns.WriteNpy(w)
+}
+
+// WriteStrings writes an array of strings to the archive, as fixed-width
+// byte strings (numpy |S dtype), e.g., for the labels of index arrays
+func (nz *NpzWriter) WriteStrings(name string, strs []string) error {
+	w, err := nz.create(name)
+	if err != nil {
+		return err
+	}
+	wd := 1
+	for _, s := range strs {
+		if len(s) > wd {
+			wd = len(s)
+		}
+	}
+	if err := WriteNpyHeader(w, fmt.Sprintf("|S%d", wd), []int{len(strs)}); err != nil {
+		return err
+	}
+	for _, s := range strs {
+		if _, err := io.WriteString(w, s+strings.Repeat("\x00", wd-len(s))); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Close finishes the archive and closes the file
+func (nz *NpzWriter) Close() error {
+	err := nz.zw.Close()
+	if cerr := nz.fp.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
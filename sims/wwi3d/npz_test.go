@@ -0,0 +1,110 @@
+// Copyright (c) 2020, The CCNLab Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"archive/zip"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// TestNpzRoundTrip tests that the arrays written to a .npz by NpyStreams are
+// read back by ReadNpy with the same shapes and values
+func TestNpzRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	acts, err := NewNpyStream("acts", dir, []int{2, 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer acts.Close()
+	idxs, err := NewNpyStream("idxs", dir, []int{1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer idxs.Close()
+	idxs.Int = true
+	nr := 5
+	want := map[string][]float64{}
+	for r := 0; r < nr; r++ {
+		row := make([]float32, acts.RowLen)
+		for i := range row {
+			row[i] = float32(r) + float32(i)*0.25 - 1
+			want["acts"] = append(want["acts"], float64(row[i]))
+		}
+		if err := acts.AddRow(row); err != nil {
+			t.Fatal(err)
+		}
+		if err := idxs.AddRow([]float32{float32(-r * 3)}); err != nil {
+			t.Fatal(err)
+		}
+		want["idxs"] = append(want["idxs"], float64(-r*3))
+	}
+	if err := acts.AddRow(make([]float32, 2)); err == nil {
+		t.Errorf("AddRow: no error for a row of the wrong length")
+	}
+	rows := make([]float32, 2*acts.RowLen)
+	if n, err := acts.ReadRows(nr-1, rows); err != nil || n != 1 || float64(rows[0]) != want["acts"][(nr-1)*acts.RowLen] {
+		t.Errorf("ReadRows: %d rows %v %v", n, rows, err)
+	}
+
+	fnm := filepath.Join(dir, "test.npz")
+	nz, err := CreateNpz(fnm)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, ns := range []*NpyStream{acts, idxs} {
+		if err := nz.WriteStream(ns); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := nz.WriteStrings("names", []string{"a", "bcd"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := nz.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	shapes := map[string][]int{"acts": {nr, 2, 3}, "idxs": {nr, 1}}
+	zr, err := zip.OpenReader(fnm)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer zr.Close()
+	if len(zr.File) != 3 {
+		t.Errorf("%d entries, want 3", len(zr.File))
+	}
+	for _, zf := range zr.File {
+		nm := strings.TrimSuffix(zf.Name, ".npy")
+		rc, err := zf.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if nm == "names" { // strings are not numeric -- check the data
+			b, err := ioutil.ReadAll(rc)
+			rc.Close()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !strings.HasSuffix(string(b), "a\x00\x00bcd") || !strings.Contains(string(b), "'|S3'") {
+				t.Errorf("names: %q", b)
+			}
+			continue
+		}
+		vals, shape, err := ReadNpy(rc)
+		rc.Close()
+		if err != nil {
+			t.Fatalf("%s: %v", nm, err)
+		}
+		if !reflect.DeepEqual(shape, shapes[nm]) {
+			t.Errorf("%s: shape %v, want %v", nm, shape, shapes[nm])
+		}
+		if !reflect.DeepEqual(vals, want[nm]) {
+			t.Errorf("%s: vals %v, want %v", nm, vals, want[nm])
+		}
+	}
+}
@@ -49,7 +49,8 @@ type Obj3DSacEnv struct {
 	Saccade etensor.Float32 `view:"saccade popcode "`
 	ObjVel  etensor.Float32 `view:"object velocity"`
 
-	Image image.Image `view:"-" desc:"rendered image as loaded"`
+	Image    image.Image `view:"-" desc:"rendered image as loaded"`
+	ActRFImg image.Point `desc:"size of the grey image, downsampled from the filtered image, that is the Image source of activation-based receptive fields -- see ActRFSrc"`
 }
 
 func (ev *Obj3DSacEnv) Name() string { return ev.Nm }
@@ -91,6 +92,7 @@ func (ev *Obj3DSacEnv) Defaults() {
 
 	ev.V1Med.Defaults(24, 8)
 	ev.V1Hi.Defaults(12, 4)
+	ev.ActRFImg = image.Point{32, 32}
 
 }
 
@@ -175,25 +177,22 @@ func (ev *Obj3DSacEnv) FilterImage() error {
 	return nil
 }
 
-// EncodePops encodes population codes from current row data
-func (ev *Obj3DSacEnv) EncodePops() {
+// CurVec2 returns the raw 2D value of given column for the current row:
+// EyePos, SacPlan, Saccade, or ObjVel
+func (ev *Obj3DSacEnv) CurVec2(col string) mat32.Vec2 {
 	row := ev.CurRow()
 	val := mat32.Vec2{}
-	val.X = float32(ev.Table.CellTensorFloat1D("EyePos", row, 0))
-	val.Y = float32(ev.Table.CellTensorFloat1D("EyePos", row, 1))
-	ev.EyePop.Encode(&ev.EyePos, val, popcode.Set)
-
-	val.X = float32(ev.Table.CellTensorFloat1D("SacPlan", row, 0))
-	val.Y = float32(ev.Table.CellTensorFloat1D("SacPlan", row, 1))
-	ev.SacPop.Encode(&ev.SacPlan, val, popcode.Set)
-
-	val.X = float32(ev.Table.CellTensorFloat1D("Saccade", row, 0))
-	val.Y = float32(ev.Table.CellTensorFloat1D("Saccade", row, 1))
-	ev.SacPop.Encode(&ev.Saccade, val, popcode.Set)
+	val.X = float32(ev.Table.CellTensorFloat1D(col, row, 0))
+	val.Y = float32(ev.Table.CellTensorFloat1D(col, row, 1))
+	return val
+}
 
-	val.X = float32(ev.Table.CellTensorFloat1D("ObjVel", row, 0))
-	val.Y = float32(ev.Table.CellTensorFloat1D("ObjVel", row, 1))
-	ev.ObjVelPop.Encode(&ev.ObjVel, val, popcode.Set)
+// EncodePops encodes population codes from current row data
+func (ev *Obj3DSacEnv) EncodePops() {
+	ev.EyePop.Encode(&ev.EyePos, ev.CurVec2("EyePos"), popcode.Set)
+	ev.SacPop.Encode(&ev.SacPlan, ev.CurVec2("SacPlan"), popcode.Set)
+	ev.SacPop.Encode(&ev.Saccade, ev.CurVec2("Saccade"), popcode.Set)
+	ev.ObjVelPop.Encode(&ev.ObjVel, ev.CurVec2("ObjVel"), popcode.Set)
 }
 
 // SetCtrs sets ctrs from current row data
@@ -263,6 +262,57 @@ func (ev *Obj3DSacEnv) State(element string) etensor.Tensor {
 	return et
 }
 
+// ActRFSrcs are the sources of activation-based receptive fields provided by ActRFSrc
+var ActRFSrcs = []string{"Image", "V1m", "V1h", "EyePos", "SacPlan", "Saccade", "ObjVel", "Cat", "Obj"}
+
+// ActRFSrc sets tsr to the current values of given source of activation-based
+// receptive fields (one of ActRFSrcs): the grey Image downsampled to ActRFImg,
+// the V1m or V1h filter outputs, the EyePos, SacPlan, Saccade or ObjVel popcodes,
+// or the one-hot current Cat (of Cats) or Obj (of Objs).
+func (ev *Obj3DSacEnv) ActRFSrc(nm string, tsr *etensor.Float32) error {
+	switch nm {
+	case "Image":
+		vi := &ev.V1Med
+		pad := vi.V1sGeom.FiltRt.X
+		ny, nx := ev.ActRFImg.Y, ev.ActRFImg.X
+		by, bx := vi.ImgSize.Y/ny, vi.ImgSize.X/nx
+		tsr.SetShape([]int{ny, nx}, nil, []string{"Y", "X"})
+		for y := 0; y < ny; y++ {
+			for x := 0; x < nx; x++ {
+				sum := float32(0)
+				for iy := 0; iy < by; iy++ {
+					for ix := 0; ix < bx; ix++ {
+						sum += vi.ImgTsr.Value([]int{pad + y*by + iy, pad + x*bx + ix})
+					}
+				}
+				tsr.Set([]int{y, x}, sum/float32(by*bx))
+			}
+		}
+	case "V1m", "V1h", "EyePos", "SacPlan", "Saccade", "ObjVel":
+		src := ev.State(nm).(*etensor.Float32)
+		tsr.CopyShapeFrom(src)
+		copy(tsr.Values, src.Values)
+	case "Cat", "Obj":
+		lst, cur := ev.Cats, ev.CurCat
+		if nm == "Obj" {
+			lst, cur = ev.Objs, ev.CurCat+"/"+ev.CurObj
+		}
+		if len(lst) == 0 {
+			return fmt.Errorf("Obj3DSacEnv: ActRF source %s: no %ss loaded", nm, nm)
+		}
+		tsr.SetShape([]int{len(lst)}, nil, []string{nm})
+		tsr.SetZeros()
+		for i, v := range lst {
+			if v == cur {
+				tsr.Values[i] = 1
+			}
+		}
+	default:
+		return fmt.Errorf("Obj3DSacEnv: ActRF source %s not found -- must be one of: %v", nm, ActRFSrcs)
+	}
+	return nil
+}
+
 func (ev *Obj3DSacEnv) Action(element string, input etensor.Tensor) {
 	// nop
 }
@@ -281,3 +281,13 @@ var ParamSets = params.Sets{
 		},
 	}},
 }
+
+// Scheds are the default epoch schedules for each ParamSet -- Base is used for any
+// ParamSet without its own schedule.  Use -sched to load a schedule from a JSON file instead.
+var Scheds = EpochScheds{
+	"Base": {Name: "Base", Desc: "learning rate drops at 250, 500, 750", Acts: []SchedAct{
+		{Epoch: 250, Act: "Lrate", Val: 0.5},
+		{Epoch: 500, Act: "Lrate", Val: 0.2},
+		{Epoch: 750, Act: "Lrate", Val: 0.1},
+	}},
+}
@@ -0,0 +1,88 @@
+// Copyright (c) 2020, The CCNLab Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+)
+
+// probeData returns n noisy patterns of nu units, in nc classes with distinct
+// mean patterns, and their class labels
+func probeData(rnd *rand.Rand, n, nu, nc int) ([][]float64, []string) {
+	means := make([][]float64, nc)
+	for c := range means {
+		means[c] = make([]float64, nu)
+		for i := range means[c] {
+			means[c][i] = 2 * rnd.NormFloat64()
+		}
+	}
+	vecs := make([][]float64, n)
+	lbls := make([]string, n)
+	for i := range vecs {
+		c := i % nc
+		vecs[i] = make([]float64, nu)
+		for j := range vecs[i] {
+			vecs[i][j] = means[c][j] + 0.5*rnd.NormFloat64()
+		}
+		lbls[i] = fmt.Sprintf("c%d", c)
+	}
+	return vecs, lbls
+}
+
+// TestProbeClassAcc tests that the probes decode linearly separable classes with
+// near-perfect cross-validated accuracy, and shuffled labels at chance
+func TestProbeClassAcc(t *testing.T) {
+	n, nu, nc := 200, 20, 4
+	rnd := rand.New(rand.NewSource(1))
+	vecs, lbls := probeData(rnd, n, nu, nc)
+	shuf := append([]string(nil), lbls...)
+	rnd.Shuffle(n, func(i, j int) { shuf[i], shuf[j] = shuf[j], shuf[i] })
+	k := GramMatrix(vecs)
+	for _, logistic := range []bool{false, true} {
+		pr := &Probe{}
+		pr.Defaults()
+		pr.Logistic = logistic
+		folds := pr.FoldIdxs(n)
+		if acc := pr.ClassAcc(k, n, folds, lbls); acc < 0.95 {
+			t.Errorf("Logistic: %v: separable classes: accuracy %g, want >= 0.95", logistic, acc)
+		}
+		if acc := pr.ClassAcc(k, n, folds, shuf); acc > 0.4 {
+			t.Errorf("Logistic: %v: shuffled labels: accuracy %g, want chance (0.25)", logistic, acc)
+		}
+	}
+}
+
+// TestProbeRegR2 tests that ridge regression recovers a linear function of the
+// patterns with R^2 near 1, and unrelated values with R^2 near 0 or below
+func TestProbeRegR2(t *testing.T) {
+	n, nu, d := 200, 20, 2
+	rnd := rand.New(rand.NewSource(1))
+	vecs, _ := probeData(rnd, n, nu, 4)
+	w := make([]float64, nu*d)
+	for i := range w {
+		w[i] = rnd.NormFloat64()
+	}
+	ys := make([]float64, n*d)
+	rys := make([]float64, n*d)
+	for i, v := range vecs {
+		MatMul(v, 1, nu, w, d, ys[i*d:(i+1)*d])
+	}
+	for i := range rys {
+		rys[i] = rnd.NormFloat64()
+	}
+	k := GramMatrix(vecs)
+	pr := &Probe{}
+	pr.Defaults()
+	pr.Lambda = 0.001
+	folds := pr.FoldIdxs(n)
+	if r2 := pr.RegR2(k, n, folds, ys, d); r2 < 0.95 {
+		t.Errorf("linear targets: R^2 %g, want >= 0.95", r2)
+	}
+	if r2 := pr.RegR2(k, n, folds, rys, d); r2 > 0.1 {
+		t.Errorf("random targets: R^2 %g, want <= 0.1", r2)
+	}
+}
@@ -0,0 +1,128 @@
+// Copyright (c) 2020, The CCNLab Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"image"
+	"image/png"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/emer/etable/etensor"
+	"github.com/emer/etable/norm"
+	"github.com/emer/vision/vfilter"
+)
+
+// ReconSpec specifies a layer to reconstruct V1 images from: the layer must
+// have V1AllTsr-shaped pools (Y, X, Polarity = 5, Angle) starting at unit row Row,
+// from the V1 filters of Vis (V1m or V1h) -- e.g., the V1 pulvinar layers,
+// or the V1 rows of a pulvinar layer driven by V1m and other layers.
+type ReconSpec struct {
+	Lay string `desc:"name of the layer"`
+	Vis string `desc:"name of the V1 filtering whose output the layer represents: V1m or V1h"`
+	Row int    `desc:"starting unit row of the V1 filtering rows within each pool of the layer"`
+}
+
+// ParseReconSpec parses a lay:vis[:row] spec
+func ParseReconSpec(spec string) (ReconSpec, error) {
+	rs := ReconSpec{}
+	sp := strings.Split(spec, ":")
+	if len(sp) < 2 || len(sp) > 3 {
+		return rs, fmt.Errorf("ReconSpec: must be lay:vis[:row], not: %s", spec)
+	}
+	rs.Lay = sp[0]
+	rs.Vis = sp[1]
+	if len(sp) == 3 {
+		row, err := strconv.Atoi(sp[2])
+		if err != nil {
+			return rs, fmt.Errorf("ReconSpec %s: %v", spec, err)
+		}
+		rs.Row = row
+	}
+	return rs, nil
+}
+
+// Name returns the name of the spec used in file names: Lay_Vis
+func (rs *ReconSpec) Name() string {
+	return rs.Lay + "_" + rs.Vis
+}
+
+// Recon reconstructs images from V1 activity patterns as encoded in V1AllTsr,
+// by unpooling the pooled simple-cell rows (3, 4) and deconvolving them with the
+// V1 simple gabor filters -- comparing the reconstructions of the minus phase
+// (prediction) and plus phase (actual) shows what the network predicts.
+type Recon struct {
+	V1sPoolTsr etensor.Float32 `view:"no-inline" desc:"pooled V1 simple tensor extracted from the layer"`
+	V1sTsr     etensor.Float32 `view:"no-inline" desc:"unpooled V1 simple tensor"`
+	ImgTsr     etensor.Float32 `view:"no-inline" desc:"reconstructed image tensor, with padding"`
+}
+
+// V1sFmLay extracts the pooled V1 simple rows from layer values with pools of
+// shape Y, X, Polarity, Angle, starting at unit row row
+func (rc *Recon) V1sFmLay(vals *etensor.Float32, row int) error {
+	if vals.NumDims() != 4 {
+		return fmt.Errorf("Recon: layer values must be 4D, not: %v", vals.Shapes())
+	}
+	py, px, fy, nang := vals.Dim(0), vals.Dim(1), vals.Dim(2), vals.Dim(3)
+	if row+5 > fy {
+		return fmt.Errorf("Recon: layer has %d unit rows, needs %d", fy, row+5)
+	}
+	rc.V1sPoolTsr.SetShape([]int{py, px, 2, nang}, nil, []string{"Y", "X", "Polarity", "Angle"})
+	for y := 0; y < py; y++ {
+		for x := 0; x < px; x++ {
+			for pol := 0; pol < 2; pol++ {
+				for ang := 0; ang < nang; ang++ {
+					rc.V1sPoolTsr.Set([]int{y, x, pol, ang}, vals.Value([]int{y, x, row + 3 + pol, ang}))
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// UnPool replicates each pooled value into the 2x2 simple cells it was max-pooled from
+func (rc *Recon) UnPool() {
+	py, px, np, nang := rc.V1sPoolTsr.Dim(0), rc.V1sPoolTsr.Dim(1), rc.V1sPoolTsr.Dim(2), rc.V1sPoolTsr.Dim(3)
+	rc.V1sTsr.SetShape([]int{2 * py, 2 * px, np, nang}, nil, []string{"Y", "X", "Polarity", "Angle"})
+	for y := 0; y < 2*py; y++ {
+		for x := 0; x < 2*px; x++ {
+			for pol := 0; pol < np; pol++ {
+				for ang := 0; ang < nang; ang++ {
+					rc.V1sTsr.Set([]int{y, x, pol, ang}, rc.V1sPoolTsr.Value([]int{y / 2, x / 2, pol, ang}))
+				}
+			}
+		}
+	}
+}
+
+// Image reconstructs the image from given layer values, using the filters of vi
+func (rc *Recon) Image(vi *Vis, vals *etensor.Float32, row int) (*image.Gray, error) {
+	if err := rc.V1sFmLay(vals, row); err != nil {
+		return nil, err
+	}
+	sy, sx := vi.V1sTsr.Dim(0), vi.V1sTsr.Dim(1)
+	if 2*rc.V1sPoolTsr.Dim(0) != sy || 2*rc.V1sPoolTsr.Dim(1) != sx {
+		return nil, fmt.Errorf("Recon: layer pools %d x %d do not match the pooled V1 simple cells %d x %d", rc.V1sPoolTsr.Dim(0), rc.V1sPoolTsr.Dim(1), sy/2, sx/2)
+	}
+	rc.UnPool()
+	pad := vi.V1sGeom.FiltRt.X
+	rc.ImgTsr.SetShape([]int{vi.ImgSize.Y + 2*pad, vi.ImgSize.X + 2*pad}, nil, []string{"Y", "X"})
+	rc.ImgTsr.SetZeros()
+	vfilter.Deconv(&vi.V1sGeom, &vi.V1sGaborTsr, &rc.ImgTsr, &rc.V1sTsr, vi.V1sGabor.Gain)
+	norm.Unit32(rc.ImgTsr.Values)
+	return vfilter.GreyTensorToImage(nil, &rc.ImgTsr, pad, false), nil
+}
+
+// SavePNG saves an image to a png file
+func SavePNG(img image.Image, fname string) error {
+	fp, err := os.Create(fname)
+	if err != nil {
+		return err
+	}
+	defer fp.Close()
+	return png.Encode(fp, img)
+}
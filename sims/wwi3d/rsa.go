@@ -75,17 +75,37 @@ var LbaCatsBlanks []string // LbaCats5 with repeats all blank -- for labels
 
 // RSA handles representational similarity analysis
 type RSA struct {
-	Interval   int                      `desc:"how often to run RSA analyses over epochs"`
-	Cats       []string                 `desc:"category names for each row of simmat / activation table -- call SetCats"`
-	Sims       map[string]*simat.SimMat `desc:"similarity matricies for each layer"`
-	V1Sims     []float64                `desc:"similarity for each layer relative to V1"`
-	CatDists   []float64                `desc:"AvgContrastDist for each layer under LbaCats5 centroid meta categories"`
-	BasicDists []float64                `desc:"AvgBasicDist for each layer -- basic-level distances"`
-	ExptDists  []float64                `desc:"AvgExptDist for each layer -- distances from expt data"`
-	Cat5Sims   map[string]*simat.SimMat `desc:"similarity matricies for each layer, organized into LbaCats5 and sorted"`
-	Cat5Objs   map[string]*[]string     `desc:"corresponding ordering of objects in sorted Cat5Sims lists"`
-	PermNCats  map[string]int           `desc:"number of categories remaining after permutation from LbaCat"`
-	PermDists  map[string]float64       `desc:"avg contrast dist for permutation"`
+	Interval       int                          `desc:"how often to run RSA analyses over epochs"`
+	Tick           int                          `desc:"tick to use for the standard single-tick RSA analyses (Sims, CatDists etc)"`
+	TickMin        int                          `desc:"first tick to include in tick-resolved RSA analyses"`
+	TickMax        int                          `desc:"last tick (inclusive) to include in tick-resolved RSA analyses -- -1 = last tick in the activations table"`
+	Cats           []string                     `desc:"category names for each row of simmat / activation table -- call SetCats"`
+	Sims           map[string]*simat.SimMat     `desc:"similarity matricies for each layer"`
+	V1Sims         []float64                    `desc:"similarity for each layer relative to V1"`
+	CatDists       []float64                    `desc:"AvgContrastDist for each layer under LbaCats5 centroid meta categories"`
+	BasicDists     []float64                    `desc:"AvgBasicDist for each layer -- basic-level distances"`
+	ExptDists      []float64                    `desc:"AvgExptDist for each layer -- distances from expt data"`
+	Cat5Sims       map[string]*simat.SimMat     `desc:"similarity matricies for each layer, organized into LbaCats5 and sorted"`
+	Cat5Objs       map[string]*[]string         `desc:"corresponding ordering of objects in sorted Cat5Sims lists"`
+	PermNCats      map[string]int               `desc:"number of categories remaining after permutation from LbaCat"`
+	PermDists      map[string]float64           `desc:"avg contrast dist for permutation"`
+	PermCats       map[string]map[string]string `desc:"category of each object after permutation from LbaCats5 -- i.e., the discovered categories"`
+	TickCatDists   map[string][]float64         `desc:"CatDists for each layer computed separately at each tick in TickMin..TickMax"`
+	TickGens       map[string]*simat.SimMat     `desc:"time-by-time generalization matrix for each layer: LbaCats5 CatDist computed on the cross-tick distance matrix between objects at tick (row) vs. tick (col)"`
+	TickRDMCors    map[string]*simat.SimMat     `desc:"correlation between the object similarity matricies at tick (row) vs. tick (col), for each layer"`
+	TickGenAvgs    map[string]float64           `desc:"average off-diagonal TickGens value for each layer -- how well category structure generalizes across ticks"`
+	TickRDMCorAvgs map[string]float64           `desc:"average off-diagonal TickRDMCors value for each layer -- how stable the similarity structure is across ticks"`
+	RBFSigma       float64                      `desc:"width of the RBF kernel used for RBF CKA, as a multiple of the median distance between activation patterns"`
+	LayCmp         LayCmp                       `view:"inline" desc:"layer-by-layer comparison of all recorded layers"`
+	XCmp           LayCmp                       `view:"inline" desc:"layer-by-layer comparison of recorded layers (rows) against layers from another acts file (cols), e.g., from another model -- see CmpCatActs"`
+	Exts           map[string]*ExtRSA           `desc:"external RDM or activation data (e.g., PredNet, backprop models, neural recordings) mapped onto Objs, with the same stats as the layers -- see OpenExt"`
+	Stream         StreamSim                    `view:"inline" desc:"blockwise parallel similarity matrix computation for large numbers of rows -- used by SimMatFmActs for IdxViews with at least Stream.MinRows rows"`
+	Tree           CatTree                      `desc:"hierarchical category tree (e.g., shape class, basic-level object, instance) for the nested contrast distances and permutation tests in Trees -- defaults to LbaCatTree"`
+	TreeLays       []string                     `desc:"layers to compute the category Tree stats for"`
+	TreeTick       int                          `desc:"tick to use for the category Tree stats -- -1 = all ticks, which gives multiple views of each object instance"`
+	TreeNPerm      int                          `desc:"number of nested permutations per level for the category Tree stats"`
+	TreeSeed       int64                        `desc:"random seed for the nested permutations"`
+	Trees          map[string]*etable.Table     `view:"no-inline" desc:"category Tree stats (TreeStats) for each level (rows), for each of the TreeLays"`
 }
 
 // Init initializes maps etc if not done yet
@@ -103,6 +123,12 @@ func (rs *RSA) Init(lays []string) {
 	rs.ExptDists = make([]float64, nc)
 	rs.PermNCats = make(map[string]int)
 	rs.PermDists = make(map[string]float64)
+	rs.PermCats = make(map[string]map[string]string)
+	rs.TickCatDists = make(map[string][]float64, nc)
+	rs.TickGens = make(map[string]*simat.SimMat, nc)
+	rs.TickRDMCors = make(map[string]*simat.SimMat, nc)
+	rs.TickGenAvgs = make(map[string]float64, nc)
+	rs.TickRDMCorAvgs = make(map[string]float64, nc)
 
 	if ObjIdxs == nil {
 		no := len(Objs)
@@ -158,9 +184,11 @@ func (rs *RSA) Cat5ObjByName(cn string) *[]string {
 	return sm
 }
 
-// StatsFmActs computes RSA stats from given acts table, for given columns (layer names)
+// StatsFmActs computes RSA stats from given acts table, for given columns (layer names).
+// The standard stats use the Tick tick, and TickStatsFmActs is then called for the
+// tick-resolved versions.
 func (rs *RSA) StatsFmActs(acts *etable.Table, lays []string) {
-	tick := 2 // use this tick for analyses..
+	tick := rs.Tick
 	tix := etable.NewIdxView(acts)
 	tix.Filter(func(et *etable.Table, row int) bool {
 		tck := int(et.CellFloat("Tick", row))
@@ -180,8 +208,7 @@ func (rs *RSA) StatsFmActs(acts *etable.Table, lays []string) {
 		rs.ExptDists[i] = dist
 	}
 
-	v1sm := rs.Sims["V1m"]
-	v1sm64 := v1sm.Mat.(*etensor.Float64)
+	v1sm := rs.Sims["V1m"] // V1Sims are 0 if V1m is not one of the lays
 	for i, cn := range lays {
 		osm := rs.SimByName(cn)
 
@@ -192,13 +219,24 @@ func (rs *RSA) StatsFmActs(acts *etable.Table, lays []string) {
 			rs.V1Sims[i] = 1
 			continue
 		}
+		if v1sm == nil || v1sm.Mat == nil {
+			rs.V1Sims[i] = 0
+			continue
+		}
 		osm64 := osm.Mat.(*etensor.Float64)
-		rs.V1Sims[i] = metric.Correlation64(osm64.Values, v1sm64.Values)
+		rs.V1Sims[i] = metric.Correlation64(osm64.Values, v1sm.Mat.(*etensor.Float64).Values)
 	}
 	cat5s := []string{"TE"}
 	for _, cn := range cat5s {
-		rs.StatsSortPermuteCat5(cn)
+		for _, lnm := range lays {
+			if lnm == cn {
+				rs.StatsSortPermuteCat5(cn)
+				break
+			}
+		}
 	}
+	rs.TreeStatsFmActs(acts, rs.TreeLays)
+	rs.TickStatsFmActs(acts, lays)
 }
 
 func (rs *RSA) StatsSortPermuteCat5(laynm string) {
@@ -217,6 +255,7 @@ func (rs *RSA) StatsSortPermuteCat5(laynm string) {
 	obj5p := rs.Cat5ObjByName(pnm)
 	copy(*obj5p, objp)
 	rs.PermNCats[laynm] = ncat
+	rs.PermCats[laynm] = pcats
 	rs.PermDists[laynm] = pdist
 }
 
@@ -231,8 +270,19 @@ func (rs *RSA) ConfigSimMat(sm *simat.SimMat) {
 }
 
 // SimMatFmActs computes the given SimMat from given acts table (IdxView),
-// for given column name.
+// for given column name.  The result is always an etensor.Float64, as the
+// RSA stats require -- Stream.Float32 only applies to SaveNpy (RepRDMs).
 func (rs *RSA) SimMatFmActs(sm *simat.SimMat, acts *etable.IdxView, colnm string) {
+	if rs.Stream.MinRows > 0 && acts.Len() >= rs.Stream.MinRows {
+		st := rs.Stream
+		st.Float32 = false
+		if err := st.TableCol(sm, acts, colnm, "Cat", true); err != nil {
+			log.Println(err)
+			return
+		}
+		rs.ConfigSimMat(sm)
+		return
+	}
 	sm.Init()
 	rs.ConfigSimMat(sm)
 
@@ -563,7 +613,9 @@ func (rs *RSA) ObjSimMat(osm *simat.SimMat, fsm *simat.SimMat, nms []string) {
 	for ri := 0; ri < ono; ri++ {
 		for ci := 0; ci < ono; ci++ {
 			oidx := ri*ono + ci
-			osmat.Values[oidx] /= nmat.Values[oidx]
+			if nmat.Values[oidx] > 0 {
+				osmat.Values[oidx] /= nmat.Values[oidx]
+			}
 		}
 	}
 	norm.DivNorm64(osmat.Values, norm.Max64)
@@ -0,0 +1,207 @@
+// Copyright (c) 2020, The CCNLab Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"log"
+	"math"
+	"strconv"
+
+	"github.com/emer/etable/etable"
+	"github.com/emer/etable/etensor"
+	"github.com/emer/etable/metric"
+	"github.com/emer/etable/simat"
+)
+
+// TickRange returns the range of ticks (inclusive) to use for tick-resolved
+// RSA, based on TickMin, TickMax and the ticks present in the acts table.
+func (rs *RSA) TickRange(acts *etable.Table) (st, ed int) {
+	mx := 0
+	for row := 0; row < acts.Rows; row++ {
+		tck := int(acts.CellFloat("Tick", row))
+		if tck > mx {
+			mx = tck
+		}
+	}
+	st = rs.TickMin
+	ed = rs.TickMax
+	if ed < 0 || ed > mx {
+		ed = mx
+	}
+	if st < 0 {
+		st = 0
+	}
+	if st > ed {
+		st = ed
+	}
+	return
+}
+
+// TickSimByName returns the tick x tick SimMat for given layer name
+// in given map, configured with given tick labels.
+func (rs *RSA) TickSimByName(sms map[string]*simat.SimMat, cn string, tlbls []string) *simat.SimMat {
+	sm, ok := sms[cn]
+	if !ok || sm == nil {
+		sm = &simat.SimMat{}
+		sms[cn] = sm
+	}
+	nt := len(tlbls)
+	sm.Init()
+	smat := sm.Mat.(*etensor.Float64)
+	smat.SetShape([]int{nt, nt}, nil, nil)
+	smat.SetMetaData("colormap", "Viridis")
+	smat.SetMetaData("grid-fill", "1")
+	sm.Rows = tlbls
+	sm.Cols = tlbls
+	return sm
+}
+
+// TickActs returns the activation vectors for given column, for each row
+// in acts at given tick, in row order -- nil if there is no such column.
+func TickActs(acts *etable.Table, colnm string, tick int) [][]float64 {
+	var vecs [][]float64
+	for row := 0; row < acts.Rows; row++ {
+		if int(acts.CellFloat("Tick", row)) != tick {
+			continue
+		}
+		tsr := acts.CellTensor(colnm, row)
+		if tsr == nil {
+			return nil
+		}
+		v := make([]float64, tsr.Len())
+		for i := range v {
+			v[i] = tsr.FloatVal1D(i)
+		}
+		vecs = append(vecs, v)
+	}
+	return vecs
+}
+
+// NormTickActs returns the activation vectors for given column, for each row
+// in acts at given tick (in row order), normalized as in NormVecs.
+func NormTickActs(acts *etable.Table, colnm string, tick int) [][]float64 {
+	return NormVecs(TickActs(acts, colnm, tick))
+}
+
+// NormVecs normalizes each of the vectors to zero mean and unit length,
+// in place, so that the dot product between two of them is their correlation.
+func NormVecs(vecs [][]float64) [][]float64 {
+	for _, v := range vecs {
+		n := len(v)
+		mean := 0.0
+		for _, a := range v {
+			mean += a
+		}
+		if n > 0 {
+			mean /= float64(n)
+		}
+		ss := 0.0
+		for i := range v {
+			v[i] -= mean
+			ss += v[i] * v[i]
+		}
+		if ss > 0 {
+			nrm := 1 / math.Sqrt(ss)
+			for i := range v {
+				v[i] *= nrm
+			}
+		}
+	}
+	return vecs
+}
+
+// CrossTickDists returns the n x n InvCorrelation distance matrix between
+// normalized vectors in a (rows) and b (cols), as from NormTickActs.
+func CrossTickDists(a, b [][]float64) []float64 {
+	n := len(a)
+	ds := make([]float64, n*n)
+	for ri, av := range a {
+		for ci, bv := range b {
+			dp := 0.0
+			for i, v := range av {
+				dp += v * bv[i]
+			}
+			ds[ri*n+ci] = 1 - dp
+		}
+	}
+	return ds
+}
+
+// TickStatsFmActs computes the tick-resolved RSA stats from given acts table,
+// for given columns (layer names): the CatDist at each tick, the time-by-time
+// generalization matrix of CatDists computed on cross-tick object distances,
+// and the correlations between the similarity matricies at each pair of ticks.
+// Layers without a row for each object at each tick are skipped.
+func (rs *RSA) TickStatsFmActs(acts *etable.Table, lays []string) {
+	st, ed := rs.TickRange(acts)
+	nt := ed - st + 1
+	tlbls := make([]string, nt)
+	for ti := range tlbls {
+		tlbls[ti] = strconv.Itoa(st + ti)
+	}
+	no := len(rs.Cats)
+	csm := &simat.SimMat{}
+	csm.Init()
+	cmat := csm.Mat.(*etensor.Float64)
+	cmat.SetShape([]int{no, no}, nil, nil)
+	csm.Rows = rs.Cats
+	csm.Cols = rs.Cats
+
+	for _, cn := range lays {
+		tacts := make([][][]float64, nt)
+		ragged := false
+		for ti := range tacts {
+			tacts[ti] = NormTickActs(acts, cn, st+ti)
+			if len(tacts[ti]) != no {
+				log.Printf("TickStatsFmActs: layer %s has %d rows at tick %d instead of %d objects -- skipped\n", cn, len(tacts[ti]), st+ti, no)
+				ragged = true
+				break
+			}
+		}
+		if ragged {
+			continue
+		}
+		gsm := rs.TickSimByName(rs.TickGens, cn, tlbls)
+		gen := gsm.Mat.(*etensor.Float64)
+		rsm := rs.TickSimByName(rs.TickRDMCors, cn, tlbls)
+		rcor := rsm.Mat.(*etensor.Float64)
+		rcor.SetMetaData("max", "1")
+		rcor.SetMetaData("min", "-1")
+		cds := make([]float64, nt)
+		rdms := make([][]float64, nt)
+		for t1 := 0; t1 < nt; t1++ {
+			for t2 := 0; t2 < nt; t2++ {
+				ds := CrossTickDists(tacts[t1], tacts[t2])
+				copy(cmat.Values, ds)
+				cd := -rs.AvgContrastDist(csm, rs.Cats, LbaCats5)
+				gen.Values[t1*nt+t2] = cd
+				if t1 == t2 {
+					cds[t1] = cd
+					rdms[t1] = ds
+				}
+			}
+		}
+		gavg := 0.0
+		ravg := 0.0
+		for t1 := 0; t1 < nt; t1++ {
+			for t2 := 0; t2 < nt; t2++ {
+				r := 1.0
+				if t1 != t2 {
+					r = metric.Correlation64(rdms[t1], rdms[t2])
+					gavg += gen.Values[t1*nt+t2]
+					ravg += r
+				}
+				rcor.Values[t1*nt+t2] = r
+			}
+		}
+		if nt > 1 {
+			gavg /= float64(nt * (nt - 1))
+			ravg /= float64(nt * (nt - 1))
+		}
+		rs.TickCatDists[cn] = cds
+		rs.TickGenAvgs[cn] = gavg
+		rs.TickRDMCorAvgs[cn] = ravg
+	}
+}
@@ -0,0 +1,155 @@
+// Copyright (c) 2020, The CCNLab Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/emer/etable/etable"
+	"github.com/emer/etable/etensor"
+)
+
+// TestTickStatsRagged tests that a layer without a row for each object at each
+// tick is skipped, and the tick stats of the other layers are still computed
+func TestTickStatsRagged(t *testing.T) {
+	objs := Objs[:10]
+	nt := 3
+	nu := 8
+	sch := etable.Schema{
+		{"Tick", etensor.INT64, nil, nil},
+		{"A", etensor.FLOAT32, []int{nu}, nil},
+		{"B", etensor.FLOAT32, []int{nu}, nil},
+	}
+	acts := &etable.Table{}
+	acts.SetFromSchema(sch, nt*len(objs))
+	rnd := rand.New(rand.NewSource(1))
+	for _, cn := range []string{"A", "B"} {
+		vals := acts.ColByName(cn).(*etensor.Float32).Values
+		for i := range vals {
+			vals[i] = rnd.Float32()
+		}
+	}
+	for row := 0; row < acts.Rows; row++ {
+		acts.SetCellFloat("Tick", row, float64(row/len(objs)))
+	}
+
+	rs := &RSA{}
+	rs.TickMax = -1
+	rs.Init([]string{"A", "Ragged", "B"})
+	rs.Cats = objs
+	rs.TickStatsFmActs(acts, []string{"A", "Ragged", "B"}) // Ragged has no rows
+
+	if _, ok := rs.TickCatDists["Ragged"]; ok {
+		t.Errorf("ragged layer should be skipped")
+	}
+	for _, cn := range []string{"A", "B"} {
+		cds, ok := rs.TickCatDists[cn]
+		if !ok || len(cds) != nt {
+			t.Errorf("layer %s: TickCatDists %v, want %d ticks", cn, cds, nt)
+		}
+		if gsm := rs.TickGens[cn]; gsm == nil || gsm.Mat.Len() != nt*nt {
+			t.Errorf("layer %s: no %d x %d TickGens", cn, nt, nt)
+		}
+	}
+}
+
+func TestCrossTickDists(t *testing.T) {
+	a := NormVecs([][]float64{{1, 2, 3}, {3, 1, 2}})
+	b := NormVecs([][]float64{{2, 4, 6}, {-3, -1, -2}})
+	ds := CrossTickDists(a, b)
+	want := []float64{0, 0.5, 1.5, 2}
+	for i, d := range ds {
+		if math.Abs(d-want[i]) > 1.0e-10 {
+			t.Errorf("CrossTickDists: %v, want %v", ds, want)
+			break
+		}
+	}
+}
+
+// TestTickStatsCats tests that the tick stats find category structure only at the
+// ticks where it was put: layer A has the same category prototypes at ticks 1 and 2,
+// and B at tick 0 only, with random patterns at the other ticks
+func TestTickStatsCats(t *testing.T) {
+	no := len(Objs)
+	nt := 3
+	nu := 50
+	sch := etable.Schema{
+		{"Tick", etensor.INT64, nil, nil},
+		{"A", etensor.FLOAT32, []int{nu}, nil},
+		{"B", etensor.FLOAT32, []int{nu}, nil},
+	}
+	acts := &etable.Table{}
+	acts.SetFromSchema(sch, nt*no)
+	rnd := rand.New(rand.NewSource(1))
+	protos := make(map[string][]float32)
+	for _, ob := range Objs {
+		cat := LbaCats5[ob]
+		if _, has := protos[cat]; has {
+			continue
+		}
+		p := make([]float32, nu)
+		for i := range p {
+			p[i] = float32(rnd.NormFloat64())
+		}
+		protos[cat] = p
+	}
+	lays := []string{"A", "B"}
+	catTicks := map[string][]bool{"A": {false, true, true}, "B": {true, false, false}}
+	for row := 0; row < acts.Rows; row++ {
+		tck := row / no
+		acts.SetCellFloat("Tick", row, float64(tck))
+		p := protos[LbaCats5[Objs[row%no]]]
+		for _, cn := range lays {
+			cts := catTicks[cn]
+			vals := acts.ColByName(cn).(*etensor.Float32).Values[row*nu : (row+1)*nu]
+			for i := range vals {
+				vals[i] = float32(rnd.NormFloat64())
+				if cts[tck] {
+					vals[i] = p[i] + 0.3*vals[i]
+				}
+			}
+		}
+	}
+
+	rs := &RSA{}
+	rs.TickMax = -1
+	rs.Init(lays)
+	rs.Cats = Objs
+	rs.TickStatsFmActs(acts, lays)
+
+	for _, cn := range lays {
+		cts := catTicks[cn]
+		cds := rs.TickCatDists[cn]
+		if len(cds) != nt {
+			t.Fatalf("layer %s: TickCatDists %v, want %d ticks", cn, cds, nt)
+		}
+		for tck, cat := range cts {
+			if cat && cds[tck] < 0.5 {
+				t.Errorf("layer %s tick %d: CatDist %g, want category structure (> 0.5)", cn, tck, cds[tck])
+			}
+			if !cat && math.Abs(cds[tck]) > 0.2 {
+				t.Errorf("layer %s tick %d: CatDist %g, want none (~0)", cn, tck, cds[tck])
+			}
+		}
+		gen := rs.TickGens[cn].Mat.(*etensor.Float64).Values
+		for t1 := 0; t1 < nt; t1++ {
+			for t2 := 0; t2 < nt; t2++ {
+				g := gen[t1*nt+t2]
+				if cts[t1] && cts[t2] && g < 0.5 {
+					t.Errorf("layer %s TickGens %d, %d: %g, want category structure (> 0.5)", cn, t1, t2, g)
+				}
+				if !(cts[t1] && cts[t2]) && math.Abs(g) > 0.2 {
+					t.Errorf("layer %s TickGens %d, %d: %g, want none (~0)", cn, t1, t2, g)
+				}
+			}
+		}
+	}
+	rcor := rs.TickRDMCors["A"].Mat
+	if r, r01 := rcor.FloatVal([]int{1, 2}), rcor.FloatVal([]int{0, 1}); r < 0.5 || r <= r01 {
+		t.Errorf("layer A TickRDMCors 1, 2: %g, want the same similarity structure (> 0.5, and > 0, 1: %g)", r, r01)
+	}
+}
@@ -0,0 +1,300 @@
+// Copyright (c) 2020, The CCNLab Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"sync"
+
+	"github.com/emer/empi/empi"
+	"github.com/emer/empi/mpi"
+	"github.com/emer/etable/etable"
+	"github.com/emer/etable/etensor"
+)
+
+// SimComm is the communicator of data-parallel training, where each proc trains
+// a replica of the network on its own slice of the trials, and the weight changes
+// and stats are summed over the procs: MPIComm for MPI procs, or the LocalWorker
+// of a LocalComm for in-process workers, for multi-core training without MPI.
+type SimComm interface {
+	// Rank returns the rank of this proc, 0 .. Size-1
+	Rank() int
+
+	// Size returns the number of procs
+	Size() int
+
+	// AllReduceF32 reduces src over all procs with given op (sum, max or min),
+	// into dest on all procs
+	AllReduceF32(op mpi.Op, dest, src []float32) error
+
+	// ReduceTable reduces the numerical columns of src over all procs with given
+	// op, into dest on all procs, which must have the same columns as src
+	ReduceTable(dest, src *etable.Table, op mpi.Op) error
+
+	// GatherTableRows gathers the rows of src of all procs, in rank order, into
+	// dest on all procs, which must have the same columns as src
+	GatherTableRows(dest, src *etable.Table) error
+
+	// Serial calls fun on one proc at a time -- in-process workers share the global
+	// math/rand, so using it in fun, after seeding, gets the same numbers as on a
+	// separate MPI proc
+	Serial(fun func())
+
+	// Seed seeds the global math/rand with given seed on all procs, when they all
+	// call it at the same point -- in-process workers share it, so it is seeded once,
+	// by rank 0, after all the workers have reached this point, and before any of them
+	// go on, so none of them draw from it while it is seeded.  Outside of Serial, the
+	// workers must not draw from it while training concurrently.
+	Seed(seed int64)
+}
+
+// AllocN returns the range of n items allocated to given rank of size procs, as
+// empi.AllocN does for MPI procs: an error if n is not a multiple of size
+func AllocN(n, rank, size int) (st, end int, err error) {
+	if n%size != 0 {
+		err = fmt.Errorf("AllocN: number: %d is not an even multiple of number of procs: %d -- must be!", n, size)
+	}
+	pt := n / size
+	st = pt * rank
+	end = st + pt
+	return
+}
+
+// MPIComm is the SimComm of MPI procs
+type MPIComm struct {
+	Comm *mpi.Comm `desc:"communicator of all the procs"`
+}
+
+func (cm *MPIComm) Rank() int { return mpi.WorldRank() }
+func (cm *MPIComm) Size() int { return mpi.WorldSize() }
+
+func (cm *MPIComm) AllReduceF32(op mpi.Op, dest, src []float32) error {
+	return cm.Comm.AllReduceF32(op, dest, src)
+}
+
+func (cm *MPIComm) ReduceTable(dest, src *etable.Table, op mpi.Op) error {
+	return empi.ReduceTable(dest, src, cm.Comm, op)
+}
+
+func (cm *MPIComm) GatherTableRows(dest, src *etable.Table) error {
+	return empi.GatherTableRows(dest, src, cm.Comm)
+}
+
+// Serial just calls fun, as each MPI proc has its own process
+func (cm *MPIComm) Serial(fun func()) { fun() }
+
+// Seed seeds the math/rand of this proc
+func (cm *MPIComm) Seed(seed int64) { rand.Seed(seed) }
+
+// LocalComm is shared by N in-process workers, each training a replica of the sim
+// on its own goroutine: the collective ops wait at a barrier for all the workers,
+// and reduce their buffers directly in shared memory, in rank order, with each worker
+// reducing its own part of the values.
+type LocalComm struct {
+	N int `desc:"number of workers"`
+
+	mu   sync.Mutex
+	cond *sync.Cond
+	cnt  int
+	gen  int
+	srcs []interface{}
+	dsts []interface{}
+	ser  sync.Mutex
+}
+
+// NewLocalComm returns a new LocalComm for n workers
+func NewLocalComm(n int) *LocalComm {
+	lc := &LocalComm{N: n}
+	lc.cond = sync.NewCond(&lc.mu)
+	lc.srcs = make([]interface{}, n)
+	lc.dsts = make([]interface{}, n)
+	return lc
+}
+
+// Worker returns the SimComm of the worker of given rank
+func (lc *LocalComm) Worker(rank int) *LocalWorker {
+	return &LocalWorker{Comm: lc, Rnk: rank}
+}
+
+// Barrier waits until all N workers have called it
+func (lc *LocalComm) Barrier() {
+	lc.mu.Lock()
+	gen := lc.gen
+	lc.cnt++
+	if lc.cnt == lc.N {
+		lc.cnt = 0
+		lc.gen++
+		lc.cond.Broadcast()
+	} else {
+		for gen == lc.gen {
+			lc.cond.Wait()
+		}
+	}
+	lc.mu.Unlock()
+}
+
+// Part returns the part of n values reduced by given rank
+func (lc *LocalComm) Part(n, rank int) (st, end int) {
+	pt := (n + lc.N - 1) / lc.N
+	st = rank * pt
+	end = st + pt
+	if st > n {
+		st = n
+	}
+	if end > n {
+		end = n
+	}
+	return
+}
+
+// ReduceOp returns the function for given reduction op: sum, max or min
+func ReduceOp(op mpi.Op) (func(a, b float64) float64, error) {
+	switch op {
+	case mpi.OpSum:
+		return func(a, b float64) float64 { return a + b }, nil
+	case mpi.OpMax:
+		return math.Max, nil
+	case mpi.OpMin:
+		return math.Min, nil
+	}
+	return nil, fmt.Errorf("LocalComm: reduction op %v not supported -- only sum, max and min", op)
+}
+
+// LocalWorker is the SimComm of an in-process worker of a LocalComm
+type LocalWorker struct {
+	Comm *LocalComm `desc:"communicator shared by all the workers"`
+	Rnk  int        `desc:"rank of this worker"`
+}
+
+func (lw *LocalWorker) Rank() int { return lw.Rnk }
+func (lw *LocalWorker) Size() int { return lw.Comm.N }
+
+// post posts the src and dest of this worker, and waits for all the others
+func (lw *LocalWorker) post(src, dest interface{}) {
+	lw.Comm.srcs[lw.Rnk] = src
+	lw.Comm.dsts[lw.Rnk] = dest
+	lw.Comm.Barrier()
+}
+
+func (lw *LocalWorker) AllReduceF32(op mpi.Op, dest, src []float32) error {
+	lc := lw.Comm
+	fun, err := ReduceOp(op)
+	lw.post(src, dest)
+	defer lc.Barrier() // the others may still be reading our src
+	if err != nil {
+		return err
+	}
+	srcs := make([][]float32, lc.N)
+	dsts := make([][]float32, lc.N)
+	n := len(src)
+	for r := range srcs {
+		srcs[r] = lc.srcs[r].([]float32)
+		dsts[r] = lc.dsts[r].([]float32)
+		if len(srcs[r]) != n || len(dsts[r]) != n {
+			return fmt.Errorf("LocalComm AllReduceF32: all src and dest must have the same length: %d", n)
+		}
+	}
+	st, end := lc.Part(n, lw.Rnk)
+	for i := st; i < end; i++ {
+		v := srcs[0][i]
+		for r := 1; r < lc.N; r++ {
+			if op == mpi.OpSum {
+				v += srcs[r][i]
+			} else {
+				v = float32(fun(float64(v), float64(srcs[r][i])))
+			}
+		}
+		for r := range dsts {
+			dsts[r][i] = v
+		}
+	}
+	return nil
+}
+
+func (lw *LocalWorker) ReduceTable(dest, src *etable.Table, op mpi.Op) error {
+	lc := lw.Comm
+	fun, err := ReduceOp(op)
+	dest.SetNumRows(src.Rows)
+	lw.post(src, dest)
+	defer lc.Barrier()
+	if err != nil {
+		return err
+	}
+	srcs := make([]*etable.Table, lc.N)
+	dsts := make([]*etable.Table, lc.N)
+	for r := range srcs {
+		srcs[r] = lc.srcs[r].(*etable.Table)
+		dsts[r] = lc.dsts[r].(*etable.Table)
+		if srcs[r].Rows != src.Rows || len(srcs[r].Cols) != len(src.Cols) || len(dsts[r].Cols) != len(src.Cols) {
+			return fmt.Errorf("LocalComm ReduceTable: all src and dest must have the same rows and columns: %d x %d", src.Rows, len(src.Cols))
+		}
+	}
+	for ci, scl := range src.Cols {
+		if scl.DataType() == etensor.STRING {
+			continue
+		}
+		st, end := lc.Part(scl.Len(), lw.Rnk)
+		for i := st; i < end; i++ {
+			v := srcs[0].Cols[ci].FloatVal1D(i)
+			for r := 1; r < lc.N; r++ {
+				v = fun(v, srcs[r].Cols[ci].FloatVal1D(i))
+			}
+			for r := range dsts {
+				dsts[r].Cols[ci].SetFloat1D(i, v)
+			}
+		}
+	}
+	return nil
+}
+
+func (lw *LocalWorker) GatherTableRows(dest, src *etable.Table) error {
+	lc := lw.Comm
+	lw.post(src, dest)
+	defer lc.Barrier()
+	rows := 0
+	for r := 0; r < lc.N; r++ {
+		rows += lc.srcs[r].(*etable.Table).Rows
+	}
+	dest.SetNumRows(rows)
+	row := 0
+	for r := 0; r < lc.N; r++ {
+		sdt := lc.srcs[r].(*etable.Table)
+		for ci, scl := range sdt.Cols {
+			dcl := dest.Cols[ci]
+			csz := 1
+			if sdt.Rows > 0 {
+				csz = scl.Len() / sdt.Rows
+			}
+			off := row * csz
+			for i := 0; i < sdt.Rows*csz; i++ {
+				if scl.DataType() == etensor.STRING {
+					dcl.SetString1D(off+i, scl.StringVal1D(i))
+				} else {
+					dcl.SetFloat1D(off+i, scl.FloatVal1D(i))
+				}
+			}
+		}
+		row += sdt.Rows
+	}
+	return nil
+}
+
+// Serial calls fun with the lock held that the workers share for it
+func (lw *LocalWorker) Serial(fun func()) {
+	lw.Comm.ser.Lock()
+	defer lw.Comm.ser.Unlock()
+	fun()
+}
+
+// Seed seeds the shared math/rand once, on rank 0, between barriers of all the workers
+func (lw *LocalWorker) Seed(seed int64) {
+	lw.Comm.Barrier()
+	if lw.Rnk == 0 {
+		rand.Seed(seed)
+	}
+	lw.Comm.Barrier()
+}
@@ -0,0 +1,265 @@
+// Copyright (c) 2020, The CCNLab Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"image"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/emer/emergent/emer"
+	"github.com/emer/emergent/prjn"
+	"github.com/emer/etable/etable"
+	"github.com/emer/etable/etensor"
+	"github.com/emer/etable/metric"
+)
+
+// WtAnal has the parameters of the analysis of trained weights in the wtanal
+// subcommand: projection stats, and weight-based receptive fields in V1.
+type WtAnal struct {
+	SparseThr float32  `def:"0.1" desc:"weights below this value count as zero in the Sparse proportion of the projection stats"`
+	RFs       []string `desc:"weight-based receptive fields to compute, as paths of layers from the layer down to the V1 layer (V1m or V1h), each receiving from the next, e.g., V4:V2:V1m -- see WtRFSpec"`
+	RFUnits   int      `def:"2" desc:"number of units in each pool (the first ones) to save the receptive fields of"`
+}
+
+func (wa *WtAnal) Defaults() {
+	wa.SparseThr = 0.1
+	wa.RFs = []string{"V2:V1m", "V4:V2:V1m", "TEO:V4:V2:V1m"}
+	wa.RFUnits = 2
+}
+
+// PrjnWts are the weights of a projection organized by receiving unit, in the
+// order of the connections of each, with the index of the sending unit of each.
+type PrjnWts struct {
+	Name string      `desc:"name of the projection"`
+	Wts  [][]float32 `desc:"weights of each receiving unit"`
+	Sidx [][]int     `desc:"sending unit index of each weight"`
+}
+
+// WtStats are summary statistics of the weights of a projection
+type WtStats struct {
+	N      int     `desc:"number of weights"`
+	Mean   float64 `desc:"mean weight"`
+	Var    float64 `desc:"variance of the weights"`
+	Max    float64 `desc:"maximum weight"`
+	Sparse float64 `desc:"proportion of the weights below the SparseThr"`
+}
+
+// Stats returns the summary stats of the weights, with weights below thr
+// counted as zero in the Sparse proportion
+func (pw *PrjnWts) Stats(thr float32) WtStats {
+	st := WtStats{}
+	nsp := 0
+	for _, rw := range pw.Wts {
+		for _, w := range rw {
+			if st.N == 0 || float64(w) > st.Max {
+				st.Max = float64(w)
+			}
+			st.N++
+			st.Mean += float64(w)
+			if w < thr {
+				nsp++
+			}
+		}
+	}
+	if st.N == 0 {
+		return st
+	}
+	st.Mean /= float64(st.N)
+	for _, rw := range pw.Wts {
+		for _, w := range rw {
+			d := float64(w) - st.Mean
+			st.Var += d * d
+		}
+	}
+	st.Var /= float64(st.N)
+	st.Sparse = float64(nsp) / float64(st.N)
+	return st
+}
+
+// PrjnTopoScales returns the topographic scales of the PoolTile pattern of the
+// projection (see PoolTile.TopoWts), or nil if the pattern is not a PoolTile
+// with a Gaussian or sigmoidal topography turned on
+func PrjnTopoScales(pj emer.Prjn) (*etensor.Float32, error) {
+	pt, ok := pj.Pattern().(*prjn.PoolTile)
+	if !ok || !(pt.GaussFull.On || pt.GaussInPool.On || pt.SigFull.On || pt.SigInPool.On) {
+		return nil, nil
+	}
+	scales := &etensor.Float32{}
+	if err := pt.TopoWts(pj.SendLay().Shape(), pj.RecvLay().Shape(), scales); err != nil {
+		return nil, fmt.Errorf("%s: %v", pj.Name(), err)
+	}
+	return scales, nil
+}
+
+// TopoCor returns the correlation across all the weights between the weights
+// and the topographic scales (as returned by PrjnTopoScales), which are indexed
+// by the unit within the receiving pool and the connection, as in SetScalesRPool:
+// how much the weights follow the topography of the pattern.
+func (pw *PrjnWts) TopoCor(scales *etensor.Float32) float64 {
+	rnu := scales.Dim(0) * scales.Dim(1)
+	rfsz := scales.Len() / rnu
+	var wts, scs []float32
+	for ri, rw := range pw.Wts {
+		scst := (ri % rnu) * rfsz
+		for ci, w := range rw {
+			if ci >= rfsz {
+				break
+			}
+			wts = append(wts, w)
+			scs = append(scs, scales.Values[scst+ci])
+		}
+	}
+	if len(wts) == 0 {
+		return math.NaN()
+	}
+	return float64(metric.Correlation32(wts, scs))
+}
+
+// ConfigWtAnalLog configures the table of projection stats for the receiving projections of a layer
+func ConfigWtAnalLog(dt *etable.Table, lay string) {
+	dt.SetMetaData("name", "WtAnal_"+lay)
+	dt.SetMetaData("desc", "stats of the weights of the receiving projections of "+lay)
+	dt.SetMetaData("precision", strconv.Itoa(LogPrec))
+	sch := etable.Schema{
+		{"Prjn", etensor.STRING, nil, nil},
+		{"Send", etensor.STRING, nil, nil},
+		{"Class", etensor.STRING, nil, nil},
+		{"Pat", etensor.STRING, nil, nil},
+		{"N", etensor.INT64, nil, nil},
+		{"Mean", etensor.FLOAT64, nil, nil},
+		{"Var", etensor.FLOAT64, nil, nil},
+		{"Max", etensor.FLOAT64, nil, nil},
+		{"Sparse", etensor.FLOAT64, nil, nil},
+		{"TopoCor", etensor.FLOAT64, nil, nil},
+		{"TopoCorInit", etensor.FLOAT64, nil, nil},
+	}
+	dt.SetFromSchema(sch, 0)
+}
+
+// AddWtAnal adds a row of the stats of the projection to the table, with the
+// TopoCor of the trained and initial weights (NaN if not topographic)
+func AddWtAnal(dt *etable.Table, pj emer.Prjn, st WtStats, topo, topoInit float64) {
+	row := dt.Rows
+	dt.SetNumRows(row + 1)
+	dt.SetCellString("Prjn", row, pj.Name())
+	dt.SetCellString("Send", row, pj.SendLay().Name())
+	dt.SetCellString("Class", row, pj.Class())
+	dt.SetCellString("Pat", row, pj.Pattern().Name())
+	dt.SetCellFloat("N", row, float64(st.N))
+	dt.SetCellFloat("Mean", row, st.Mean)
+	dt.SetCellFloat("Var", row, st.Var)
+	dt.SetCellFloat("Max", row, st.Max)
+	dt.SetCellFloat("Sparse", row, st.Sparse)
+	dt.SetCellFloat("TopoCor", row, topo)
+	dt.SetCellFloat("TopoCorInit", row, topoInit)
+}
+
+// WtRFSpec specifies a weight-based receptive field: a path of layers from the
+// layer down to the V1 layer (V1m or V1h, named for its V1 filtering), each
+// receiving a projection from the next.
+type WtRFSpec struct {
+	Lays []string `desc:"layers of the path, from the layer to the V1 layer"`
+}
+
+// ParseWtRFSpec parses a lay:...:vis spec
+func ParseWtRFSpec(spec string) (WtRFSpec, error) {
+	rs := WtRFSpec{Lays: strings.Split(spec, ":")}
+	if len(rs.Lays) < 2 {
+		return rs, fmt.Errorf("WtRFSpec: must be lay:...:vis, not: %s", spec)
+	}
+	if vis := rs.Vis(); vis != "V1m" && vis != "V1h" {
+		return rs, fmt.Errorf("WtRFSpec %s: must end in V1m or V1h", spec)
+	}
+	return rs, nil
+}
+
+// Lay returns the layer whose receptive fields are computed
+func (rs *WtRFSpec) Lay() string {
+	return rs.Lays[0]
+}
+
+// Vis returns the V1 layer at the end of the path
+func (rs *WtRFSpec) Vis() string {
+	return rs.Lays[len(rs.Lays)-1]
+}
+
+// Name returns the name of the spec used in file names: Lay_Vis
+func (rs *WtRFSpec) Name() string {
+	return rs.Lay() + "_" + rs.Vis()
+}
+
+// WtRF computes weight-based receptive fields in the V1 layer, by back-projecting
+// the weights of the projections along the path of a WtRFSpec: the RF of a unit
+// is the sum of the RFs of its sending units times its weights from them.
+type WtRF struct {
+	Prjns []*PrjnWts          `desc:"projections of the path, Prjns[i] from layer i+1 to layer i"`
+	NV    int                 `desc:"number of units in the V1 layer"`
+	RFs   []map[int][]float32 `view:"-" desc:"RFs computed so far, by layer of the path and unit"`
+}
+
+// Init initializes for the projections of the path, to the V1 layer of nv units
+func (wr *WtRF) Init(prjns []*PrjnWts, nv int) {
+	wr.Prjns = prjns
+	wr.NV = nv
+	wr.RFs = make([]map[int][]float32, len(prjns))
+	for i := range wr.RFs {
+		wr.RFs[i] = make(map[int][]float32)
+	}
+}
+
+// RF returns the receptive field in the V1 layer of unit ui of layer li of the path
+func (wr *WtRF) RF(li, ui int) []float32 {
+	if rf, ok := wr.RFs[li][ui]; ok {
+		return rf
+	}
+	rf := make([]float32, wr.NV)
+	pw := wr.Prjns[li]
+	last := li == len(wr.Prjns)-1
+	for ci, si := range pw.Sidx[ui] {
+		w := pw.Wts[ui][ci]
+		if last {
+			rf[si] += w
+			continue
+		}
+		for i, v := range wr.RF(li+1, si) {
+			rf[i] += w * v
+		}
+	}
+	wr.RFs[li][ui] = rf
+	return rf
+}
+
+// ConfigWtRFLog configures the table of the receptive fields of a WtRFSpec,
+// in the V1 layer of given shape
+func ConfigWtRFLog(dt *etable.Table, rs *WtRFSpec, shp []int) {
+	dt.SetMetaData("name", "WtRF_"+rs.Name())
+	dt.SetMetaData("desc", "weight-based receptive fields of "+rs.Lay()+" in "+rs.Vis())
+	dt.SetMetaData("precision", strconv.Itoa(LogPrec))
+	sch := etable.Schema{
+		{"Pool", etensor.INT64, nil, nil},
+		{"Unit", etensor.INT64, nil, nil},
+		{"RF", etensor.FLOAT32, shp, nil},
+	}
+	dt.SetFromSchema(sch, 0)
+}
+
+// RFImage renders the receptive field in the V1 layer, of given shape, through
+// the V1 filters of vi -- the RF is centered on its mean, so that the image shows
+// the features weighted more than average.
+func RFImage(rc *Recon, vi *Vis, rf []float32, shp []int) (*image.Gray, error) {
+	vt := etensor.NewFloat32(shp, nil, nil)
+	mn := float32(0)
+	for _, v := range rf {
+		mn += v
+	}
+	mn /= float32(len(rf))
+	for i, v := range rf {
+		vt.Values[i] = v - mn
+	}
+	return rc.Image(vi, vt, 0)
+}
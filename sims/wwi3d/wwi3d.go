@@ -11,14 +11,21 @@ wwi = what, where integration: both pathways combine to predict object --
 package main
 
 import (
+	"bufio"
+	"bytes"
+	"encoding/json"
 	"flag"
 	"fmt"
 	"log"
+	"math"
 	"math/rand"
 	"os"
+	"path/filepath"
 	"runtime"
+	"sort"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	// "github.com/ccnlab/leabrax/deep"
@@ -29,8 +36,6 @@ import (
 	"github.com/emer/emergent/netview"
 	"github.com/emer/emergent/params"
 	"github.com/emer/emergent/prjn"
-	"github.com/emer/emergent/relpos"
-	"github.com/emer/empi/empi"
 	"github.com/emer/empi/mpi"
 	"github.com/emer/etable/agg"
 	"github.com/emer/etable/eplot"
@@ -38,6 +43,7 @@ import (
 	"github.com/emer/etable/etensor"
 	"github.com/emer/etable/etview" // include to get gui views
 	"github.com/emer/etable/metric"
+	"github.com/emer/etable/simat"
 	"github.com/emer/etable/split"
 
 	"github.com/emer/leabra/deep"
@@ -79,50 +85,81 @@ const LogPrec = 4
 // as arguments to methods, and provides the core GUI interface (note the view tags
 // for the fields which provide hints to how things should be displayed).
 type Sim struct {
-	Net              *deep.Network     `view:"no-inline" desc:"the network -- click to view / edit parameters for layers, prjns, etc"`
-	LIPOnly          bool              `desc:"if true, only build, train the LIP portion"`
-	BinarizeV1       bool              `desc:"if true, V1 inputs are binarized -- todo: test continued need for this"`
-	TrnTrlLog        *etable.Table     `view:"no-inline" desc:"training trial-level log data"`
-	TrnTrlLogAll     *etable.Table     `view:"no-inline" desc:"all training trial-level log data (aggregated from MPI)"`
-	CatLayActs       *etable.Table     `view:"no-inline" desc:"super layer activations per category / object"`
-	CatLayActsDest   *etable.Table     `view:"no-inline" desc:"MPI dest super layer activations per category / object"`
-	RSA              RSA               `view:"no-inline" desc:"RSA data"`
-	TrnEpcLog        *etable.Table     `view:"no-inline" desc:"training epoch-level log data"`
-	TstEpcLog        *etable.Table     `view:"no-inline" desc:"testing epoch-level log data"`
-	TstTrlLog        *etable.Table     `view:"no-inline" desc:"testing trial-level log data"`
-	TstTrlLogAll     *etable.Table     `view:"no-inline" desc:"all testing trial-level log data (aggregated from MPI)"`
-	ActRFs           actrf.RFs         `view:"no-inline" desc:"activation-based receptive fields"`
-	RunLog           *etable.Table     `view:"no-inline" desc:"summary log of each run"`
-	RunStats         *etable.Table     `view:"no-inline" desc:"aggregate stats on all runs"`
-	Params           params.Sets       `view:"no-inline" desc:"full collection of param sets"`
-	ParamSet         string            `desc:"which set of *additional* parameters to use -- always applies Base and optionaly this next if set"`
-	Tag              string            `desc:"extra tag string to add to any file names output from sim (e.g., weights files, log files, params for run)"`
-	Prjn4x4Skp2      *prjn.PoolTile    `view:"Standard feedforward topographic projection, recv = 1/2 send size"`
-	Prjn4x4Skp2Recip *prjn.PoolTile    `view:"Reciprocal"`
-	Prjn2x2Skp2      *prjn.PoolTile    `view:"sparser skip 2 -- no overlap"`
-	Prjn2x2Skp2Recip *prjn.PoolTile    `view:"Reciprocal"`
-	Prjn3x3Skp1      *prjn.PoolTile    `view:"Standard same-to-same size topographic projection"`
-	PrjnSigTopo      *prjn.PoolTile    `view:"sigmoidal topographic projection used in LIP saccade remapping layers"`
-	PrjnGaussTopo    *prjn.PoolTile    `view:"gaussian topographic projection used in LIP saccade remapping layers"`
-	StartRun         int               `desc:"starting run number -- typically 0 but can be set in command args for parallel runs on a cluster"`
-	MaxRuns          int               `desc:"maximum number of model runs to perform (starting from StartRun)"`
-	MaxEpcs          int               `desc:"maximum number of epochs to run per model run"`
-	MaxTrls          int               `desc:"maximum number of training trials per epoch (each trial is MaxTicks ticks)"`
-	MaxTicks         int               `desc:"max number of ticks, for logs, stats"`
-	NZeroStop        int               `desc:"if a positive number, training will stop after this many epochs with zero SSE"`
-	TrainEnv         Obj3DSacEnv       `desc:"Training environment -- 3D Object training"`
-	TestEnv          Obj3DSacEnv       `desc:"Testing environment -- testing 3D Objects"`
-	Time             leabra.Time       `desc:"leabra timing parameters and state"`
-	ViewOn           bool              `desc:"whether to update the network view while running"`
-	TrainUpdt        leabra.TimeScales `desc:"at what time scale to update the display during training?  Anything longer than Epoch updates at Epoch in this model"`
-	TestUpdt         leabra.TimeScales `desc:"at what time scale to update the display during testing?  Anything longer than Epoch updates at Epoch in this model"`
-	LayStatNms       []string          `desc:"names of layers to collect more detailed stats on (avg act, etc)"`
-	ActRFNms         []string          `desc:"names of layers to compute activation rfields on"`
+	Net               *deep.Network     `view:"no-inline" desc:"the network -- click to view / edit parameters for layers, prjns, etc"`
+	LIPOnly           bool              `desc:"if true, only build, train the LIP portion"`
+	BinarizeV1        bool              `desc:"if true, V1 inputs are binarized -- todo: test continued need for this"`
+	TrnTrlLog         *etable.Table     `view:"no-inline" desc:"training trial-level log data"`
+	TrnTrlLogAll      *etable.Table     `view:"no-inline" desc:"all training trial-level log data (aggregated from MPI)"`
+	TrnTrlRepLog      *etable.Table     `view:"no-inline" desc:"training trial-level reps log data"`
+	TrnTrlRepLogAll   *etable.Table     `view:"no-inline" desc:"all training trial-level reps log data (aggregated from MPI)"`
+	CatLayActs        *etable.Table     `view:"no-inline" desc:"super layer activations per category / object"`
+	CatLayActsDest    *etable.Table     `view:"no-inline" desc:"MPI dest super layer activations per category / object"`
+	TstCatLayActs     *etable.Table     `view:"no-inline" desc:"layer activations for each cat / obj averaged over the testing trials of TestAll, for the test-set RSA in TstRSA"`
+	TstCatLayActsDest *etable.Table     `view:"no-inline" desc:"MPI dest testing layer activations per category / object"`
+	TstRSA            RSA               `view:"no-inline" desc:"RSA stats of the TstCatLayActs -- uses the analysis params of RSA"`
+	RSA               RSA               `view:"no-inline" desc:"RSA data"`
+	Embed             Embed             `view:"no-inline" desc:"low-dimensional embeddings of layer representations, computed every RSA.Interval epochs"`
+	Geom              Geom              `view:"no-inline" desc:"representational geometry metrics (dimensionality, sparseness, selectivity) of layer representations, computed every RSA.Interval epochs"`
+	Drift             Drift             `view:"no-inline" desc:"representational drift and category emergence over epochs, from the RSA similarity matricies computed every RSA.Interval epochs"`
+	Probe             Probe             `view:"no-inline" desc:"linear decoding probes on TrnTrlRepLog layer representations, run every RSA.Interval epochs"`
+	Invar             Invar             `view:"no-inline" desc:"invariance of TrnTrlRepLog layer representations across views, eye positions and saccades, computed every RSA.Interval epochs"`
+	TrnEpcLog         *etable.Table     `view:"no-inline" desc:"training epoch-level log data"`
+	TstEpcLog         *etable.Table     `view:"no-inline" desc:"testing epoch-level log data"`
+	TstTrlLog         *etable.Table     `view:"no-inline" desc:"testing trial-level log data"`
+	TstTrlLogAll      *etable.Table     `view:"no-inline" desc:"all testing trial-level log data (aggregated from MPI)"`
+	ActRFs            actrf.RFs         `view:"no-inline" desc:"activation-based receptive fields"`
+	RunLog            *etable.Table     `view:"no-inline" desc:"summary log of each run"`
+	RunStats          *etable.Table     `view:"no-inline" desc:"aggregate stats on all runs"`
+	Params            params.Sets       `view:"no-inline" desc:"full collection of param sets"`
+	ParamSet          string            `desc:"which set of *additional* parameters to use -- always applies Base and optionaly this next if set"`
+	XParamSets        []string          `desc:"names of additional param sets applied in order after Base and ParamSet, loaded with OpenXParams (e.g., -xparams from the sweep command)"`
+	Tag               string            `desc:"extra tag string to add to any file names output from sim (e.g., weights files, log files, params for run)"`
+	Prjn4x4Skp2       *prjn.PoolTile    `view:"Standard feedforward topographic projection, recv = 1/2 send size"`
+	Prjn4x4Skp2Recip  *prjn.PoolTile    `view:"Reciprocal"`
+	Prjn2x2Skp2       *prjn.PoolTile    `view:"sparser skip 2 -- no overlap"`
+	Prjn2x2Skp2Recip  *prjn.PoolTile    `view:"Reciprocal"`
+	Prjn3x3Skp1       *prjn.PoolTile    `view:"Standard same-to-same size topographic projection"`
+	PrjnSigTopo       *prjn.PoolTile    `view:"sigmoidal topographic projection used in LIP saccade remapping layers"`
+	PrjnGaussTopo     *prjn.PoolTile    `view:"gaussian topographic projection used in LIP saccade remapping layers"`
+	StartRun          int               `desc:"starting run number -- typically 0 but can be set in command args for parallel runs on a cluster"`
+	MaxRuns           int               `desc:"maximum number of model runs to perform (starting from StartRun)"`
+	MaxEpcs           int               `desc:"maximum number of epochs to run per model run"`
+	MaxTrls           int               `desc:"maximum number of training trials per epoch (each trial is MaxTicks ticks)"`
+	MaxTstTrls        int               `desc:"maximum number of testing trials per epoch"`
+	MaxTicks          int               `desc:"max number of ticks, for logs, stats"`
+	NZeroStop         int               `desc:"if a positive number, training will stop after this many epochs with zero SSE"`
+	TrainEnv          Obj3DSacEnv       `desc:"Training environment -- 3D Object training"`
+	TestEnv           Obj3DSacEnv       `desc:"Testing environment -- testing 3D Objects"`
+	Time              leabra.Time       `desc:"leabra timing parameters and state"`
+	ViewOn            bool              `desc:"whether to update the network view while running"`
+	TrainUpdt         leabra.TimeScales `desc:"at what time scale to update the display during training?  Anything longer than Epoch updates at Epoch in this model"`
+	TestUpdt          leabra.TimeScales `desc:"at what time scale to update the display during testing?  Anything longer than Epoch updates at Epoch in this model"`
+	LayStatNms        []string          `desc:"names of layers to collect more detailed stats on (avg act, etc)"`
+	ActRFNms          []string          `desc:"activation-based receptive fields to compute during testing, as lay:src specs, where src is one of the ActRFSrcs of the env: Image, V1m, V1h, EyePos, SacPlan, Saccade, ObjVel, Cat, Obj -- see UpdtActRFs"`
+	RSALays           []string          `desc:"layers and variables recorded in CatLayActs and analyzed by the RSA, as layer[:var[:ctr]] specs, e.g., TE, TECT:ActP, V4:ActM:ctr -- var defaults to ActM, and ctr restricts to the center pools of 4D layers -- if empty, all super, CT and pulvinar layers are used -- must be set before Config"`
+	RepRDMs           bool              `desc:"save the trial-level RDMs of the TrnTrlRepLog reps for each of the HidLays every RSA.Interval epochs, as .npy files computed and written block-by-block by RSA.Stream, with the Obj label of each row in a reprdm_rows log file"`
+	CkptInterval      int               `desc:"if > 0, save a checkpoint of the full training state every this many epochs, from which an interrupted run can be resumed exactly with -resume -- see SaveCkpt"`
+	TestInterval      int               `desc:"if > 0, training is paused every this many epochs to run TestAll on the held-out TestEnv items without learning, recording the test-set pulvinar CosDiff, layer stats and RSA in TstEpcLog -- see PeriodicTest"`
+	ActRFLog          bool              `desc:"if true, save the ActRFs after each periodic test (TestInterval) when running without the gui"`
+	Sched             EpochSched        `view:"no-inline" desc:"schedule of actions triggered at given training epochs: lrate changes, weight saves, layers on / off, params, tests -- set at Config from SchedFile if specified, else from the Scheds for the current ParamSet"`
+	Conv              ConvMon           `view:"no-inline" desc:"convergence monitor of epoch log columns (e.g., pulvinar CosDiff, TE CatDst), with smoothing, patience and minimum-delta rules, which can end the run, save the weights, or perform EpochSched actions when converged -- loaded from ConvFile"`
+	NetSpec           NetSpec           `view:"no-inline" desc:"declarative specification of the network architecture: layers, projections, positions and threads -- DefNetSpec unless loaded from NetSpecFile -- see ConfigNetSpec"`
+	ImagesDir         string            `desc:"directory of the rendered images dataset, with train and test subdirectories -- must be set before Config"`
+	ReconLays         []string          `desc:"layers to reconstruct V1 images from in the reconstruct subcommand, as lay:vis[:row] specs, where vis is the V1m or V1h filtering that the layer pools represent, starting at unit row row -- see ReconSpec"`
+	WtAnal            WtAnal            `view:"inline" desc:"parameters of the analysis of trained weights in the wtanal subcommand: projection stats and weight-based receptive fields in V1 -- see CmdWtAnal"`
+	NpzExport         NpzExport         `view:"inline" desc:"export of trial-level layer activations and trial metadata as .npy arrays in a .npz archive per epoch, for analysis in python -- see SaveNpz"`
+	SchedFile         string            `desc:"if set, name of a JSON file to load the Sched from, instead of using the compiled-in Scheds for the ParamSet"`
+	ConvFile          string            `desc:"if set, name of a JSON file to load the Conv monitor from -- no monitoring otherwise"`
+	NetSpecFile       string            `desc:"if set, name of a JSON file to load the NetSpec from, instead of DefNetSpec -- must be set before Config"`
+	LesionFile        string            `desc:"name of a JSON file with the list of Lesions to test in the lesion subcommand -- see CmdLesion"`
+	StatusAddr        string            `desc:"if set, localhost:port address of a StatusServer to serve the status of the training run on, for monitoring headless runs -- see StatusServer"`
 
 	// statistics: note use float64 as that is best for etable.Table
 	PulvLays       []string  `view:"-" desc:"pulvinar layers -- for stats"`
 	HidLays        []string  `view:"-" desc:"hidden layers: super and CT -- for hogging stats"`
 	SuperLays      []string  `view:"-" desc:"superficial layers"`
+	RSASpecs       []RSALay  `view:"-" desc:"parsed RSALays specs"`
+	RSACols        []string  `view:"-" desc:"CatLayActs column (RSA layer) names for the RSALays: all layers recorded in CatLayActs for RSA and layer-by-layer comparison"`
 	PulvCosDiff    []float64 `inactive:"+" desc:"trial stats cos diff for pulvs"`
 	PulvAvgSSE     []float64 `inactive:"+" desc:"trial stats AvgSSE for pulvs"`
 	PulvTrlCosDiff []float64 `inactive:"+" desc:"trial stats trial cos diff for pulvs"`
@@ -142,10 +179,20 @@ type Sim struct {
 	TstEpcPlot   *eplot.Plot2D                 `view:"-" desc:"the testing epoch plot"`
 	TstTrlPlot   *eplot.Plot2D                 `view:"-" desc:"the test-trial plot"`
 	RunPlot      *eplot.Plot2D                 `view:"-" desc:"the run plot"`
+	EmbedPlot    *eplot.Plot2D                 `view:"-" desc:"the embedding plot for the first Embed layer"`
 	TrnEpcFile   *os.File                      `view:"-" desc:"log file"`
 	TrnTrlFile   *os.File                      `view:"-" desc:"log file"`
+	TstEpcFile   *os.File                      `view:"-" desc:"log file"`
+	TstTrlFile   *os.File                      `view:"-" desc:"log file"`
+	TstCatN      []float32                     `view:"-" desc:"number of testing trials summed into each row of TstCatLayActs"`
+	NpzSpecs     []RSALay                      `view:"-" desc:"parsed NpzExport.Lays specs"`
+	NpzStrms     []*NpyStream                  `view:"-" desc:"streams of the .npz arrays of the trials recorded so far, in order: NpzIdxs, ProbeRegs, NpzSpecs"`
+	Status       *StatusServer                 `view:"-" desc:"status server, if StatusAddr is set (rank 0 only under MPI)"`
+	Manifest     *Manifest                     `view:"-" desc:"provenance manifest of the runs, saved as JSON with the logs when running from the command line -- see InitManifest"`
+	ParamsRec    ParamsRec                     `view:"-" desc:"the params in effect on each layer, projection and the sim, as applied by SetParamsSet, for the Manifest"`
 	RunFile      *os.File                      `view:"-" desc:"log file"`
 	ValsTsrs     map[string]*etensor.Float32   `view:"-" desc:"for holding layer values"`
+	OutDir       string                        `view:"-" desc:"directory for all the files saved by the sim: logs, weights, analyses -- current directory if empty"`
 	SaveWts      bool                          `view:"-" desc:"for command-line run only, auto-save final weights after each run"`
 	NoGui        bool                          `view:"-" desc:"if true, runing in no GUI mode"`
 	LogSetParams bool                          `view:"-" desc:"if true, print message for all params that are set"`
@@ -153,12 +200,18 @@ type Sim struct {
 	StopNow      bool                          `view:"-" desc:"flag to stop running"`
 	NeedsNewRun  bool                          `view:"-" desc:"flag to initialize NewRun if last one finished"`
 	RndSeeds     []int64                       `view:"-" desc:"the current random seeds to use for each run"`
+	LesionSt     LesionState                   `view:"-" desc:"state of the current Lesion of the network, to undo it"`
+	LrateMult    float32                       `view:"-" desc:"current learning rate multiplier set by the Sched"`
+	SchedActs    string                        `view:"-" desc:"actions triggered by the Sched at the end of the last epoch, recorded in the epoch log"`
+	EpcSeed      int64                         `view:"-" desc:"random seed with which EpochRndSeed re-seeded the random number generator at the start of the current epoch"`
+	CkptPending  bool                          `view:"-" desc:"set by OpenCkpt: the env is already at the first trial of the checkpoint epoch, so the next TrainTrial does not step it"`
 	LastEpcTime  time.Time                     `view:"-" desc:"timer for last epoch"`
 	LastTrlTime  time.Time                     `view:"-" desc:"timer for last trial"`
 
 	UseMPI      bool      `view:"-" desc:"if true, use MPI to distribute computation across nodes"`
+	Workers     int       `view:"-" desc:"if > 1, number of in-process workers to distribute training across without MPI, each training a replica of the sim on its own goroutine -- see NewWorkers"`
 	SaveProcLog bool      `view:"-" desc:"if true, save logs per processor"`
-	Comm        *mpi.Comm `view:"-" desc:"mpi communicator"`
+	Comm        SimComm   `view:"-" desc:"communicator of data-parallel training, across MPI procs or in-process Workers -- nil if not distributed"`
 	AllDWts     []float32 `view:"-" desc:"buffer of all dwt weight changes -- for mpi sharing"`
 	SumDWts     []float32 `view:"-" desc:"buffer of MPI summed dwt weight changes"`
 }
@@ -172,20 +225,11 @@ var TheSim Sim
 
 // New creates new blank elements and initializes defaults
 func (ss *Sim) New() {
-	ss.Net = &deep.Network{}
+	ss.NewObjs()
 	ss.LIPOnly = false
 	ss.BinarizeV1 = true
-	ss.TrnTrlLog = &etable.Table{}
-	ss.TrnTrlLogAll = &etable.Table{}
-	ss.CatLayActs = &etable.Table{}
-	ss.CatLayActsDest = &etable.Table{}
-	ss.TrnEpcLog = &etable.Table{}
-	ss.TstEpcLog = &etable.Table{}
-	ss.TstTrlLog = &etable.Table{}
-	ss.TstTrlLogAll = &etable.Table{}
-	ss.RunLog = &etable.Table{}
-	ss.RunStats = &etable.Table{}
 	ss.Params = ParamSets
+	ss.NetSpec = DefNetSpec
 	ss.RndSeeds = make([]int64, 100) // make enough for plenty of runs
 	for i := 0; i < 100; i++ {
 		ss.RndSeeds[i] = int64(i) + 1 // exclude 0
@@ -194,13 +238,53 @@ func (ss *Sim) New() {
 	ss.TrainUpdt = leabra.Phase
 	ss.TestUpdt = leabra.Phase
 	ss.LayStatNms = []string{"LIPP"}
-	ss.ActRFNms = []string{"V4:Image", "V4:Output", "IT:Image", "IT:Output"}
+	ss.ActRFNms = []string{"V4:Image", "TEO:Image", "TE:Cat", "TE:Obj", "LIP:EyePos", "LIP:SacPlan", "LIPCT:ObjVel"}
 	ss.Defaults()
 }
 
+// NewObjs creates the new blank network and log tables
+func (ss *Sim) NewObjs() {
+	ss.Net = &deep.Network{}
+	ss.TrnTrlLog = &etable.Table{}
+	ss.TrnTrlLogAll = &etable.Table{}
+	ss.TrnTrlRepLog = &etable.Table{}
+	ss.TrnTrlRepLogAll = &etable.Table{}
+	ss.CatLayActs = &etable.Table{}
+	ss.CatLayActsDest = &etable.Table{}
+	ss.TstCatLayActs = &etable.Table{}
+	ss.TstCatLayActsDest = &etable.Table{}
+	ss.TrnEpcLog = &etable.Table{}
+	ss.TstEpcLog = &etable.Table{}
+	ss.TstTrlLog = &etable.Table{}
+	ss.TstTrlLogAll = &etable.Table{}
+	ss.RunLog = &etable.Table{}
+	ss.RunStats = &etable.Table{}
+}
+
 // Defaults sets default values for params / prjns
 func (ss *Sim) Defaults() {
 	ss.RSA.Interval = 10
+	ss.RSA.Tick = 2
+	ss.RSA.TickMin = 0
+	ss.RSA.TickMax = -1
+	ss.RSA.RBFSigma = 1
+	ss.RSA.Stream.Defaults()
+	ss.RSA.Stream.Float32 = true
+	ss.RSA.Stream.Packed = true
+	ss.RSA.Tree = LbaCatTree()
+	ss.RSA.TreeLays = []string{"TE"}
+	ss.RSA.TreeTick = -1
+	ss.RSA.TreeNPerm = 100
+	ss.RSA.TreeSeed = 1
+	ss.ImagesDir = "images"
+	ss.ReconLays = []string{"V1h:V1h", "V2P:V1m:0"}
+	ss.WtAnal.Defaults()
+	ss.NpzExport.Defaults()
+	ss.Probe.Defaults()
+	ss.Invar.Defaults()
+	ss.Embed.Defaults()
+	ss.Geom.Defaults()
+	ss.Drift.Defaults()
 
 	ss.Prjn4x4Skp2 = prjn.NewPoolTile()
 	ss.Prjn4x4Skp2.Size.Set(4, 4)
@@ -267,18 +351,24 @@ func (ss *Sim) Defaults() {
 func (ss *Sim) Config() {
 	ss.ConfigEnv()
 	ss.ConfigNet(ss.Net)
+	ss.ConfigSched()
 	ss.InitStats()
-	ss.ConfigCatLayActs(ss.CatLayActs)
-	if ss.UseMPI {
-		ss.ConfigCatLayActs(ss.CatLayActsDest)
+	ss.ConfigCatLayActs(ss.CatLayActs, ss.TrainEnv.Objs)
+	ss.ConfigCatLayActs(ss.TstCatLayActs, ss.TestEnv.Objs)
+	if ss.Comm != nil {
+		ss.ConfigCatLayActs(ss.CatLayActsDest, ss.TrainEnv.Objs)
+		ss.ConfigCatLayActs(ss.TstCatLayActsDest, ss.TestEnv.Objs)
 	}
 	ss.ConfigTrnTrlLog(ss.TrnTrlLog)
 	ss.ConfigTrnTrlLog(ss.TrnTrlLogAll)
+	ss.ConfigTrnTrlRepLog(ss.TrnTrlRepLog)
+	ss.ConfigTrnTrlRepLog(ss.TrnTrlRepLogAll)
 	ss.ConfigTrnEpcLog(ss.TrnEpcLog)
 	ss.ConfigTstEpcLog(ss.TstEpcLog)
 	ss.ConfigTstTrlLog(ss.TstTrlLog)
 	ss.ConfigTstTrlLog(ss.TstTrlLogAll)
 	ss.ConfigRunLog(ss.RunLog)
+	ss.ConfigConv() // after the epoch logs, to check the columns
 }
 
 func (ss *Sim) ConfigEnv() {
@@ -293,6 +383,9 @@ func (ss *Sim) ConfigEnv() {
 		}
 		ss.NZeroStop = -1
 	}
+	if ss.MaxTstTrls == 0 { // allow user override
+		ss.MaxTstTrls = 500
+	}
 	if ss.MaxTrls == 0 { // allow user override
 		ss.MaxTrls = 64
 		ss.MaxTicks = 8
@@ -301,6 +394,7 @@ func (ss *Sim) ConfigEnv() {
 	ss.TrainEnv.Nm = "TrainEnv"
 	ss.TrainEnv.Dsc = "training params and state"
 	ss.TrainEnv.Defaults()
+	ss.TrainEnv.Path = filepath.Join(ss.ImagesDir, "train")
 	ss.TrainEnv.Run.Max = ss.MaxRuns // note: we are not setting epoch max -- do that manually
 	ss.TrainEnv.Trial.Max = ss.MaxTrls
 	ss.TrainEnv.V1Med.Binarize = ss.BinarizeV1
@@ -309,32 +403,67 @@ func (ss *Sim) ConfigEnv() {
 	ss.TestEnv.Nm = "TestEnv"
 	ss.TestEnv.Dsc = "testing params and state"
 	ss.TestEnv.Defaults()
-	ss.TestEnv.Path = "images/test"
-	ss.TestEnv.Trial.Max = 500
+	ss.TestEnv.Path = filepath.Join(ss.ImagesDir, "test")
+	ss.TestEnv.Trial.Max = ss.MaxTstTrls
 	ss.TestEnv.V1Med.Binarize = ss.BinarizeV1
 	ss.TestEnv.V1Hi.Binarize = ss.BinarizeV1
 
 	ss.TrainEnv.Init(0)
 	ss.TestEnv.Init(0)
-	if ss.UseMPI { // filter trials to subset for each proc
-		st, ed, _ := empi.AllocN(ss.MaxTrls)
+	if ss.Comm != nil { // filter trials to subset for each proc
+		st, ed, _ := AllocN(ss.MaxTrls, ss.Rank(), ss.NProcs())
 		ss.TrainEnv.IdxView = etable.NewIdxView(ss.TrainEnv.Table)
 		ss.TrainEnv.IdxView.Filter(func(et *etable.Table, row int) bool {
 			trl := int(et.CellFloat("Trial", row))
 			return trl >= st && trl < ed
 		})
-		mpi.Printf("trial allocs: %d .. %d  idx len: %d\n", st, ed, ss.TrainEnv.IdxView.Len())
+		ss.Printf("trial allocs: %d .. %d  idx len: %d\n", st, ed, ss.TrainEnv.IdxView.Len())
+		tst, ted, _ := AllocN(ss.MaxTstTrls, ss.Rank(), ss.NProcs())
+		ss.TestEnv.IdxView = etable.NewIdxView(ss.TestEnv.Table)
+		ss.TestEnv.IdxView.Filter(func(et *etable.Table, row int) bool {
+			trl := int(et.CellFloat("Trial", row))
+			return trl >= tst && trl < ted
+		})
+		ss.Printf("test trial allocs: %d .. %d  idx len: %d\n", tst, ted, ss.TestEnv.IdxView.Len())
 	}
 	ss.TrainEnv.Validate()
 	ss.TestEnv.Validate()
 }
 
-func (ss *Sim) ConfigNet(net *deep.Network) {
-	net.InitName(net, "WWI3D")
-	ss.ConfigNetLIP(net)
+// CheckAllocs returns an error if the training trials, or the testing trials if test,
+// can not be split evenly over the procs, which would otherwise silently skip the remainder
+func (ss *Sim) CheckAllocs(test bool) error {
+	if ss.Comm == nil {
+		return nil
+	}
+	if _, _, err := AllocN(ss.MaxTrls, 0, ss.NProcs()); err != nil {
+		return fmt.Errorf("training trials (MaxTrls): %v", err)
+	}
+	if !test {
+		return nil
+	}
+	if _, _, err := AllocN(ss.MaxTstTrls, 0, ss.NProcs()); err != nil {
+		return fmt.Errorf("test trials (-trials): %v", err)
+	}
+	return nil
+}
 
+func (ss *Sim) ConfigNet(net *deep.Network) {
+	if ss.NetSpecFile != "" {
+		if err := ss.NetSpec.OpenJSON(ss.NetSpecFile); err != nil {
+			log.Println(err)
+			return
+		}
+		ss.Printf("Using NetSpec from: %s\n", ss.NetSpecFile)
+	}
+	net.InitName(net, ss.NetSpec.Name)
+	if err := ss.ConfigNetSpec(net, &ss.NetSpec); err != nil {
+		log.Println(err)
+		return
+	}
 	if !ss.LIPOnly {
-		ss.ConfigNetRest(net)
+		// net.LockThreads = true // makes no difference
+		runtime.GOMAXPROCS(8) // makes no diff: otherwise gets it from slurm request and it is too small
 	}
 
 	net.Defaults()
@@ -347,392 +476,148 @@ func (ss *Sim) ConfigNet(net *deep.Network) {
 
 	if !ss.NoGui {
 		sr := net.SizeReport()
-		mpi.Printf("%s", sr)
+		ss.Printf("%s", sr)
 	}
 
 	//	ar := net.ThreadAlloc(4) // must be done after build
 	ar := net.ThreadReport() // hand tuning now..
-	mpi.Printf("%s", ar)
+	ss.Printf("%s", ar)
 
 	// ss.InitWts(net) // too slow
 }
 
-// ConfigNetLIP configures just the V1 and LIP dorsal path part
-func (ss *Sim) ConfigNetLIP(net *deep.Network) {
-	v1m := net.AddLayer4D("V1m", 8, 8, 5, 4, emer.Input)
-	v1h := net.AddLayer4D("V1h", 16, 16, 5, 4, emer.Input)
-
-	lip, lipct, lipp := net.AddDeep4D("LIP", 8, 8, 4, 4)
-	lipp.Shape().SetShape([]int{8, 8, 1, 1}, nil, nil)
-
-	mtpos := net.AddLayer4D("MTPos", 8, 8, 1, 1, emer.Hidden)
-
-	lipp.(*deep.TRCLayer).Drivers.Add("MTPos")
-
-	eyepos := net.AddLayer2D("EyePos", 21, 21, emer.Input)
-	sacplan := net.AddLayer2D("SacPlan", 11, 11, emer.Input)
-	sac := net.AddLayer2D("Saccade", 11, 11, emer.Input)
-	objvel := net.AddLayer2D("ObjVel", 11, 11, emer.Input)
-
-	v1m.SetClass("V1")
-	v1h.SetClass("V1")
-
-	mtpos.SetClass("LIP")
-	lip.SetClass("LIP")
-	lipct.SetClass("LIP")
-	lipp.SetClass("LIP")
-	sacplan.SetClass("PopIn")
-	sac.SetClass("PopIn")
-	objvel.SetClass("PopIn")
-
-	v1h.SetRelPos(relpos.Rel{Rel: relpos.RightOf, Other: v1m.Name(), YAlign: relpos.Front, Space: 2})
-	lip.SetRelPos(relpos.Rel{Rel: relpos.Above, Other: v1m.Name(), XAlign: relpos.Left, YAlign: relpos.Front})
-	lipct.SetRelPos(relpos.Rel{Rel: relpos.Behind, Other: lip.Name(), XAlign: relpos.Left, Space: 10})
-	lipp.SetRelPos(relpos.Rel{Rel: relpos.Behind, Other: lipct.Name(), XAlign: relpos.Left, Space: 10})
-	mtpos.SetRelPos(relpos.Rel{Rel: relpos.RightOf, Other: lipp.Name(), YAlign: relpos.Front, Space: 4})
-
-	eyepos.SetRelPos(relpos.Rel{Rel: relpos.RightOf, Other: lip.Name(), YAlign: relpos.Front, Space: 2})
-	sacplan.SetRelPos(relpos.Rel{Rel: relpos.Behind, Other: eyepos.Name(), XAlign: relpos.Left, Space: 10})
-	sac.SetRelPos(relpos.Rel{Rel: relpos.Behind, Other: sacplan.Name(), XAlign: relpos.Left, Space: 10})
-	objvel.SetRelPos(relpos.Rel{Rel: relpos.Behind, Other: sac.Name(), XAlign: relpos.Left, Space: 10})
-
-	full := prjn.NewFull()
-	pone2one := prjn.NewPoolOneToOne()
-
-	var pj emer.Prjn
-
-	net.ConnectLayers(v1m, mtpos, pone2one, emer.Forward).SetClass("Fixed")
-	net.ConnectLayers(mtpos, lip, pone2one, emer.Forward).SetClass("Fixed") // has .5 wtscale in Params
-
-	lipp.RecvPrjns().SendName("LIPCT").SetPattern(full)
-	lip.RecvPrjns().SendName("LIPP").SetClass("FmPulv FmLIP")
-	lipct.RecvPrjns().SendName("LIPP").SetClass("FmPulv FmLIP")
-	lipct.RecvPrjns().SendName("LIP").SetClass("CTCtxtStd")
-
-	net.ConnectLayers(eyepos, lip, full, emer.Forward)  // InitWts sets ss.PrjnGaussTopo
-	net.ConnectLayers(sacplan, lip, full, emer.Forward) // InitWts sets ss.PrjnSigTopo
-	net.ConnectLayers(objvel, lip, full, emer.Forward)  // InitWts sets ss.PrjnSigTopo
-
-	pj = lipct.RecvPrjns().SendName("LIP")
-	pj.SetPattern(ss.Prjn3x3Skp1)
-
-	net.ConnectLayers(eyepos, lipct, full, emer.Forward) // InitWts sets ss.PrjnGaussTopo
-	net.ConnectLayers(sac, lipct, full, emer.Forward)    // InitWts sets ss.PrjnSigTopo
-	net.ConnectLayers(objvel, lipct, full, emer.Forward) // InitWts sets ss.PrjnSigTopo
+// ConfigNetSpec adds the layers and projections of the parts of given NetSpec
+// to the network, in order, and sets their positions and threads --
+// just the LIP part if LIPOnly.
+func (ss *Sim) ConfigNetSpec(net *deep.Network, ns *NetSpec) error {
+	if err := ns.Validate(); err != nil {
+		return err
+	}
+	pats := ss.SpecPats()
+	for pi := range ns.Parts {
+		pt := &ns.Parts[pi]
+		if ss.LIPOnly && pt.Name != "LIP" {
+			continue
+		}
+		for li := range pt.Lays {
+			if err := ss.AddLaySpec(net, &pt.Lays[li]); err != nil {
+				return err
+			}
+		}
+		for pj := range pt.Prjns {
+			if err := ss.ConnectPrjnSpec(net, &pt.Prjns[pj], pats); err != nil {
+				return err
+			}
+		}
+		for _, lp := range pt.Pos {
+			ly, err := net.LayerByNameTry(lp.Lay)
+			if err != nil {
+				return err
+			}
+			ly.SetRelPos(lp.Rel)
+		}
+		for lnm, th := range pt.Threads {
+			ly, err := net.LayerByNameTry(lnm)
+			if err != nil {
+				return err
+			}
+			ly.SetThread(th)
+		}
+	}
+	return nil
 }
 
-// ConfigNetRest configures the rest of the network
-func (ss *Sim) ConfigNetRest(net *deep.Network) {
-	// replace with AddDeep4DFakeCT to disable CT
-	v2, v2ct, v2p := net.AddDeep4D("V2", 8, 8, 10, 10)
-	v2p.Shape().SetShape([]int{8, 8, 10, 4}, nil, nil)
-	v2p.(*deep.TRCLayer).Drivers.Add("V1m", "V1h") // y 0..4 = v1m, 5..9 = v1h
-
-	v3, v3ct, v3p := net.AddDeep4D("V3", 4, 4, 10, 10)
-	v3p.Shape().SetShape([]int{4, 4, 4, 10}, nil, nil)
-	v3p.(*deep.TRCLayer).Drivers.Add("V1m", "V1h") // y 0..1 = v1m, 2..3 = v1h, 4..13 = V2 -- todo: v2?
-
-	dp, dpct, dpp := net.AddDeep4D("DP", 1, 1, 10, 10)
-	dpp.Shape().SetShape([]int{1, 1, 4, 10}, nil, nil)
-	dpp.(*deep.TRCLayer).Drivers.Add("V1m", "V1h") // , should be "V3" -- orig had note about V3p->DP bad..
-
-	v4, v4ct, v4p := net.AddDeep4D("V4", 4, 4, 10, 10)
-	v4p.Shape().SetShape([]int{4, 4, 4, 10}, nil, nil)
-	v4p.(*deep.TRCLayer).Drivers.Add("V1m", "V1h") // y 0..1 = v1m, 2..3 = v1h, 4..13 = V2 -- todo: v2?
-
-	teo, teoct, teop := net.AddDeep4D("TEO", 4, 4, 10, 10) // 2x2 doesn't work with big V2 topo prjn
-	teop.Shape().SetShape([]int{4, 4, 14, 10}, nil, nil)
-	teop.(*deep.TRCLayer).Drivers.Add("V1m", "V1h", "V4") // def better clusters with V4
-	// note: has Layer.TRC.NoTopo set to true in params by default
-
-	te, tect, tep := net.AddDeep4D("TE", 2, 2, 10, 10)
-	tep.Shape().SetShape([]int{2, 2, 14, 10}, nil, nil)
-	tep.(*deep.TRCLayer).Drivers.Add("V1m", "V1h", "V4")
-	// note: has Layer.TRC.NoTopo set to true in params by default
-
-	v2.SetClass("V2")
-	v2ct.SetClass("V2")
-	v2p.SetClass("V2")
-
-	v3.SetClass("V3")
-	v3ct.SetClass("V3")
-	v3p.SetClass("V3")
-
-	v4.SetClass("V4")
-	v4ct.SetClass("V4")
-	v4p.SetClass("V4")
-
-	dp.SetClass("DP")
-	dpct.SetClass("DP")
-	dpp.SetClass("DP")
-
-	teo.SetClass("TEO")
-	teoct.SetClass("TEO")
-	teop.SetClass("TEO")
-
-	te.SetClass("TE")
-	tect.SetClass("TE")
-	tep.SetClass("TE")
-
-	v1m := net.LayerByName("V1m")
-	v1h := net.LayerByName("V1h")
-	lip := net.LayerByName("LIP")
-	lipct := net.LayerByName("LIPCT")
-	eyepos := net.LayerByName("EyePos")
-
-	// lesion stuff here
-	/*
-		dp.SetOff(true)
-		dpct.SetOff(true)
-		dpp.SetOff(true)
-
-		v3.SetOff(true)
-		v3ct.SetOff(true)
-		v3p.SetOff(true)
-
-		lip.SetOff(true)
-		lipct.SetOff(true)
-		lipp := net.LayerByName("LIPP")
-		lipp.SetOff(true)
-	*/
-
-	v2.SetRelPos(relpos.Rel{Rel: relpos.Above, Other: v1m.Name(), XAlign: relpos.Left, YAlign: relpos.Front})
-	lip.SetRelPos(relpos.Rel{Rel: relpos.Above, Other: v2.Name(), XAlign: relpos.Left, YAlign: relpos.Front})
-	v2p.SetRelPos(relpos.Rel{Rel: relpos.Behind, Other: v1m.Name(), XAlign: relpos.Left, Space: 10})
-	v2ct.SetRelPos(relpos.Rel{Rel: relpos.Behind, Other: v2.Name(), XAlign: relpos.Left, Space: 10})
-
-	v3.SetRelPos(relpos.Rel{Rel: relpos.RightOf, Other: v2.Name(), YAlign: relpos.Front, Space: 2})
-	v3ct.SetRelPos(relpos.Rel{Rel: relpos.Behind, Other: v3.Name(), XAlign: relpos.Left, Space: 10})
-	v3p.SetRelPos(relpos.Rel{Rel: relpos.RightOf, Other: v3ct.Name(), YAlign: relpos.Front, Space: 2})
-
-	dp.SetRelPos(relpos.Rel{Rel: relpos.RightOf, Other: v3.Name(), YAlign: relpos.Front, Space: 2})
-	dpct.SetRelPos(relpos.Rel{Rel: relpos.Behind, Other: dp.Name(), XAlign: relpos.Left, Space: 10})
-	dpp.SetRelPos(relpos.Rel{Rel: relpos.RightOf, Other: dpct.Name(), YAlign: relpos.Front, Space: 2})
-
-	v4.SetRelPos(relpos.Rel{Rel: relpos.Behind, Other: v3ct.Name(), XAlign: relpos.Left, Space: 10})
-	v4ct.SetRelPos(relpos.Rel{Rel: relpos.Behind, Other: v4.Name(), XAlign: relpos.Left, Space: 10})
-	v4p.SetRelPos(relpos.Rel{Rel: relpos.RightOf, Other: v4ct.Name(), YAlign: relpos.Back, Space: 2})
-
-	teo.SetRelPos(relpos.Rel{Rel: relpos.RightOf, Other: eyepos.Name(), YAlign: relpos.Front, Space: 2})
-	teoct.SetRelPos(relpos.Rel{Rel: relpos.Behind, Other: teo.Name(), XAlign: relpos.Left, Space: 10})
-	teop.SetRelPos(relpos.Rel{Rel: relpos.Behind, Other: teoct.Name(), XAlign: relpos.Left, Space: 10})
-
-	te.SetRelPos(relpos.Rel{Rel: relpos.RightOf, Other: teo.Name(), YAlign: relpos.Front, Space: 2})
-	tect.SetRelPos(relpos.Rel{Rel: relpos.Behind, Other: te.Name(), XAlign: relpos.Left, Space: 10})
-	tep.SetRelPos(relpos.Rel{Rel: relpos.Behind, Other: tect.Name(), XAlign: relpos.Left, Space: 10})
-
-	full := prjn.NewFull()
-	pone2one := prjn.NewPoolOneToOne()
-	one2one := prjn.NewOneToOne()
-	sameu := prjn.NewPoolSameUnit()
-	sameu.SelfCon = false
-	_ = one2one
-
-	// basic super cons
-	net.ConnectLayers(v1m, v2, ss.Prjn3x3Skp1, emer.Forward) // todo: uses V1V2 version of prjn?
-	net.ConnectLayers(v1h, v2, ss.Prjn4x4Skp2, emer.Forward) // todo: uses V1V2 version of prjn?
-
-	_, v4v2 := net.BidirConnectLayers(v2, v4, ss.Prjn4x4Skp2)
-	v4v2.SetPattern(ss.Prjn4x4Skp2Recip)
-
-	_, v3v2 := net.BidirConnectLayers(v2, v3, ss.Prjn4x4Skp2)
-	v3v2.SetClass("BackMax") // "BackMax") // this is critical!
-	v3v2.SetPattern(ss.Prjn4x4Skp2Recip)
-
-	_, dpv3 := net.BidirConnectLayers(v3, dp, full)
-	dpv3.SetClass("BackStrong") // likely key (in 233) -- retest
-
-	_, teov4 := net.BidirConnectLayers(v4, teo, ss.Prjn3x3Skp1) // 3x3 > full
-	teov4.SetClass("BackStrong")                                // todo: test
-
-	_, teteo := net.BidirConnectLayers(teo, te, ss.Prjn4x4Skp2) // 4x4 > full
-	teteo.SetPattern(ss.Prjn4x4Skp2Recip)
-
-	// non-basic cons
-
-	////////////////////
-	// to LIP -- weak from v2, v3
-
-	net.ConnectLayers(v2, lip, pone2one, emer.Forward).SetClass("FwdWeak")
-	net.ConnectLayers(v3, lip, ss.Prjn2x2Skp2Recip, emer.Forward).SetClass("FwdWeak")
-
-	net.ConnectLayers(v2ct, lipct, pone2one, emer.Forward).SetClass("FwdWeak")
-	net.ConnectLayers(v3ct, lipct, ss.Prjn2x2Skp2Recip, emer.Forward).SetClass("FwdWeak")
-
-	////////////////////
-	// to V2
-
-	net.ConnectLayers(v2, v2, sameu, emer.Lateral)
-
-	net.ConnectCtxtToCT(v2ct, v2ct, ss.Prjn3x3Skp1).SetClass("CTSelfLower") // was pone2one
-	v2ct.RecvPrjns().SendName(v2.Name()).SetClass("CTFmSuperLower")
-
-	net.ConnectLayers(lip, v2, pone2one, emer.Back).SetClass("BackMax FmLIP") // key top-down attn .5 > .2
-	net.ConnectLayers(teoct, v2, ss.Prjn4x4Skp2Recip, emer.Back)              // key! .1 def
-
-	// net.ConnectLayers(teo, v2, ss.Prjn4x4Skp2Recip, emer.Back) // too strong of top-down
-
-	net.ConnectLayers(lipct, v2ct, pone2one, emer.Back).SetClass("CTBackMax FmLIP")
-	net.ConnectLayers(v3ct, v2ct, ss.Prjn4x4Skp2Recip, emer.Back).SetClass("CTBackMax")
-	net.ConnectLayers(v4ct, v2ct, ss.Prjn4x4Skp2Recip, emer.Back).SetClass("CTBackMax")
-
-	// net.ConnectLayers(teoct, v2ct, ss.Prjn4x4Skp2Recip, emer.Back).SetClass("CTBackMax") // not beneficial
-
-	net.ConnectLayers(v3, v2ct, ss.Prjn2x2Skp2Recip, emer.Back).SetClass("SToCTMax")  // s -> ct leak
-	net.ConnectLayers(teo, v2ct, ss.Prjn4x4Skp2Recip, emer.Back).SetClass("SToCTMax") // s -> ct leak -- key @ max
-
-	// CTBack generically worse, generally important for cosdiff
-	net.ConnectLayers(v3ct, v2p, ss.Prjn4x4Skp2Recip, emer.Back).SetClass("BackToPulv")
-	net.ConnectLayers(v4ct, v2p, ss.Prjn4x4Skp2Recip, emer.Back).SetClass("BackToPulv") // better without?  not clear
-
-	////////////////////
-	// to V3
-
-	net.ConnectLayers(v3, v3, sameu, emer.Lateral)
-
-	net.ConnectCtxtToCT(v3ct, v3ct, ss.Prjn3x3Skp1).SetClass("CTSelfLower") // was pone2one
-	v3ct.RecvPrjns().SendName(v3.Name()).SetClass("CTFmSuperLower")
-
-	net.ConnectLayers(v4, v3, ss.Prjn3x3Skp1, emer.Back).SetClass("BackStrong")
-	net.ConnectLayers(lip, v3, ss.Prjn2x2Skp2, emer.Back).SetClass("FmLIP")
-
-	net.ConnectLayers(teo, v3, ss.Prjn3x3Skp1, emer.Back)
-	net.ConnectLayers(teoct, v3, ss.Prjn3x3Skp1, emer.Back)
-
-	net.ConnectLayers(lipct, v3ct, ss.Prjn2x2Skp2, emer.Back).SetClass("CTBack FmLIP")
-	net.ConnectLayers(dpct, v3ct, full, emer.Back).SetClass("CTBack")
-	net.ConnectLayers(v4ct, v3ct, ss.Prjn3x3Skp1, emer.Back).SetClass("CTBack")
-
-	// todo: retest again:
-	net.ConnectLayers(dp, v3ct, full, emer.Back).SetClass("SToCT")
-	net.ConnectLayers(v4, v3ct, ss.Prjn3x3Skp1, emer.Back).SetClass("SToCT") // s -> ct, 3x3 ok
-
-	// net.ConnectLayers(dpct, v3p, full, emer.Back).SetClass("BackToPulv") // not much effect on cosdiff
-	net.ConnectLayers(v2ct, v3p, ss.Prjn4x4Skp2, emer.Forward).SetClass("FwdToPulv") // has major effect on cosdiff
-
-	////////////////////
-	// to DP
-
-	net.ConnectCtxtToCT(dpct, dpct, full).SetClass("CTSelfLower") // not much effect, but consistent
-
-	// net.ConnectLayers(v2, dp, full, emer.Forward) // no effect, expensive
-
-	net.ConnectLayers(teo, dp, full, emer.Back) // todo: test again
-
-	net.ConnectLayers(teoct, dpct, full, emer.Back).SetClass("CTBack")
-
-	////////////////////
-	// to V4
-
-	net.ConnectLayers(v4, v4, sameu, emer.Lateral)
-
-	net.ConnectCtxtToCT(v4ct, v4ct, ss.Prjn3x3Skp1).SetClass("CTSelfLower") // was pone2one
-
-	// net.ConnectLayers(teoct, v4, ss.Prjn3x3Skp1, emer.Back).SetClass("CTBack") // very not beneficial
-
-	// Prjn4x4Skp2Recip is same as full, but has topo scales -- better than full
-	net.ConnectLayers(te, v4, ss.Prjn4x4Skp2Recip, emer.Back).SetClass("BackStrong")
-
-	net.ConnectLayers(teoct, v4ct, ss.Prjn3x3Skp1, emer.Back).SetClass("CTBack")
-	net.ConnectLayers(teo, v4ct, ss.Prjn3x3Skp1, emer.Back).SetClass("SToCT") // s -> ct -- important
-
-	// Prjn4x4Skp2Recip is same as full, but has topo scales -- better
-	net.ConnectLayers(tect, v4ct, ss.Prjn4x4Skp2Recip, emer.Back).SetClass("CTBack")
-
-	// net.ConnectLayers(v2ct, v4ct, ss.Prjn4x4Skp2, emer.Forward).SetClass("CTBack") // instead of direct to v2p -- not helpful
-
-	// net.ConnectLayers(teoct, v4p, ss.Prjn3x3Skp1, emer.Back) // not much additional benefit for cosdiff
-
-	net.ConnectLayers(v2ct, v4p, ss.Prjn4x4Skp2, emer.Forward).SetClass("FwdToPulv") // has major effect on cosdiff
-
-	////////////////////
-	// to TEO
-
-	// net.ConnectLayers(teo, teo, sameu, emer.Lateral)
-
-	net.ConnectCtxtToCT(teoct, teoct, pone2one).SetClass("CTSelfHigher") // pone2one similar to 3x3 -- bit better
-
-	net.ConnectLayers(tect, teoct, ss.Prjn4x4Skp2Recip, emer.Back).SetClass("CTBack") // CTBack > not
-
-	net.ConnectLayers(v4ct, teoct, full, emer.Forward).SetClass("CTBack") // instead of direct to v2p
-
-	// todo: test topo on both
-	// net.ConnectLayers(v4ct, teop, full, emer.Forward).SetClass("FwdToPulv") // sig effect on TEOP cosdiff, but improves TEP
-	net.ConnectLayers(tect, teop, full, emer.Back).SetClass("BackToPulv") // no effect on cosdiff, but better Cat without
-
-	////////////////////
-	// to TE
-
-	// net.ConnectLayers(te, te, sameu, emer.Lateral)
-
-	net.ConnectCtxtToCT(tect, tect, pone2one).SetClass("CTSelfHigher") // pone2one > full
-
-	net.ConnectLayers(teoct, tect, ss.Prjn4x4Skp2, emer.Forward).SetClass("CTBack") // was FwdWeak
-
-	net.ConnectLayers(teoct, tep, full, emer.Back).SetClass("FwdToPulv") // sig effect on cosdiff, not much other eff
-
-	////////////////////
-
-	// net.LockThreads = true // makes no difference
-	runtime.GOMAXPROCS(8) // makes no diff: otherwise gets it from slurm request and it is too small
-
-	/*
-		// 4 threads = about 500 msec / trl @8 mpi
-		v2.SetThread(1)
-		v2ct.SetThread(1)
-		v2p.SetThread(1)
-
-		dp.SetThread(1)
-		dpct.SetThread(1)
-		dpp.SetThread(1)
-
-		v3ct.SetThread(1)
-
-		v3p.SetThread(2)
-		v3.SetThread(2)
-
-		v4.SetThread(3)
-		v4ct.SetThread(2)
-		v4p.SetThread(2)
-
-		teo.SetThread(3) // 23 M -- by far biggest
-
-		teoct.SetThread(0) // 19 M
-		teop.SetThread(0)
-
-		te.SetThread(2)
-
-		tect.SetThread(0)
-		tep.SetThread(0)
-	*/
-
-	//	2 threads = only slight advantage over 1 thread
-	v2.SetThread(0)
-	v2ct.SetThread(0)
-	v2p.SetThread(0)
-
-	dp.SetThread(0)
-	dpct.SetThread(0)
-	dpp.SetThread(0)
-
-	v3ct.SetThread(0)
-
-	v3p.SetThread(1)
-	v3.SetThread(1)
-
-	v4.SetThread(1)
-	v4ct.SetThread(1)
-	v4p.SetThread(1)
-
-	teo.SetThread(1) // 23 M -- by far biggest
-
-	teoct.SetThread(0) // 19 M
-	teop.SetThread(0)
+// SpecPats returns the patterns for the NetSpec, with the PoolTile patterns of the sim
+func (ss *Sim) SpecPats() *NetSpecPats {
+	return &NetSpecPats{Named: map[string]prjn.Pattern{
+		"Prjn4x4Skp2":      ss.Prjn4x4Skp2,
+		"Prjn4x4Skp2Recip": ss.Prjn4x4Skp2Recip,
+		"Prjn2x2Skp2":      ss.Prjn2x2Skp2,
+		"Prjn2x2Skp2Recip": ss.Prjn2x2Skp2Recip,
+		"Prjn3x3Skp1":      ss.Prjn3x3Skp1,
+		"PrjnSigTopo":      ss.PrjnSigTopo,
+		"PrjnGaussTopo":    ss.PrjnGaussTopo,
+	}}
+}
 
-	te.SetThread(1)
+// AddLaySpec adds the layer(s) of given spec to the network: Input, Hidden, and Deep (AddDeep4D)
+func (ss *Sim) AddLaySpec(net *deep.Network, ls *LaySpec) error {
+	var lays []emer.Layer
+	sh := ls.Shape
+	switch ls.Type {
+	case "Input", "Hidden":
+		typ := emer.Input
+		if ls.Type == "Hidden" {
+			typ = emer.Hidden
+		}
+		if len(sh) == 2 {
+			lays = append(lays, net.AddLayer2D(ls.Name, sh[0], sh[1], typ))
+		} else {
+			lays = append(lays, net.AddLayer4D(ls.Name, sh[0], sh[1], sh[2], sh[3], typ))
+		}
+	case "Deep":
+		super, ct, pulv := net.AddDeep4D(ls.Name, sh[0], sh[1], sh[2], sh[3])
+		if ls.PulvName != "" {
+			pulv.SetName(ls.PulvName)
+		}
+		if len(ls.PulvShape) > 0 {
+			pulv.Shape().SetShape(ls.PulvShape, nil, nil)
+		}
+		pulv.(*deep.TRCLayer).Drivers.Add(ls.Drivers...)
+		lays = append(lays, super, ct, pulv)
+	default:
+		return fmt.Errorf("LaySpec %s: Type %s is not supported in this sim", ls.Name, ls.Type)
+	}
+	if ls.Class != "" {
+		for _, ly := range lays {
+			ly.SetClass(ls.Class)
+		}
+	}
+	return nil
+}
 
-	tect.SetThread(0)
-	tep.SetThread(0)
+// ConnectPrjnSpec connects the projection of given spec, or sets the existing one
+func (ss *Sim) ConnectPrjnSpec(net *deep.Network, ps *PrjnSpec, pats *NetSpecPats) error {
+	send, err := net.LayerByNameTry(ps.Send)
+	if err != nil {
+		return fmt.Errorf("PrjnSpec %s: %v", ps, err)
+	}
+	recv, err := net.LayerByNameTry(ps.Recv)
+	if err != nil {
+		return fmt.Errorf("PrjnSpec %s: %v", ps, err)
+	}
+	var pat prjn.Pattern
+	if ps.Pat != "" {
+		pat, err = pats.Pat(ps.Pat)
+		if err != nil {
+			return fmt.Errorf("PrjnSpec %s: %v", ps, err)
+		}
+	}
+	var pj emer.Prjn
+	switch ps.Type {
+	case "Forward":
+		pj = net.ConnectLayers(send, recv, pat, emer.Forward)
+	case "Back":
+		pj = net.ConnectLayers(send, recv, pat, emer.Back)
+	case "Lateral":
+		pj = net.ConnectLayers(send, recv, pat, emer.Lateral)
+	case "CTCtxt":
+		pj = net.ConnectCtxtToCT(send, recv, pat)
+	case "Set":
+		pj, err = recv.RecvPrjns().SendNameTry(ps.Send)
+		if err != nil {
+			return fmt.Errorf("PrjnSpec %s: %v", ps, err)
+		}
+		if pat != nil {
+			pj.SetPattern(pat)
+		}
+	default:
+		return fmt.Errorf("PrjnSpec %s: Type %s is not supported in this sim", ps, ps.Type)
+	}
+	if ps.Class != "" {
+		pj.SetClass(ps.Class)
+	}
+	return nil
 }
 
 func (ss *Sim) SetTopoScales(net *deep.Network, send, recv string, pooltile *prjn.PoolTile) {
@@ -763,11 +648,12 @@ func (ss *Sim) InitWts(net *deep.Network) {
 
 	net.InitWts()
 	if !ss.LIPOnly {
-		mpi.Printf("loading lip_pretrained.wts.gz...\n")
+		ss.Printf("loading lip_pretrained.wts.gz...\n")
 		net.OpenWtsJSON(gi.FileName("lip_pretrained.wts.gz"))
 	}
 
 	net.LrateMult(1) // restore initial learning rate value
+	ss.LrateMult = 1
 }
 
 ////////////////////////////////////////////////////////////////////////////////
@@ -791,6 +677,20 @@ func (ss *Sim) InitRndSeed() {
 	rand.Seed(ss.RndSeeds[run])
 }
 
+// EpochRndSeed re-seeds the random number generator from the run seed and given
+// epoch at the start of every epoch, so that a run resumed from a checkpoint continues
+// with the same random numbers -- returns the seed
+func (ss *Sim) EpochRndSeed(epc int) int64 {
+	run := ss.TrainEnv.Run.Cur
+	seed := ss.RndSeeds[run] + 1000*int64(epc)
+	if ss.Comm != nil {
+		ss.Comm.Seed(seed)
+	} else {
+		rand.Seed(seed)
+	}
+	return seed
+}
+
 // NewRndSeed gets a new set of random seeds based on current time -- otherwise uses
 // the same random seeds for every run
 func (ss *Sim) NewRndSeed() {
@@ -913,18 +813,24 @@ func (ss *Sim) TrainTrial() {
 		ss.NewRun()
 	}
 
-	ss.TrainEnv.Step() // the Env encapsulates and manages all counter state
+	resumed := ss.CkptPending // env is already at the first trial of the checkpoint epoch
+	ss.CkptPending = false
+	if !resumed {
+		ss.TrainEnv.Step() // the Env encapsulates and manages all counter state
+	}
 
 	// Key to query counters FIRST because current state is in NEXT epoch
 	// if epoch counter has changed
 	epc, _, chg := ss.TrainEnv.Counter(env.Epoch)
-	if chg {
+	if chg && !resumed {
+		ss.RunSched(epc) // before log, so the actions are recorded in it
 		ss.LogTrnEpc(ss.TrnEpcLog)
-		ss.LrateSched(epc)
+		ss.PeriodicTest() // before any checkpoint, so its results are saved in it
+		conv := ss.CheckConv()
 		if ss.ViewOn && ss.TrainUpdt > leabra.AlphaCycle {
 			ss.UpdateView(true)
 		}
-		if epc >= ss.MaxEpcs {
+		if epc >= ss.MaxEpcs || conv {
 			// done with training..
 			ss.RunEnd()
 			if ss.TrainEnv.Run.Incr() { // we are done!
@@ -935,6 +841,10 @@ func (ss *Sim) TrainTrial() {
 				return
 			}
 		}
+		ss.EpcSeed = ss.EpochRndSeed(epc)
+		if ss.CkptInterval > 0 && epc%ss.CkptInterval == 0 {
+			ss.SaveCkpt(ss.CkptDirName(epc))
+		}
 	}
 
 	// note: type must be in place before apply inputs
@@ -942,6 +852,15 @@ func (ss *Sim) TrainTrial() {
 	ss.AlphaCyc(true) // train
 	ss.TrialStats()
 	ss.LogTrnTrl(ss.TrnTrlLog)
+	if ss.RecReps(epc) {
+		ss.LogTrnRepTrl(ss.TrnTrlRepLog)
+	}
+	if ss.RecNpz(epc) {
+		ss.NpzRecTrial(&ss.TrainEnv)
+	}
+	if ss.StatusAddr != "" {
+		ss.UpdtStatus()
+	}
 	if ss.CurImgGrid != nil {
 		ss.CurImgGrid.UpdateSig()
 	}
@@ -950,6 +869,10 @@ func (ss *Sim) TrainTrial() {
 // RunEnd is called at the end of a run -- save weights, record final log, etc here
 func (ss *Sim) RunEnd() {
 	ss.LogRun(ss.RunLog)
+	if ss.Manifest != nil {
+		ss.Manifest.EndRun()
+		ss.SaveManifest()
+	}
 	if ss.SaveWts {
 		fnm := ss.WeightsFileName()
 		fmt.Printf("Saving Weights to: %v\n", fnm)
@@ -967,17 +890,24 @@ func (ss *Sim) RunEnd() {
 // NewRun intializes a new run of the model, using the TrainEnv.Run counter
 // for the new run value
 func (ss *Sim) NewRun() {
-	ss.InitRndSeed()
 	run := ss.TrainEnv.Run.Cur
+	if ss.Manifest != nil {
+		ss.Manifest.StartRun(run, ss.RndSeeds[run])
+	}
 	ss.TrainEnv.Init(run)
 	ss.TestEnv.Init(run)
 	ss.Time.Reset()
-	ss.InitWts(ss.Net)
+	ss.Serial(func() { // in-process Workers share math/rand: all get the same weights
+		ss.InitRndSeed()
+		ss.InitWts(ss.Net)
+	})
 	ss.InitStats()
 	ss.TrnEpcLog.SetNumRows(0)
 	ss.TrnTrlLog.SetNumRows(0)
 	ss.TstEpcLog.SetNumRows(0)
 	ss.TstTrlLog.SetNumRows(0)
+	ss.Drift.Init()
+	ss.Conv.Init()
 	ss.NeedsNewRun = false
 }
 
@@ -1013,8 +943,23 @@ func (ss *Sim) InitStats() {
 	ss.HidGeMaxM = make([]float64, nh)
 	ss.HidTrlCosDiff = make([]float64, nh)
 
-	ss.RSA.Init(ss.SuperLays)
+	if len(ss.RSALays) == 0 {
+		ss.RSALays = append([]string{}, ss.SuperLays...)
+		for _, lnm := range ss.HidLays {
+			if ss.Net.LayerByName(lnm).Type() == deep.CT {
+				ss.RSALays = append(ss.RSALays, lnm)
+			}
+		}
+		ss.RSALays = append(ss.RSALays, ss.PulvLays...)
+	}
+	ss.ConfigRSALays()
+	if ss.NpzExport.Interval > 0 || ss.NpzExport.ExportActs {
+		ss.NpzSpecs = ss.ParseLaySpecs(ss.NpzExport.Lays)
+	}
+
+	ss.RSA.Init(ss.RSACols)
 	ss.RSA.SetCats(ss.TrainEnv.Objs)
+	ss.ConfigTstRSA()
 }
 
 // TrialStats computes the trial-level statistics.
@@ -1123,55 +1068,237 @@ func (ss *Sim) SaveWeights(filename gi.FileName) {
 	ss.Net.SaveWtsJSON(filename)
 }
 
-// LrateSched implements the learning rate schedule
-func (ss *Sim) LrateSched(epc int) {
-	switch epc {
-	case 250:
-		ss.Net.LrateMult(0.5)
-		mpi.Printf("dropped lrate to 0.5 at epoch: %d\n", epc)
-	case 500:
-		ss.Net.LrateMult(0.2)
-		mpi.Printf("dropped lrate to 0.2 at epoch: %d\n", epc)
-	case 750:
-		ss.Net.LrateMult(0.1)
-		mpi.Printf("dropped lrate to 0.1 at epoch: %d\n", epc)
+// ConfigSched sets the Sched from SchedFile if specified, else from the
+// compiled-in Scheds for the current ParamSet (or Base).  If the SchedFile
+// cannot be used, the Sched is left empty, and CmdArgs exits with the error.
+func (ss *Sim) ConfigSched() {
+	if ss.SchedFile != "" {
+		err := ss.Sched.OpenJSON(ss.SchedFile)
+		if err == nil {
+			err = CheckSchedLays(ss.Sched.Acts) // no InitOffNms
+		}
+		if err != nil {
+			log.Println(err)
+			ss.Sched = EpochSched{}
+			return
+		}
+		ss.Printf("Using EpochSched from: %s\n", ss.SchedFile)
+		return
+	}
+	sc := Scheds.ForParamSet(ss.ParamsName())
+	if sc == nil {
+		ss.Sched = EpochSched{}
+		return
+	}
+	ss.Sched.CopyFrom(sc)
+	if err := ss.Sched.Validate(); err != nil {
+		log.Println(err)
 	}
 }
 
-// OpenTrainedWts opens trained weights
-// func (ss *Sim) OpenTrainedWts() {
-// 	ab, err := Asset("objrec_train1.wts") // embedded in executable
-// 	if err != nil {
-// 		log.Println(err)
-// 	}
-// 	ss.Net.ReadWtsJSON(bytes.NewBuffer(ab))
-// 	// ss.Net.OpenWtsJSON("objrec_train1.wts.gz")
-// }
-
-////////////////////////////////////////////////////////////////////////////////////////////
-// Testing
-
-// TestTrial runs one trial of testing -- always sequentially presented inputs
-func (ss *Sim) TestTrial(returnOnChg bool) {
-	ss.TestEnv.Step()
+// RunSched performs the actions of the Sched triggered at given epoch,
+// recording them in SchedActs for the epoch log
+func (ss *Sim) RunSched(epc int) {
+	var strs []string
+	for _, sa := range ss.Sched.ActsAt(epc) {
+		if err := ss.DoSchedAct(sa, epc); err != nil {
+			log.Println(err)
+			continue
+		}
+		ss.Printf("sched: %s at epoch: %d\n", sa, epc)
+		strs = append(strs, sa.String())
+	}
+	ss.SchedActs = strings.Join(strs, " ")
+}
 
-	// Query counters FIRST
-	_, _, chg := ss.TestEnv.Counter(env.Epoch)
-	if chg {
-		if ss.ViewOn && ss.TestUpdt > leabra.AlphaCycle {
-			ss.UpdateView(false)
+// ReplaySched re-applies the actions of the Sched, and of the Conv monitor when it
+// converged (ConvEpcs), that set params, triggered up to and including given epoch,
+// in the same order as in TrainTrial, when resuming from a checkpoint at that epoch
+func (ss *Sim) ReplaySched(epc int) {
+	for e := 0; e <= epc; e++ {
+		acts := ss.Sched.ActsAt(e)
+		for _, ce := range ss.Conv.ConvEpcs {
+			if ce == e-1 { // CheckConv is for the previous epoch
+				for i := range ss.Conv.Acts {
+					acts = append(acts, &ss.Conv.Acts[i])
+				}
+			}
 		}
-		ss.LogTstEpc(ss.TstEpcLog)
-		if returnOnChg {
-			return
+		for _, sa := range acts {
+			if !sa.SetsParams() {
+				continue
+			}
+			if err := ss.DoSchedAct(sa, e); err != nil {
+				log.Println(err)
+			}
 		}
 	}
+}
 
-	// note: type must be in place before apply inputs
-	ss.ApplyInputs(&ss.TestEnv)
+// DoSchedAct performs one Sched action at the start of given training epoch,
+// recording the params it applies in the Manifest SchedParams
+func (ss *Sim) DoSchedAct(sa *SchedAct, epc int) error {
+	rec := ss.SchedRec(sa, epc)
+	switch sa.Act {
+	case "Lrate":
+		ss.LrateMult = sa.Val
+		ss.Net.LrateMult(ss.LrateMult)
+		if rec != nil {
+			rec(ManifestParam{Obj: "Sim", Path: "LrateMult", Val: fmt.Sprintf("%g", sa.Val)})
+		}
+	case "SaveWts":
+		if ss.Rank() == 0 {
+			fnm := ss.WeightsFileName()
+			ss.Printf("Saving Weights to: %s\n", fnm)
+			ss.Net.SaveWtsJSON(gi.FileName(fnm))
+		}
+	case "LaysOn", "LaysOff":
+		if len(sa.Lays) == 0 {
+			return fmt.Errorf("%s needs Lays", sa.Act)
+		}
+		for _, lnm := range sa.Lays {
+			ly, err := ss.Net.LayerByNameTry(lnm)
+			if err != nil {
+				return err
+			}
+			ly.SetOff(sa.Act == "LaysOff")
+		}
+	case "Sheet":
+		var err error
+		ss.Serial(func() { // the param sets are shared by in-process Workers
+			err = ss.ApplyParamsSet(sa.Set, "Network", ss.LogSetParams, rec)
+		})
+		return err
+	case "Param":
+		sh := &params.Sheet{{Sel: sa.Sel, Desc: "EpochSched Param", Params: params.Params{sa.Path: sa.Str}}}
+		return ss.ApplyNetParams("", sh, ss.LogSetParams, rec)
+	case "PMD":
+		sh := &params.Sheet{{Sel: "TRCLayer", Desc: "EpochSched PMD", Params: params.Params{"Layer.TRC.DriveScale": fmt.Sprintf("%g", sa.Val)}}}
+		return ss.ApplyNetParams("", sh, ss.LogSetParams, rec)
+	case "Test":
+		ss.TestAllKeepState()
+	default:
+		return sa.Validate()
+	}
+	return nil
+}
+
+// SchedRec returns the function recording the params applied by given Sched or Conv
+// action at given epoch in the Manifest SchedParams -- nil if there is no Manifest
+func (ss *Sim) SchedRec(sa *SchedAct, epc int) func(mp ManifestParam) {
+	if ss.Manifest == nil {
+		return nil
+	}
+	return func(mp ManifestParam) {
+		mp.Act = sa.String()
+		mp.Epoch = epc
+		ss.Manifest.SchedParams = append(ss.Manifest.SchedParams, mp)
+	}
+}
+
+// ConfigConv loads the Conv monitor from ConvFile if set, and checks that its
+// columns are in the epoch logs -- it is not On if there is an error
+func (ss *Sim) ConfigConv() {
+	if ss.ConvFile == "" {
+		return
+	}
+	if err := ss.Conv.OpenJSON(ss.ConvFile); err != nil {
+		log.Println(err)
+		return
+	}
+	if err := ss.Conv.CheckCols(ss.TrnEpcLog, ss.TstEpcLog); err != nil {
+		log.Println(err)
+		ss.Conv.On = false
+		return
+	}
+	if err := CheckSchedLays(ss.Conv.Acts); err != nil { // no InitOffNms
+		log.Println("ConvMon:", err)
+		ss.Conv.On = false
+		return
+	}
+	ss.Printf("Using ConvMon from: %s\n", ss.ConvFile)
+}
+
+// CheckConv checks the Conv criteria on the epoch logs at the end of the training
+// epoch, and performs its actions if converged -- returns true if the run should end.
+// The RSA stats are only computed on rank 0, so under MPI it decides for all procs.
+func (ss *Sim) CheckConv() bool {
+	if !ss.Conv.On {
+		return false
+	}
+	epc := ss.TrainEnv.Epoch.Prv // triggered by increment so use previous value
+	conv := ss.Conv.Check(epc, ss.TrnEpcLog, ss.TstEpcLog)
+	if ss.Comm != nil {
+		cv := []float32{0}
+		if conv && ss.Rank() == 0 {
+			cv[0] = 1
+		}
+		ac := []float32{0}
+		ss.Comm.AllReduceF32(mpi.OpMax, ac, cv)
+		if ac[0] > 0 && !conv {
+			ss.Conv.ConvEpcs = append(ss.Conv.ConvEpcs, epc)
+		}
+		conv = ac[0] > 0
+	}
+	if !conv {
+		return false
+	}
+	ss.Printf("conv: converged at epoch: %d  %s\n", epc, ss.Conv.String())
+	for i := range ss.Conv.Acts {
+		sa := &ss.Conv.Acts[i]
+		if err := ss.DoSchedAct(sa, ss.TrainEnv.Epoch.Cur); err != nil {
+			log.Println(err)
+			continue
+		}
+		ss.Printf("conv: %s at epoch: %d\n", sa, epc)
+	}
+	if ss.Conv.SaveWts && ss.Rank() == 0 {
+		fnm := ss.WeightsFileName()
+		ss.Printf("Saving Weights to: %s\n", fnm)
+		ss.Net.SaveWtsJSON(gi.FileName(fnm))
+	}
+	if ss.Conv.Stop {
+		return true
+	}
+	ss.Conv.Reset() // look for the next plateau
+	return false
+}
+
+// OpenTrainedWts opens trained weights
+// func (ss *Sim) OpenTrainedWts() {
+// 	ab, err := Asset("objrec_train1.wts") // embedded in executable
+// 	if err != nil {
+// 		log.Println(err)
+// 	}
+// 	ss.Net.ReadWtsJSON(bytes.NewBuffer(ab))
+// 	// ss.Net.OpenWtsJSON("objrec_train1.wts.gz")
+// }
+
+////////////////////////////////////////////////////////////////////////////////////////////
+// Testing
+
+// TestTrial runs one trial of testing -- always sequentially presented inputs
+func (ss *Sim) TestTrial(returnOnChg bool) {
+	ss.TestEnv.Step()
+
+	// Query counters FIRST
+	_, _, chg := ss.TestEnv.Counter(env.Epoch)
+	if chg {
+		if ss.ViewOn && ss.TestUpdt > leabra.AlphaCycle {
+			ss.UpdateView(false)
+		}
+		ss.LogTstEpc(ss.TstEpcLog)
+		if returnOnChg {
+			return
+		}
+	}
+
+	// note: type must be in place before apply inputs
+	ss.ApplyInputs(&ss.TestEnv)
 	ss.AlphaCyc(false) // !train
 	ss.TrialStats()
-	// todo: actrf etc
+	ss.UpdtActRFs()
+	ss.RecTstCatLayActs()
 	ss.LogTstTrl(ss.TstTrlLog)
 }
 
@@ -1179,6 +1306,8 @@ func (ss *Sim) TestTrial(returnOnChg bool) {
 func (ss *Sim) TestAll() {
 	ss.TestEnv.Init(ss.TrainEnv.Run.Cur)
 	ss.ActRFs.Reset()
+	ss.TstTrlLog.SetNumRows(0)
+	ss.InitTstCatLayActs()
 	for {
 		ss.TestTrial(true) // return on chg, don't present
 		_, _, chg := ss.TestEnv.Counter(env.Epoch)
@@ -1186,6 +1315,9 @@ func (ss *Sim) TestAll() {
 			break
 		}
 	}
+	if ss.Comm != nil {
+		ss.MPISumActRFs()
+	}
 	ss.ActRFs.Avg()
 	ss.ActRFs.Norm()
 	ss.ViewActRFs()
@@ -1198,44 +1330,89 @@ func (ss *Sim) RunTestAll() {
 	ss.Stopped()
 }
 
-// UpdtActRFs updates activation rf's -- only called during testing
-func (ss *Sim) UpdtActRFs() {
-	oly := ss.Net.LayerByName("Output")
-	ovt := ss.ValsTsr("Output")
-	oly.UnitValsTensor(ovt, "ActM")
-	// if _, ok := ss.ValsTsrs["Image"]; !ok {
-	// 	ss.ValsTsrs["Image"] = &ss.TestEnv.Vis.ImgTsr
-	// }
-	naf := len(ss.ActRFNms)
-	if len(ss.ActRFs.RFs) != naf {
-		for _, anm := range ss.ActRFNms {
-			sp := strings.Split(anm, ":")
-			lnm := sp[0]
-			ly := ss.Net.LayerByName(lnm)
-			if ly == nil {
-				continue
-			}
-			lvt := ss.ValsTsr(lnm)
-			ly.UnitValsTensor(lvt, "ActM")
-			tnm := sp[1]
-			tvt := ss.ValsTsr(tnm)
-			ss.ActRFs.AddRF(anm, lvt, tvt)
-			// af.NormRF.SetMetaData("min", "0")
+// PeriodicTest runs TestAll at the end of every TestInterval training epochs,
+// restoring the network state and Time afterward (see TestAllKeepState).
+// Under MPI it must be called on all procs, which each test their subset of items.
+func (ss *Sim) PeriodicTest() {
+	epc := ss.TrainEnv.Epoch.Prv // triggered by increment so use previous value
+	if ss.TestInterval <= 0 || epc%ss.TestInterval != 0 {
+		return
+	}
+	ss.TestAllKeepState()
+	if ss.ActRFLog && ss.NoGui && ss.Rank() == 0 {
+		if err := ss.SaveActRFs(fmt.Sprintf("_%03d", epc)); err != nil {
+			log.Println(err)
 		}
 	}
+}
+
+// TestAllKeepState runs TestAll during training, restoring the network state
+// (activations etc, see WriteNetState) and Time afterward, so that training
+// continues exactly as it would have without testing
+func (ss *Sim) TestAllKeepState() {
+	var buf bytes.Buffer
+	if err := WriteNetState(&buf, ss.Net, false); err != nil {
+		log.Println(err)
+		return
+	}
+	tm := ss.Time
+	ss.TestAll()
+	ss.Time = tm
+	if err := ReadNetState(&buf, ss.Net, false); err != nil {
+		log.Println(err)
+	}
+}
+
+// UpdtActRFs accumulates the activation-based receptive fields of the ActRFNms,
+// from the minus phase activations of each layer and the current values of each
+// source of the TestEnv -- called during testing.  Layers that are not in the
+// network (e.g., with LIPOnly) are skipped.
+func (ss *Sim) UpdtActRFs() {
 	for _, anm := range ss.ActRFNms {
 		sp := strings.Split(anm, ":")
-		lnm := sp[0]
-		ly := ss.Net.LayerByName(lnm)
-		if ly == nil {
+		if len(sp) != 2 {
+			log.Printf("UpdtActRFs: %s must be lay:src\n", anm)
 			continue
 		}
-		lvt := ss.ValsTsr(lnm)
+		ly, err := ss.Net.LayerByNameTry(sp[0])
+		if err != nil {
+			continue
+		}
+		lvt := ss.ValsTsr(sp[0] + "_ActM")
 		ly.UnitValsTensor(lvt, "ActM")
-		tnm := sp[1]
-		tvt := ss.ValsTsr(tnm)
-		ss.ActRFs.Add(anm, lvt, tvt, 0.01) // thr prevent weird artifacts
+		svt := ss.ValsTsr("ActRFSrc_" + sp[1])
+		if err := ss.TestEnv.ActRFSrc(sp[1], svt); err != nil {
+			log.Println(err)
+			continue
+		}
+		if ss.ActRFs.RFByName(anm) == nil {
+			ss.ActRFs.AddRF(anm, lvt, svt)
+		}
+		ss.ActRFs.Add(anm, lvt, svt, 0.01) // thr prevent weird artifacts
+	}
+}
+
+// MPISumActRFs sums the ActRFs accumulated by each proc on its subset of the test items
+func (ss *Sim) MPISumActRFs() {
+	for _, rf := range ss.ActRFs.RFs {
+		for _, tsr := range []*etensor.Float32{&rf.SumProd, &rf.SumSrc} {
+			sum := make([]float32, len(tsr.Values))
+			ss.Comm.AllReduceF32(mpi.OpSum, sum, tsr.Values)
+			copy(tsr.Values, sum)
+		}
+	}
+}
+
+// SaveActRFs saves the normalized ActRFs, each to an actrf_<lay>_<src> log,
+// with given suffix (e.g., the epoch) added to the log name
+func (ss *Sim) SaveActRFs(sfx string) error {
+	for _, rf := range ss.ActRFs.RFs {
+		fnm := ss.LogFileName("actrf_" + strings.Replace(rf.Name, ":", "_", -1) + sfx)
+		if err := etensor.SaveCSV(&rf.NormRF, gi.FileName(fnm), etable.Tab.Rune()); err != nil {
+			return err
+		}
 	}
+	return nil
 }
 
 // ViewActRFs displays act rfs
@@ -1247,6 +1424,9 @@ func (ss *Sim) ViewActRFs() {
 		tg := ss.ActRFGrids[nm]
 		if tg.Tensor == nil {
 			rf := ss.ActRFs.RFByName(nm)
+			if rf == nil { // layer not in network
+				continue
+			}
 			tg.SetTensor(&rf.NormRF)
 		} else {
 			tg.UpdateSig()
@@ -1278,14 +1458,41 @@ func (ss *Sim) SetParams(sheet string, setMsg bool) error {
 	if ss.ParamSet != "" && ss.ParamSet != "Base" {
 		err = ss.SetParamsSet(ss.ParamSet, sheet, setMsg)
 	}
+	for _, xnm := range ss.XParamSets {
+		if xerr := ss.SetParamsSet(xnm, sheet, setMsg); xerr != nil {
+			err = xerr
+		}
+	}
 	return err
 }
 
+// OpenXParams opens additional param sets from a JSON file (as saved by
+// params.Sets SaveJSON), adding them to Params and to the XParamSets
+// that are applied after Base and ParamSet in SetParams.
+func (ss *Sim) OpenXParams(fname string) error {
+	var xps params.Sets
+	if err := xps.OpenJSON(gi.FileName(fname)); err != nil {
+		return err
+	}
+	for _, ps := range xps {
+		ss.Params = append(ss.Params, ps)
+		ss.XParamSets = append(ss.XParamSets, ps.Name)
+	}
+	return nil
+}
+
 // SetParamsSet sets the params for given params.Set name.
 // If sheet is empty, then it applies all avail sheets (e.g., Network, Sim)
 // otherwise just the named sheet
 // if setMsg = true then we output a message for each param that was set.
+// The params in effect are recorded in ParamsRec.
 func (ss *Sim) SetParamsSet(setNm string, sheet string, setMsg bool) error {
+	return ss.ApplyParamsSet(setNm, sheet, setMsg, ss.ParamsRec.Add)
+}
+
+// ApplyParamsSet applies the params of given params.Set name as SetParamsSet does,
+// calling rec, if non-nil, with each param applied to each layer, projection and the sim
+func (ss *Sim) ApplyParamsSet(setNm string, sheet string, setMsg bool, rec func(mp ManifestParam)) error {
 	pset, err := ss.Params.SetByNameTry(setNm)
 	if err != nil {
 		return err
@@ -1293,14 +1500,19 @@ func (ss *Sim) SetParamsSet(setNm string, sheet string, setMsg bool) error {
 	if sheet == "" || sheet == "Network" {
 		netp, ok := pset.Sheets["Network"]
 		if ok {
-			ss.Net.ApplyParams(netp, setMsg)
+			ss.ApplyNetParams(setNm, netp, setMsg, rec)
 		}
 	}
 
 	if sheet == "" || sheet == "Sim" {
 		simp, ok := pset.Sheets["Sim"]
 		if ok {
-			simp.Apply(ss, setMsg)
+			for _, sl := range *simp {
+				app, _ := (&params.Sheet{sl}).Apply(ss, setMsg)
+				if app && rec != nil {
+					RecParams(rec, setNm, sl.Sel, "Sim", sl.Params)
+				}
+			}
 		}
 	}
 	// note: if you have more complex environments with parameters, definitely add
@@ -1308,6 +1520,40 @@ func (ss *Sim) SetParamsSet(setNm string, sheet string, setMsg bool) error {
 	return err
 }
 
+// ApplyNetParams applies given Network params sheet of set setNm to the network, as
+// Net.ApplyParams does, but one selector at a time to each layer and projection, so
+// it can call rec, if non-nil, with each param applied to each of them
+func (ss *Sim) ApplyNetParams(setNm string, sh *params.Sheet, setMsg bool, rec func(mp ManifestParam)) error {
+	var rerr error
+	for _, sl := range *sh {
+		lsh, psh := SplitSel(sl)
+		for _, ly := range ss.Net.Layers {
+			if lsh != nil {
+				app, err := ly.ApplyParams(lsh, setMsg)
+				if err != nil {
+					rerr = err
+				}
+				if app && rec != nil {
+					RecParams(rec, setNm, sl.Sel, ly.Name(), (*lsh)[0].Params)
+				}
+			}
+			if psh == nil {
+				continue
+			}
+			for _, pj := range *ly.RecvPrjns() {
+				app, err := pj.ApplyParams(psh, setMsg)
+				if err != nil {
+					rerr = err
+				}
+				if app && rec != nil {
+					RecParams(rec, setNm, sl.Sel, pj.Name(), (*psh)[0].Params)
+				}
+			}
+		}
+	}
+	return rerr
+}
+
 ////////////////////////////////////////////////////////////////////////////////////////////
 // 		Logging
 
@@ -1346,17 +1592,17 @@ func (ss *Sim) RunEpochName(run, epc int) string {
 
 // WeightsFileName returns default current weights file name
 func (ss *Sim) WeightsFileName() string {
-	return ss.Net.Nm + "_" + ss.RunName() + "_" + ss.RunEpochName(ss.TrainEnv.Run.Cur, ss.TrainEnv.Epoch.Cur) + ".wts.gz"
+	return filepath.Join(ss.OutDir, ss.Net.Nm+"_"+ss.RunName()+"_"+ss.RunEpochName(ss.TrainEnv.Run.Cur, ss.TrainEnv.Epoch.Cur)+".wts.gz")
 }
 
 // LogFileName returns default log file name
 func (ss *Sim) LogFileName(lognm string) string {
 	nm := ss.Net.Nm + "_" + ss.RunName() + "_" + lognm
-	if mpi.WorldRank() > 0 {
-		nm += fmt.Sprintf("_%d", mpi.WorldRank())
+	if ss.Rank() > 0 {
+		nm += fmt.Sprintf("_%d", ss.Rank())
 	}
 	nm += ".tsv"
-	return nm
+	return filepath.Join(ss.OutDir, nm)
 }
 
 //////////////////////////////////////////////
@@ -1409,7 +1655,7 @@ func (ss *Sim) LogTrnTrl(dt *etable.Table) {
 
 	// mpi.Printf("trl: %d %d %d: msec: %5.0f \t obj:%s\n", epc, trl, tick, ss.LastTrlMSec, ss.TrainEnv.String())
 
-	if ss.TrnTrlFile != nil && (!ss.UseMPI || ss.SaveProcLog) { // otherwise written at end of epoch, integrated
+	if ss.TrnTrlFile != nil && (ss.Comm == nil || ss.SaveProcLog) { // otherwise written at end of epoch, integrated
 		if ss.TrainEnv.Run.Cur == ss.StartRun && epc == 0 && row == 0 {
 			dt.WriteCSVHeaders(ss.TrnTrlFile, etable.Tab)
 		}
@@ -1474,6 +1720,86 @@ func (ss *Sim) ConfigTrnTrlPlot(plt *eplot.Plot2D, dt *etable.Table) *eplot.Plot
 //////////////////////////////////////////////
 //  CatLayActs
 
+// ConfigRSALays parses the RSALays specs into RSASpecs and RSACols,
+// skipping any with invalid layer or variable names
+func (ss *Sim) ConfigRSALays() {
+	ss.RSASpecs = ss.ParseLaySpecs(ss.RSALays)
+	ss.RSACols = nil
+	for i := range ss.RSASpecs {
+		ss.RSACols = append(ss.RSACols, ss.RSASpecs[i].Col())
+	}
+}
+
+// ParseLaySpecs parses layer[:var[:ctr]] specs (see RSALay) of the layers of the
+// network, skipping any with invalid layer or variable names
+func (ss *Sim) ParseLaySpecs(specs []string) []RSALay {
+	var rls []RSALay
+	for _, spec := range specs {
+		rl, err := ParseRSALay(spec)
+		if err != nil {
+			log.Println(err)
+			continue
+		}
+		ly, err := ss.Net.LayerByNameTry(rl.Lay)
+		if err != nil {
+			log.Println(err)
+			continue
+		}
+		if _, err := ly.UnitVarIdx(rl.Var); err != nil {
+			log.Println(err)
+			continue
+		}
+		if rl.Ctr && !ly.Is4D() {
+			log.Printf("ParseLaySpecs: layer %s is not 4D -- using all units instead of center pools\n", rl.Lay)
+			rl.Ctr = false
+		}
+		rls = append(rls, rl)
+	}
+	return rls
+}
+
+// RSAColIdx returns the index of given column name in RSACols, -1 if not found
+func (ss *Sim) RSAColIdx(col string) int {
+	for i, cn := range ss.RSACols {
+		if cn == col {
+			return i
+		}
+	}
+	return -1
+}
+
+// RSALayShape returns the shape of the CatLayActs column for given RSALay
+func (ss *Sim) RSALayShape(rl *RSALay) ([]int, []string) {
+	ly := ss.Net.LayerByName(rl.Lay).(leabra.LeabraLayer).AsLeabra()
+	if rl.Ctr {
+		nu, sis := ss.CenterPoolsIdxs(ly)
+		return []int{len(sis) * nu}, nil
+	}
+	return ly.Shp.Shp, ly.Shp.Nms
+}
+
+// RSALayVals returns the current values of given RSALay variable,
+// restricted to the center pools if Ctr
+func (ss *Sim) RSALayVals(rl *RSALay) []float32 {
+	ly := ss.Net.LayerByName(rl.Lay).(leabra.LeabraLayer).AsLeabra()
+	tsr := ss.ValsTsr("RSA_" + rl.Col())
+	ly.UnitValsTensor(tsr, rl.Var)
+	if !rl.Ctr {
+		return tsr.Values
+	}
+	ctsr := ss.ValsTsr("RSA_" + rl.Col() + "_ctr")
+	nu, sis := ss.CenterPoolsIdxs(ly)
+	ctsr.SetShape([]int{len(sis) * nu}, nil, nil)
+	ti := 0
+	for _, si := range sis {
+		for ni := 0; ni < nu; ni++ {
+			ctsr.Values[ti] = tsr.Values[si+ni]
+			ti++
+		}
+	}
+	return ctsr.Values
+}
+
 func (ss *Sim) RecCatLayActs(dt *etable.Table) {
 	obj := ss.TrainEnv.CurObj
 	rows := dt.RowsByString("Obj", obj, etable.Equals, etable.UseCase)
@@ -1484,22 +1810,26 @@ func (ss *Sim) RecCatLayActs(dt *etable.Table) {
 	row := rows[0] + ss.TrainEnv.Tick.Cur
 	avgDt := float32(0.1)
 	avgDtC := 1 - avgDt
-	for _, lnm := range ss.SuperLays {
-		ly := ss.Net.LayerByName(lnm).(leabra.LeabraLayer).AsLeabra()
-		cv := dt.CellTensor(lnm, row).(*etensor.Float32)
-		for i := range ly.Neurons {
-			cv.Values[i] = avgDtC*cv.Values[i] + avgDt*ly.Neurons[i].ActM
+	for i := range ss.RSASpecs {
+		rl := &ss.RSASpecs[i]
+		vals := ss.RSALayVals(rl)
+		cv := dt.CellTensor(rl.Col(), row).(*etensor.Float32)
+		for j, v := range vals {
+			cv.Values[j] = avgDtC*cv.Values[j] + avgDt*v
 		}
 	}
 }
 
 // ShareCatLayActs shares CatLayActs table across processors, for MPI mode
 func (ss *Sim) ShareCatLayActs() {
-	if ss.LIPOnly || !ss.UseMPI {
+	if ss.LIPOnly || ss.Comm == nil {
+		return
+	}
+	np := float32(1) / float32(ss.NProcs())
+	if err := ss.Comm.ReduceTable(ss.CatLayActsDest, ss.CatLayActs, mpi.OpSum); err != nil {
+		log.Println("ShareCatLayActs:", err)
 		return
 	}
-	np := float32(1) / float32(mpi.WorldSize())
-	empi.ReduceTable(ss.CatLayActsDest, ss.CatLayActs, ss.Comm, mpi.OpSum)
 	for ci, dcoli := range ss.CatLayActs.Cols {
 		if dcoli.DataType() != etensor.FLOAT32 {
 			continue
@@ -1512,7 +1842,7 @@ func (ss *Sim) ShareCatLayActs() {
 	}
 }
 
-func (ss *Sim) ConfigCatLayActs(dt *etable.Table) {
+func (ss *Sim) ConfigCatLayActs(dt *etable.Table, objs []string) {
 	dt.SetMetaData("name", "CatLayActs")
 	dt.SetMetaData("desc", "layer activations for each cat / obj")
 	dt.SetMetaData("read-only", "true")
@@ -1523,15 +1853,16 @@ func (ss *Sim) ConfigCatLayActs(dt *etable.Table) {
 		{"Obj", etensor.STRING, nil, nil},
 		{"Tick", etensor.INT64, nil, nil},
 	}
-	for _, lnm := range ss.SuperLays {
-		ly := ss.Net.LayerByName(lnm).(leabra.LeabraLayer).AsLeabra()
-		sch = append(sch, etable.Column{lnm, etensor.FLOAT32, ly.Shp.Shp, ly.Shp.Nms})
+	for i := range ss.RSASpecs {
+		rl := &ss.RSASpecs[i]
+		shp, nms := ss.RSALayShape(rl)
+		sch = append(sch, etable.Column{rl.Col(), etensor.FLOAT32, shp, nms})
 	}
 
-	nobj := len(ss.TrainEnv.Objs)
+	nobj := len(objs)
 	dt.SetFromSchema(sch, nobj*ss.MaxTicks)
 	row := 0
-	for _, ob := range ss.TrainEnv.Objs {
+	for _, ob := range objs {
 		co := strings.Split(ob, "/")
 		for t := 0; t < ss.MaxTicks; t++ {
 			dt.SetCellString("Cat", row, co[0])
@@ -1543,110 +1874,554 @@ func (ss *Sim) ConfigCatLayActs(dt *etable.Table) {
 }
 
 //////////////////////////////////////////////
-//  TrnEpcLog
+//  TrnTrlRepLog
 
-// HogDead computes the proportion of units in given layer name with ActAvg over hog thr
-// and under dead threshold
-func (ss *Sim) HogDead(lnm string) (hog, dead float64) {
-	ly := ss.Net.LayerByName(lnm).(leabra.LeabraLayer).AsLeabra()
-	n := 0
-	if ly.Is4D() {
-		npy := ly.Shp.Dim(0)
-		npx := ly.Shp.Dim(1)
-		nny := ly.Shp.Dim(2)
-		nnx := ly.Shp.Dim(3)
-		nn := nny * nnx
-		if npy == 8 { // exclude periphery
-			n = 16 * nn
-			for py := 2; py < 6; py++ {
-				for px := 2; px < 6; px++ {
-					pi := (py*npx + px) * nn
-					for ni := 0; ni < nn; ni++ {
-						nrn := &ly.Neurons[pi+ni]
-						if nrn.ActAvg > 0.3 {
-							hog += 1
-						} else if nrn.ActAvg < 0.01 {
-							dead += 1
-						}
-					}
-				}
-			}
-		} else if ly.Shp.Dim(0) == 4 && ly.Nm[:2] != "TE" {
-			n = 4 * nn
-			for py := 1; py < 3; py++ {
-				for px := 1; px < 3; px++ {
-					pi := (py*npx + px) * nn
-					for ni := 0; ni < nn; ni++ {
-						nrn := &ly.Neurons[pi+ni]
-						if nrn.ActAvg > 0.3 {
-							hog += 1
-						} else if nrn.ActAvg < 0.01 {
-							dead += 1
-						}
-					}
-				}
-			}
-		}
+// ProbeClss are the TrnTrlRepLog class columns decoded by the Probe
+var ProbeClss = []string{"Cat", "Obj", "Tick"}
+
+// ProbeRegs are the TrnTrlRepLog continuous columns decoded by the Probe
+var ProbeRegs = []string{"EyePos", "SacPlan", "Saccade", "ObjVel"}
+
+// RecReps returns true if the TrnTrlRepLog should be recorded for given epoch
+func (ss *Sim) RecReps(epc int) bool {
+	return ss.RSA.Interval > 0 && epc%ss.RSA.Interval == 0
+}
+
+// CenterPoolsIdxs returns the indexes for 2x2 center pools:
+// nu = number of units per pool, sis = starting indexes
+func (ss *Sim) CenterPoolsIdxs(ly *leabra.Layer) (nu int, sis []int) {
+	nu = ly.Shp.Dim(2) * ly.Shp.Dim(3)
+	npy := ly.Shp.Dim(0)
+	npx := ly.Shp.Dim(1)
+	cpy := (npy - 1) / 2
+	cpx := (npx - 1) / 2
+	if npx <= 2 {
+		cpx = 0
 	}
-	if n == 0 {
-		n = len(ly.Neurons)
-		for ni := range ly.Neurons {
-			nrn := &ly.Neurons[ni]
-			if nrn.ActAvg > 0.3 {
-				hog += 1
-			} else if nrn.ActAvg < 0.01 {
-				dead += 1
-			}
+	if npy <= 2 {
+		cpy = 0
+	}
+
+	for py := 0; py < 2; py++ {
+		for px := 0; px < 2; px++ {
+			y := py + cpy
+			x := px + cpx
+			si := (y*npx + x) * nu
+			sis = append(sis, si)
 		}
 	}
-	hog /= float64(n)
-	dead /= float64(n)
 	return
 }
 
-// LogTrnEpc adds data from current epoch to the TrnEpcLog table.
-// computes epoch averages prior to logging.
-func (ss *Sim) LogTrnEpc(dt *etable.Table) {
-	// if mpi.WorldRank() == 0 {
-	// 	ss.Net.TimerReport()
-	// 	ss.Net.ThrTimerReset()
-	// }
+// CopyCenterPools copy 2 center pools of ActM to tensor
+func (ss *Sim) CopyCenterPools(ly *leabra.Layer, vl *etensor.Float32) {
+	nu, sis := ss.CenterPoolsIdxs(ly)
+	vl.SetShape([]int{len(sis) * nu}, nil, nil)
+	ti := 0
+	for _, si := range sis {
+		for ni := 0; ni < nu; ni++ {
+			vl.Values[ti] = ly.Neurons[si+ni].ActM
+			ti++
+		}
+	}
+}
+
+// LogTrnRepTrl adds data from current trial to the TrnTrlRepLog table.
+func (ss *Sim) LogTrnRepTrl(dt *etable.Table) {
+	ss.LogRepTrl(dt, &ss.TrainEnv)
+}
 
+// LogRepTrl adds the layer representations of the current trial of given env
+// to dt, configured by ConfigTrnTrlRepLog -- resets dt at a new epoch.
+func (ss *Sim) LogRepTrl(dt *etable.Table, ev *Obj3DSacEnv) {
+	epc := ev.Epoch.Cur
+	trl := ev.Trial.Cur
+	tick := ev.Tick.Cur
 	row := dt.Rows
-	dt.SetNumRows(row + 1)
 
-	trl := ss.TrnTrlLog
-	if ss.UseMPI {
-		empi.GatherTableRows(ss.TrnTrlLogAll, ss.TrnTrlLog, ss.Comm)
-		trl = ss.TrnTrlLogAll
-		ss.ShareCatLayActs()
+	if row > 0 { // reset at new epoch
+		lstepc := int(dt.CellFloat("Epoch", row-1))
+		if lstepc != epc {
+			dt.SetNumRows(0)
+			row = 0
+		}
+	}
+	if dt.Rows <= row {
+		dt.SetNumRows(row + 1)
+	}
+
+	dt.SetCellFloat("Run", row, float64(ev.Run.Cur))
+	dt.SetCellFloat("Epoch", row, float64(epc))
+	dt.SetCellFloat("Trial", row, float64(trl))
+	dt.SetCellFloat("Tick", row, float64(tick))
+	dt.SetCellFloat("Idx", row, float64(row))
+	dt.SetCellString("Cat", row, ev.CurCat)
+	dt.SetCellString("Obj", row, ev.CurObj)
+	dt.SetCellString("TrialName", row, ev.String())
+	for _, cn := range ProbeRegs {
+		v := ev.CurVec2(cn)
+		dt.SetCellTensorFloat1D(cn, row, 0, float64(v.X))
+		dt.SetCellTensorFloat1D(cn, row, 1, float64(v.Y))
+	}
+
+	for _, lnm := range ss.HidLays {
+		ly := ss.Net.LayerByName(lnm).(leabra.LeabraLayer).AsLeabra()
+		lvt := ss.ValsTsr(lnm)
+		if ly.Is4D() && ly.Shp.Dim(0) > 2 && ly.Shp.Dim(2) > 2 && !strings.HasPrefix(ly.Nm, "TE") {
+			ss.CopyCenterPools(ly, lvt)
+			dt.SetCellTensor(lnm, row, lvt)
+		} else {
+			ly.UnitValsTensor(lvt, "ActM")
+			dt.SetCellTensor(lnm, row, lvt)
+		}
+	}
+}
+
+func (ss *Sim) ConfigTrnTrlRepLog(dt *etable.Table) {
+	dt.SetMetaData("name", "TrnTrlRepLog")
+	dt.SetMetaData("desc", "Record of layer representations per training trial")
+	dt.SetMetaData("read-only", "true")
+	dt.SetMetaData("precision", strconv.Itoa(LogPrec))
+
+	sch := etable.Schema{
+		{"Run", etensor.INT64, nil, nil},
+		{"Epoch", etensor.INT64, nil, nil},
+		{"Trial", etensor.INT64, nil, nil},
+		{"Tick", etensor.INT64, nil, nil},
+		{"Idx", etensor.INT64, nil, nil},
+		{"Cat", etensor.STRING, nil, nil},
+		{"Obj", etensor.STRING, nil, nil},
+		{"TrialName", etensor.STRING, nil, nil},
+	}
+	for _, cn := range ProbeRegs {
+		sch = append(sch, etable.Column{cn, etensor.FLOAT64, []int{2}, nil})
+	}
+	for _, lnm := range ss.HidLays {
+		ly := ss.Net.LayerByName(lnm).(leabra.LeabraLayer).AsLeabra()
+		if ly.Is4D() && ly.Shp.Dim(0) > 2 && ly.Shp.Dim(2) > 2 && !strings.HasPrefix(ly.Nm, "TE") {
+			nu, sis := ss.CenterPoolsIdxs(ly)
+			sch = append(sch, etable.Column{lnm, etensor.FLOAT64, []int{len(sis) * nu}, nil})
+		} else {
+			sch = append(sch, etable.Column{lnm, etensor.FLOAT64, ly.Shp.Shp, nil})
+		}
+	}
+	dt.SetFromSchema(sch, 0)
+}
+
+// ProbeReps runs the linear decoding Probe on given reps from TrnTrlRepLog,
+// for all HidLays, and saves the results to the probe log file
+func (ss *Sim) ProbeReps(reps *etable.IdxView) {
+	if reps == nil || reps.Len() == 0 {
+		return
+	}
+	ss.Probe.Run(reps, ss.HidLays, ProbeClss, ProbeRegs)
+	fnm := ss.LogFileName("probe")
+	ss.Probe.Results.SaveCSV(gi.FileName(fnm), etable.Tab, etable.Headers)
+}
+
+// EpcInvarStats are the Invar stats recorded in the epoch log for each of the HidLays
+var EpcInvarStats = []string{"ObjInvar", "PosInvar", "SacInvar"}
+
+// InvarReps computes the Invar invariance stats on given reps from TrnTrlRepLog,
+// for all HidLays, and saves the history over epochs to the invar log file
+func (ss *Sim) InvarReps(reps *etable.IdxView, epc int) {
+	if reps == nil || reps.Len() == 0 {
+		return
+	}
+	ss.Invar.Run(reps, ss.HidLays, epc)
+	fnm := ss.LogFileName("invar")
+	ss.Invar.Hist.SaveCSV(gi.FileName(fnm), etable.Tab, etable.Headers)
+}
+
+// RDMReps saves the trial-level RDMs of given reps from TrnTrlRepLog for all HidLays,
+// if RepRDMs is set, using RSA.Stream so that only a block of rows is in memory at a time
+func (ss *Sim) RDMReps(reps *etable.IdxView, epc int) {
+	if !ss.RepRDMs || reps == nil || reps.Len() == 0 {
+		return
+	}
+	fnm := ss.LogFileName(fmt.Sprintf("reprdm_rows_%03d", epc))
+	fp, err := os.Create(fnm)
+	if err != nil {
+		log.Println(err)
+		return
+	}
+	bw := bufio.NewWriter(fp)
+	fmt.Fprintln(bw, "Obj")
+	for _, row := range reps.Idxs {
+		fmt.Fprintln(bw, reps.Table.CellString("Obj", row))
+	}
+	bw.Flush()
+	fp.Close()
+	for _, lnm := range ss.HidLays {
+		fnm = strings.TrimSuffix(ss.LogFileName(fmt.Sprintf("%s_reprdm_%03d", lnm, epc)), ".tsv") + ".npy"
+		if err := ss.RSA.Stream.SaveNpy(fnm, reps, lnm); err != nil {
+			log.Println(err)
+		}
+	}
+}
+
+//////////////////////////////////////////////
+//  Npz export
+
+// RecNpz returns true if the training trials should be exported to a .npz for given epoch
+func (ss *Sim) RecNpz(epc int) bool {
+	return ss.NpzExport.Interval > 0 && epc%ss.NpzExport.Interval == 0
+}
+
+// NpzIdxs are the trial metadata exported as int32 arrays in the .npz, before the
+// ProbeRegs: Cat and Obj are the indexes into the Cats and Objs label arrays
+var NpzIdxs = []string{"Cat", "Obj", "Tick", "Trial"}
+
+// OpenNpzStrms opens the NpzStrms of the trial metadata (NpzIdxs and ProbeRegs)
+// and of the NpzSpecs layer variables, spilling to the OutDir
+func (ss *Sim) OpenNpzStrms() error {
+	ss.CloseNpzStrms()
+	add := func(name string, shp []int, isInt bool) error {
+		ns, err := NewNpyStream(name, ss.OutDir, shp)
+		if err != nil {
+			return err
+		}
+		ns.Int = isInt
+		ss.NpzStrms = append(ss.NpzStrms, ns)
+		return nil
+	}
+	for _, cn := range NpzIdxs {
+		if err := add(cn, nil, true); err != nil {
+			return err
+		}
+	}
+	for _, cn := range ProbeRegs {
+		if err := add(cn, []int{2}, false); err != nil {
+			return err
+		}
+	}
+	for i := range ss.NpzSpecs {
+		rl := &ss.NpzSpecs[i]
+		shp, _ := ss.RSALayShape(rl)
+		if err := add(rl.Col(), shp, false); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// CloseNpzStrms closes the NpzStrms, removing their spill files
+func (ss *Sim) CloseNpzStrms() {
+	for _, ns := range ss.NpzStrms {
+		ns.Close()
+	}
+	ss.NpzStrms = nil
+}
+
+// NpzRecTrial records the current trial of given env in the NpzStrms, opening them if needed
+func (ss *Sim) NpzRecTrial(ev *Obj3DSacEnv) {
+	if ss.NpzStrms == nil {
+		if err := ss.OpenNpzStrms(); err != nil {
+			log.Println(err)
+			ss.CloseNpzStrms()
+			return
+		}
+	}
+	ci, oi := -1, -1
+	for i, c := range ev.Cats {
+		if c == ev.CurCat {
+			ci = i
+		}
+	}
+	obj := ev.CurCat + "/" + ev.CurObj
+	for i, o := range ev.Objs {
+		if o == obj {
+			oi = i
+		}
+	}
+	si := 0
+	add := func(vals ...float32) {
+		if err := ss.NpzStrms[si].AddRow(vals); err != nil {
+			log.Println(err)
+		}
+		si++
+	}
+	add(float32(ci))
+	add(float32(oi))
+	add(float32(ev.Tick.Cur))
+	add(float32(ev.Trial.Cur))
+	for _, cn := range ProbeRegs {
+		v := ev.CurVec2(cn)
+		add(v.X, v.Y)
+	}
+	for i := range ss.NpzSpecs {
+		add(ss.RSALayVals(&ss.NpzSpecs[i])...)
+	}
+}
+
+// SaveNpz saves the trials recorded in the NpzStrms to a .npz archive file, with
+// the Cats and Objs labels of given env, and closes them -- under MPI, the trials
+// of all procs are first gathered into rank 0, which saves the file.
+// In python: d = numpy.load(fnm); d["Objs"][d["Obj"]] are the objects of the trials.
+func (ss *Sim) SaveNpz(fnm string, ev *Obj3DSacEnv) error {
+	defer ss.CloseNpzStrms()
+	if ss.NpzStrms == nil { // no trials recorded: still needed for the MPI gather
+		if err := ss.OpenNpzStrms(); err != nil {
+			return err
+		}
+	}
+	if ss.Comm != nil {
+		if err := ss.MPIGatherNpz(); err != nil {
+			return err
+		}
+		if ss.Rank() != 0 {
+			return nil
+		}
+	}
+	ss.Printf("Saving %d trials of layer activations to: %s\n", ss.NpzStrms[0].Rows, fnm)
+	nz, err := CreateNpz(fnm)
+	if err != nil {
+		return err
+	}
+	for _, ns := range ss.NpzStrms {
+		if err := nz.WriteStream(ns); err != nil {
+			nz.Close()
+			return err
+		}
+	}
+	if err := nz.WriteStrings("Cats", ev.Cats); err != nil {
+		nz.Close()
+		return err
+	}
+	if err := nz.WriteStrings("Objs", ev.Objs); err != nil {
+		nz.Close()
+		return err
+	}
+	return nz.Close()
+}
+
+// MPIGatherNpz gathers the trials recorded in the NpzStrms of all procs into those
+// of rank 0, in order of rank, NpzExport.BlockRows at a time: each block is summed
+// across procs with only the sending proc filling it, so that memory is bounded.
+func (ss *Sim) MPIGatherNpz() error {
+	np := ss.NProcs()
+	rank := ss.Rank()
+	nr := make([]float32, np)
+	nrs := make([]float32, np)
+	nr[rank] = float32(ss.NpzStrms[0].Rows)
+	ss.Comm.AllReduceF32(mpi.OpSum, nrs, nr)
+	bs := ss.NpzExport.BlockRows
+	if bs <= 0 {
+		bs = 256
+	}
+	var rerr error
+	for _, ns := range ss.NpzStrms {
+		src := make([]float32, bs*ns.RowLen)
+		dst := make([]float32, bs*ns.RowLen)
+		for pi := 1; pi < np; pi++ {
+			n := int(nrs[pi])
+			for st := 0; st < n; st += bs {
+				for i := range src {
+					src[i] = 0
+				}
+				if rank == pi {
+					if _, err := ns.ReadRows(st, src); err != nil && rerr == nil {
+						rerr = err // keep going, as the other procs are waiting on this one
+					}
+				}
+				ss.Comm.AllReduceF32(mpi.OpSum, dst, src)
+				if rank != 0 {
+					continue
+				}
+				nb := bs
+				if st+nb > n {
+					nb = n - st
+				}
+				for r := 0; r < nb; r++ {
+					if err := ns.AddRow(dst[r*ns.RowLen : (r+1)*ns.RowLen]); err != nil && rerr == nil {
+						rerr = err
+					}
+				}
+			}
+		}
+	}
+	return rerr
+}
+
+//////////////////////////////////////////////
+//  TrnEpcLog
+
+// HogDead computes the proportion of units in given layer name with ActAvg over hog thr
+// and under dead threshold
+func (ss *Sim) HogDead(lnm string) (hog, dead float64) {
+	ly := ss.Net.LayerByName(lnm).(leabra.LeabraLayer).AsLeabra()
+	n := 0
+	if ly.Is4D() {
+		npy := ly.Shp.Dim(0)
+		npx := ly.Shp.Dim(1)
+		nny := ly.Shp.Dim(2)
+		nnx := ly.Shp.Dim(3)
+		nn := nny * nnx
+		if npy == 8 { // exclude periphery
+			n = 16 * nn
+			for py := 2; py < 6; py++ {
+				for px := 2; px < 6; px++ {
+					pi := (py*npx + px) * nn
+					for ni := 0; ni < nn; ni++ {
+						nrn := &ly.Neurons[pi+ni]
+						if nrn.ActAvg > 0.3 {
+							hog += 1
+						} else if nrn.ActAvg < 0.01 {
+							dead += 1
+						}
+					}
+				}
+			}
+		} else if ly.Shp.Dim(0) == 4 && ly.Nm[:2] != "TE" {
+			n = 4 * nn
+			for py := 1; py < 3; py++ {
+				for px := 1; px < 3; px++ {
+					pi := (py*npx + px) * nn
+					for ni := 0; ni < nn; ni++ {
+						nrn := &ly.Neurons[pi+ni]
+						if nrn.ActAvg > 0.3 {
+							hog += 1
+						} else if nrn.ActAvg < 0.01 {
+							dead += 1
+						}
+					}
+				}
+			}
+		}
+	}
+	if n == 0 {
+		n = len(ly.Neurons)
+		for ni := range ly.Neurons {
+			nrn := &ly.Neurons[ni]
+			if nrn.ActAvg > 0.3 {
+				hog += 1
+			} else if nrn.ActAvg < 0.01 {
+				dead += 1
+			}
+		}
+	}
+	hog /= float64(n)
+	dead /= float64(n)
+	return
+}
+
+// LogTrnEpc adds data from current epoch to the TrnEpcLog table.
+// computes epoch averages prior to logging.
+func (ss *Sim) LogTrnEpc(dt *etable.Table) {
+	// if mpi.WorldRank() == 0 {
+	// 	ss.Net.TimerReport()
+	// 	ss.Net.ThrTimerReset()
+	// }
+
+	row := dt.Rows
+	dt.SetNumRows(row + 1)
+
+	trl := ss.TrnTrlLog
+	if ss.Comm != nil {
+		ss.Comm.GatherTableRows(ss.TrnTrlLogAll, ss.TrnTrlLog)
+		trl = ss.TrnTrlLogAll
+		ss.ShareCatLayActs()
 	}
 
 	epc := ss.TrainEnv.Epoch.Prv // this is triggered by increment so use previous value
 	nt := float64(trl.Rows)
 
-	if !ss.LIPOnly && mpi.WorldRank() == 0 {
+	if ss.RecReps(epc) {
+		reps := etable.NewIdxView(ss.TrnTrlRepLog)
+		if ss.Comm != nil {
+			ss.Comm.GatherTableRows(ss.TrnTrlRepLogAll, ss.TrnTrlRepLog)
+			reps = etable.NewIdxView(ss.TrnTrlRepLogAll)
+		}
+		if ss.Rank() == 0 {
+			ss.ProbeReps(reps)
+			ss.InvarReps(reps, epc)
+			ss.RDMReps(reps, epc)
+		}
+	}
+	if ss.RecNpz(epc) {
+		fnm := strings.TrimSuffix(ss.LogFileName(fmt.Sprintf("acts_%03d", epc)), ".tsv") + ".npz"
+		if err := ss.SaveNpz(fnm, &ss.TrainEnv); err != nil {
+			log.Println(err)
+		}
+	}
+	for _, lnm := range ss.HidLays {
+		for _, cn := range ProbeClss {
+			dt.SetCellFloat(lnm+"_Prb"+cn, row, ss.Probe.Val(lnm, cn))
+		}
+		for _, cn := range ProbeRegs {
+			dt.SetCellFloat(lnm+"_Prb"+cn, row, ss.Probe.Val(lnm, cn))
+		}
+		for _, sn := range EpcInvarStats {
+			dt.SetCellFloat(lnm+"_"+sn, row, ss.Invar.Val(lnm, sn))
+		}
+	}
+
+	if !ss.LIPOnly && ss.Rank() == 0 {
 		if (epc % ss.RSA.Interval) == 0 {
-			ss.RSA.StatsFmActs(ss.CatLayActs, ss.SuperLays)
-			fnm := ss.LogFileName("TEsim")
-			fmt.Printf("Saving TEsim to: %v\n", fnm)
-			sm := ss.RSA.Sims["TE"]
-			etensor.SaveCSV(sm.Mat, gi.FileName(fnm), etable.Tab.Rune())
+			ss.RSA.StatsFmActs(ss.CatLayActs, ss.RSACols)
+			if sm, ok := ss.RSA.Sims["TE"]; ok && sm.Mat != nil {
+				fnm := ss.LogFileName("TEsim")
+				fmt.Printf("Saving TEsim to: %v\n", fnm)
+				etensor.SaveCSV(sm.Mat, gi.FileName(fnm), etable.Tab.Rune())
+				if ss.Status != nil {
+					ss.StatusSimMat(sm)
+				}
+			}
+			if gsm, ok := ss.RSA.TickGens["TE"]; ok {
+				fnm := ss.LogFileName("TEtickgen")
+				etensor.SaveCSV(gsm.Mat, gi.FileName(fnm), etable.Tab.Rune())
+			}
+			ss.RSA.LayCmpFmActs(&ss.RSA.LayCmp, ss.CatLayActs, ss.RSACols, ss.CatLayActs, ss.RSACols)
+			ss.SaveLayCmp(&ss.RSA.LayCmp, "laycmp")
+			ss.EmbedReps(epc)
+			ss.GeomReps(epc)
+			ss.DriftReps(epc)
+			ss.CatTreeReps()
 		}
-		for li, lnm := range ss.SuperLays {
+		for li, lnm := range ss.RSACols {
 			dt.SetCellFloat(lnm+"_V1Sim", row, ss.RSA.V1Sims[li])
 			dt.SetCellFloat(lnm+"_CatDst", row, ss.RSA.CatDists[li])
 		}
-		pr := 0.0
-		teidx := len(ss.SuperLays) - 1
-		if ss.RSA.PermDists["TE"] > 0 {
-			pr = ss.RSA.CatDists[teidx] / ss.RSA.PermDists["TE"]
+		tst, ted := ss.RSA.TickRange(ss.CatLayActs)
+		for _, lnm := range ss.RSACols {
+			cds := ss.RSA.TickCatDists[lnm]
+			for tck := tst; tck <= ted; tck++ {
+				cd := 0.0
+				if tck-tst < len(cds) {
+					cd = cds[tck-tst]
+				}
+				dt.SetCellFloat(fmt.Sprintf("%s_CatDst_%d", lnm, tck), row, cd)
+			}
+			dt.SetCellFloat(lnm+"_TickGen", row, ss.RSA.TickGenAvgs[lnm])
+			dt.SetCellFloat(lnm+"_TickRDMCor", row, ss.RSA.TickRDMCorAvgs[lnm])
+		}
+		for _, lnm := range ss.RSACols {
+			for _, sn := range GeomStats {
+				dt.SetCellFloat(lnm+"_"+sn, row, ss.Geom.Val(lnm, sn))
+			}
+		}
+		for _, lnm := range ss.Drift.Lays {
+			for _, sn := range DriftStats {
+				dt.SetCellFloat(lnm+"_"+sn, row, ss.Drift.Val(lnm, sn))
+			}
+		}
+		for _, lnm := range ss.RSA.TreeLays {
+			for _, lv := range ss.RSA.Tree.Levels {
+				dt.SetCellFloat(lnm+"_"+lv.Name+"Dst", row, ss.RSA.TreeVal(lnm, lv.Name, "NestDst"))
+				dt.SetCellFloat(lnm+"_"+lv.Name+"P", row, ss.RSA.TreeVal(lnm, lv.Name, "P"))
+			}
+		}
+		if teidx := ss.RSAColIdx("TE"); teidx >= 0 {
+			pr := 0.0
+			if ss.RSA.PermDists["TE"] > 0 {
+				pr = ss.RSA.CatDists[teidx] / ss.RSA.PermDists["TE"]
+			}
+			dt.SetCellFloat("TE_PermRatio", row, pr)
+			dt.SetCellFloat("TE_PermDst", row, ss.RSA.PermDists["TE"])
+			dt.SetCellFloat("TE_PermNCat", row, float64(ss.RSA.PermNCats["TE"]))
+			dt.SetCellFloat("TE_BasicDst", row, ss.RSA.BasicDists[teidx])
+			dt.SetCellFloat("TE_ExptDst", row, ss.RSA.ExptDists[teidx])
 		}
-		dt.SetCellFloat("TE_PermRatio", row, pr)
-		dt.SetCellFloat("TE_PermDst", row, ss.RSA.PermDists["TE"])
-		dt.SetCellFloat("TE_PermNCat", row, float64(ss.RSA.PermNCats["TE"]))
-		dt.SetCellFloat("TE_BasicDst", row, ss.RSA.BasicDists[teidx])
-		dt.SetCellFloat("TE_ExptDst", row, ss.RSA.ExptDists[teidx])
 	}
 
 	if ss.LastEpcTime.IsZero() {
@@ -1660,6 +2435,7 @@ func (ss *Sim) LogTrnEpc(dt *etable.Table) {
 	dt.SetCellFloat("Run", row, float64(ss.TrainEnv.Run.Cur))
 	dt.SetCellFloat("Epoch", row, float64(epc))
 	dt.SetCellFloat("PerTrlMSec", row, ss.EpcPerTrlMSec)
+	dt.SetCellString("SchedActs", row, ss.SchedActs)
 
 	for li, lnm := range ss.HidLays {
 		hog, dead := ss.HogDead(lnm)
@@ -1740,8 +2516,15 @@ func (ss *Sim) LogTrnEpc(dt *etable.Table) {
 		}
 		dt.WriteCSVRow(ss.TrnEpcFile, row, etable.Tab)
 	}
+	if ss.Status != nil {
+		ss.Status.AddEpoch(dt, row)
+	}
+	if ss.Manifest != nil {
+		ss.Manifest.UpdtRun(epc+1, ss.EpcPerTrlMSec)
+		ss.SaveManifest()
+	}
 
-	if ss.TrnTrlFile != nil && !(!ss.UseMPI || ss.SaveProcLog) { // saved at trial level otherwise
+	if ss.TrnTrlFile != nil && !(ss.Comm == nil || ss.SaveProcLog) { // saved at trial level otherwise
 		if ss.TrainEnv.Run.Cur == ss.StartRun && row == 0 {
 			// note: can't just use row=0 b/c reset table each run
 			trl.WriteCSVHeaders(ss.TrnTrlFile, etable.Tab)
@@ -1762,6 +2545,7 @@ func (ss *Sim) ConfigTrnEpcLog(dt *etable.Table) {
 		{"Run", etensor.INT64, nil, nil},
 		{"Epoch", etensor.INT64, nil, nil},
 		{"PerTrlMSec", etensor.FLOAT64, nil, nil},
+		{"SchedActs", etensor.STRING, nil, nil},
 	}
 	for _, lnm := range ss.HidLays {
 		sch = append(sch, etable.Column{lnm + "_Dead", etensor.FLOAT64, nil, nil})
@@ -1774,10 +2558,10 @@ func (ss *Sim) ConfigTrnEpcLog(dt *etable.Table) {
 		sch = append(sch, etable.Column{lnm + "_TrlCosDiff", etensor.FLOAT64, nil, nil})
 		sch = append(sch, etable.Column{lnm + "_TrlCosDiff0", etensor.FLOAT64, nil, nil})
 	}
-	for _, lnm := range ss.SuperLays {
+	for _, lnm := range ss.RSACols {
 		sch = append(sch, etable.Column{lnm + "_V1Sim", etensor.FLOAT64, nil, nil})
 	}
-	for _, lnm := range ss.SuperLays {
+	for _, lnm := range ss.RSACols {
 		sch = append(sch, etable.Column{lnm + "_CatDst", etensor.FLOAT64, nil, nil})
 	}
 	sch = append(sch, etable.Column{"TE_PermRatio", etensor.FLOAT64, nil, nil})
@@ -1785,6 +2569,41 @@ func (ss *Sim) ConfigTrnEpcLog(dt *etable.Table) {
 	sch = append(sch, etable.Column{"TE_PermNCat", etensor.FLOAT64, nil, nil})
 	sch = append(sch, etable.Column{"TE_BasicDst", etensor.FLOAT64, nil, nil})
 	sch = append(sch, etable.Column{"TE_ExptDst", etensor.FLOAT64, nil, nil})
+	tst, ted := ss.RSA.TickRange(ss.CatLayActs)
+	for _, lnm := range ss.RSACols {
+		for tck := tst; tck <= ted; tck++ {
+			sch = append(sch, etable.Column{fmt.Sprintf("%s_CatDst_%d", lnm, tck), etensor.FLOAT64, nil, nil})
+		}
+		sch = append(sch, etable.Column{lnm + "_TickGen", etensor.FLOAT64, nil, nil})
+		sch = append(sch, etable.Column{lnm + "_TickRDMCor", etensor.FLOAT64, nil, nil})
+	}
+	for _, lnm := range ss.RSACols {
+		for _, sn := range GeomStats {
+			sch = append(sch, etable.Column{lnm + "_" + sn, etensor.FLOAT64, nil, nil})
+		}
+	}
+	for _, lnm := range ss.Drift.Lays {
+		for _, sn := range DriftStats {
+			sch = append(sch, etable.Column{lnm + "_" + sn, etensor.FLOAT64, nil, nil})
+		}
+	}
+	for _, lnm := range ss.RSA.TreeLays {
+		for _, lv := range ss.RSA.Tree.Levels {
+			sch = append(sch, etable.Column{lnm + "_" + lv.Name + "Dst", etensor.FLOAT64, nil, nil})
+			sch = append(sch, etable.Column{lnm + "_" + lv.Name + "P", etensor.FLOAT64, nil, nil})
+		}
+	}
+	for _, lnm := range ss.HidLays {
+		for _, cn := range ProbeClss {
+			sch = append(sch, etable.Column{lnm + "_Prb" + cn, etensor.FLOAT64, nil, nil})
+		}
+		for _, cn := range ProbeRegs {
+			sch = append(sch, etable.Column{lnm + "_Prb" + cn, etensor.FLOAT64, nil, nil})
+		}
+		for _, sn := range EpcInvarStats {
+			sch = append(sch, etable.Column{lnm + "_" + sn, etensor.FLOAT64, nil, nil})
+		}
+	}
 	for tck := 0; tck < ss.MaxTicks; tck++ {
 		for _, lnm := range ss.PulvLays {
 			sch = append(sch, etable.Column{fmt.Sprintf("%s_CosDiff_%d", lnm, tck), etensor.FLOAT64, nil, nil})
@@ -1814,7 +2633,7 @@ func (ss *Sim) ConfigTrnEpcPlot(plt *eplot.Plot2D, dt *etable.Table) *eplot.Plot
 		plt.SetColParams(lnm+"_TrlCosDiff", eplot.Off, eplot.FixMin, 0, eplot.FloatMax, 1)
 		plt.SetColParams(lnm+"_TrlCosDiff0", eplot.Off, eplot.FixMin, 0, eplot.FloatMax, 1)
 	}
-	for _, lnm := range ss.SuperLays {
+	for _, lnm := range ss.RSACols {
 		on := lnm == "TE"
 		plt.SetColParams(lnm+"_V1Sim", on, eplot.FixMin, 0, eplot.FixMax, 1)
 		plt.SetColParams(lnm+"_CatDst", on, eplot.FixMin, 0, eplot.FixMax, 1)
@@ -1824,20 +2643,120 @@ func (ss *Sim) ConfigTrnEpcPlot(plt *eplot.Plot2D, dt *etable.Table) *eplot.Plot
 	plt.SetColParams("TE_PermNCat", eplot.On, eplot.FixMin, 0, eplot.FixMax, 1)
 	plt.SetColParams("TE_BasicDst", eplot.On, eplot.FixMin, 0, eplot.FixMax, 1)
 	plt.SetColParams("TE_ExptDst", eplot.Off, eplot.FixMin, 0, eplot.FloatMax, 1)
-	for tck := 0; tck < ss.MaxTicks; tck++ {
-		for _, lnm := range ss.PulvLays {
-			plt.SetColParams(fmt.Sprintf("%s_CosDiff_%d", lnm, tck), eplot.On, eplot.FixMin, 0, eplot.FixMax, 1)
-			// plt.SetColParams(fmt.Sprintf("%s_AvgSSE_%d", lnm, tck), eplot.Off, eplot.FixMin, 0, eplot.FloatMax, 1)
+	tst, ted := ss.RSA.TickRange(ss.CatLayActs)
+	for _, lnm := range ss.RSACols {
+		for tck := tst; tck <= ted; tck++ {
+			plt.SetColParams(fmt.Sprintf("%s_CatDst_%d", lnm, tck), eplot.Off, eplot.FixMin, 0, eplot.FixMax, 1)
 		}
+		on := lnm == "TE"
+		plt.SetColParams(lnm+"_TickGen", on, eplot.FixMin, 0, eplot.FixMax, 1)
+		plt.SetColParams(lnm+"_TickRDMCor", eplot.Off, eplot.FixMin, 0, eplot.FixMax, 1)
+	}
+	for _, lnm := range ss.RSACols {
+		for _, sn := range GeomStats {
+			on := lnm == "TE" && sn == "CatSelFrac"
+			if sn == "PR" {
+				plt.SetColParams(lnm+"_"+sn, on, eplot.FixMin, 0, eplot.FloatMax, 1)
+			} else {
+				plt.SetColParams(lnm+"_"+sn, on, eplot.FixMin, 0, eplot.FixMax, 1)
+			}
+		}
+	}
+	for _, lnm := range ss.Drift.Lays {
+		for _, sn := range DriftStats {
+			if sn == "NCats" {
+				plt.SetColParams(lnm+"_"+sn, eplot.Off, eplot.FixMin, 0, eplot.FloatMax, 1)
+			} else {
+				plt.SetColParams(lnm+"_"+sn, eplot.Off, eplot.FixMin, 0, eplot.FixMax, 1)
+			}
+		}
+	}
+	for _, lnm := range ss.RSA.TreeLays {
+		for _, lv := range ss.RSA.Tree.Levels {
+			plt.SetColParams(lnm+"_"+lv.Name+"Dst", eplot.Off, eplot.FloatMin, 0, eplot.FloatMax, 1)
+			plt.SetColParams(lnm+"_"+lv.Name+"P", eplot.Off, eplot.FixMin, 0, eplot.FixMax, 1)
+		}
+	}
+	for _, lnm := range ss.HidLays {
+		for _, cn := range ProbeClss {
+			on := lnm == "TE" && cn == "Cat"
+			plt.SetColParams(lnm+"_Prb"+cn, on, eplot.FixMin, 0, eplot.FixMax, 1)
+		}
+		for _, cn := range ProbeRegs {
+			plt.SetColParams(lnm+"_Prb"+cn, eplot.Off, eplot.FixMin, 0, eplot.FixMax, 1)
+		}
+		for _, sn := range EpcInvarStats {
+			on := lnm == "TE" && sn == "ObjInvar"
+			plt.SetColParams(lnm+"_"+sn, on, eplot.FloatMin, 0, eplot.FloatMax, 1)
+		}
+	}
+	for tck := 0; tck < ss.MaxTicks; tck++ {
+		for _, lnm := range ss.PulvLays {
+			plt.SetColParams(fmt.Sprintf("%s_CosDiff_%d", lnm, tck), eplot.On, eplot.FixMin, 0, eplot.FixMax, 1)
+			// plt.SetColParams(fmt.Sprintf("%s_AvgSSE_%d", lnm, tck), eplot.Off, eplot.FixMin, 0, eplot.FloatMax, 1)
+		}
+	}
+	return plt
+}
+
+// OpenCatActs Open a catact file with layer activity by category,
+// and then run the RSA analysis on it -- see RSA for results
+func (ss *Sim) OpenCatActs(fname gi.FileName) {
+	ss.CatLayActs.OpenCSV(fname, etable.Tab)
+	ss.RSA.StatsFmActs(ss.CatLayActs, ss.RSACols)
+	ss.RSA.LayCmpFmActs(&ss.RSA.LayCmp, ss.CatLayActs, ss.RSACols, ss.CatLayActs, ss.RSACols)
+}
+
+// CmpCatActs opens a catact file from another run or model (e.g., wwi3d vs. wwi3d_axon)
+// and compares all of its layers against the current CatLayActs layers -- see RSA.XCmp,
+// which is also saved to an xcmp log file.
+func (ss *Sim) CmpCatActs(fname gi.FileName) {
+	acts := &etable.Table{}
+	err := acts.OpenCSV(fname, etable.Tab)
+	if err != nil {
+		log.Println(err)
+		return
+	}
+	var olays []string
+	for ci, cl := range acts.Cols {
+		if cl.DataType() == etensor.FLOAT32 {
+			olays = append(olays, acts.ColNames[ci])
+		}
+	}
+	ss.RSA.LayCmpFmActs(&ss.RSA.XCmp, ss.CatLayActs, ss.RSACols, acts, olays)
+	ss.SaveLayCmp(&ss.RSA.XCmp, "xcmp")
+}
+
+// OpenExtRSA opens external RDM or activation data (.tsv, .csv or .npy) with a separate
+// label file giving the object for each item (e.g., PredNet, backprop models, neural data),
+// computes the standard RSA stats on it, and compares it against the current CatLayActs
+// layers -- see RSA Exts for results, which are also saved to ext log files.
+func (ss *Sim) OpenExtRSA(fname, lblfname gi.FileName) {
+	ex, err := ss.RSA.OpenExt("", string(fname), string(lblfname))
+	if err != nil {
+		log.Println(err)
+		return
+	}
+	fmt.Printf("%s: CatDist: %g  BasicDist: %g  ExptDist: %g  PermNCats: %d\n", ex.Name, ex.CatDist, ex.BasicDist, ex.ExptDist, ex.PermNCats)
+	ss.RSA.ExtStatsTable().SaveCSV(gi.FileName(ss.LogFileName("ext")), etable.Tab, etable.Headers)
+	if ss.CatLayActs.Rows == 0 {
+		return
+	}
+	ss.RSA.ExtCmpFmActs(ex, ss.CatLayActs, ss.RSACols)
+	ex.Cmp.SaveCSV(gi.FileName(ss.LogFileName("ext_"+ex.Name)), etable.Tab, etable.Headers)
+}
+
+// SaveLayCmp saves the RDMCor, LinCKA and RBFCKA layer comparison tables
+// to log files with given base type name
+func (ss *Sim) SaveLayCmp(lc *LayCmp, lognm string) {
+	if lc.RDMCor == nil {
+		return
+	}
+	nms := []string{"rdmcor", "lincka", "rbfcka"}
+	for i, sm := range []*simat.SimMat{lc.RDMCor, lc.LinCKA, lc.RBFCKA} {
+		fnm := ss.LogFileName(lognm + "_" + nms[i])
+		SimMatTable(sm).SaveCSV(gi.FileName(fnm), etable.Tab, etable.Headers)
 	}
-	return plt
-}
-
-// OpenCatActs Open a catact file with layer activity by category,
-// and then run the RSA analysis on it -- see RSA for results
-func (ss *Sim) OpenCatActs(fname gi.FileName) {
-	ss.CatLayActs.OpenCSV(fname, etable.Tab)
-	ss.RSA.StatsFmActs(ss.CatLayActs, ss.SuperLays)
 }
 
 // OpenSimMat Open a TEsim TE similarity matrix in standard object order
@@ -1845,6 +2764,203 @@ func (ss *Sim) OpenSimMat(fname gi.FileName) {
 	ss.RSA.OpenSimMat("TE", fname)
 }
 
+// EmbedReps computes the embeddings of CatLayActs for the Embed.Lays layers,
+// and saves the history of embeddings over epochs to log files
+func (ss *Sim) EmbedReps(epc int) {
+	for _, lnm := range ss.Embed.Lays {
+		if ss.CatLayActs.ColIdx(lnm) < 0 {
+			continue
+		}
+		ss.Embed.FmActs(ss.CatLayActs, lnm, epc)
+		fnm := ss.LogFileName(lnm + "_embed")
+		ss.Embed.Hists[lnm].SaveCSV(gi.FileName(fnm), etable.Tab, etable.Headers)
+	}
+	if ss.EmbedPlot != nil {
+		ss.EmbedPlot.GoUpdate()
+	}
+}
+
+// GeomReps computes the representational geometry stats of CatLayActs for all
+// recorded layers, and saves the history over epochs to the geom log file
+func (ss *Sim) GeomReps(epc int) {
+	ss.Geom.FmActs(ss.CatLayActs, ss.RSACols, epc)
+	fnm := ss.LogFileName("geom")
+	ss.Geom.Hist.SaveCSV(gi.FileName(fnm), etable.Tab, etable.Headers)
+}
+
+// CatTreeReps saves the category tree stats computed by the RSA (contrast distances
+// and nested permutation tests at each level) for each of the RSA TreeLays
+func (ss *Sim) CatTreeReps() {
+	for _, lnm := range ss.RSA.TreeLays {
+		dt, ok := ss.RSA.Trees[lnm]
+		if !ok {
+			continue
+		}
+		fnm := ss.LogFileName(lnm + "_cattree")
+		dt.SaveCSV(gi.FileName(fnm), etable.Tab, etable.Headers)
+	}
+}
+
+// DriftReps updates the Drift history with the current RSA similarity matricies,
+// and saves the drift stats, the category emergence epochs of each pair of objects,
+// and the animated heatmap of the object similarity matricies over epochs
+func (ss *Sim) DriftReps(epc int) {
+	ss.Drift.Update(&ss.RSA, epc)
+	fnm := ss.LogFileName("drift")
+	ss.Drift.Stats.SaveCSV(gi.FileName(fnm), etable.Tab, etable.Headers)
+	for _, lnm := range ss.Drift.Lays {
+		if len(ss.Drift.Hists[lnm]) == 0 {
+			continue
+		}
+		fnm = ss.LogFileName(lnm + "_emerge")
+		ss.Drift.EmergeTable(lnm).SaveCSV(gi.FileName(fnm), etable.Tab, etable.Headers)
+		fnm = strings.TrimSuffix(ss.LogFileName(lnm+"_drift"), ".tsv") + ".gif"
+		if err := ss.Drift.SaveGIF(lnm, fnm); err != nil {
+			log.Println(err)
+		}
+		if ss.Drift.PNGs {
+			fnm = strings.TrimSuffix(ss.LogFileName(fmt.Sprintf("%s_drift_%03d", lnm, epc)), ".tsv") + ".png"
+			if err := ss.Drift.SavePNG(lnm, fnm); err != nil {
+				log.Println(err)
+			}
+		}
+	}
+}
+
+// EmbedSimMat computes the embeddings of the RSA similarity matrix for given layer,
+// e.g., as loaded by OpenSimMat -- see Embed Embeds for results
+func (ss *Sim) EmbedSimMat(laynm string) {
+	sm, ok := ss.RSA.Sims[laynm]
+	if !ok || sm.Mat == nil {
+		log.Printf("EmbedSimMat: no sim mat for layer: %s\n", laynm)
+		return
+	}
+	ss.Embed.FmSimMat(sm, ss.RSA.Cats, laynm+"_sim", ss.TrainEnv.Epoch.Cur)
+}
+
+func (ss *Sim) ConfigEmbedPlot(plt *eplot.Plot2D, dt *etable.Table) *eplot.Plot2D {
+	plt.Params.Title = "What-Where-Integration 3DObj Embedding Plot"
+	plt.Params.XAxisCol = "TSNE_0"
+	plt.Params.LegendCol = "Cat"
+	plt.Params.Lines = false
+	plt.Params.Points = true
+	plt.SetTable(dt)
+	// order of params: on, fixMin, min, fixMax, max
+	plt.SetColParams("Epoch", eplot.Off, eplot.FixMin, 0, eplot.FloatMax, 0)
+	plt.SetColParams("Tick", eplot.Off, eplot.FixMin, 0, eplot.FloatMax, 0)
+	for _, mth := range EmbedMethods {
+		for d := 0; d < ss.Embed.NDims; d++ {
+			cn := fmt.Sprintf("%s_%d", mth, d)
+			plt.SetColParams(cn, cn == "TSNE_1", eplot.FloatMin, 0, eplot.FloatMax, 0)
+		}
+	}
+	return plt
+}
+
+//////////////////////////////////////////////
+//  TstCatLayActs
+
+// ConfigTstRSA configures the TstRSA analyses of the TstCatLayActs,
+// using the same analysis params as the RSA of the training CatLayActs.
+// The category tree stats are only computed in training.
+func (ss *Sim) ConfigTstRSA() {
+	tr := &ss.TstRSA
+	tr.Tick = ss.RSA.Tick
+	tr.TickMin = ss.RSA.TickMin
+	tr.TickMax = ss.RSA.TickMax
+	tr.RBFSigma = ss.RSA.RBFSigma
+	tr.Stream = ss.RSA.Stream
+	tr.Tree = ss.RSA.Tree
+	tr.Init(ss.RSACols)
+	tr.SetCats(ss.TestEnv.Objs)
+}
+
+// InitTstCatLayActs zeros the TstCatLayActs sums, at the start of TestAll
+func (ss *Sim) InitTstCatLayActs() {
+	dt := ss.TstCatLayActs
+	for _, coli := range dt.Cols {
+		if coli.DataType() != etensor.FLOAT32 {
+			continue
+		}
+		col := coli.(*etensor.Float32)
+		for i := range col.Values {
+			col.Values[i] = 0
+		}
+	}
+	if len(ss.TstCatN) != dt.Rows {
+		ss.TstCatN = make([]float32, dt.Rows)
+	}
+	for i := range ss.TstCatN {
+		ss.TstCatN[i] = 0
+	}
+}
+
+// RecTstCatLayActs adds the RSA layer activations of the current testing trial
+// to the TstCatLayActs row for its object and tick -- see AvgTstCatLayActs
+func (ss *Sim) RecTstCatLayActs() {
+	dt := ss.TstCatLayActs
+	if ss.LIPOnly || dt.Rows == 0 {
+		return
+	}
+	if len(ss.TstCatN) != dt.Rows { // not started by TestAll
+		ss.InitTstCatLayActs()
+	}
+	obj := ss.TestEnv.CurObj
+	tick := ss.TestEnv.Tick.Cur
+	rows := dt.RowsByString("Obj", obj, etable.Equals, etable.UseCase)
+	if len(rows) != ss.MaxTicks || tick >= ss.MaxTicks {
+		log.Printf("RecTstCatLayActs: error: object not found: %s\n", obj)
+		return
+	}
+	row := rows[0] + tick
+	for i := range ss.RSASpecs {
+		rl := &ss.RSASpecs[i]
+		vals := ss.RSALayVals(rl)
+		cv := dt.CellTensor(rl.Col(), row).(*etensor.Float32)
+		for j, v := range vals {
+			cv.Values[j] += v
+		}
+	}
+	ss.TstCatN[row]++
+}
+
+// AvgTstCatLayActs turns the TstCatLayActs sums into averages over the testing
+// trials of each object and tick, summing over all procs for MPI mode
+func (ss *Sim) AvgTstCatLayActs() {
+	dt := ss.TstCatLayActs
+	if ss.LIPOnly || dt.Rows == 0 {
+		return
+	}
+	if ss.Comm != nil {
+		ss.Comm.ReduceTable(ss.TstCatLayActsDest, dt, mpi.OpSum)
+		for ci, dcoli := range dt.Cols {
+			if dcoli.DataType() != etensor.FLOAT32 {
+				continue
+			}
+			copy(dcoli.(*etensor.Float32).Values, ss.TstCatLayActsDest.Cols[ci].(*etensor.Float32).Values)
+		}
+		ns := make([]float32, len(ss.TstCatN))
+		ss.Comm.AllReduceF32(mpi.OpSum, ns, ss.TstCatN)
+		copy(ss.TstCatN, ns)
+	}
+	for _, dcoli := range dt.Cols {
+		if dcoli.DataType() != etensor.FLOAT32 {
+			continue
+		}
+		dcol := dcoli.(*etensor.Float32)
+		nu := dcol.Len() / dt.Rows
+		for row, n := range ss.TstCatN {
+			if n == 0 {
+				continue
+			}
+			vals := dcol.Values[row*nu : (row+1)*nu]
+			for i := range vals {
+				vals[i] /= n
+			}
+		}
+	}
+}
+
 //////////////////////////////////////////////
 //  TstTrlLog
 
@@ -1861,9 +2977,21 @@ func (ss *Sim) LogTstTrl(dt *etable.Table) {
 	dt.SetCellFloat("Run", row, float64(ss.TrainEnv.Run.Cur))
 	dt.SetCellFloat("Epoch", row, float64(epc))
 	dt.SetCellFloat("Trial", row, float64(trl))
-	dt.SetCellString("Obj", row, ss.TestEnv.CurCat)
+	dt.SetCellFloat("Tick", row, float64(ss.TestEnv.Tick.Cur))
+	dt.SetCellString("Cat", row, ss.TestEnv.CurCat)
+	dt.SetCellString("Obj", row, ss.TestEnv.CurObj)
 	dt.SetCellString("TrialName", row, ss.TestEnv.String())
 
+	for li, lnm := range ss.PulvLays {
+		dt.SetCellFloat(lnm+"_CosDiff", row, ss.PulvCosDiff[li])
+		dt.SetCellFloat(lnm+"_TrlCosDiff", row, ss.PulvTrlCosDiff[li])
+		dt.SetCellFloat(lnm+"_AvgSSE", row, ss.PulvAvgSSE[li])
+	}
+	for li, lnm := range ss.HidLays {
+		ly := ss.Net.LayerByName(lnm).(leabra.LeabraLayer).AsLeabra()
+		dt.SetCellFloat(lnm+"_TrlCosDiff", row, ss.HidTrlCosDiff[li])
+		dt.SetCellFloat(lnm+"_ActMAvg", row, float64(ly.Pools[0].ActM.Avg))
+	}
 	for _, lnm := range ss.LayStatNms {
 		ly := ss.Net.LayerByName(lnm).(leabra.LeabraLayer).AsLeabra()
 		if ly.IsOff() {
@@ -1876,9 +3004,6 @@ func (ss *Sim) LogTstTrl(dt *etable.Table) {
 }
 
 func (ss *Sim) ConfigTstTrlLog(dt *etable.Table) {
-	// inp := ss.Net.LayerByName("V1").(leabra.LeabraLayer).AsLeabra()
-	// out := ss.Net.LayerByName("Output").(leabra.LeabraLayer).AsLeabra()
-
 	dt.SetMetaData("name", "TstTrlLog")
 	dt.SetMetaData("desc", "Record of testing per input pattern")
 	dt.SetMetaData("read-only", "true")
@@ -1888,9 +3013,20 @@ func (ss *Sim) ConfigTstTrlLog(dt *etable.Table) {
 		{"Run", etensor.INT64, nil, nil},
 		{"Epoch", etensor.INT64, nil, nil},
 		{"Trial", etensor.INT64, nil, nil},
+		{"Tick", etensor.INT64, nil, nil},
+		{"Cat", etensor.STRING, nil, nil},
 		{"Obj", etensor.STRING, nil, nil},
 		{"TrialName", etensor.STRING, nil, nil},
 	}
+	for _, lnm := range ss.PulvLays {
+		sch = append(sch, etable.Column{lnm + "_CosDiff", etensor.FLOAT64, nil, nil})
+		sch = append(sch, etable.Column{lnm + "_TrlCosDiff", etensor.FLOAT64, nil, nil})
+		sch = append(sch, etable.Column{lnm + "_AvgSSE", etensor.FLOAT64, nil, nil})
+	}
+	for _, lnm := range ss.HidLays {
+		sch = append(sch, etable.Column{lnm + "_TrlCosDiff", etensor.FLOAT64, nil, nil})
+		sch = append(sch, etable.Column{lnm + "_ActMAvg", etensor.FLOAT64, nil, nil})
+	}
 	for _, lnm := range ss.LayStatNms {
 		sch = append(sch, etable.Column{lnm + " ActM.Avg", etensor.FLOAT64, nil, nil})
 	}
@@ -1905,9 +3041,20 @@ func (ss *Sim) ConfigTstTrlPlot(plt *eplot.Plot2D, dt *etable.Table) *eplot.Plot
 	plt.SetColParams("Run", eplot.Off, eplot.FixMin, 0, eplot.FloatMax, 0)
 	plt.SetColParams("Epoch", eplot.Off, eplot.FixMin, 0, eplot.FloatMax, 0)
 	plt.SetColParams("Trial", eplot.Off, eplot.FixMin, 0, eplot.FloatMax, 0)
+	plt.SetColParams("Tick", eplot.Off, eplot.FixMin, 0, eplot.FloatMax, 0)
+	plt.SetColParams("Cat", eplot.Off, eplot.FixMin, 0, eplot.FloatMax, 0)
 	plt.SetColParams("Obj", eplot.Off, eplot.FixMin, 0, eplot.FloatMax, 0)
 	plt.SetColParams("TrialName", eplot.Off, eplot.FixMin, 0, eplot.FloatMax, 0)
 
+	for _, lnm := range ss.PulvLays {
+		plt.SetColParams(lnm+"_CosDiff", eplot.On, eplot.FixMin, 0, eplot.FixMax, 1)
+		plt.SetColParams(lnm+"_TrlCosDiff", eplot.Off, eplot.FixMin, 0, eplot.FixMax, 1)
+		plt.SetColParams(lnm+"_AvgSSE", eplot.Off, eplot.FixMin, 0, eplot.FixMax, 1)
+	}
+	for _, lnm := range ss.HidLays {
+		plt.SetColParams(lnm+"_TrlCosDiff", eplot.Off, eplot.FixMin, 0, eplot.FixMax, 1)
+		plt.SetColParams(lnm+"_ActMAvg", eplot.Off, eplot.FixMin, 0, eplot.FixMax, 0.5)
+	}
 	for _, lnm := range ss.LayStatNms {
 		plt.SetColParams(lnm+" ActM.Avg", eplot.Off, eplot.FixMin, 0, eplot.FixMax, 0.5)
 	}
@@ -1917,28 +3064,81 @@ func (ss *Sim) ConfigTstTrlPlot(plt *eplot.Plot2D, dt *etable.Table) *eplot.Plot
 //////////////////////////////////////////////
 //  TstEpcLog
 
+// LogTstEpc adds a row of test-set stats to the TstEpcLog at the end of TestAll:
+// the trial stats averaged over all the testing trials (TrlCosDiff over ticks >= 2,
+// as in training), and the TstRSA stats of the TstCatLayActs.
+// Under MPI it must be called on all procs.
 func (ss *Sim) LogTstEpc(dt *etable.Table) {
 	trl := ss.TstTrlLog
-	if ss.UseMPI {
-		empi.GatherTableRows(ss.TstTrlLogAll, ss.TstTrlLog, ss.Comm)
+	if ss.Comm != nil {
+		ss.Comm.GatherTableRows(ss.TstTrlLogAll, ss.TstTrlLog)
 		trl = ss.TstTrlLogAll
 	}
-	tix := etable.NewIdxView(trl)
-	// epc := ss.TrainEnv.Epoch.Prv // ?
+	ss.AvgTstCatLayActs()
 
-	spl := split.GroupBy(tix, []string{"Obj"})
-	_, err := split.AggTry(spl, "Err", agg.AggMean)
-	if err != nil {
-		log.Println(err)
+	row := dt.Rows
+	dt.SetNumRows(row + 1)
+
+	epc := ss.TrainEnv.Epoch.Prv // this is triggered by increment so use previous value
+	dt.SetCellFloat("Run", row, float64(ss.TrainEnv.Run.Cur))
+	dt.SetCellFloat("Epoch", row, float64(epc))
+	dt.SetCellFloat("NTrials", row, float64(trl.Rows))
+
+	tix := etable.NewIdxView(trl)
+	t2tix := etable.NewIdxView(trl)
+	t2tix.Filter(func(et *etable.Table, row int) bool {
+		tck := int(et.CellFloat("Tick", row))
+		return tck >= 2
+	})
+	for _, lnm := range ss.PulvLays {
+		dt.SetCellFloat(lnm+"_CosDiff", row, agg.Agg(tix, lnm+"_CosDiff", agg.AggMean)[0])
+		dt.SetCellFloat(lnm+"_TrlCosDiff", row, agg.Agg(t2tix, lnm+"_TrlCosDiff", agg.AggMean)[0])
+		dt.SetCellFloat(lnm+"_AvgSSE", row, agg.Agg(tix, lnm+"_AvgSSE", agg.AggMean)[0])
+	}
+	for _, lnm := range ss.HidLays {
+		dt.SetCellFloat(lnm+"_TrlCosDiff", row, agg.Agg(t2tix, lnm+"_TrlCosDiff", agg.AggMean)[0])
+		dt.SetCellFloat(lnm+"_ActMAvg", row, agg.Agg(tix, lnm+"_ActMAvg", agg.AggMean)[0])
 	}
-	objs := spl.AggsToTable(etable.AddAggName)
-	no := objs.Rows
-	dt.SetNumRows(no)
-	for i := 0; i < no; i++ {
-		dt.SetCellFloat("Obj", i, float64(i))
-		dt.SetCellFloat("PctErr", i, objs.Cols[1].FloatVal1D(i))
+
+	if !ss.LIPOnly && ss.TstCatLayActs.Rows > 0 && ss.Rank() == 0 {
+		ss.TstRSA.StatsFmActs(ss.TstCatLayActs, ss.RSACols)
+		if sm, ok := ss.TstRSA.Sims["TE"]; ok && sm.Mat != nil {
+			fnm := ss.LogFileName("tstTEsim")
+			fmt.Printf("Saving test TEsim to: %v\n", fnm)
+			etensor.SaveCSV(sm.Mat, gi.FileName(fnm), etable.Tab.Rune())
+		}
+		for li, lnm := range ss.RSACols {
+			dt.SetCellFloat(lnm+"_V1Sim", row, ss.TstRSA.V1Sims[li])
+			dt.SetCellFloat(lnm+"_CatDst", row, ss.TstRSA.CatDists[li])
+			dt.SetCellFloat(lnm+"_TickGen", row, ss.TstRSA.TickGenAvgs[lnm])
+		}
+		if teidx := ss.RSAColIdx("TE"); teidx >= 0 {
+			pr := 0.0
+			if ss.TstRSA.PermDists["TE"] > 0 {
+				pr = ss.TstRSA.CatDists[teidx] / ss.TstRSA.PermDists["TE"]
+			}
+			dt.SetCellFloat("TE_PermRatio", row, pr)
+			dt.SetCellFloat("TE_BasicDst", row, ss.TstRSA.BasicDists[teidx])
+			dt.SetCellFloat("TE_ExptDst", row, ss.TstRSA.ExptDists[teidx])
+		}
 	}
+
+	// note: essential to use Go version of update when called from another goroutine
 	ss.TstEpcPlot.GoUpdate()
+	if ss.TstEpcFile != nil {
+		if ss.TrainEnv.Run.Cur == ss.StartRun && row == 0 {
+			dt.WriteCSVHeaders(ss.TstEpcFile, etable.Tab)
+		}
+		dt.WriteCSVRow(ss.TstEpcFile, row, etable.Tab)
+	}
+	if ss.TstTrlFile != nil {
+		if ss.TrainEnv.Run.Cur == ss.StartRun && row == 0 {
+			trl.WriteCSVHeaders(ss.TstTrlFile, etable.Tab)
+		}
+		for ri := 0; ri < trl.Rows; ri++ {
+			trl.WriteCSVRow(ss.TstTrlFile, ri, etable.Tab)
+		}
+	}
 }
 
 func (ss *Sim) ConfigTstEpcLog(dt *etable.Table) {
@@ -1947,20 +3147,57 @@ func (ss *Sim) ConfigTstEpcLog(dt *etable.Table) {
 	dt.SetMetaData("read-only", "true")
 	dt.SetMetaData("precision", strconv.Itoa(LogPrec))
 
-	dt.SetFromSchema(etable.Schema{
-		{"Obj", etensor.INT64, nil, nil},
-		{"PctErr", etensor.FLOAT64, nil, nil},
-	}, 0)
+	sch := etable.Schema{
+		{"Run", etensor.INT64, nil, nil},
+		{"Epoch", etensor.INT64, nil, nil},
+		{"NTrials", etensor.INT64, nil, nil},
+	}
+	for _, lnm := range ss.PulvLays {
+		sch = append(sch, etable.Column{lnm + "_CosDiff", etensor.FLOAT64, nil, nil})
+		sch = append(sch, etable.Column{lnm + "_TrlCosDiff", etensor.FLOAT64, nil, nil})
+		sch = append(sch, etable.Column{lnm + "_AvgSSE", etensor.FLOAT64, nil, nil})
+	}
+	for _, lnm := range ss.HidLays {
+		sch = append(sch, etable.Column{lnm + "_TrlCosDiff", etensor.FLOAT64, nil, nil})
+		sch = append(sch, etable.Column{lnm + "_ActMAvg", etensor.FLOAT64, nil, nil})
+	}
+	for _, lnm := range ss.RSACols {
+		sch = append(sch, etable.Column{lnm + "_V1Sim", etensor.FLOAT64, nil, nil})
+		sch = append(sch, etable.Column{lnm + "_CatDst", etensor.FLOAT64, nil, nil})
+		sch = append(sch, etable.Column{lnm + "_TickGen", etensor.FLOAT64, nil, nil})
+	}
+	sch = append(sch, etable.Column{"TE_PermRatio", etensor.FLOAT64, nil, nil})
+	sch = append(sch, etable.Column{"TE_BasicDst", etensor.FLOAT64, nil, nil})
+	sch = append(sch, etable.Column{"TE_ExptDst", etensor.FLOAT64, nil, nil})
+	dt.SetFromSchema(sch, 0)
 }
 
 func (ss *Sim) ConfigTstEpcPlot(plt *eplot.Plot2D, dt *etable.Table) *eplot.Plot2D {
 	plt.Params.Title = "What-Where-Integration 3DObj Testing Epoch Plot"
-	plt.Params.XAxisCol = "Obj"
-	plt.Params.Type = eplot.Bar
+	plt.Params.XAxisCol = "Epoch"
 	plt.SetTable(dt)
 	// order of params: on, fixMin, min, fixMax, max
-	plt.SetColParams("Obj", eplot.Off, eplot.FixMin, 0, eplot.FloatMax, 0)
-	plt.SetColParams("PctErr", eplot.On, eplot.FixMin, 0, eplot.FixMax, 1)
+	plt.SetColParams("Run", eplot.Off, eplot.FixMin, 0, eplot.FloatMax, 0)
+	plt.SetColParams("Epoch", eplot.Off, eplot.FixMin, 0, eplot.FloatMax, 0)
+	plt.SetColParams("NTrials", eplot.Off, eplot.FixMin, 0, eplot.FloatMax, 0)
+
+	for _, lnm := range ss.PulvLays {
+		plt.SetColParams(lnm+"_CosDiff", eplot.On, eplot.FixMin, 0, eplot.FixMax, 1)
+		plt.SetColParams(lnm+"_TrlCosDiff", eplot.Off, eplot.FixMin, 0, eplot.FixMax, 1)
+		plt.SetColParams(lnm+"_AvgSSE", eplot.Off, eplot.FixMin, 0, eplot.FixMax, 1)
+	}
+	for _, lnm := range ss.HidLays {
+		plt.SetColParams(lnm+"_TrlCosDiff", eplot.Off, eplot.FixMin, 0, eplot.FixMax, 1)
+		plt.SetColParams(lnm+"_ActMAvg", eplot.Off, eplot.FixMin, 0, eplot.FixMax, 0.5)
+	}
+	for _, lnm := range ss.RSACols {
+		plt.SetColParams(lnm+"_V1Sim", eplot.Off, eplot.FixMin, 0, eplot.FixMax, 1)
+		plt.SetColParams(lnm+"_CatDst", eplot.Off, eplot.FloatMin, 0, eplot.FloatMax, 0)
+		plt.SetColParams(lnm+"_TickGen", eplot.Off, eplot.FloatMin, 0, eplot.FloatMax, 0)
+	}
+	plt.SetColParams("TE_PermRatio", eplot.Off, eplot.FloatMin, 0, eplot.FloatMax, 0)
+	plt.SetColParams("TE_BasicDst", eplot.Off, eplot.FloatMin, 0, eplot.FloatMax, 0)
+	plt.SetColParams("TE_ExptDst", eplot.Off, eplot.FloatMin, 0, eplot.FloatMax, 0)
 	return plt
 }
 
@@ -1988,6 +3225,7 @@ func (ss *Sim) LogRun(dt *etable.Table) {
 	// todo: fix or will crash..
 	dt.SetCellFloat("Run", row, float64(run))
 	dt.SetCellString("Params", row, params)
+	dt.SetCellFloat("ConvEpc", row, float64(ss.Conv.LastEpc()))
 
 	// runix := etable.NewIdxView(dt)
 	// spl := split.GroupBy(runix, []string{"Params"})
@@ -2014,6 +3252,7 @@ func (ss *Sim) ConfigRunLog(dt *etable.Table) {
 	dt.SetFromSchema(etable.Schema{
 		{"Run", etensor.INT64, nil, nil},
 		{"Params", etensor.STRING, nil, nil},
+		{"ConvEpc", etensor.INT64, nil, nil},
 	}, 0)
 }
 
@@ -2098,6 +3337,11 @@ func (ss *Sim) ConfigGui() *gi.Window {
 	plt = tv.AddNewTab(eplot.KiT_Plot2D, "RunPlot").(*eplot.Plot2D)
 	ss.RunPlot = ss.ConfigRunPlot(plt, ss.RunLog)
 
+	if len(ss.Embed.Lays) > 0 {
+		plt = tv.AddNewTab(eplot.KiT_Plot2D, "EmbedPlot").(*eplot.Plot2D)
+		ss.EmbedPlot = ss.ConfigEmbedPlot(plt, ss.Embed.Table(ss.Embed.Lays[0]))
+	}
+
 	ss.ActRFGrids = make(map[string]*etview.TensorGrid)
 	for _, nm := range ss.ActRFNms {
 		tg := tv.AddNewTab(etview.KiT_TensorGrid, nm).(*etview.TensorGrid)
@@ -2220,6 +3464,16 @@ func (ss *Sim) ConfigGui() *gi.Window {
 			giv.CallMethod(ss, "OpenCatActs", vp)
 		})
 
+	tbar.AddAction(gi.ActOpts{Label: "Cmp CatActs", Icon: "file-open", Tooltip: "Open a catact file from another run or model, and compare its layers against the current CatLayActs layers -- see RSA XCmp for results"}, win.This(),
+		func(recv, send ki.Ki, sig int64, data interface{}) {
+			giv.CallMethod(ss, "CmpCatActs", vp)
+		})
+
+	tbar.AddAction(gi.ActOpts{Label: "Open Ext RSA", Icon: "file-open", Tooltip: "Open external RDM or activation data (.tsv, .csv, .npy) with a label file giving the object for each item, and compare against the current layers -- see RSA Exts for results"}, win.This(),
+		func(recv, send ki.Ki, sig int64, data interface{}) {
+			giv.CallMethod(ss, "OpenExtRSA", vp)
+		})
+
 	tbar.AddSeparator("misc")
 
 	tbar.AddAction(gi.ActOpts{Label: "New Seed", Icon: "new", Tooltip: "Generate a new initial random seed to get different results.  By default, Init re-establishes the same initial seed every time."}, win.This(),
@@ -2327,17 +3581,59 @@ var SimProps = ki.Props{
 				}},
 			},
 		}},
+		{"EmbedSimMat", ki.Props{
+			"desc": "Compute low-dimensional embeddings of the RSA similarity matrix for given layer (e.g., TE as loaded by OpenSimMat) -- see Embed for results",
+			"icon": "file-sheet",
+			"Args": ki.PropSlice{
+				{"Layer", ki.Props{}},
+			},
+		}},
+		{"CmpCatActs", ki.Props{
+			"desc": "Open a catact file from another run or model, and compare its layers against the current CatLayActs layers -- see RSA XCmp for results",
+			"icon": "file-open",
+			"Args": ki.PropSlice{
+				{"File Name", ki.Props{
+					"ext": ".tsv",
+				}},
+			},
+		}},
+		{"OpenExtRSA", ki.Props{
+			"desc": "Open external RDM or activation data (.tsv, .csv, .npy) with a label file giving the object for each item, and compare against the current layers -- see RSA Exts for results",
+			"icon": "file-open",
+			"Args": ki.PropSlice{
+				{"Data File", ki.Props{
+					"ext": ".tsv,.csv,.npy",
+				}},
+				{"Label File", ki.Props{
+					"ext": ".tsv,.csv",
+				}},
+			},
+		}},
 	},
 }
 
 func (ss *Sim) CmdArgs() {
+	cmd, args, err := ParseSubCmd(os.Args[1:], SimSubCmds)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	flag.Usage = func() { SubCmdUsage(SimSubCmds) }
 	ss.NoGui = true
 	var nogui bool
 	var saveEpcLog bool
 	var saveTrlLog bool
 	var saveRunLog bool
+	var saveTstEpcLog bool
+	var saveTstTrlLog bool
 	var note string
+	var rsalays string
+	var xparams string
+	var wts, acts, simat, recon, wtrf, actrfs, npz string
+	var resume string
+	var savenetspec string
 	flag.StringVar(&ss.ParamSet, "params", "", "ParamSet name to use -- must be valid name as listed in compiled-in params or loaded params")
+	flag.StringVar(&xparams, "xparams", "", "JSON file of additional param sets to apply in order after Base and ParamSet, e.g., as written by the sweep command")
 	flag.StringVar(&ss.Tag, "tag", "", "extra tag to add to file names saved from this run")
 	flag.StringVar(&note, "note", "", "user note -- describe the run params etc")
 	flag.IntVar(&ss.StartRun, "run", 0, "starting run number -- determines the random seed -- runs counts from there -- can do all runs in parallel by launching separate jobs with each run, runs = 1")
@@ -2347,88 +3643,1167 @@ func (ss *Sim) CmdArgs() {
 	flag.BoolVar(&saveEpcLog, "epclog", true, "if true, save train epoch log to file")
 	flag.BoolVar(&saveTrlLog, "trllog", false, "if true, save train trial log to file")
 	flag.BoolVar(&saveRunLog, "runlog", true, "if true, save run epoch log to file")
+	flag.IntVar(&ss.TestInterval, "testint", 0, "if > 0, run the test items without learning every this many epochs, recording the test-set stats in the test epoch log")
+	flag.BoolVar(&ss.ActRFLog, "actrflog", false, "if true, save the ActRFs after each test with -testint")
+	flag.BoolVar(&saveTstEpcLog, "tstepclog", true, "if true, save test epoch log to file, when testing with -testint")
+	flag.BoolVar(&saveTstTrlLog, "tsttrllog", false, "if true, save test trial log to file, when testing with -testint")
 	flag.BoolVar(&ss.SaveProcLog, "proclog", false, "if true, save log files separately for each processor (for debugging)")
 	flag.BoolVar(&nogui, "nogui", true, "if not passing any other args and want to run nogui, use nogui")
 	flag.BoolVar(&ss.UseMPI, "mpi", false, "if set, use MPI for distributed computation")
-	flag.Parse()
-
-	if ss.UseMPI {
-		ss.MPIInit()
+	flag.IntVar(&ss.Workers, "workers", 0, "if > 1, train data-parallel on this many in-process workers, without MPI, each training a replica of the network on its own slice of the trials, as with -mpi on this many procs")
+	flag.BoolVar(&ss.RepRDMs, "reprdms", false, "if true, save trial-level RDMs of the TrnTrlRepLog reps every RSA.Interval epochs -- see RepRDMs")
+	flag.IntVar(&ss.CkptInterval, "ckpt", 0, "if > 0, save a checkpoint of the full training state every this many epochs -- see CkptInterval")
+	flag.StringVar(&ss.ConvFile, "conv", "", "JSON file with the ConvMon convergence criteria on epoch log columns, and what to do when converged: Stop, SaveWts, Acts -- see Conv")
+	flag.StringVar(&ss.NetSpecFile, "netspec", "", "JSON file with the NetSpec of the network architecture to build, instead of the default -- see NetSpec")
+	flag.StringVar(&savenetspec, "savenetspec", "", "save the NetSpec of the network to this JSON file, e.g., as a starting point for a variant to use with -netspec")
+	flag.StringVar(&ss.SchedFile, "sched", "", "JSON file with the EpochSched of actions triggered at given training epochs, instead of the compiled-in Scheds for the ParamSet -- see SchedFile")
+	flag.StringVar(&resume, "resume", "", "checkpoint directory to resume an interrupted run from, as saved with -ckpt -- must use the same params and number of procs")
+	flag.StringVar(&ss.StatusAddr, "status", "", "if set, serve the status of the training run as JSON on this localhost:port address, e.g., localhost:8080, with /status, /epochs, /simat.png, and POST /stop and /savewts -- see StatusServer")
+	flag.StringVar(&ss.OutDir, "out", "", "output directory for all the files saved: logs, weights, analyses -- current directory if empty")
+	flag.StringVar(&ss.ImagesDir, "images", ss.ImagesDir, "directory of the rendered images dataset, with train and test subdirectories")
+	flag.IntVar(&ss.MaxTstTrls, "trials", 0, "number of test items for test, export-acts, reconstruct and lesion -- 500 if 0")
+	flag.StringVar(&wts, "weights", "", "trained weights file to open for test, export-acts, reconstruct, lesion and wtanal, e.g., as saved with -wts")
+	flag.StringVar(&acts, "acts", "", "catact log file with the CatLayActs saved by a training run, for rsa")
+	flag.StringVar(&simat, "simat", "", "TE similarity matrix file to analyze for rsa, e.g., a TEsim log file")
+	flag.StringVar(&ss.LesionFile, "lesions", "", "JSON file with the list of Lesions to test for lesion -- see CmdLesion")
+	flag.StringVar(&recon, "recon", "", "comma-separated list of lay:vis[:row] specs of layers to reconstruct V1 images from, for reconstruct -- see ReconLays")
+	flag.StringVar(&wtrf, "wtrf", "", "comma-separated list of lay:...:vis paths of layers to compute weight-based receptive fields in V1 for, for wtanal -- see WtAnal.RFs")
+	flag.IntVar(&ss.WtAnal.RFUnits, "wtrfunits", ss.WtAnal.RFUnits, "number of units in each pool to save the weight-based receptive fields of, for wtanal")
+	flag.StringVar(&actrfs, "actrfs", "", "comma-separated list of lay:src specs of the activation-based receptive fields to compute during testing, where src is one of: "+strings.Join(ActRFSrcs, ", ")+" -- see ActRFNms")
+	flag.StringVar(&npz, "npz", "", "comma-separated list of layer[:var[:ctr]] specs of layer variables to export to .npz with -npzint or -npzacts -- see NpzExport.Lays")
+	flag.IntVar(&ss.NpzExport.Interval, "npzint", 0, "if > 0, export the layer variables and metadata of the training trials every this many epochs, to an acts_<epoch> .npz archive")
+	flag.BoolVar(&ss.NpzExport.ExportActs, "npzacts", false, "if true, export-acts saves the test trials in a tstacts .npz archive of the -npz layer variables, instead of a tsv log")
+	flag.StringVar(&rsalays, "rsalays", "", "comma-separated list of layer[:var[:ctr]] specs of layers and variables to record in CatLayActs and analyze with RSA -- see RSALays")
+	flag.CommandLine.Parse(args)
+	if rsalays != "" {
+		ss.RSALays = strings.Split(rsalays, ",")
+	}
+	if recon != "" {
+		ss.ReconLays = strings.Split(recon, ",")
+	}
+	if wtrf != "" {
+		ss.WtAnal.RFs = strings.Split(wtrf, ",")
+	}
+	if actrfs != "" {
+		ss.ActRFNms = strings.Split(actrfs, ",")
+	}
+	if npz != "" {
+		ss.NpzExport.Lays = strings.Split(npz, ",")
+	}
+	if ss.OutDir != "" {
+		if err := os.MkdirAll(ss.OutDir, 0755); err != nil {
+			log.Println(err)
+			return
+		}
+	}
+	if xparams != "" {
+		if err := ss.OpenXParams(xparams); err != nil {
+			log.Println(err)
+			return
+		}
+	}
+
+	if ss.UseMPI {
+		ss.MPIInit()
+	}
+	var wks []*Sim
+	if ss.Workers > 1 {
+		if ss.UseMPI || cmd != "train" {
+			log.Println("-workers is only for training, without -mpi")
+			ss.MPIFinalize()
+			return
+		}
+		wks = ss.NewWorkers()
+	}
+
+	// key for Config and Init to be after MPIInit
+	ss.Config()
+	if err := ss.CheckAllocs(cmd != "train" || ss.TestInterval > 0); err != nil {
+		log.Println(err)
+		ss.MPIFinalize()
+		os.Exit(1)
+	}
+	if ss.SchedFile != "" && ss.Sched.Name == "" { // error logged by ConfigSched -- OpenJSON sets the Name
+		ss.MPIFinalize()
+		os.Exit(1)
+	}
+	if ss.ConvFile != "" && !ss.Conv.On { // error logged by ConfigConv
+		ss.MPIFinalize()
+		os.Exit(1)
+	}
+	ss.Init()
+
+	if savenetspec != "" && ss.Rank() == 0 {
+		if err := ss.NetSpec.SaveJSON(savenetspec); err != nil {
+			log.Println(err)
+		}
 	}
-
-	// key for Config and Init to be after MPIInit
-	ss.Config()
-	ss.Init()
-
 	if note != "" {
-		mpi.Printf("note: %s\n", note)
+		ss.Printf("note: %s\n", note)
 	}
 	if ss.ParamSet != "" {
-		mpi.Printf("Using ParamSet: %s\n", ss.ParamSet)
+		ss.Printf("Using ParamSet: %s\n", ss.ParamSet)
+	}
+	if len(ss.XParamSets) > 0 {
+		ss.Printf("Using XParamSets: %v\n", ss.XParamSets)
+	}
+	ss.InitManifest(cmd, note)
+	if cmd != "train" {
+		err := ss.RunSubCmd(cmd, wts, acts, simat)
+		ss.SaveManifest()
+		ss.MPIFinalize()
+		if err != nil {
+			log.Println(err)
+			os.Exit(1)
+		}
+		return
 	}
 
-	if saveEpcLog && (ss.SaveProcLog || mpi.WorldRank() == 0) {
+	if saveEpcLog && (ss.SaveProcLog || ss.Rank() == 0) {
 		var err error
 		fnm := ss.LogFileName("epc")
-		ss.TrnEpcFile, err = os.Create(fnm)
+		ss.TrnEpcFile, err = CreateLogFile(fnm, resume != "")
 		if err != nil {
 			log.Println(err)
 			ss.TrnEpcFile = nil
 		} else {
-			mpi.Printf("Saving epoch log to: %v\n", fnm)
+			ss.Printf("Saving epoch log to: %v\n", fnm)
 			defer ss.TrnEpcFile.Close()
 		}
 	}
-	if saveTrlLog && (ss.SaveProcLog || mpi.WorldRank() == 0) {
+	if saveTrlLog && (ss.SaveProcLog || ss.Rank() == 0) {
 		var err error
 		fnm := ss.LogFileName("trl")
-		ss.TrnTrlFile, err = os.Create(fnm)
+		ss.TrnTrlFile, err = CreateLogFile(fnm, resume != "")
 		if err != nil {
 			log.Println(err)
 			ss.TrnTrlFile = nil
 		} else {
-			mpi.Printf("Saving trial log to: %v\n", fnm)
+			ss.Printf("Saving trial log to: %v\n", fnm)
 			defer ss.TrnTrlFile.Close()
 		}
 	}
-	if saveRunLog && (ss.SaveProcLog || mpi.WorldRank() == 0) {
+	if saveRunLog && (ss.SaveProcLog || ss.Rank() == 0) {
 		var err error
 		fnm := ss.LogFileName("run")
-		ss.RunFile, err = os.Create(fnm)
+		ss.RunFile, err = CreateLogFile(fnm, resume != "")
 		if err != nil {
 			log.Println(err)
 			ss.RunFile = nil
 		} else {
-			mpi.Printf("Saving run log to: %v\n", fnm)
+			ss.Printf("Saving run log to: %v\n", fnm)
 			defer ss.RunFile.Close()
 		}
 	}
+	if ss.TestInterval > 0 {
+		ss.Printf("Testing every %d epochs\n", ss.TestInterval)
+	}
+	if ss.TestInterval > 0 && saveTstEpcLog && ss.Rank() == 0 {
+		var err error
+		fnm := ss.LogFileName("tstepc")
+		ss.TstEpcFile, err = CreateLogFile(fnm, resume != "")
+		if err != nil {
+			log.Println(err)
+			ss.TstEpcFile = nil
+		} else {
+			ss.Printf("Saving test epoch log to: %v\n", fnm)
+			defer ss.TstEpcFile.Close()
+		}
+	}
+	if ss.TestInterval > 0 && saveTstTrlLog && ss.Rank() == 0 {
+		var err error
+		fnm := ss.LogFileName("tsttrl")
+		ss.TstTrlFile, err = CreateLogFile(fnm, resume != "")
+		if err != nil {
+			log.Println(err)
+			ss.TstTrlFile = nil
+		} else {
+			ss.Printf("Saving test trial log to: %v\n", fnm)
+			defer ss.TstTrlFile.Close()
+		}
+	}
 	if ss.SaveWts {
-		if mpi.WorldRank() != 0 {
+		if ss.Rank() != 0 {
 			ss.SaveWts = false
 		}
-		mpi.Printf("Saving final weights per run\n")
+		ss.Printf("Saving final weights per run\n")
 	}
-	mpi.Printf("Running %d Runs starting at %d\n", ss.MaxRuns, ss.StartRun)
+	ss.Printf("Running %d Runs starting at %d\n", ss.MaxRuns, ss.StartRun)
 	ss.TrainEnv.Run.Set(ss.StartRun)
 	ss.TrainEnv.Run.Max = ss.StartRun + ss.MaxRuns
-	ss.NewRun()
-	ss.Train()
+	if resume != "" {
+		if err := ss.OpenCkpt(resume); err != nil {
+			log.Println(err)
+			ss.MPIFinalize()
+			os.Exit(1)
+		}
+		if ss.Manifest != nil {
+			run := ss.TrainEnv.Run.Cur
+			ss.Manifest.StartRun(run, ss.RndSeeds[run])
+		}
+		ss.ResumeLogFile(ss.TrnEpcFile, ss.TrnEpcLog, true)
+		ss.ResumeLogFile(ss.TstEpcFile, ss.TstEpcLog, true)
+		ss.ResumeLogFile(ss.TrnTrlFile, ss.TrnTrlLog, false)
+		ss.ResumeLogFile(ss.TstTrlFile, ss.TstTrlLog, false)
+		if ss.RunFile != nil { // LogRun continues the run log of the earlier runs
+			hdr, err := TruncLogRuns(ss.RunFile.Name(), ss.TrainEnv.Run.Cur, 0)
+			if err != nil {
+				log.Println(err)
+			}
+			if hdr {
+				if err := ss.RunLog.OpenCSV(gi.FileName(ss.RunFile.Name()), etable.Tab); err != nil {
+					log.Println(err)
+				}
+			}
+		}
+	} else {
+		ss.NewRun()
+	}
+	for _, ws := range wks {
+		if err := ws.InitWorker(resume); err != nil {
+			log.Println(err)
+			ss.MPIFinalize()
+			os.Exit(1)
+		}
+	}
+	ss.StartStatus()
+	ss.TrainAll(wks)
+	if ss.Status != nil {
+		ss.Status.Update(func(st *Status) { st.Running = false })
+		ss.Status.Close()
+	}
+	ss.SaveManifest()
 	ss.MPIFinalize()
 }
 
+//////////////////////////////////////////////
+//  Manifest
+
+// InitManifest initializes the Manifest of the runs of given subcommand, with
+// the user note and the params applied at Config, and saves it (rank 0 only)
+func (ss *Sim) InitManifest(cmd, note string) {
+	if ss.Rank() != 0 {
+		return
+	}
+	mf := &Manifest{Name: ss.Net.Nm + "_" + ss.RunName(), Cmd: cmd, Args: os.Args, Note: note, ParamSet: ss.ParamSet, XParamSets: ss.XParamSets, Tag: ss.Tag}
+	mf.InitBuild(ss.NProcs())
+	mf.Params = append([]ManifestParam{}, ss.ParamsRec.Params...)
+	mf.AddData(ss.TrainEnv.Path, "data.tsv", "objs.json")
+	mf.AddData(ss.TestEnv.Path, "data.tsv", "objs.json")
+	ss.Manifest = mf
+	ss.SaveManifest()
+}
+
+// SaveManifest saves the Manifest, if set, to a manifest .json file with the logs
+func (ss *Sim) SaveManifest() {
+	if ss.Manifest == nil {
+		return
+	}
+	fnm := strings.TrimSuffix(ss.LogFileName("manifest"), ".tsv") + ".json"
+	if err := ss.Manifest.Save(fnm); err != nil {
+		log.Println(err)
+	}
+}
+
+//////////////////////////////////////////////
+//  Status server
+
+// StartStatus starts the Status server on StatusAddr, on rank 0
+func (ss *Sim) StartStatus() {
+	if ss.StatusAddr == "" || ss.Rank() != 0 {
+		return
+	}
+	ss.Status = &StatusServer{}
+	if err := ss.Status.Start(ss.StatusAddr); err != nil {
+		log.Println(err)
+		ss.Status = nil
+		return
+	}
+	ss.Printf("Serving status at: http://%s/status  controls token (%s header): %s\n", ss.Status.Addr, StatusTokenHeader, ss.Status.Token)
+}
+
+// UpdtStatus updates the Status with the current counters at the end of a
+// training trial, and applies the stop and save weights requests made through it --
+// under MPI or -workers, the requests are shared from rank 0 so all procs act on them
+// together, so it must be called on all procs (all have StatusAddr set).
+func (ss *Sim) UpdtStatus() {
+	ctl := make([]float32, 2)
+	if ss.Status != nil {
+		ss.Status.Update(func(st *Status) {
+			st.Name = ss.Net.Nm + "_" + ss.RunName()
+			st.Counters = ss.Counters(true)
+			st.Run = ss.TrainEnv.Run.Cur
+			st.Epoch = ss.TrainEnv.Epoch.Cur
+			st.Trial = ss.TrainEnv.Trial.Cur
+			st.EpcPerTrlMSec = ss.EpcPerTrlMSec
+			st.Running = true
+		})
+		stop, save := ss.Status.Ctrls()
+		if stop {
+			ctl[0] = 1
+		}
+		if save {
+			ctl[1] = 1
+		}
+	}
+	if ss.Comm != nil {
+		sctl := make([]float32, 2)
+		ss.Comm.AllReduceF32(mpi.OpMax, sctl, ctl)
+		ctl = sctl
+	}
+	if ctl[1] > 0 && ss.Rank() == 0 {
+		fnm := ss.WeightsFileName()
+		ss.Printf("Saving weights (status request) to: %s\n", fnm)
+		ss.Net.SaveWtsJSON(gi.FileName(fnm))
+		ss.Status.Update(func(st *Status) { st.SavedWts = fnm })
+	}
+	if ctl[0] > 0 {
+		ss.Printf("Stopping (status request) at: %s\n", ss.Counters(true))
+		ss.Stop()
+	}
+}
+
+// StatusSimMat sets the similarity matrix image of the Status server from given SimMat
+func (ss *Sim) StatusSimMat(sm *simat.SimMat) {
+	smat, ok := sm.Mat.(*etensor.Float64)
+	if !ok || smat.NumDims() != 2 {
+		return
+	}
+	min, max := math.Inf(1), math.Inf(-1)
+	for _, v := range smat.Values {
+		if math.IsNaN(v) {
+			continue
+		}
+		min = math.Min(min, v)
+		max = math.Max(max, v)
+	}
+	if err := ss.Status.SetSimMat(HeatmapImage(smat.Values, smat.Dim(0), 4, min, max)); err != nil {
+		log.Println(err)
+	}
+}
+
+////////////////////////////////////////////////////////////////////
+//  Checkpoints
+
+// CkptDirName returns the name of the checkpoint directory for given epoch of the current run
+func (ss *Sim) CkptDirName(epc int) string {
+	return filepath.Join(ss.OutDir, ss.Net.Nm+"_"+ss.RunName()+"_ckpt_"+ss.RunEpochName(ss.TrainEnv.Run.Cur, epc))
+}
+
+// SaveCkpt saves a checkpoint of the full training state to given directory, at the
+// start of the current epoch (before its first trial is run) -- see ckpt.go for the files.
+// Under MPI it must be called on all procs, which each save their own state.
+func (ss *Sim) SaveCkpt(dir string) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		log.Println(err)
+		return
+	}
+	rank := ss.Rank()
+	run := ss.TrainEnv.Run.Cur
+	epc := ss.TrainEnv.Epoch.Cur
+	if ss.Comm != nil { // pending weight changes are summed over procs at the start of the next trial
+		ss.CollectDWts(&ss.Net.Network)
+		ndw := len(ss.AllDWts)
+		if len(ss.SumDWts) != ndw {
+			ss.SumDWts = make([]float32, ndw)
+		}
+		if err := ss.Comm.AllReduceF32(mpi.OpSum, ss.SumDWts, ss.AllDWts); err != nil {
+			log.Println("SaveCkpt:", err)
+		}
+	}
+	if rank == 0 {
+		ck := &Ckpt{Run: run, Epoch: epc, NProcs: ss.NProcs(), Seed: ss.RndSeeds[run], EpcSeed: ss.EpcSeed, LrateMult: ss.LrateMult, ParamSet: ss.ParamSet, Tag: ss.Tag, Saved: time.Now().Format(time.RFC3339)}
+		ck.Time, _ = json.Marshal(&ss.Time)
+		ck.Conv, _ = json.Marshal(&ss.Conv)
+		if err := SaveCkptJSON(ck, filepath.Join(dir, CkptFile)); err != nil {
+			log.Println(err)
+		}
+		if err := SaveNetState(filepath.Join(dir, "syns.gob"), ss.Net, true); err != nil {
+			log.Println(err)
+		}
+		if ss.Comm != nil {
+			if err := SaveDWts(filepath.Join(dir, "dwts.bin"), ss.SumDWts); err != nil {
+				log.Println(err)
+			}
+		}
+	}
+	if err := SaveNetState(CkptRankFile(dir, "state", rank, ".gob"), ss.Net, false); err != nil {
+		log.Println(err)
+	}
+	ce := ss.TrainEnv.CkptState()
+	if err := SaveCkptJSON(&ce, CkptRankFile(dir, "env", rank, ".json")); err != nil {
+		log.Println(err)
+	}
+	if !ss.LIPOnly {
+		if err := SaveTableExact(ss.CatLayActs, CkptRankFile(dir, "catact", rank, ".tsv")); err != nil {
+			log.Println(err)
+		}
+	}
+	if err := SaveTableExact(ss.TrnEpcLog, CkptRankFile(dir, "epc", rank, ".tsv")); err != nil {
+		log.Println(err)
+	}
+	if err := SaveTableExact(ss.TstEpcLog, CkptRankFile(dir, "tstepc", rank, ".tsv")); err != nil {
+		log.Println(err)
+	}
+	ss.Printf("Saved checkpoint at epoch %d to: %s\n", epc, dir)
+}
+
+// OpenCkpt restores the full training state from a checkpoint saved by SaveCkpt in given
+// directory, so that Train continues exactly as the checkpointed run would have.
+// Must be called after Config, with the same params and number of MPI procs.
+func (ss *Sim) OpenCkpt(dir string) error {
+	ck := &Ckpt{}
+	if err := OpenCkptJSON(ck, filepath.Join(dir, CkptFile)); err != nil {
+		return err
+	}
+	if ck.NProcs != ss.NProcs() {
+		return fmt.Errorf("OpenCkpt: %s was saved with %d procs but running with %d", dir, ck.NProcs, ss.NProcs())
+	}
+	rank := ss.Rank()
+	ss.TrainEnv.Run.Set(ck.Run)
+	if ss.TrainEnv.Run.Max <= ck.Run {
+		ss.TrainEnv.Run.Max = ck.Run + 1
+	}
+	ss.NewRun()
+	if err := OpenNetState(filepath.Join(dir, "syns.gob"), ss.Net, true); err != nil {
+		return fmt.Errorf("OpenCkpt: syns: %v", err)
+	}
+	if err := OpenNetState(CkptRankFile(dir, "state", rank, ".gob"), ss.Net, false); err != nil {
+		return fmt.Errorf("OpenCkpt: state: %v", err)
+	}
+	if ss.Comm != nil { // rank 0 has the summed pending weight changes, others none, so the next sum is the same
+		ss.CollectDWts(&ss.Net.Network)
+		dwts := make([]float32, len(ss.AllDWts))
+		if rank == 0 {
+			sdw, err := OpenDWts(filepath.Join(dir, "dwts.bin"))
+			if err != nil {
+				return err
+			}
+			if len(sdw) != len(dwts) {
+				return fmt.Errorf("OpenCkpt: dwts has %d values but network has %d", len(sdw), len(dwts))
+			}
+			dwts = sdw
+		}
+		ss.Net.SetDWts(dwts)
+	}
+	if err := json.Unmarshal(ck.Time, &ss.Time); err != nil {
+		return err
+	}
+	if len(ck.Conv) > 0 {
+		if err := json.Unmarshal(ck.Conv, &ss.Conv); err != nil {
+			return err
+		}
+	}
+	ss.ReplaySched(ck.Epoch) // params are not in the state, including the LrateMult
+	ce := &CkptEnv{}
+	if err := OpenCkptJSON(ce, CkptRankFile(dir, "env", rank, ".json")); err != nil {
+		return err
+	}
+	if err := ss.TrainEnv.SetCkptState(ce); err != nil {
+		return err
+	}
+	if !ss.LIPOnly {
+		if err := ss.CatLayActs.OpenCSV(gi.FileName(CkptRankFile(dir, "catact", rank, ".tsv")), etable.Tab); err != nil {
+			return err
+		}
+	}
+	if err := ss.TrnEpcLog.OpenCSV(gi.FileName(CkptRankFile(dir, "epc", rank, ".tsv")), etable.Tab); err != nil {
+		return err
+	}
+	tfnm := CkptRankFile(dir, "tstepc", rank, ".tsv")
+	if _, err := os.Stat(tfnm); err == nil { // not in checkpoints saved before periodic testing
+		if err := ss.TstEpcLog.OpenCSV(gi.FileName(tfnm), etable.Tab); err != nil {
+			return err
+		}
+	}
+	ss.EpcSeed = ck.EpcSeed
+	rand.Seed(ck.EpcSeed)
+	ss.CkptPending = true
+	ss.Printf("Resuming run %d at epoch %d from checkpoint: %s\n", ck.Run, ck.Epoch, dir)
+	return nil
+}
+
+// ResumeLogFile truncates given log file (if open) when resuming the current run from
+// a checkpoint (see TruncLogRuns): if restored, to the earlier runs, followed by the
+// rows of dt restored from the checkpoint, otherwise to the rows before the checkpoint
+// epoch, which are not in dt.  The headers of dt are written if they were removed.
+func (ss *Sim) ResumeLogFile(fp *os.File, dt *etable.Table, restored bool) {
+	if fp == nil {
+		return
+	}
+	epc := ss.TrainEnv.Epoch.Cur
+	if restored {
+		epc = 0
+	}
+	hdr, err := TruncLogRuns(fp.Name(), ss.TrainEnv.Run.Cur, epc)
+	if err != nil {
+		log.Println(err)
+	}
+	if !restored {
+		if !hdr {
+			dt.WriteCSVHeaders(fp, etable.Tab)
+		}
+		return
+	}
+	if !hdr && dt.Rows > 0 { // otherwise written with the first row
+		dt.WriteCSVHeaders(fp, etable.Tab)
+	}
+	for row := 0; row < dt.Rows; row++ {
+		dt.WriteCSVRow(fp, row, etable.Tab)
+	}
+}
+
+////////////////////////////////////////////////////////////////////
+//  Subcommands
+
+// SimSubCmds are the subcommands supported by this sim -- see SubCmds
+var SimSubCmds = []string{"train", "test", "rsa", "export-acts", "reconstruct", "lesion", "wtanal"}
+
+// RunSubCmd runs given subcommand other than train, after Config and Init.
+// Under MPI, test splits the items across procs, rsa runs on rank 0 only,
+// and the others are not supported.
+func (ss *Sim) RunSubCmd(cmd, wts, acts, simat string) error {
+	if cmd == "rsa" {
+		if ss.Rank() != 0 {
+			return nil
+		}
+		return ss.CmdRSA(acts, simat)
+	}
+	if ss.UseMPI && cmd != "test" {
+		return fmt.Errorf("%s runs on a single proc -- run it without -mpi", cmd)
+	}
+	if wts == "" {
+		return fmt.Errorf("%s needs the trained -weights", cmd)
+	}
+	ss.TrainEnv.Run.Set(ss.StartRun)
+	ss.NewRun()
+	var topoInit map[string]float64
+	if cmd == "wtanal" {
+		topoInit = ss.TopoCors() // of the initial weights, for reference
+	}
+	ss.Printf("Opening weights: %s\n", wts)
+	if err := ss.Net.OpenWtsJSON(gi.FileName(wts)); err != nil {
+		return err
+	}
+	switch cmd {
+	case "test":
+		return ss.CmdTest()
+	case "export-acts":
+		return ss.CmdExportActs()
+	case "reconstruct":
+		return ss.CmdReconstruct()
+	case "lesion":
+		return ss.CmdLesion()
+	case "wtanal":
+		return ss.CmdWtAnal(topoInit)
+	}
+	return fmt.Errorf("subcommand %s is not supported", cmd)
+}
+
+// CmdTest runs the test items and saves the test trial and epoch logs and the ActRFs
+func (ss *Sim) CmdTest() error {
+	ss.TestAll()
+	if ss.Rank() != 0 {
+		return nil
+	}
+	trl := ss.TstTrlLog
+	if ss.Comm != nil {
+		trl = ss.TstTrlLogAll
+	}
+	fnm := ss.LogFileName("tsttrl")
+	ss.Printf("Saving test trial log to: %s\n", fnm)
+	if err := trl.SaveCSV(gi.FileName(fnm), etable.Tab, etable.Headers); err != nil {
+		return err
+	}
+	if err := ss.TstEpcLog.SaveCSV(gi.FileName(ss.LogFileName("tstepc")), etable.Tab, etable.Headers); err != nil {
+		return err
+	}
+	return ss.SaveActRFs("")
+}
+
+// CmdRSA runs the RSA analyses on the CatLayActs saved by a training run in a
+// catact log (acts), and / or on a saved TE similarity matrix (simat),
+// saving the similarity matricies, stats, layer comparisons, geometry and category trees
+func (ss *Sim) CmdRSA(acts, simat string) error {
+	if acts == "" && simat == "" {
+		return fmt.Errorf("rsa needs -acts and / or -simat")
+	}
+	if acts != "" {
+		ss.Printf("Opening CatLayActs: %s\n", acts)
+		if err := ss.CatLayActs.OpenCSV(gi.FileName(acts), etable.Tab); err != nil {
+			return err
+		}
+		ss.RSA.StatsFmActs(ss.CatLayActs, ss.RSACols)
+		for _, lnm := range ss.RSACols {
+			if sm, ok := ss.RSA.Sims[lnm]; ok {
+				etensor.SaveCSV(sm.Mat, gi.FileName(ss.LogFileName(lnm+"sim")), etable.Tab.Rune())
+			}
+		}
+		ss.RSA.LayCmpFmActs(&ss.RSA.LayCmp, ss.CatLayActs, ss.RSACols, ss.CatLayActs, ss.RSACols)
+		ss.SaveLayCmp(&ss.RSA.LayCmp, "laycmp")
+		ss.EmbedReps(0)
+		ss.GeomReps(0)
+		ss.CatTreeReps()
+		dt := &etable.Table{}
+		dt.SetMetaData("name", "RSAStats")
+		dt.SetMetaData("desc", "RSA stats of each layer")
+		dt.SetMetaData("precision", strconv.Itoa(LogPrec))
+		dt.SetFromSchema(etable.Schema{
+			{"Lay", etensor.STRING, nil, nil},
+			{"V1Sim", etensor.FLOAT64, nil, nil},
+			{"CatDst", etensor.FLOAT64, nil, nil},
+			{"TickGen", etensor.FLOAT64, nil, nil},
+			{"TickRDMCor", etensor.FLOAT64, nil, nil},
+		}, len(ss.RSACols))
+		for li, lnm := range ss.RSACols {
+			dt.SetCellString("Lay", li, lnm)
+			dt.SetCellFloat("V1Sim", li, ss.RSA.V1Sims[li])
+			dt.SetCellFloat("CatDst", li, ss.RSA.CatDists[li])
+			dt.SetCellFloat("TickGen", li, ss.RSA.TickGenAvgs[lnm])
+			dt.SetCellFloat("TickRDMCor", li, ss.RSA.TickRDMCorAvgs[lnm])
+		}
+		if err := dt.SaveCSV(gi.FileName(ss.LogFileName("rsa")), etable.Tab, etable.Headers); err != nil {
+			return err
+		}
+	}
+	if simat != "" {
+		ss.Printf("Opening TE similarity matrix: %s\n", simat)
+		ss.RSA.OpenSimMat("TE", gi.FileName(simat))
+	}
+	nms := make([]string, 0, len(ss.RSA.PermDists))
+	for nm := range ss.RSA.PermDists {
+		nms = append(nms, nm)
+	}
+	sort.Strings(nms)
+	dt := &etable.Table{}
+	dt.SetMetaData("name", "RSAPermDists")
+	dt.SetMetaData("desc", "RSA permutation and category distance stats")
+	dt.SetMetaData("precision", strconv.Itoa(LogPrec))
+	dt.SetFromSchema(etable.Schema{
+		{"Name", etensor.STRING, nil, nil},
+		{"Dist", etensor.FLOAT64, nil, nil},
+	}, len(nms))
+	for i, nm := range nms {
+		dt.SetCellString("Name", i, nm)
+		dt.SetCellFloat("Dist", i, ss.RSA.PermDists[nm])
+	}
+	return dt.SaveCSV(gi.FileName(ss.LogFileName("rsaperm")), etable.Tab, etable.Headers)
+}
+
+// CmdExportActs runs the test items, saving the layer representations of every
+// trial (as in the TrnTrlRepLog) to the tstacts log
+func (ss *Sim) CmdExportActs() error {
+	if ss.NpzExport.ExportActs {
+		return ss.ExportActsNpz()
+	}
+	dt := &etable.Table{}
+	ss.ConfigTstTrlRepLog(dt)
+	ss.TestReps(dt)
+	fnm := ss.LogFileName("tstacts")
+	ss.Printf("Saving %d trials of layer activations to: %s\n", dt.Rows, fnm)
+	return dt.SaveCSV(gi.FileName(fnm), etable.Tab, etable.Headers)
+}
+
+// ExportActsNpz runs through the full set of testing items as in TestAll, saving
+// the NpzExport layer variables and metadata of each trial in a tstacts .npz archive
+func (ss *Sim) ExportActsNpz() error {
+	ss.TestEnv.Init(ss.TrainEnv.Run.Cur)
+	ss.TstTrlLog.SetNumRows(0)
+	ss.InitTstCatLayActs()
+	if err := ss.OpenNpzStrms(); err != nil {
+		return err
+	}
+	for {
+		ss.TestTrial(true) // return on chg, don't present
+		_, _, chg := ss.TestEnv.Counter(env.Epoch)
+		if chg || ss.StopNow {
+			break
+		}
+		ss.NpzRecTrial(&ss.TestEnv)
+	}
+	fnm := strings.TrimSuffix(ss.LogFileName("tstacts"), ".tsv") + ".npz"
+	return ss.SaveNpz(fnm, &ss.TestEnv)
+}
+
+// ConfigTstTrlRepLog configures a log of the layer representations per testing
+// trial, as in the TrnTrlRepLog -- see TestReps
+func (ss *Sim) ConfigTstTrlRepLog(dt *etable.Table) {
+	ss.ConfigTrnTrlRepLog(dt)
+	dt.SetMetaData("name", "TstTrlRepLog")
+	dt.SetMetaData("desc", "Record of layer representations per testing trial")
+}
+
+// TestReps runs through the full set of testing items as in TestAll, also
+// recording the layer representations of each trial in reps (TstTrlRepLog)
+func (ss *Sim) TestReps(reps *etable.Table) {
+	ss.TestEnv.Init(ss.TrainEnv.Run.Cur)
+	ss.TstTrlLog.SetNumRows(0)
+	ss.InitTstCatLayActs()
+	reps.SetNumRows(0)
+	for {
+		ss.TestTrial(true) // return on chg, don't present
+		_, _, chg := ss.TestEnv.Counter(env.Epoch)
+		if chg || ss.StopNow {
+			break
+		}
+		ss.LogRepTrl(reps, &ss.TestEnv)
+	}
+}
+
+////////////////////////////////////////////////////////////////////
+//  Lesions
+
+// PrjnByName returns the projection of given standard name, e.g., V4ToTEO
+func (ss *Sim) PrjnByName(pnm string) (*leabra.Prjn, error) {
+	for _, ly := range ss.Net.Layers {
+		for _, pj := range *ly.RecvPrjns() {
+			if pj.Name() == pnm {
+				return pj.(leabra.LeabraPrjn).AsLeabra(), nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("projection %s not found", pnm)
+}
+
+// Lesion applies given lesion to the network, after undoing any current one:
+// turns off the Lays, scales the Prjns, and silences Prop of the Units.
+// Use UnLesion to restore the intact network.
+func (ss *Sim) Lesion(ls *Lesion) error {
+	ss.UnLesion()
+	st := &ss.LesionSt
+	st.Cur = ls
+	for _, lnm := range ls.Lays {
+		ly, err := ss.Net.LayerByNameTry(lnm)
+		if err != nil {
+			return err
+		}
+		if ly.IsOff() {
+			continue
+		}
+		ly.SetOff(true)
+		st.Off = append(st.Off, lnm)
+	}
+	for _, pnm := range ls.Prjns {
+		pj, err := ss.PrjnByName(pnm)
+		if err != nil {
+			return err
+		}
+		if st.Abs == nil {
+			st.Abs = make(map[string]float32)
+		}
+		if _, has := st.Abs[pnm]; !has {
+			st.Abs[pnm] = pj.WtScale.Abs
+		}
+		pj.WtScale.Abs = st.Abs[pnm] * ls.Scale // input scaling is recomputed from Abs every trial
+	}
+	for _, lnm := range ls.Units {
+		ly, err := ss.Net.LayerByNameTry(lnm)
+		if err != nil {
+			return err
+		}
+		ly.(leabra.LeabraLayer).AsLeabra().LesionNeurons(ls.Prop)
+		st.Units = append(st.Units, lnm)
+	}
+	return nil
+}
+
+// UnLesion undoes the current Lesion, if any, restoring the intact network
+func (ss *Sim) UnLesion() {
+	st := &ss.LesionSt
+	for _, lnm := range st.Off {
+		ss.Net.LayerByName(lnm).SetOff(false)
+	}
+	for pnm, abs := range st.Abs {
+		if pj, err := ss.PrjnByName(pnm); err == nil {
+			pj.WtScale.Abs = abs
+		}
+	}
+	for _, lnm := range st.Units {
+		ss.Net.LayerByName(lnm).(leabra.LeabraLayer).AsLeabra().UnLesionNeurons()
+	}
+	st.Reset()
+}
+
+// LesionStats returns the names and values of the stats compared across lesions
+// after TestReps: the test-set pulvinar prediction error and TE RSA stats from the
+// last row of the TstEpcLog, and the cross-validated Probe decoding accuracy of the
+// ProbeClss from each of the superficial layers in reps.
+func (ss *Sim) LesionStats(reps *etable.Table) ([]string, []float64) {
+	var nms []string
+	var vals []float64
+	dt := ss.TstEpcLog
+	row := dt.Rows - 1
+	if row < 0 {
+		return nms, vals
+	}
+	cols := []string{}
+	for _, lnm := range ss.PulvLays {
+		cols = append(cols, lnm+"_CosDiff", lnm+"_AvgSSE")
+	}
+	if ss.RSAColIdx("TE") >= 0 {
+		cols = append(cols, "TE_CatDst", "TE_PermRatio", "TE_BasicDst", "TE_ExptDst")
+	}
+	for _, cn := range cols {
+		nms = append(nms, cn)
+		vals = append(vals, dt.CellFloat(cn, row))
+	}
+	var lays []string
+	for _, lnm := range ss.SuperLays {
+		if reps.ColIdx(lnm) >= 0 {
+			lays = append(lays, lnm)
+		}
+	}
+	ss.Probe.Run(etable.NewIdxView(reps), lays, ProbeClss, nil)
+	for _, lnm := range lays {
+		for _, cn := range ProbeClss {
+			nms = append(nms, lnm+"_"+cn+"Dec")
+			vals = append(vals, ss.Probe.Val(lnm, cn))
+		}
+	}
+	return nms, vals
+}
+
+// CmdLesion runs the lesion battery: the test items are run on the intact network
+// and with each of the Lesions in LesionFile, and the LesionStats of each are saved
+// to the lesion log, along with their difference (_Diff) from the intact network.
+// The random units of each lesion are chosen with the random seed of the run.
+func (ss *Sim) CmdLesion() error {
+	if ss.LesionFile == "" {
+		return fmt.Errorf("lesion needs the -lesions file")
+	}
+	lss, err := OpenLesionsJSON(ss.LesionFile)
+	if err != nil {
+		return err
+	}
+	reps := &etable.Table{}
+	ss.ConfigTstTrlRepLog(reps)
+	ss.UnLesion()
+	ss.TestReps(reps)
+	nms, intact := ss.LesionStats(reps)
+
+	dt := &etable.Table{}
+	dt.SetMetaData("name", "LesionLog")
+	dt.SetMetaData("desc", "test-set stats of each lesion, and their difference from the intact network")
+	dt.SetMetaData("precision", strconv.Itoa(LogPrec))
+	sch := etable.Schema{
+		{"Lesion", etensor.STRING, nil, nil},
+		{"Desc", etensor.STRING, nil, nil},
+	}
+	for _, nm := range nms {
+		sch = append(sch, etable.Column{nm, etensor.FLOAT64, nil, nil})
+		sch = append(sch, etable.Column{nm + "_Diff", etensor.FLOAT64, nil, nil})
+	}
+	dt.SetFromSchema(sch, len(lss)+1)
+	dt.SetCellString("Lesion", 0, "Intact")
+	for i, nm := range nms {
+		dt.SetCellFloat(nm, 0, intact[i])
+	}
+	for li := range lss {
+		ls := &lss[li]
+		ss.Printf("Lesion: %s\n", ls)
+		ss.InitRndSeed()
+		if err := ss.Lesion(ls); err != nil {
+			ss.UnLesion()
+			return err
+		}
+		ss.TestReps(reps)
+		_, vals := ss.LesionStats(reps)
+		row := li + 1
+		dt.SetCellString("Lesion", row, ls.Name)
+		dt.SetCellString("Desc", row, ls.String())
+		for i, nm := range nms {
+			dt.SetCellFloat(nm, row, vals[i])
+			dt.SetCellFloat(nm+"_Diff", row, vals[i]-intact[i])
+		}
+	}
+	ss.UnLesion()
+	fnm := ss.LogFileName("lesion")
+	ss.Printf("Saving lesion log to: %s\n", fnm)
+	return dt.SaveCSV(gi.FileName(fnm), etable.Tab, etable.Headers)
+}
+
+// CmdReconstruct runs the test items, saving for each trial the input image and
+// the V1 images reconstructed from each of the ReconLays in the minus phase
+// (prediction) and plus phase (actual), and a recon log with the correlation
+// between the minus and plus phase activity of each layer.
+func (ss *Sim) CmdReconstruct() error {
+	specs := make([]ReconSpec, len(ss.ReconLays))
+	for i, sp := range ss.ReconLays {
+		rs, err := ParseReconSpec(sp)
+		if err != nil {
+			return err
+		}
+		if _, err := ss.Net.LayerByNameTry(rs.Lay); err != nil {
+			return err
+		}
+		if rs.Vis != "V1m" && rs.Vis != "V1h" {
+			return fmt.Errorf("ReconSpec %s: vis must be V1m or V1h", sp)
+		}
+		specs[i] = rs
+	}
+	dt := &etable.Table{}
+	dt.SetMetaData("name", "ReconLog")
+	dt.SetMetaData("desc", "correlation between minus and plus phase of reconstructed layers per testing trial")
+	dt.SetMetaData("precision", strconv.Itoa(LogPrec))
+	sch := etable.Schema{
+		{"Trial", etensor.INT64, nil, nil},
+		{"Tick", etensor.INT64, nil, nil},
+		{"Cat", etensor.STRING, nil, nil},
+		{"Obj", etensor.STRING, nil, nil},
+		{"TrialName", etensor.STRING, nil, nil},
+	}
+	for _, rs := range specs {
+		sch = append(sch, etable.Column{rs.Name() + "_Cor", etensor.FLOAT64, nil, nil})
+	}
+	dt.SetFromSchema(sch, 0)
+
+	rc := &Recon{}
+	ev := &ss.TestEnv
+	ev.Init(ss.TrainEnv.Run.Cur)
+	for {
+		ss.TestTrial(true) // return on chg, don't present
+		_, _, chg := ev.Counter(env.Epoch)
+		if chg || ss.StopNow {
+			break
+		}
+		trl := ev.Trial.Cur
+		tick := ev.Tick.Cur
+		row := dt.Rows
+		dt.SetNumRows(row + 1)
+		dt.SetCellFloat("Trial", row, float64(trl))
+		dt.SetCellFloat("Tick", row, float64(tick))
+		dt.SetCellString("Cat", row, ev.CurCat)
+		dt.SetCellString("Obj", row, ev.CurObj)
+		dt.SetCellString("TrialName", row, ev.String())
+		base := strings.TrimSuffix(ss.LogFileName(fmt.Sprintf("recon_%03d_%d", trl, tick)), ".tsv")
+		if err := SavePNG(ev.Image, base+"_img.png"); err != nil {
+			return err
+		}
+		for _, rs := range specs {
+			vi := &ev.V1Med
+			if rs.Vis == "V1h" {
+				vi = &ev.V1Hi
+			}
+			ly := ss.Net.LayerByName(rs.Lay).(leabra.LeabraLayer).AsLeabra()
+			for _, vnm := range []string{"ActM", "ActP"} {
+				vt := ss.ValsTsr(rs.Lay + "_" + vnm)
+				ly.UnitValsTensor(vt, vnm)
+				img, err := rc.Image(vi, vt, rs.Row)
+				if err != nil {
+					return fmt.Errorf("reconstruct %s: %v", rs.Lay, err)
+				}
+				if err := SavePNG(img, base+"_"+rs.Name()+"_"+vnm+".png"); err != nil {
+					return err
+				}
+			}
+			cor := metric.Correlation32(ss.ValsTsr(rs.Lay+"_ActM").Values, ss.ValsTsr(rs.Lay+"_ActP").Values)
+			dt.SetCellFloat(rs.Name()+"_Cor", row, float64(cor))
+		}
+	}
+	fnm := ss.LogFileName("recon")
+	ss.Printf("Saved %d trials of reconstructions, log: %s\n", dt.Rows, fnm)
+	return dt.SaveCSV(gi.FileName(fnm), etable.Tab, etable.Headers)
+}
+
+// PrjnWts returns the weights of the projection by receiving unit
+func (ss *Sim) PrjnWts(pj emer.Prjn) *PrjnWts {
+	lpj := pj.(leabra.LeabraPrjn).AsLeabra()
+	nr := pj.RecvLay().Shape().Len()
+	pw := &PrjnWts{Name: pj.Name(), Wts: make([][]float32, nr), Sidx: make([][]int, nr)}
+	for ri := 0; ri < nr; ri++ {
+		nc := int(lpj.RConN[ri])
+		st := int(lpj.RConIdxSt[ri])
+		rw := make([]float32, nc)
+		rs := make([]int, nc)
+		for ci := 0; ci < nc; ci++ {
+			rw[ci] = lpj.Syns[lpj.RSynIdx[st+ci]].Wt
+			rs[ci] = int(lpj.RConIdx[st+ci])
+		}
+		pw.Wts[ri] = rw
+		pw.Sidx[ri] = rs
+	}
+	return pw
+}
+
+// TopoCors returns the TopoCor of the current weights of all the projections
+// with topographic PoolTile patterns, by projection name
+func (ss *Sim) TopoCors() map[string]float64 {
+	tcs := make(map[string]float64)
+	for _, ly := range ss.Net.Layers {
+		for _, pj := range *ly.RecvPrjns() {
+			scales, err := PrjnTopoScales(pj)
+			if err != nil {
+				log.Println(err)
+			}
+			if scales != nil {
+				tcs[pj.Name()] = ss.PrjnWts(pj).TopoCor(scales)
+			}
+		}
+	}
+	return tcs
+}
+
+// CmdWtAnal analyzes the trained weights: the stats of the receiving projections
+// of each layer are saved in a wtanal_<lay> log, with the TopoCor of the trained
+// weights and of the initial weights (topoInit) for the topographic projections,
+// which shows how much of the topography of the initial weights survived learning.
+// The weight-based receptive fields of the first WtAnal.RFUnits units of each
+// pool of the layers of the WtAnal.RFs are saved in a wtrf_<lay>_<vis> log,
+// and rendered as images through the V1 filters.
+func (ss *Sim) CmdWtAnal(topoInit map[string]float64) error {
+	for _, ly := range ss.Net.Layers {
+		if ly.IsOff() || len(*ly.RecvPrjns()) == 0 {
+			continue
+		}
+		dt := &etable.Table{}
+		ConfigWtAnalLog(dt, ly.Name())
+		for _, pj := range *ly.RecvPrjns() {
+			if pj.IsOff() {
+				continue
+			}
+			pw := ss.PrjnWts(pj)
+			topo := math.NaN()
+			tinit := math.NaN()
+			scales, err := PrjnTopoScales(pj)
+			if err != nil {
+				log.Println(err)
+			}
+			if scales != nil {
+				topo = pw.TopoCor(scales)
+				if tc, ok := topoInit[pj.Name()]; ok {
+					tinit = tc
+				}
+			}
+			AddWtAnal(dt, pj, pw.Stats(ss.WtAnal.SparseThr), topo, tinit)
+		}
+		if err := dt.SaveCSV(gi.FileName(ss.LogFileName("wtanal_"+ly.Name())), etable.Tab, etable.Headers); err != nil {
+			return err
+		}
+	}
+	ss.Printf("Saved wtanal logs of %d layers\n", len(ss.Net.Layers))
+
+	if len(ss.WtAnal.RFs) == 0 {
+		return nil
+	}
+	ev := &ss.TestEnv
+	ev.Init(ss.TrainEnv.Run.Cur)
+	ev.Step() // filters an image, for the V1 geometry
+	rc := &Recon{}
+	for _, sp := range ss.WtAnal.RFs {
+		rs, err := ParseWtRFSpec(sp)
+		if err != nil {
+			return err
+		}
+		if err := ss.WtRFs(&rs, rc); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WtRFs computes, saves and renders the weight-based receptive fields of a WtRFSpec
+func (ss *Sim) WtRFs(rs *WtRFSpec, rc *Recon) error {
+	prjns := make([]*PrjnWts, len(rs.Lays)-1)
+	for i := range prjns {
+		rly, err := ss.Net.LayerByNameTry(rs.Lays[i])
+		if err != nil {
+			return err
+		}
+		pj, err := rly.RecvPrjns().SendNameTry(rs.Lays[i+1])
+		if err != nil {
+			return fmt.Errorf("WtRFSpec %s: %v", rs.Name(), err)
+		}
+		prjns[i] = ss.PrjnWts(pj)
+	}
+	vly := ss.Net.LayerByName(rs.Vis())
+	vi := &ss.TestEnv.V1Med
+	if rs.Vis() == "V1h" {
+		vi = &ss.TestEnv.V1Hi
+	}
+	wr := &WtRF{}
+	wr.Init(prjns, vly.Shape().Len())
+	dt := &etable.Table{}
+	ConfigWtRFLog(dt, rs, vly.Shape().Shp)
+
+	ly := ss.Net.LayerByName(rs.Lay())
+	sh := ly.Shape()
+	npl, nu := 1, sh.Len()
+	if sh.NumDims() == 4 {
+		npl = sh.Dim(0) * sh.Dim(1)
+		nu = sh.Dim(2) * sh.Dim(3)
+	}
+	nru := ss.WtAnal.RFUnits
+	if nru > nu {
+		nru = nu
+	}
+	base := strings.TrimSuffix(ss.LogFileName("wtrf_"+rs.Name()), ".tsv")
+	for pi := 0; pi < npl; pi++ {
+		for u := 0; u < nru; u++ {
+			rf := wr.RF(0, pi*nu+u)
+			row := dt.Rows
+			dt.SetNumRows(row + 1)
+			dt.SetCellFloat("Pool", row, float64(pi))
+			dt.SetCellFloat("Unit", row, float64(u))
+			dt.SetCellTensor("RF", row, etensor.NewFloat32Shape(vly.Shape(), rf))
+			img, err := RFImage(rc, vi, rf, vly.Shape().Shp)
+			if err != nil {
+				return fmt.Errorf("WtRFSpec %s: %v", rs.Name(), err)
+			}
+			if err := SavePNG(img, fmt.Sprintf("%s_%03d_%d.png", base, pi, u)); err != nil {
+				return err
+			}
+		}
+	}
+	fnm := ss.LogFileName("wtrf_" + rs.Name())
+	ss.Printf("Saved %d weight-based receptive fields of %s, log: %s\n", dt.Rows, rs.Lay(), fnm)
+	return dt.SaveCSV(gi.FileName(fnm), etable.Tab, etable.Headers)
+}
+
 ////////////////////////////////////////////////////////////////////
 //  MPI code
 
+// Rank returns the rank of this proc in data-parallel training, across MPI procs
+// or in-process Workers -- 0 if not distributed
+func (ss *Sim) Rank() int {
+	if ss.Comm == nil {
+		return 0
+	}
+	return ss.Comm.Rank()
+}
+
+// NProcs returns the number of procs in data-parallel training -- 1 if not distributed
+func (ss *Sim) NProcs() int {
+	if ss.Comm == nil {
+		return 1
+	}
+	return ss.Comm.Size()
+}
+
+// Printf prints on rank 0 only, as mpi.Printf does, and for in-process Workers too
+func (ss *Sim) Printf(fs string, args ...interface{}) {
+	if ss.Rank() == 0 {
+		fmt.Printf(fs, args...)
+	}
+}
+
+// Serial calls fun on one proc at a time, for in-process Workers -- see SimComm
+func (ss *Sim) Serial(fun func()) {
+	if ss.Comm == nil {
+		fun()
+		return
+	}
+	ss.Comm.Serial(fun)
+}
+
 // MPIInit initializes MPI
 func (ss *Sim) MPIInit() {
 	mpi.Init()
-	var err error
-	ss.Comm, err = mpi.NewComm(nil) // use all procs
+	comm, err := mpi.NewComm(nil) // use all procs
 	if err != nil {
 		log.Println(err)
 		ss.UseMPI = false
 	} else {
+		ss.Comm = &MPIComm{Comm: comm}
 		mpi.Printf("MPI running on %d procs\n", mpi.WorldSize())
 	}
 }
@@ -2444,22 +4819,113 @@ func (ss *Sim) MPIFinalize() {
 func (ss *Sim) CollectDWts(net *leabra.Network) {
 	made := net.CollectDWts(&ss.AllDWts, 78163328) // plug in number from printout below, to avoid realloc
 	if made {
-		mpi.Printf("MPI: AllDWts len: %d\n", len(ss.AllDWts)) // put this number in above make
+		ss.Printf("MPI: AllDWts len: %d\n", len(ss.AllDWts)) // put this number in above make
 	}
 }
 
-// MPIWtFmDWt updates weights from weight changes, using MPI to integrate
-// DWt changes across parallel nodes, each of which are learning on different
-// sequences of inputs.
+// MPIWtFmDWt updates weights from weight changes, using MPI (or the in-process
+// Workers) to integrate DWt changes across parallel nodes, each of which are
+// learning on different sequences of inputs.
 func (ss *Sim) MPIWtFmDWt() {
-	if ss.UseMPI {
+	if ss.Comm != nil {
 		ss.CollectDWts(&ss.Net.Network)
 		ndw := len(ss.AllDWts)
 		if len(ss.SumDWts) != ndw {
 			ss.SumDWts = make([]float32, ndw)
 		}
-		ss.Comm.AllReduceF32(mpi.OpSum, ss.SumDWts, ss.AllDWts)
+		if err := ss.Comm.AllReduceF32(mpi.OpSum, ss.SumDWts, ss.AllDWts); err != nil {
+			log.Println("MPIWtFmDWt: weight changes not summed:", err)
+			return
+		}
 		ss.Net.SetDWts(ss.SumDWts)
 	}
 	ss.Net.WtFmDWt()
 }
+
+////////////////////////////////////////////////////////////////////
+//  In-process workers
+
+// NewWorkers sets up data-parallel training on Workers in-process workers, without
+// MPI, with this sim as rank 0, and returns the others: new sims with the same
+// command line settings (CopyArgs), made after the args and before Config.
+// Each trains on its own slice of the trials, on its own goroutine, exactly as on
+// separate MPI procs, with the weight changes and stats summed in shared memory
+// through a LocalComm.
+func (ss *Sim) NewWorkers() []*Sim {
+	lc := NewLocalComm(ss.Workers)
+	ss.Comm = lc.Worker(0)
+	wks := make([]*Sim, ss.Workers-1)
+	for i := range wks {
+		ws := &Sim{}
+		ws.New()
+		ws.CopyArgs(ss)
+		ws.Comm = lc.Worker(i + 1)
+		wks[i] = ws
+	}
+	ss.Printf("Training on %d in-process workers\n", ss.Workers)
+	return wks
+}
+
+// CopyArgs copies the settings made from the command line args by CmdArgs, before
+// Config, from given sim, e.g., to a worker of NewWorkers, which only trains -- the
+// StatusAddr is copied so the workers share the status controls (UpdtStatus), but
+// only rank 0 serves the status
+func (ss *Sim) CopyArgs(fm *Sim) {
+	ss.NoGui = fm.NoGui
+	ss.ParamSet = fm.ParamSet
+	ss.Params = append(params.Sets{}, fm.Params...) // with the -xparams sets
+	ss.XParamSets = append([]string{}, fm.XParamSets...)
+	ss.Tag = fm.Tag
+	ss.StartRun = fm.StartRun
+	ss.MaxRuns = fm.MaxRuns
+	ss.LogSetParams = fm.LogSetParams
+	ss.TestInterval = fm.TestInterval
+	ss.ActRFLog = fm.ActRFLog
+	ss.SaveProcLog = fm.SaveProcLog
+	ss.Workers = fm.Workers
+	ss.RepRDMs = fm.RepRDMs
+	ss.CkptInterval = fm.CkptInterval
+	ss.StatusAddr = fm.StatusAddr
+	ss.ConvFile = fm.ConvFile
+	ss.NetSpecFile = fm.NetSpecFile
+	ss.SchedFile = fm.SchedFile
+	ss.OutDir = fm.OutDir
+	ss.ImagesDir = fm.ImagesDir
+	ss.MaxTstTrls = fm.MaxTstTrls
+	ss.RSALays = append([]string{}, fm.RSALays...)
+	ss.ActRFNms = append([]string{}, fm.ActRFNms...)
+	ss.NpzExport.Interval = fm.NpzExport.Interval
+	ss.NpzExport.Lays = append([]string{}, fm.NpzExport.Lays...)
+}
+
+// InitWorker configures and initializes a worker of NewWorkers for training, from
+// the checkpoint in resume if set, as CmdArgs does for rank 0 -- the workers save
+// no logs or weights, only their own state in checkpoints
+func (ss *Sim) InitWorker(resume string) error {
+	rand.Seed(1) // the initial state in a new proc, e.g., for UnifRnd prjns at Config
+	ss.Config()
+	ss.Init()
+	ss.SaveWts = false
+	ss.TrainEnv.Run.Set(ss.StartRun)
+	ss.TrainEnv.Run.Max = ss.StartRun + ss.MaxRuns
+	if resume != "" {
+		return ss.OpenCkpt(resume)
+	}
+	ss.NewRun()
+	return nil
+}
+
+// TrainAll trains this sim, and the workers of NewWorkers if any, each on its own
+// goroutine, returning when all are done
+func (ss *Sim) TrainAll(wks []*Sim) {
+	var wg sync.WaitGroup
+	for _, ws := range wks {
+		wg.Add(1)
+		go func(ws *Sim) {
+			defer wg.Done()
+			ws.Train()
+		}(ws)
+	}
+	ss.Train()
+	wg.Wait()
+}
@@ -0,0 +1,261 @@
+// Copyright (c) 2020, The CCNLab Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	"github.com/emer/etable/etable"
+)
+
+// ConvCrit is one convergence criterion of a ConvMon: the values of an epoch log
+// column are smoothed over Window samples, and the criterion is met when the
+// smoothed value has not improved by more than MinDelta for Patience samples.
+type ConvCrit struct {
+	Col      string  `desc:"epoch log column, e.g., V1mP_CosDiff, TE_CatDst, TE_ExptDst"`
+	Tst      bool    `desc:"if true, Col is in the TstEpcLog of periodic testing (sampled every TestInterval epochs), else in the training epoch log"`
+	Max      bool    `desc:"if true, larger values are better (e.g., CosDiff, CatDst), else smaller values are better (e.g., ExptDst, AvgSSE)"`
+	Every    int     `desc:"sample the column only every this many epochs, e.g., RSA.Interval for the RSA stats, which are only updated then -- 0 or 1 = every epoch"`
+	Window   int     `desc:"number of samples in the moving average used to smooth the values -- 0 or 1 = no smoothing"`
+	Patience int     `desc:"number of samples without improvement of the smoothed value after which the criterion is met"`
+	MinDelta float64 `desc:"minimum change of the smoothed value, in the better direction, that counts as an improvement"`
+
+	Vals []float64 `view:"-" desc:"sampled values, up to Window"`
+	Best float64   `inactive:"+" desc:"best smoothed value so far"`
+	NImp int       `inactive:"+" desc:"number of samples since the last improvement"`
+	N    int       `inactive:"+" desc:"number of smoothed values so far"`
+}
+
+// Reset resets the state of the criterion
+func (cc *ConvCrit) Reset() {
+	cc.Vals = nil
+	cc.Best = 0
+	cc.NImp = 0
+	cc.N = 0
+}
+
+// Add adds a sample value and updates the state of the criterion
+func (cc *ConvCrit) Add(val float64) {
+	win := cc.Window
+	if win < 1 {
+		win = 1
+	}
+	cc.Vals = append(cc.Vals, val)
+	if len(cc.Vals) > win {
+		cc.Vals = cc.Vals[len(cc.Vals)-win:]
+	}
+	if len(cc.Vals) < win {
+		return
+	}
+	sm := 0.0
+	for _, v := range cc.Vals {
+		sm += v
+	}
+	sm /= float64(win)
+	cc.N++
+	imp := sm - cc.Best
+	if !cc.Max {
+		imp = -imp
+	}
+	if cc.N == 1 || imp > cc.MinDelta {
+		cc.Best = sm
+		cc.NImp = 0
+		return
+	}
+	cc.NImp++
+}
+
+// Met returns true if the criterion is met
+func (cc *ConvCrit) Met() bool {
+	return cc.N > 0 && cc.NImp >= cc.Patience
+}
+
+// Sample returns the value of the Col at the last row of the epoch log dt
+// if it is for given epoch, and whether there is one
+func (cc *ConvCrit) Sample(dt *etable.Table, epc int) (float64, bool) {
+	if dt == nil || dt.Rows == 0 {
+		return 0, false
+	}
+	row := dt.Rows - 1
+	if int(dt.CellFloat("Epoch", row)) != epc {
+		return 0, false
+	}
+	col := dt.ColByName(cc.Col)
+	if col == nil {
+		return 0, false
+	}
+	return col.FloatVal1D(row), true
+}
+
+// String returns a summary of the state of the criterion
+func (cc *ConvCrit) String() string {
+	return fmt.Sprintf("%s: best: %g no-imp: %d/%d", cc.Col, cc.Best, cc.NImp, cc.Patience)
+}
+
+// Validate returns an error if the criterion is not well specified
+func (cc *ConvCrit) Validate() error {
+	if cc.Col == "" {
+		return fmt.Errorf("ConvCrit needs a Col")
+	}
+	if cc.Patience < 1 {
+		return fmt.Errorf("ConvCrit %s: Patience must be >= 1", cc.Col)
+	}
+	if cc.MinDelta < 0 {
+		return fmt.Errorf("ConvCrit %s: MinDelta must be >= 0", cc.Col)
+	}
+	return nil
+}
+
+// ConvMon monitors the convergence of training on epoch log columns, e.g., the
+// pulvinar prediction error and TE category structure, with one ConvCrit for each.
+// When any (or with All, all) of the criteria are met, the run has converged,
+// and the sim performs the Acts, saves the weights if SaveWts, and ends the run if
+// Stop -- otherwise the criteria are reset and monitoring continues, e.g., so that
+// a Lrate act anneals the learning rate at each plateau.
+type ConvMon struct {
+	On      bool       `desc:"monitor convergence -- set by loading a ConvMon with -conv"`
+	Crits   []ConvCrit `desc:"the convergence criteria"`
+	All     bool       `desc:"if true, all the criteria must be met, else any one"`
+	MinEpcs int        `desc:"do not sample before this epoch, e.g., to skip the initial transient"`
+	Stop    bool       `desc:"end the run when converged"`
+	SaveWts bool       `desc:"save the weights when converged, with the std weights file name"`
+	Acts    []SchedAct `desc:"EpochSched actions to perform when converged (the Epoch and Every of the acts are ignored)"`
+
+	ConvEpcs []int `inactive:"+" desc:"epochs at which the run has converged so far"`
+}
+
+// Reset resets the state of all the criteria
+func (cm *ConvMon) Reset() {
+	for i := range cm.Crits {
+		cm.Crits[i].Reset()
+	}
+}
+
+// Init resets the state of all the criteria and the converged epochs, for a new run
+func (cm *ConvMon) Init() {
+	cm.Reset()
+	cm.ConvEpcs = nil
+}
+
+// Check samples the criteria from the epoch logs at the end of given training epoch
+// (the last row of each log is for the epoch if it has been logged), and returns
+// true if the run has converged, recording the epoch in ConvEpcs.
+func (cm *ConvMon) Check(epc int, trn, tst *etable.Table) bool {
+	if !cm.On || len(cm.Crits) == 0 || epc < cm.MinEpcs {
+		return false
+	}
+	nmet := 0
+	for i := range cm.Crits {
+		cc := &cm.Crits[i]
+		if cc.Every > 1 && epc%cc.Every != 0 {
+			if cc.Met() {
+				nmet++
+			}
+			continue
+		}
+		dt := trn
+		if cc.Tst {
+			dt = tst
+		}
+		if val, ok := cc.Sample(dt, epc); ok {
+			cc.Add(val)
+		}
+		if cc.Met() {
+			nmet++
+		}
+	}
+	conv := nmet > 0
+	if cm.All {
+		conv = nmet == len(cm.Crits)
+	}
+	if conv {
+		cm.ConvEpcs = append(cm.ConvEpcs, epc)
+	}
+	return conv
+}
+
+// LastEpc returns the last epoch at which the run converged, -1 if none
+func (cm *ConvMon) LastEpc() int {
+	if len(cm.ConvEpcs) == 0 {
+		return -1
+	}
+	return cm.ConvEpcs[len(cm.ConvEpcs)-1]
+}
+
+// String returns a summary of the state of all the criteria
+func (cm *ConvMon) String() string {
+	strs := make([]string, len(cm.Crits))
+	for i := range cm.Crits {
+		strs[i] = cm.Crits[i].String()
+	}
+	return strings.Join(strs, ", ")
+}
+
+// Validate returns an error for the first criterion or action that is not well specified
+func (cm *ConvMon) Validate() error {
+	for i := range cm.Crits {
+		if err := cm.Crits[i].Validate(); err != nil {
+			return err
+		}
+	}
+	for i := range cm.Acts {
+		if err := cm.Acts[i].Validate(); err != nil {
+			return fmt.Errorf("ConvMon: %v", err)
+		}
+	}
+	return nil
+}
+
+// CheckCols returns an error for the criteria whose Col is not in its epoch log:
+// the training epoch log trn, or the testing epoch log tst if Tst -- such a
+// criterion would otherwise never be met
+func (cm *ConvMon) CheckCols(trn, tst *etable.Table) error {
+	var errs []string
+	for i := range cm.Crits {
+		cc := &cm.Crits[i]
+		dt, lnm := trn, "training"
+		if cc.Tst {
+			dt, lnm = tst, "testing"
+		}
+		if dt.ColByName(cc.Col) == nil {
+			errs = append(errs, fmt.Sprintf("%s not in the %s epoch log", cc.Col, lnm))
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("ConvMon: Col: %s", strings.Join(errs, ", "))
+	}
+	return nil
+}
+
+// OpenJSON opens the monitor from a JSON file, validates it, and turns it On
+func (cm *ConvMon) OpenJSON(fname string) error {
+	b, err := ioutil.ReadFile(fname)
+	if err != nil {
+		return err
+	}
+	nm := ConvMon{}
+	if err := json.Unmarshal(b, &nm); err != nil {
+		return fmt.Errorf("ConvMon %s: %v", fname, err)
+	}
+	if err := nm.Validate(); err != nil {
+		return err
+	}
+	nm.On = true
+	nm.Init()
+	*cm = nm
+	return nil
+}
+
+// SaveJSON saves the monitor to a JSON file
+func (cm *ConvMon) SaveJSON(fname string) error {
+	b, err := json.MarshalIndent(cm, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(fname, b, 0644)
+}
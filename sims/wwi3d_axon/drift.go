@@ -0,0 +1,355 @@
+// Copyright (c) 2020, The CCNLab Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"image"
+	"image/color"
+	"image/gif"
+	"image/png"
+	"math"
+	"os"
+
+	"github.com/emer/etable/etable"
+	"github.com/emer/etable/etensor"
+	"github.com/emer/etable/metric"
+	"github.com/emer/etable/simat"
+)
+
+// DriftRec is one recorded epoch of a layer's representations, for Drift
+type DriftRec struct {
+	Epoch int               `desc:"epoch when recorded"`
+	Sim   []float64         `desc:"values of the full item x item similarity matrix"`
+	Obj   []float64         `desc:"values of the Objs x Objs similarity matrix"`
+	Cats  map[string]string `desc:"discovered category of each object (PermCats)"`
+}
+
+// Drift tracks the history of RSA similarity matricies over epochs for each layer,
+// to follow how the category structure forms: stability of the similarity structure
+// from one recorded epoch to the next, churn in the discovered categories, and the
+// epoch at which each pair of objects ended up in the same discovered category.
+type Drift struct {
+	Lays    []string                 `desc:"RSA layers to track"`
+	MaxHist int                      `desc:"maximum number of epochs to keep in the history of each layer -- 0 = all"`
+	Cell    int                      `desc:"size in pixels of each object cell in the heatmap images"`
+	Delay   int                      `desc:"delay between frames in the animated heatmap gif, in 100ths of a second"`
+	PNGs    bool                     `desc:"save a png image of the object heatmap at each recorded epoch, in addition to the animated gif"`
+	Hists   map[string][]*DriftRec   `view:"-" desc:"history of recorded epochs for each layer"`
+	Emerges map[string]*simat.SimMat `desc:"for each layer, the epoch at which each pair of objects became (and remained) in the same discovered category -- -1 if not currently in the same category"`
+	Stats   *etable.Table            `view:"no-inline" desc:"stats for each layer over epochs: Stab = correlation of the similarity matrix with that of the previous recorded epoch, Churn = proportion of object pairs whose same vs. different category status changed, NCats = number of discovered categories, Within = proportion of object pairs in the same category"`
+}
+
+func (dr *Drift) Defaults() {
+	dr.Lays = []string{"TE"}
+	dr.MaxHist = 0
+	dr.Cell = 12
+	dr.Delay = 50
+	dr.PNGs = false
+}
+
+// DriftStats are the stats columns in the Drift Stats table
+var DriftStats = []string{"Stab", "Churn", "NCats", "Within"}
+
+// Val returns the most recent value of given stat for given layer
+func (dr *Drift) Val(lay, stat string) float64 {
+	if dr.Stats == nil {
+		return 0
+	}
+	for row := dr.Stats.Rows - 1; row >= 0; row-- {
+		if dr.Stats.CellString("Lay", row) == lay {
+			return dr.Stats.CellFloat(stat, row)
+		}
+	}
+	return 0
+}
+
+// Init initializes the history and stats
+func (dr *Drift) Init() {
+	dr.Hists = make(map[string][]*DriftRec)
+	dr.Emerges = make(map[string]*simat.SimMat)
+	dt := &etable.Table{}
+	dt.SetMetaData("name", "Drift")
+	dt.SetMetaData("desc", "representational drift and category emergence of each layer over epochs")
+	dt.SetMetaData("read-only", "true")
+	sch := etable.Schema{
+		{"Epoch", etensor.INT64, nil, nil},
+		{"Lay", etensor.STRING, nil, nil},
+	}
+	for _, sn := range DriftStats {
+		sch = append(sch, etable.Column{sn, etensor.FLOAT64, nil, nil})
+	}
+	dt.SetFromSchema(sch, 0)
+	dr.Stats = dt
+}
+
+// Update records the current RSA similarity matricies and discovered categories
+// for the Lays at given epoch, and updates the Stats and Emerges.  Categories are
+// discovered with PermuteCatTest if the RSA has not already done so for a layer.
+func (dr *Drift) Update(rs *RSA, epc int) {
+	if dr.Hists == nil {
+		dr.Init()
+	}
+	for _, lnm := range dr.Lays {
+		sm, ok := rs.Sims[lnm]
+		if !ok || sm.Mat == nil || len(sm.Rows) == 0 {
+			continue
+		}
+		osm, ok := rs.Sims[lnm+"_Obj"]
+		if !ok || osm.Mat == nil {
+			continue
+		}
+		cats, ok := rs.PermCats[lnm]
+		if !ok || cats == nil {
+			cats, _, _ = rs.PermuteCatTest(sm, rs.Cats, LbaCats5, lnm+"perm")
+		}
+		rec := &DriftRec{Epoch: epc, Cats: make(map[string]string, len(cats))}
+		rec.Sim = append([]float64(nil), sm.Mat.(*etensor.Float64).Values...)
+		rec.Obj = append([]float64(nil), osm.Mat.(*etensor.Float64).Values...)
+		for k, v := range cats {
+			rec.Cats[k] = v
+		}
+		hist := append(dr.Hists[lnm], rec)
+		if dr.MaxHist > 0 && len(hist) > dr.MaxHist {
+			hist = hist[len(hist)-dr.MaxHist:]
+		}
+		dr.Hists[lnm] = hist
+		dr.UpdtStats(lnm, hist)
+		dr.UpdtEmerge(lnm, hist)
+	}
+}
+
+// UpdtStats adds a row to Stats for the last record in given history
+func (dr *Drift) UpdtStats(lnm string, hist []*DriftRec) {
+	nh := len(hist)
+	cur := hist[nh-1]
+	stab := 0.0
+	churn := 0.0
+	if nh > 1 {
+		prv := hist[nh-2]
+		stab = metric.Correlation64(cur.Sim, prv.Sim)
+		churn = CatChurn(prv.Cats, cur.Cats)
+	}
+	ncats := make(map[string]bool)
+	for _, o := range Objs {
+		ncats[cur.Cats[o]] = true
+	}
+	dt := dr.Stats
+	row := dt.Rows
+	dt.SetNumRows(row + 1)
+	dt.SetCellFloat("Epoch", row, float64(cur.Epoch))
+	dt.SetCellString("Lay", row, lnm)
+	dt.SetCellFloat("Stab", row, stab)
+	dt.SetCellFloat("Churn", row, churn)
+	dt.SetCellFloat("NCats", row, float64(len(ncats)))
+	dt.SetCellFloat("Within", row, CatWithin(cur.Cats))
+}
+
+// UpdtEmerge updates the Emerges matrix for given layer from its history:
+// for each pair of objects in the same category in the last record, the epoch
+// of the first record in the final run of records where they were in the same category.
+func (dr *Drift) UpdtEmerge(lnm string, hist []*DriftRec) {
+	sm, ok := dr.Emerges[lnm]
+	if !ok {
+		sm = &simat.SimMat{}
+		dr.Emerges[lnm] = sm
+	}
+	no := len(Objs)
+	sm.Init()
+	smat := sm.Mat.(*etensor.Float64)
+	smat.SetShape([]int{no, no}, nil, nil)
+	smat.SetMetaData("min", "0")
+	smat.SetMetaData("colormap", "Viridis")
+	smat.SetMetaData("grid-fill", "1")
+	smat.SetMetaData("dim-extra", "0.15")
+	sm.Rows = LbaCatsBlanks
+	sm.Cols = LbaCatsBlanks
+	nh := len(hist)
+	for ri, ro := range Objs {
+		for ci, co := range Objs {
+			emg := -1
+			for hi := nh - 1; hi >= 0; hi-- {
+				cats := hist[hi].Cats
+				if cats[ro] != cats[co] {
+					break
+				}
+				emg = hist[hi].Epoch
+			}
+			smat.Values[ri*no+ci] = float64(emg)
+		}
+	}
+}
+
+// EmergeTable returns a table of the Emerges epochs for each pair of objects,
+// for given layer, with the current discovered categories of each object
+func (dr *Drift) EmergeTable(lnm string) *etable.Table {
+	sch := etable.Schema{
+		{"ObjA", etensor.STRING, nil, nil},
+		{"ObjB", etensor.STRING, nil, nil},
+		{"CatA", etensor.STRING, nil, nil},
+		{"CatB", etensor.STRING, nil, nil},
+		{"Emerge", etensor.FLOAT64, nil, nil},
+	}
+	dt := &etable.Table{}
+	hist := dr.Hists[lnm]
+	sm, ok := dr.Emerges[lnm]
+	if len(hist) == 0 || !ok {
+		dt.SetFromSchema(sch, 0)
+		return dt
+	}
+	cats := hist[len(hist)-1].Cats
+	no := len(Objs)
+	dt.SetFromSchema(sch, no*(no-1)/2)
+	smat := sm.Mat.(*etensor.Float64)
+	row := 0
+	for ri, ro := range Objs {
+		for ci := ri + 1; ci < no; ci++ {
+			co := Objs[ci]
+			dt.SetCellString("ObjA", row, ro)
+			dt.SetCellString("ObjB", row, co)
+			dt.SetCellString("CatA", row, cats[ro])
+			dt.SetCellString("CatB", row, cats[co])
+			dt.SetCellFloat("Emerge", row, smat.Values[ri*no+ci])
+			row++
+		}
+	}
+	return dt
+}
+
+// CatChurn returns the proportion of pairs of Objs whose same vs. different
+// category status differs between the two category maps (i.e., 1 - Rand index)
+func CatChurn(a, b map[string]string) float64 {
+	no := len(Objs)
+	nchg := 0
+	for ri := 0; ri < no; ri++ {
+		for ci := ri + 1; ci < no; ci++ {
+			sa := a[Objs[ri]] == a[Objs[ci]]
+			sb := b[Objs[ri]] == b[Objs[ci]]
+			if sa != sb {
+				nchg++
+			}
+		}
+	}
+	return float64(nchg) / float64(no*(no-1)/2)
+}
+
+// CatWithin returns the proportion of pairs of Objs in the same category
+func CatWithin(cats map[string]string) float64 {
+	no := len(Objs)
+	nw := 0
+	for ri := 0; ri < no; ri++ {
+		for ci := ri + 1; ci < no; ci++ {
+			if cats[Objs[ri]] == cats[Objs[ci]] {
+				nw++
+			}
+		}
+	}
+	return float64(nw) / float64(no*(no-1)/2)
+}
+
+// Frames returns heatmap images of the object similarity matricies
+// over the history of given layer, with values from 0 to 1
+func (dr *Drift) Frames(lnm string) []*image.Paletted {
+	hist := dr.Hists[lnm]
+	no := len(Objs)
+	frames := make([]*image.Paletted, len(hist))
+	for i, rec := range hist {
+		frames[i] = HeatmapImage(rec.Obj, no, dr.Cell, 0, 1)
+	}
+	return frames
+}
+
+// SaveGIF saves an animated gif of the object similarity heatmaps over the history
+// of given layer to given file name
+func (dr *Drift) SaveGIF(lnm, fname string) error {
+	frames := dr.Frames(lnm)
+	if len(frames) == 0 {
+		return nil
+	}
+	ag := &gif.GIF{}
+	for _, fr := range frames {
+		ag.Image = append(ag.Image, fr)
+		ag.Delay = append(ag.Delay, dr.Delay)
+	}
+	fp, err := os.Create(fname)
+	if err != nil {
+		return err
+	}
+	defer fp.Close()
+	return gif.EncodeAll(fp, ag)
+}
+
+// SavePNG saves a png of the last object similarity heatmap of given layer to given file name
+func (dr *Drift) SavePNG(lnm, fname string) error {
+	hist := dr.Hists[lnm]
+	if len(hist) == 0 {
+		return nil
+	}
+	img := HeatmapImage(hist[len(hist)-1].Obj, len(Objs), dr.Cell, 0, 1)
+	fp, err := os.Create(fname)
+	if err != nil {
+		return err
+	}
+	defer fp.Close()
+	return png.Encode(fp, img)
+}
+
+// HeatmapImage returns a heatmap image of the n x n matrix values, with each
+// value drawn as a cell x cell square, using the ViridisPalette over min..max
+func HeatmapImage(vals []float64, n, cell int, min, max float64) *image.Paletted {
+	pal := ViridisPalette()
+	sz := n * cell
+	img := image.NewPaletted(image.Rect(0, 0, sz, sz), pal)
+	rng := max - min
+	for ri := 0; ri < n; ri++ {
+		for ci := 0; ci < n; ci++ {
+			v := 0.0
+			if rng > 0 {
+				v = (vals[ri*n+ci] - min) / rng
+			}
+			if math.IsNaN(v) || v < 0 {
+				v = 0
+			}
+			if v > 1 {
+				v = 1
+			}
+			idx := uint8(v * float64(len(pal)-1))
+			for y := ri * cell; y < (ri+1)*cell; y++ {
+				for x := ci * cell; x < (ci+1)*cell; x++ {
+					img.SetColorIndex(x, y, idx)
+				}
+			}
+		}
+	}
+	return img
+}
+
+// ViridisPalette returns a 256 color palette approximating the Viridis colormap,
+// interpolated between key colors
+func ViridisPalette() color.Palette {
+	keys := []color.RGBA{
+		{68, 1, 84, 255},
+		{59, 82, 139, 255},
+		{33, 145, 140, 255},
+		{94, 201, 98, 255},
+		{253, 231, 37, 255},
+	}
+	nk := len(keys) - 1
+	pal := make(color.Palette, 256)
+	for i := range pal {
+		p := float64(i) / 255 * float64(nk)
+		ki := int(p)
+		if ki >= nk {
+			ki = nk - 1
+		}
+		f := p - float64(ki)
+		a := keys[ki]
+		b := keys[ki+1]
+		lerp := func(x, y uint8) uint8 {
+			return uint8(math.Round(float64(x) + f*(float64(y)-float64(x))))
+		}
+		pal[i] = color.RGBA{lerp(a.R, b.R), lerp(a.G, b.G), lerp(a.B, b.B), 255}
+	}
+	return pal
+}
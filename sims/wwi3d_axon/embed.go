@@ -0,0 +1,516 @@
+// Copyright (c) 2020, The CCNLab Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+
+	"github.com/emer/etable/etable"
+	"github.com/emer/etable/etensor"
+	"github.com/emer/etable/simat"
+)
+
+// EmbedMethods are the embedding methods computed by Embed, which are
+// used as the prefix for the columns in the embedding tables:
+// PCA = principal components of the activations (classical MDS for a simat),
+// MDS = classical (Torgerson) MDS on the correlation distances,
+// MMDS = metric MDS (SMACOF stress minimization) starting from MDS,
+// TSNE = t-distributed stochastic neighbor embedding.
+var EmbedMethods = []string{"PCA", "MDS", "MMDS", "TSNE"}
+
+// Embed computes low-dimensional embeddings of layer representations,
+// from the CatLayActs table or a similarity matrix, as tables with
+// Cat / Obj / Tick labels and a column for each dimension of each method.
+// Successive embeddings of the same layer can be aligned with Procrustes
+// rotation so the evolution of the representations can be followed over training.
+type Embed struct {
+	Lays       []string                 `desc:"layers to compute embeddings for during training, every RSA.Interval epochs"`
+	Tick       int                      `desc:"tick of CatLayActs rows to embed -- -1 = all ticks"`
+	NDims      int                      `desc:"number of embedding dimensions"`
+	Perplexity float64                  `desc:"t-SNE perplexity -- effective number of neighbors of each item"`
+	TSNEIters  int                      `desc:"number of t-SNE gradient descent iterations"`
+	MDSIters   int                      `desc:"number of SMACOF iterations for metric MDS"`
+	Align      bool                     `desc:"align each new embedding to the previous one for the same layer (Procrustes rotation and translation), so category separation can be followed across epochs"`
+	AlignScale bool                     `viewif:"Align" desc:"also scale each new embedding to the previous one when aligning -- hides changes in the overall spread of the representations"`
+	Seed       int64                    `desc:"random seed for t-SNE initialization"`
+	Embeds     map[string]*etable.Table `desc:"most recent embedding for each layer or simat"`
+	Hists      map[string]*etable.Table `desc:"history of all embeddings for each layer or simat, with Epoch column"`
+}
+
+func (em *Embed) Defaults() {
+	em.Lays = []string{"TE"}
+	em.Tick = 2
+	em.NDims = 2
+	em.Perplexity = 20
+	em.TSNEIters = 500
+	em.MDSIters = 100
+	em.Align = true
+	em.Seed = 1
+}
+
+// Table returns the embedding table for given name, creating if not yet made
+func (em *Embed) Table(name string) *etable.Table {
+	if em.Embeds == nil {
+		em.Embeds = make(map[string]*etable.Table)
+		em.Hists = make(map[string]*etable.Table)
+	}
+	dt, ok := em.Embeds[name]
+	if !ok {
+		dt = &etable.Table{}
+		em.ConfigTable(dt, name)
+		em.Embeds[name] = dt
+		hdt := &etable.Table{}
+		em.ConfigTable(hdt, name)
+		em.Hists[name] = hdt
+	}
+	return dt
+}
+
+// ConfigTable configures an embedding table with no rows
+func (em *Embed) ConfigTable(dt *etable.Table, name string) {
+	dt.SetMetaData("name", name+"Embed")
+	dt.SetMetaData("desc", "low-dimensional embeddings of "+name+" representations")
+	dt.SetMetaData("read-only", "true")
+	sch := etable.Schema{
+		{"Epoch", etensor.INT64, nil, nil},
+		{"Cat", etensor.STRING, nil, nil},
+		{"Obj", etensor.STRING, nil, nil},
+		{"Tick", etensor.INT64, nil, nil},
+	}
+	for _, mth := range EmbedMethods {
+		for d := 0; d < em.NDims; d++ {
+			sch = append(sch, etable.Column{fmt.Sprintf("%s_%d", mth, d), etensor.FLOAT64, nil, nil})
+		}
+	}
+	dt.SetFromSchema(sch, 0)
+}
+
+// FmActs computes embeddings of given layer column in CatLayActs-style acts table,
+// for the Tick rows, recording given epoch.  Returns the embedding table.
+func (em *Embed) FmActs(acts *etable.Table, lay string, epc int) *etable.Table {
+	var rows []int
+	var vecs [][]float64
+	for row := 0; row < acts.Rows; row++ {
+		if em.Tick >= 0 && int(acts.CellFloat("Tick", row)) != em.Tick {
+			continue
+		}
+		tsr := acts.CellTensor(lay, row)
+		v := make([]float64, tsr.Len())
+		for i := range v {
+			v[i] = tsr.FloatVal1D(i)
+		}
+		vecs = append(vecs, v)
+		rows = append(rows, row)
+	}
+	n := len(rows)
+	cats := make([]string, n)
+	objs := make([]string, n)
+	ticks := make([]int, n)
+	for i, row := range rows {
+		cats[i] = acts.CellString("Cat", row)
+		objs[i] = acts.CellString("Obj", row)
+		ticks[i] = int(acts.CellFloat("Tick", row))
+	}
+	crds := make(map[string][]float64)
+	if n > 1 {
+		crds["PCA"] = PCACoords(vecs, em.NDims)
+		nv := NormVecs(vecs)
+		em.FmDists(crds, CrossTickDists(nv, nv), n)
+	}
+	return em.SetTable(lay, epc, cats, objs, ticks, crds)
+}
+
+// FmSimMat computes embeddings of the items in given simat (which must contain distances),
+// with given category labels for each row, saving under given name and recording
+// given epoch.  PCA is the same as classical MDS here.  Returns the embedding table.
+func (em *Embed) FmSimMat(sm *simat.SimMat, cats []string, name string, epc int) *etable.Table {
+	smat := sm.Mat.(*etensor.Float64)
+	n := smat.Dim(0)
+	if len(cats) != n {
+		cats = make([]string, n)
+	}
+	ticks := make([]int, n)
+	for i := range ticks {
+		ticks[i] = -1
+	}
+	crds := make(map[string][]float64)
+	if n > 1 {
+		em.FmDists(crds, smat.Values, n)
+		crds["PCA"] = crds["MDS"]
+	}
+	return em.SetTable(name, epc, cats, make([]string, n), ticks, crds)
+}
+
+// FmDists computes the distance-based embeddings from n x n distance matrix d
+func (em *Embed) FmDists(crds map[string][]float64, d []float64, n int) {
+	mds := ClassicalMDS(d, n, em.NDims)
+	crds["MDS"] = mds
+	crds["MMDS"] = MetricMDS(d, n, em.NDims, em.MDSIters, mds)
+	crds["TSNE"] = TSNE(d, n, em.NDims, em.Perplexity, em.TSNEIters, em.Seed)
+}
+
+// SetTable sets the embedding table for given name from given labels and method
+// coordinates (n x NDims), aligning to the previous embedding if Align is on
+// and the items are the same, and appends to the history table.
+func (em *Embed) SetTable(name string, epc int, cats, objs []string, ticks []int, crds map[string][]float64) *etable.Table {
+	dt := em.Table(name)
+	n := len(cats)
+	nd := em.NDims
+	same := dt.Rows == n
+	for i := 0; same && i < n; i++ {
+		if dt.CellString("Cat", i) != cats[i] || dt.CellString("Obj", i) != objs[i] || int(dt.CellFloat("Tick", i)) != ticks[i] {
+			same = false
+		}
+	}
+	if em.Align && same {
+		for _, mth := range EmbedMethods {
+			cr, ok := crds[mth]
+			if !ok {
+				continue
+			}
+			prv := make([]float64, n*nd)
+			for i := 0; i < n; i++ {
+				for d := 0; d < nd; d++ {
+					prv[i*nd+d] = dt.CellFloat(fmt.Sprintf("%s_%d", mth, d), i)
+				}
+			}
+			Procrustes(cr, prv, n, nd, em.AlignScale)
+		}
+	}
+	dt.SetNumRows(n)
+	for i := 0; i < n; i++ {
+		dt.SetCellFloat("Epoch", i, float64(epc))
+		dt.SetCellString("Cat", i, cats[i])
+		dt.SetCellString("Obj", i, objs[i])
+		dt.SetCellFloat("Tick", i, float64(ticks[i]))
+		for _, mth := range EmbedMethods {
+			cr, ok := crds[mth]
+			for d := 0; d < nd; d++ {
+				v := 0.0
+				if ok {
+					v = cr[i*nd+d]
+				}
+				dt.SetCellFloat(fmt.Sprintf("%s_%d", mth, d), i, v)
+			}
+		}
+	}
+	hdt := em.Hists[name]
+	st := hdt.Rows
+	hdt.SetNumRows(st + n)
+	for ci, cl := range dt.Cols {
+		hcl := hdt.Cols[ci]
+		for i := 0; i < n; i++ {
+			if cl.DataType() == etensor.STRING {
+				hcl.SetString1D(st+i, cl.StringVal1D(i))
+			} else {
+				hcl.SetFloat1D(st+i, cl.FloatVal1D(i))
+			}
+		}
+	}
+	return dt
+}
+
+// PCACoords returns the projections (n x nd) of the n vectors onto their top nd
+// principal components, computed from the eigenvectors of the centered gram matrix
+func PCACoords(vecs [][]float64, nd int) []float64 {
+	n := len(vecs)
+	k := GramMatrix(vecs)
+	CenterGram(k, n)
+	return EigenCoords(k, n, nd)
+}
+
+// ClassicalMDS returns the classical (Torgerson) MDS coordinates (n x nd)
+// for n x n distance matrix d
+func ClassicalMDS(d []float64, n, nd int) []float64 {
+	b := make([]float64, n*n)
+	for i, dv := range d {
+		b[i] = -0.5 * dv * dv
+	}
+	CenterGram(b, n)
+	return EigenCoords(b, n, nd)
+}
+
+// EigenCoords returns the coordinates (n x nd) of the items in a centered gram
+// matrix k along its top nd eigenvectors, scaled by the sqrt of the eigenvalues
+func EigenCoords(k []float64, n, nd int) []float64 {
+	vals, vecs := TopEigen(k, n, nd)
+	kd := len(vals)
+	crd := make([]float64, n*nd)
+	for d := 0; d < kd; d++ {
+		sc := math.Sqrt(math.Max(vals[d], 0))
+		for i := 0; i < n; i++ {
+			crd[i*nd+d] = sc * vecs[i*kd+d]
+		}
+	}
+	return crd
+}
+
+// MetricMDS returns metric MDS coordinates (n x nd) for n x n distance matrix d,
+// using the SMACOF stress majorization algorithm starting from init coordinates.
+func MetricMDS(d []float64, n, nd, iters int, init []float64) []float64 {
+	x := make([]float64, n*nd)
+	copy(x, init)
+	nx := make([]float64, n*nd)
+	for it := 0; it < iters; it++ {
+		for i := range nx {
+			nx[i] = 0
+		}
+		for i := 0; i < n; i++ {
+			bii := 0.0
+			for j := 0; j < n; j++ {
+				if i == j {
+					continue
+				}
+				dx := 0.0
+				for k := 0; k < nd; k++ {
+					df := x[i*nd+k] - x[j*nd+k]
+					dx += df * df
+				}
+				dx = math.Sqrt(dx)
+				if dx == 0 {
+					continue
+				}
+				bij := -d[i*n+j] / dx
+				bii -= bij
+				for k := 0; k < nd; k++ {
+					nx[i*nd+k] += bij * x[j*nd+k]
+				}
+			}
+			for k := 0; k < nd; k++ {
+				nx[i*nd+k] += bii * x[i*nd+k]
+			}
+		}
+		for i := range x {
+			x[i] = nx[i] / float64(n)
+		}
+	}
+	return x
+}
+
+// TSNE returns the t-SNE embedding (n x nd) for n x n distance matrix d,
+// with given perplexity, using exact gradients (fine for up to a few thousand items).
+func TSNE(d []float64, n, nd int, perp float64, iters int, seed int64) []float64 {
+	p := TSNEProbs(d, n, perp)
+	rnd := rand.New(rand.NewSource(seed))
+	y := make([]float64, n*nd)
+	for i := range y {
+		y[i] = 1.0e-4 * rnd.NormFloat64()
+	}
+	dy := make([]float64, n*nd)
+	upd := make([]float64, n*nd)
+	gains := make([]float64, n*nd)
+	for i := range gains {
+		gains[i] = 1
+	}
+	num := make([]float64, n*n)
+	lrate := math.Max(float64(n)/12, 50)
+	exagIters := iters / 4
+	for it := 0; it < iters; it++ {
+		exag := 1.0
+		mom := 0.8
+		if it < exagIters {
+			exag = 12
+			mom = 0.5
+		}
+		sum := 0.0
+		for i := 0; i < n; i++ {
+			for j := i + 1; j < n; j++ {
+				dd := 0.0
+				for k := 0; k < nd; k++ {
+					df := y[i*nd+k] - y[j*nd+k]
+					dd += df * df
+				}
+				nm := 1 / (1 + dd)
+				num[i*n+j] = nm
+				num[j*n+i] = nm
+				sum += 2 * nm
+			}
+		}
+		for i := range dy {
+			dy[i] = 0
+		}
+		for i := 0; i < n; i++ {
+			for j := 0; j < n; j++ {
+				if i == j {
+					continue
+				}
+				nm := num[i*n+j]
+				mult := 4 * (exag*p[i*n+j] - nm/sum) * nm
+				for k := 0; k < nd; k++ {
+					dy[i*nd+k] += mult * (y[i*nd+k] - y[j*nd+k])
+				}
+			}
+		}
+		for i := range y {
+			if (dy[i] > 0) != (upd[i] > 0) {
+				gains[i] += 0.2
+			} else {
+				gains[i] = math.Max(gains[i]*0.8, 0.01)
+			}
+			upd[i] = mom*upd[i] - lrate*gains[i]*dy[i]
+			y[i] += upd[i]
+		}
+		for k := 0; k < nd; k++ {
+			mn := 0.0
+			for i := 0; i < n; i++ {
+				mn += y[i*nd+k]
+			}
+			mn /= float64(n)
+			for i := 0; i < n; i++ {
+				y[i*nd+k] -= mn
+			}
+		}
+	}
+	return y
+}
+
+// TSNEProbs returns the symmetric t-SNE joint probabilities for n x n distance
+// matrix d, with the gaussian width for each item set by binary search to
+// achieve given perplexity
+func TSNEProbs(d []float64, n int, perp float64) []float64 {
+	p := make([]float64, n*n)
+	trgH := math.Log(perp)
+	row := make([]float64, n)
+	for i := 0; i < n; i++ {
+		beta := 1.0
+		bmin := math.Inf(-1)
+		bmax := math.Inf(1)
+		for tr := 0; tr < 50; tr++ {
+			sum := 0.0
+			for j := 0; j < n; j++ {
+				row[j] = 0
+				if j == i {
+					continue
+				}
+				dd := d[i*n+j] * d[i*n+j]
+				row[j] = math.Exp(-beta * dd)
+				sum += row[j]
+			}
+			if sum == 0 {
+				sum = 1.0e-12
+			}
+			h := 0.0
+			for j := 0; j < n; j++ {
+				if j == i {
+					continue
+				}
+				row[j] /= sum
+				dd := d[i*n+j] * d[i*n+j]
+				h += beta * dd * row[j]
+			}
+			h += math.Log(sum)
+			if math.Abs(h-trgH) < 1.0e-5 {
+				break
+			}
+			if h > trgH {
+				bmin = beta
+				if math.IsInf(bmax, 1) {
+					beta *= 2
+				} else {
+					beta = (beta + bmax) / 2
+				}
+			} else {
+				bmax = beta
+				if math.IsInf(bmin, -1) {
+					beta /= 2
+				} else {
+					beta = (beta + bmin) / 2
+				}
+			}
+		}
+		copy(p[i*n:(i+1)*n], row)
+	}
+	for i := 0; i < n; i++ {
+		for j := i + 1; j < n; j++ {
+			v := math.Max((p[i*n+j]+p[j*n+i])/float64(2*n), 1.0e-12)
+			p[i*n+j] = v
+			p[j*n+i] = v
+		}
+	}
+	return p
+}
+
+// Procrustes aligns the n x nd coordinates x to the target coordinates y
+// in place, by the orthogonal rotation (and reflection) and translation,
+// and uniform scaling if scale, that minimizes the squared distance between them.
+func Procrustes(x, y []float64, n, nd int, scale bool) {
+	xm := make([]float64, nd)
+	ym := make([]float64, nd)
+	for i := 0; i < n; i++ {
+		for k := 0; k < nd; k++ {
+			xm[k] += x[i*nd+k] / float64(n)
+			ym[k] += y[i*nd+k] / float64(n)
+		}
+	}
+	// m = xc^T yc, svd m = u s v^T -> rotation r = u v^T
+	m := make([]float64, nd*nd)
+	for i := 0; i < n; i++ {
+		for a := 0; a < nd; a++ {
+			for b := 0; b < nd; b++ {
+				m[a*nd+b] += (x[i*nd+a] - xm[a]) * (y[i*nd+b] - ym[b])
+			}
+		}
+	}
+	mtm := make([]float64, nd*nd)
+	for a := 0; a < nd; a++ {
+		for b := 0; b < nd; b++ {
+			s := 0.0
+			for c := 0; c < nd; c++ {
+				s += m[c*nd+a] * m[c*nd+b]
+			}
+			mtm[a*nd+b] = s
+		}
+	}
+	vals, v := SymEigen(mtm, nd)
+	u := make([]float64, nd*nd)
+	MatMul(m, nd, nd, v, nd, u)
+	svsum := 0.0
+	for c := 0; c < nd; c++ {
+		sv := math.Sqrt(math.Max(vals[c], 0))
+		if sv < 1.0e-10 {
+			return // degenerate -- leave as is
+		}
+		svsum += sv
+		for a := 0; a < nd; a++ {
+			u[a*nd+c] /= sv
+		}
+	}
+	r := make([]float64, nd*nd)
+	for a := 0; a < nd; a++ {
+		for b := 0; b < nd; b++ {
+			s := 0.0
+			for c := 0; c < nd; c++ {
+				s += u[a*nd+c] * v[b*nd+c]
+			}
+			r[a*nd+b] = s
+		}
+	}
+	sc := 1.0
+	if scale { // least squares scale = trace of the singular values / sum sq of centered x
+		ss := 0.0
+		for i := 0; i < n; i++ {
+			for k := 0; k < nd; k++ {
+				df := x[i*nd+k] - xm[k]
+				ss += df * df
+			}
+		}
+		sc = svsum / ss
+	}
+	xc := make([]float64, nd)
+	for i := 0; i < n; i++ {
+		for k := 0; k < nd; k++ {
+			xc[k] = x[i*nd+k] - xm[k]
+		}
+		for b := 0; b < nd; b++ {
+			s := ym[b]
+			for a := 0; a < nd; a++ {
+				s += sc * xc[a] * r[a*nd+b]
+			}
+			x[i*nd+b] = s
+		}
+	}
+}
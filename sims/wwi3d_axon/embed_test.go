@@ -0,0 +1,128 @@
+// Copyright (c) 2020, The CCNLab Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+// planarPts returns n random points in the plane (n x 2)
+func planarPts(rnd *rand.Rand, n int) []float64 {
+	pts := make([]float64, n*2)
+	for i := range pts {
+		pts[i] = rnd.NormFloat64()
+	}
+	return pts
+}
+
+// ptDists returns the n x n euclidean distance matrix of the n x nd points
+func ptDists(pts []float64, n, nd int) []float64 {
+	d := make([]float64, n*n)
+	for i := 0; i < n; i++ {
+		for j := 0; j < n; j++ {
+			s := 0.0
+			for k := 0; k < nd; k++ {
+				df := pts[i*nd+k] - pts[j*nd+k]
+				s += df * df
+			}
+			d[i*n+j] = math.Sqrt(s)
+		}
+	}
+	return d
+}
+
+func TestProcrustes(t *testing.T) {
+	n := 10
+	rnd := rand.New(rand.NewSource(1))
+	y := planarPts(rnd, n)
+	cases := []struct {
+		ang, sc float64
+		refl    bool
+		tx, ty  float64
+		scale   bool
+		match   bool // aligned x should match y exactly
+	}{
+		{0.7, 1, false, 3, -2, false, true},
+		{-2.1, 1, true, -1, 0.5, false, true},
+		{1.3, 2.5, false, 4, 1, true, true},
+		{1.3, 2.5, false, 4, 1, false, false}, // scaled, so only matches with scale
+	}
+	for ci, c := range cases {
+		cs, sn := math.Cos(c.ang), math.Sin(c.ang)
+		x := make([]float64, n*2)
+		for i := 0; i < n; i++ {
+			px, py := y[i*2], y[i*2+1]
+			if c.refl {
+				py = -py
+			}
+			x[i*2] = c.sc*(cs*px-sn*py) + c.tx
+			x[i*2+1] = c.sc*(sn*px+cs*py) + c.ty
+		}
+		Procrustes(x, y, n, 2, c.scale)
+		mx := 0.0
+		for i := range x {
+			mx = math.Max(mx, math.Abs(x[i]-y[i]))
+		}
+		if c.match && mx > 1.0e-8 {
+			t.Errorf("case %d: aligned coords differ from target by up to %g", ci, mx)
+		}
+		if !c.match && mx < 1.0e-3 {
+			t.Errorf("case %d: scaled coords aligned without scale", ci)
+		}
+	}
+}
+
+// TestClassicalMDS tests that classical MDS reproduces the distances between
+// points in the plane, and the points themselves up to rotation and translation
+func TestClassicalMDS(t *testing.T) {
+	n := 12
+	rnd := rand.New(rand.NewSource(1))
+	pts := planarPts(rnd, n)
+	d := ptDists(pts, n, 2)
+	crd := ClassicalMDS(d, n, 2)
+	md := ptDists(crd, n, 2)
+	for i := range d {
+		if math.Abs(md[i]-d[i]) > 1.0e-8 {
+			t.Fatalf("MDS distance %d, %d: %g, want %g", i/n, i%n, md[i], d[i])
+		}
+	}
+	Procrustes(crd, pts, n, 2, false)
+	for i := range pts {
+		if math.Abs(crd[i]-pts[i]) > 1.0e-8 {
+			t.Fatalf("aligned MDS coord %d: %g, want %g", i, crd[i], pts[i])
+		}
+	}
+}
+
+// TestTSNESeed tests that t-SNE is deterministic for a given seed
+func TestTSNESeed(t *testing.T) {
+	n := 30
+	rnd := rand.New(rand.NewSource(1))
+	pts := make([]float64, n*5)
+	for i := range pts {
+		pts[i] = rnd.NormFloat64()
+	}
+	d := ptDists(pts, n, 5)
+	y1 := TSNE(d, n, 2, 5, 100, 1)
+	y2 := TSNE(d, n, 2, 5, 100, 1)
+	y3 := TSNE(d, n, 2, 5, 100, 2)
+	same3 := true
+	for i := range y1 {
+		if y1[i] != y2[i] {
+			t.Fatalf("same seed: coord %d: %g vs. %g", i, y1[i], y2[i])
+		}
+		if math.IsNaN(y1[i]) {
+			t.Fatalf("coord %d is NaN", i)
+		}
+		if y3[i] != y1[i] {
+			same3 = false
+		}
+	}
+	if same3 {
+		t.Errorf("different seeds gave the same embedding")
+	}
+}
@@ -0,0 +1,489 @@
+// Copyright (c) 2020, The CCNLab Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"math"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/emer/etable/etable"
+	"github.com/emer/etable/etensor"
+	"github.com/emer/etable/metric"
+	"github.com/emer/etable/simat"
+)
+
+// ExtRSA holds representational data from an external source, e.g., PredNet,
+// backprop models, or neural recordings (monkey IT), mapped onto the canonical
+// Objs so that it can be compared against the model layers with the same stats.
+// The data can be either an item x item RDM, or an item x unit activation matrix.
+type ExtRSA struct {
+	Name      string        `desc:"name of this data, by default the base file name"`
+	Labels    []string      `desc:"original label for each item (row) that was mapped onto an object"`
+	Objs      []string      `desc:"canonical object name (from Objs) for each item"`
+	Acts      [][]float64   `view:"-" desc:"activation vector for each item, if loaded from an activation matrix -- nil for RDMs"`
+	Sim       *simat.SimMat `desc:"full item x item distance matrix"`
+	ObjSim    *simat.SimMat `desc:"Objs x Objs distance matrix, averaging over items of each object, normalized to max = 1"`
+	Cat5Sim   *simat.SimMat `desc:"full distance matrix organized into LbaCats5 categories and sorted"`
+	CatDist   float64       `desc:"AvgContrastDist under LbaCats5 centroid meta categories -- same as RSA CatDists"`
+	BasicDist float64       `desc:"AvgBasicDist -- basic-level (object) distance -- same as RSA BasicDists"`
+	ExptDist  float64       `desc:"cross-entropy distance of ObjSim from expt data -- same as RSA ExptDists"`
+	PermNCats int           `desc:"number of categories remaining after permutation from LbaCats5"`
+	PermDist  float64       `desc:"avg contrast dist for permutation"`
+	Cmp       *etable.Table `view:"no-inline" desc:"comparison against each model layer: RDMCor = correlation of ObjSim with the layer's ObjSim, and LinCKA, RBFCKA = CKA of object-averaged activations (only for activation data)"`
+}
+
+// ExtByName returns the ExtRSA of given name, making a new one if not yet present
+func (rs *RSA) ExtByName(nm string) *ExtRSA {
+	if rs.Exts == nil {
+		rs.Exts = make(map[string]*ExtRSA)
+	}
+	ex, ok := rs.Exts[nm]
+	if !ok || ex == nil {
+		ex = &ExtRSA{Name: nm}
+		rs.Exts[nm] = ex
+	}
+	return ex
+}
+
+// OpenExt opens external RDM or activation data from given file (.tsv, .csv or .npy)
+// with a separate label file giving the object label for each item (row),
+// and computes the standard RSA stats on it.  The data is stored in Exts
+// under given name (base file name if empty).  Items whose label does not map
+// onto one of the canonical Objs (see ObjFmLabel) are dropped.
+// If the data matrix is square with the same number of rows as labels, and
+// symmetric, it is treated as an RDM (if its diagonal is ~1 it is a similarity
+// matrix and is converted into 1 - sim), otherwise it is an activation
+// matrix with one row per item (or one column per item, if transposed).
+func (rs *RSA) OpenExt(nm, fname, lblfname string) (*ExtRSA, error) {
+	if nm == "" {
+		nm = strings.TrimSuffix(filepath.Base(fname), filepath.Ext(fname))
+	}
+	vals, shape, err := OpenMatrix(fname)
+	if err != nil {
+		return nil, err
+	}
+	lbls, err := OpenLabels(lblfname)
+	if err != nil {
+		return nil, err
+	}
+	nl := len(lbls)
+	if len(shape) != 2 {
+		return nil, fmt.Errorf("OpenExt: %s: data must be a 2D matrix, has shape: %v", fname, shape)
+	}
+	nr, nc := shape[0], shape[1]
+	isRDM := nr == nc && nr == nl && IsSymmetric(vals, nr)
+	if !isRDM && nr != nl {
+		if nc != nl {
+			return nil, fmt.Errorf("OpenExt: %s: shape %v does not match number of labels: %d", fname, shape, nl)
+		}
+		vals = Transpose(vals, nr, nc)
+		nr, nc = nc, nr
+	}
+
+	var keep []int
+	ex := rs.ExtByName(nm)
+	ex.Labels = nil
+	ex.Objs = nil
+	for i, lb := range lbls {
+		obj := ObjFmLabel(lb)
+		if obj == "" {
+			log.Printf("OpenExt: %s: label: %s not found in Objs -- skipping\n", nm, lb)
+			continue
+		}
+		keep = append(keep, i)
+		ex.Labels = append(ex.Labels, lb)
+		ex.Objs = append(ex.Objs, obj)
+	}
+	n := len(keep)
+	if n == 0 {
+		return nil, fmt.Errorf("OpenExt: %s: no labels map onto Objs", nm)
+	}
+	if missing := MissingObjs(ex.Objs); len(missing) > 0 {
+		log.Printf("OpenExt: %s: no items for objects: %v -- obj-level stats are only partial\n", nm, missing)
+	}
+
+	if ex.Sim == nil {
+		ex.Sim = &simat.SimMat{}
+		ex.ObjSim = &simat.SimMat{}
+		ex.Cat5Sim = &simat.SimMat{}
+	}
+	sm := ex.Sim
+	sm.Init()
+	rs.ConfigSimMat(sm)
+	smat := sm.Mat.(*etensor.Float64)
+	smat.SetShape([]int{n, n}, nil, nil)
+	if isRDM {
+		ex.Acts = nil
+		dmean := 0.0
+		for i := 0; i < nr; i++ {
+			dmean += vals[i*nr+i]
+		}
+		dmean /= float64(nr)
+		sim := dmean > 0.5
+		if sim {
+			log.Printf("OpenExt: %s: diagonal is ~1: converting similarities into 1 - sim distances\n", nm)
+		}
+		for ri, r := range keep {
+			for ci, c := range keep {
+				v := vals[r*nr+c]
+				if sim {
+					v = 1 - v
+				}
+				smat.Values[ri*n+ci] = v
+			}
+		}
+	} else {
+		ex.Acts = make([][]float64, n)
+		for ri, r := range keep {
+			ex.Acts[ri] = append([]float64(nil), vals[r*nc:(r+1)*nc]...)
+		}
+		nv := make([][]float64, n)
+		for i, v := range ex.Acts {
+			nv[i] = append([]float64(nil), v...)
+		}
+		NormVecs(nv)
+		copy(smat.Values, CrossTickDists(nv, nv))
+	}
+	sm.Rows = simat.BlankRepeat(ex.Objs)
+	sm.Cols = sm.Rows
+	rs.ExtStats(ex)
+	return ex, nil
+}
+
+// ExtStats computes the standard RSA stats on given ExtRSA Sim matrix
+func (rs *RSA) ExtStats(ex *ExtRSA) {
+	sm := ex.Sim
+	rs.ObjSimMat(ex.ObjSim, sm, ex.Objs)
+	expt := rs.SimByName("Expt1")
+	ex.ExptDist = metric.CrossEntropy64(ex.ObjSim.Mat.(*etensor.Float64).Values, expt.Mat.(*etensor.Float64).Values)
+	ex.CatDist = -rs.AvgContrastDist(sm, ex.Objs, LbaCats5)
+	ex.BasicDist = rs.AvgBasicDist(sm, ex.Objs)
+	rs.CatSortSimMat(sm, ex.Cat5Sim, ex.Objs, LbaCats5, true, ex.Name+"_LbaCat")
+	_, ex.PermNCats, ex.PermDist = rs.PermuteCatTest(sm, ex.Objs, LbaCats5, ex.Name+"perm")
+}
+
+// ExtCmpFmActs compares given ExtRSA against each of given layers in acts table,
+// at the RSA Tick, into the ex.Cmp table.  The RDMs are compared at the object level
+// (ObjSim), as the items generally differ between models.  For activation data, CKA
+// is computed on the activations averaged over the items of each object.
+func (rs *RSA) ExtCmpFmActs(ex *ExtRSA, acts *etable.Table, lays []string) {
+	lays = LaysInActs(acts, lays)
+	sch := etable.Schema{
+		{"Lay", etensor.STRING, nil, nil},
+		{"RDMCor", etensor.FLOAT64, nil, nil},
+		{"LinCKA", etensor.FLOAT64, nil, nil},
+		{"RBFCKA", etensor.FLOAT64, nil, nil},
+	}
+	if ex.Cmp == nil {
+		ex.Cmp = &etable.Table{}
+	}
+	dt := ex.Cmp
+	dt.SetFromSchema(sch, len(lays))
+	objs := ActsObjs(acts, rs.Tick)
+	for i, on := range objs {
+		objs[i] = ObjFmLabel(on)
+	}
+	exov := ex.ObjSim.Mat.(*etensor.Float64).Values
+	var exreps *LayReps
+	if ex.Acts != nil {
+		exreps = LayRepsFmVecs(ObjMeanVecs(ex.Acts, ex.Objs), rs.RBFSigma)
+	}
+	lsm := &simat.SimMat{}
+	lsm.Init()
+	losm := &simat.SimMat{}
+	for li, lnm := range lays {
+		dt.SetCellString("Lay", li, lnm)
+		vecs := TickActs(acts, lnm, rs.Tick)
+		n := len(vecs)
+		if n == 0 {
+			continue
+		}
+		lsmat := lsm.Mat.(*etensor.Float64)
+		lsmat.SetShape([]int{n, n}, nil, nil)
+		lsm.Rows = objs
+		lsm.Cols = objs
+		nv := make([][]float64, n)
+		for i, v := range vecs {
+			nv[i] = append([]float64(nil), v...)
+		}
+		NormVecs(nv)
+		copy(lsmat.Values, CrossTickDists(nv, nv))
+		rs.ObjSimMat(losm, lsm, objs)
+		dt.SetCellFloat("RDMCor", li, metric.Correlation64(exov, losm.Mat.(*etensor.Float64).Values))
+		if exreps == nil {
+			continue
+		}
+		lreps := LayRepsFmVecs(ObjMeanVecs(vecs, objs), rs.RBFSigma)
+		dt.SetCellFloat("LinCKA", li, GramAlign(exreps.Lin, lreps.Lin))
+		dt.SetCellFloat("RBFCKA", li, GramAlign(exreps.RBF, lreps.RBF))
+	}
+}
+
+// ObjMeanVecs returns the average of given vectors over the items of each
+// of the canonical Objs, given the object name for each vector.
+// Objects with no items have all-zero vectors.
+func ObjMeanVecs(vecs [][]float64, objs []string) [][]float64 {
+	no := len(Objs)
+	nu := len(vecs[0])
+	mv := make([][]float64, no)
+	cnt := make([]int, no)
+	for oi := range mv {
+		mv[oi] = make([]float64, nu)
+	}
+	for i, v := range vecs {
+		oi, ok := ObjIdxs[objs[i]]
+		if !ok {
+			continue
+		}
+		cnt[oi]++
+		for ui, a := range v {
+			mv[oi][ui] += a
+		}
+	}
+	for oi, v := range mv {
+		if cnt[oi] == 0 {
+			continue
+		}
+		for ui := range v {
+			v[ui] /= float64(cnt[oi])
+		}
+	}
+	return mv
+}
+
+// MissingObjs returns those of the canonical Objs that are not in given list
+func MissingObjs(objs []string) []string {
+	has := make(map[string]bool, len(objs))
+	for _, o := range objs {
+		has[o] = true
+	}
+	var missing []string
+	for _, o := range Objs {
+		if !has[o] {
+			missing = append(missing, o)
+		}
+	}
+	return missing
+}
+
+// ObjFmLabel returns the canonical object name (from Objs) for given external item label,
+// which can be an object name, or a name with an instance suffix (layercake_003),
+// a category/instance path (banana/banana_001), or a file name starting with the object
+// name -- case and surrounding quotes are ignored.  Returns "" if no match.
+func ObjFmLabel(lbl string) string {
+	lb := strings.ToLower(strings.Trim(strings.TrimSpace(lbl), `"'`))
+	if _, ok := ObjIdxs[lb]; ok {
+		return lb
+	}
+	for _, sep := range []string{"/", "_", " ", "-", "."} {
+		if si := strings.Index(lb, sep); si > 0 {
+			if _, ok := ObjIdxs[lb[:si]]; ok {
+				return lb[:si]
+			}
+		}
+	}
+	best := ""
+	for _, o := range Objs {
+		if strings.HasPrefix(lb, o) && len(o) > len(best) {
+			best = o
+		}
+	}
+	return best
+}
+
+// LabelCols are the names of label file columns that are used for item labels,
+// in order of preference -- group_name is the cemer simat label format
+var LabelCols = []string{"group_name", "Obj", "obj", "object", "Object", "label", "Label", "name", "Name", "Cat", "cat", "categ"}
+
+// OpenLabels opens a label file, returning one label per item.  The file can be a single
+// line of comma or tab separated labels (e.g., prednet labels), or one label per line,
+// optionally with a header line, in which case the first column named in LabelCols is used
+// (e.g., group_name in cemer simat label files) -- otherwise the first column is used.
+func OpenLabels(fname string) ([]string, error) {
+	recs, err := OpenDelimRecords(fname)
+	if err != nil {
+		return nil, err
+	}
+	if len(recs) == 0 {
+		return nil, fmt.Errorf("OpenLabels: %s: no labels", fname)
+	}
+	if len(recs) == 1 {
+		return recs[0], nil
+	}
+	col := 0
+	hdr := recs[0]
+	fnd := false
+	for _, lc := range LabelCols {
+		for ci, h := range hdr {
+			if h == lc {
+				col = ci
+				fnd = true
+				break
+			}
+		}
+		if fnd {
+			break
+		}
+	}
+	if fnd {
+		recs = recs[1:]
+	}
+	lbls := make([]string, len(recs))
+	for i, rec := range recs {
+		if col < len(rec) {
+			lbls[i] = rec[col]
+		}
+	}
+	return lbls, nil
+}
+
+// OpenMatrix opens a 2D numeric matrix from a .npy, .csv (comma separated)
+// or other (tab separated) text file, returning the values in row-major order
+// and the shape.  For text files, any leading non-numeric header rows or label
+// columns are skipped.
+func OpenMatrix(fname string) ([]float64, []int, error) {
+	b, err := ioutil.ReadFile(fname)
+	if err != nil {
+		return nil, nil, err
+	}
+	if IsNpy(b) {
+		vals, shape, err := OpenNpy(fname)
+		if err == nil && len(shape) == 1 {
+			shape = []int{shape[0], 1}
+		}
+		return vals, shape, err
+	}
+	recs, err := DelimRecords(string(b), DelimFmFileName(fname))
+	if err != nil {
+		return nil, nil, err
+	}
+	var vals []float64
+	nr, nc := 0, 0
+	for _, rec := range recs {
+		var rv []float64
+		for _, fs := range rec {
+			fs = strings.TrimSpace(fs)
+			if fs == "" {
+				continue
+			}
+			v, perr := strconv.ParseFloat(fs, 64)
+			if perr != nil {
+				if len(rv) == 0 { // row label
+					continue
+				}
+				v = math.NaN()
+			}
+			rv = append(rv, v)
+		}
+		if len(rv) == 0 { // header
+			continue
+		}
+		if nc == 0 {
+			nc = len(rv)
+		} else if len(rv) != nc {
+			return nil, nil, fmt.Errorf("OpenMatrix: %s: row %d has %d values, expected %d", fname, nr, len(rv), nc)
+		}
+		vals = append(vals, rv...)
+		nr++
+	}
+	return vals, []int{nr, nc}, nil
+}
+
+// OpenDelimRecords opens a comma (.csv) or tab separated file and returns its records
+func OpenDelimRecords(fname string) ([][]string, error) {
+	b, err := ioutil.ReadFile(fname)
+	if err != nil {
+		return nil, err
+	}
+	return DelimRecords(string(b), DelimFmFileName(fname))
+}
+
+// DelimRecords parses given comma or tab separated text into records,
+// skipping blank lines, and allowing a variable number of fields per record
+func DelimRecords(s string, delim rune) ([][]string, error) {
+	rd := csv.NewReader(strings.NewReader(s))
+	rd.Comma = delim
+	rd.FieldsPerRecord = -1
+	rd.LazyQuotes = true
+	rd.TrimLeadingSpace = true
+	return rd.ReadAll()
+}
+
+// DelimFmFileName returns the comma delimiter for .csv files, and tab otherwise
+func DelimFmFileName(fname string) rune {
+	if strings.ToLower(filepath.Ext(fname)) == ".csv" {
+		return etable.Comma.Rune()
+	}
+	return etable.Tab.Rune()
+}
+
+// IsSymmetric returns true if the n x n matrix is symmetric (within tolerance)
+func IsSymmetric(vals []float64, n int) bool {
+	for i := 0; i < n; i++ {
+		for j := i + 1; j < n; j++ {
+			a := vals[i*n+j]
+			b := vals[j*n+i]
+			if math.Abs(a-b) > 1.0e-5*(1+math.Abs(a)+math.Abs(b)) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// Transpose returns the transpose of the nr x nc matrix
+func Transpose(vals []float64, nr, nc int) []float64 {
+	tv := make([]float64, len(vals))
+	for r := 0; r < nr; r++ {
+		for c := 0; c < nc; c++ {
+			tv[c*nr+r] = vals[r*nc+c]
+		}
+	}
+	return tv
+}
+
+// ExtNames returns the sorted names of the Exts
+func (rs *RSA) ExtNames() []string {
+	nms := make([]string, 0, len(rs.Exts))
+	for nm := range rs.Exts {
+		nms = append(nms, nm)
+	}
+	sort.Strings(nms)
+	return nms
+}
+
+// ExtStatsTable returns a table with the standard RSA stats for each of the Exts
+func (rs *RSA) ExtStatsTable() *etable.Table {
+	nms := rs.ExtNames()
+	sch := etable.Schema{
+		{"Name", etensor.STRING, nil, nil},
+		{"NItems", etensor.INT64, nil, nil},
+		{"CatDist", etensor.FLOAT64, nil, nil},
+		{"BasicDist", etensor.FLOAT64, nil, nil},
+		{"ExptDist", etensor.FLOAT64, nil, nil},
+		{"PermNCats", etensor.INT64, nil, nil},
+		{"PermDist", etensor.FLOAT64, nil, nil},
+	}
+	dt := &etable.Table{}
+	dt.SetFromSchema(sch, len(nms))
+	for i, nm := range nms {
+		ex := rs.Exts[nm]
+		dt.SetCellString("Name", i, nm)
+		dt.SetCellFloat("NItems", i, float64(len(ex.Objs)))
+		dt.SetCellFloat("CatDist", i, ex.CatDist)
+		dt.SetCellFloat("BasicDist", i, ex.BasicDist)
+		dt.SetCellFloat("ExptDist", i, ex.ExptDist)
+		dt.SetCellFloat("PermNCats", i, float64(ex.PermNCats))
+		dt.SetCellFloat("PermDist", i, ex.PermDist)
+	}
+	return dt
+}
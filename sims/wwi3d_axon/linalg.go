@@ -0,0 +1,262 @@
+// Copyright (c) 2020, The CCNLab Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"math"
+	"math/rand"
+	"sort"
+)
+
+// small dense linear algebra routines on row-major []float64 matricies,
+// used by the probe and embedding analyses.
+
+// CholSolve solves a x = b for symmetric positive definite n x n matrix a,
+// and n x m matrix b, using the Cholesky decomposition.  a is overwritten
+// with the decomposition and b with the solution x.
+// returns false if a is not positive definite.
+func CholSolve(a []float64, n int, b []float64, m int) bool {
+	for j := 0; j < n; j++ {
+		s := a[j*n+j]
+		for k := 0; k < j; k++ {
+			s -= a[j*n+k] * a[j*n+k]
+		}
+		if s <= 0 {
+			return false
+		}
+		d := math.Sqrt(s)
+		a[j*n+j] = d
+		for i := j + 1; i < n; i++ {
+			s := a[i*n+j]
+			for k := 0; k < j; k++ {
+				s -= a[i*n+k] * a[j*n+k]
+			}
+			a[i*n+j] = s / d
+		}
+	}
+	for c := 0; c < m; c++ {
+		// forward: L y = b
+		for i := 0; i < n; i++ {
+			s := b[i*m+c]
+			for k := 0; k < i; k++ {
+				s -= a[i*n+k] * b[k*m+c]
+			}
+			b[i*m+c] = s / a[i*n+i]
+		}
+		// backward: L^T x = y
+		for i := n - 1; i >= 0; i-- {
+			s := b[i*m+c]
+			for k := i + 1; k < n; k++ {
+				s -= a[k*n+i] * b[k*m+c]
+			}
+			b[i*m+c] = s / a[i*n+i]
+		}
+	}
+	return true
+}
+
+// GramMatrix returns the n x n matrix of dot products between the n vectors
+func GramMatrix(vecs [][]float64) []float64 {
+	n := len(vecs)
+	k := make([]float64, n*n)
+	for i := 0; i < n; i++ {
+		for j := i; j < n; j++ {
+			dp := 0.0
+			for ui, a := range vecs[i] {
+				dp += a * vecs[j][ui]
+			}
+			k[i*n+j] = dp
+			k[j*n+i] = dp
+		}
+	}
+	return k
+}
+
+// SymEigen computes all the eigenvalues and eigenvectors of the symmetric
+// n x n matrix a using the cyclic Jacobi method -- a is not modified.
+// Returns the eigenvalues in descending order, and the corresponding
+// eigenvectors as the columns of the n x n matrix vecs.
+func SymEigen(a []float64, n int) (vals, vecs []float64) {
+	m := make([]float64, n*n)
+	copy(m, a)
+	v := make([]float64, n*n)
+	for i := 0; i < n; i++ {
+		v[i*n+i] = 1
+	}
+	for sweep := 0; sweep < 100; sweep++ {
+		off := 0.0
+		tot := 0.0
+		for p := 0; p < n; p++ {
+			for q := 0; q < n; q++ {
+				sq := m[p*n+q] * m[p*n+q]
+				tot += sq
+				if p != q {
+					off += sq
+				}
+			}
+		}
+		if off <= 1.0e-24*tot {
+			break
+		}
+		for p := 0; p < n-1; p++ {
+			for q := p + 1; q < n; q++ {
+				apq := m[p*n+q]
+				if apq == 0 {
+					continue
+				}
+				theta := (m[q*n+q] - m[p*n+p]) / (2 * apq)
+				t := 1.0 / (math.Abs(theta) + math.Sqrt(theta*theta+1))
+				if theta < 0 {
+					t = -t
+				}
+				c := 1 / math.Sqrt(t*t+1)
+				s := t * c
+				for k := 0; k < n; k++ {
+					akp := m[k*n+p]
+					akq := m[k*n+q]
+					m[k*n+p] = c*akp - s*akq
+					m[k*n+q] = s*akp + c*akq
+				}
+				for k := 0; k < n; k++ {
+					apk := m[p*n+k]
+					aqk := m[q*n+k]
+					m[p*n+k] = c*apk - s*aqk
+					m[q*n+k] = s*apk + c*aqk
+				}
+				for k := 0; k < n; k++ {
+					vkp := v[k*n+p]
+					vkq := v[k*n+q]
+					v[k*n+p] = c*vkp - s*vkq
+					v[k*n+q] = s*vkp + c*vkq
+				}
+			}
+		}
+	}
+	idx := make([]int, n)
+	for i := range idx {
+		idx[i] = i
+	}
+	sort.Slice(idx, func(i, j int) bool {
+		return m[idx[i]*n+idx[i]] > m[idx[j]*n+idx[j]]
+	})
+	vals = make([]float64, n)
+	vecs = make([]float64, n*n)
+	for ci, i := range idx {
+		vals[ci] = m[i*n+i]
+		for k := 0; k < n; k++ {
+			vecs[k*n+ci] = v[k*n+i]
+		}
+	}
+	return
+}
+
+// TopEigen computes the top k eigenvalues and eigenvectors of the symmetric
+// positive semi-definite n x n matrix a.  Small matricies use SymEigen, and
+// larger ones use subspace iteration with a Rayleigh-Ritz projection.
+// Returns the k eigenvalues in descending order, and the eigenvectors
+// as the columns of the n x k matrix vecs.
+func TopEigen(a []float64, n, k int) (vals, vecs []float64) {
+	if k > n {
+		k = n
+	}
+	if n <= 200 {
+		avals, avecs := SymEigen(a, n)
+		vecs = make([]float64, n*k)
+		for i := 0; i < n; i++ {
+			copy(vecs[i*k:(i+1)*k], avecs[i*n:i*n+k])
+		}
+		return avals[:k], vecs
+	}
+	b := k + 8
+	if b > n {
+		b = n
+	}
+	rnd := rand.New(rand.NewSource(1))
+	q := make([]float64, n*b)
+	for i := range q {
+		q[i] = rnd.NormFloat64()
+	}
+	Orthonormalize(q, n, b)
+	z := make([]float64, n*b)
+	for it := 0; it < 200; it++ {
+		MatMul(a, n, n, q, b, z)
+		copy(q, z)
+		Orthonormalize(q, n, b)
+	}
+	// Rayleigh-Ritz: t = q^T a q
+	MatMul(a, n, n, q, b, z)
+	t := make([]float64, b*b)
+	for i := 0; i < b; i++ {
+		for j := 0; j < b; j++ {
+			s := 0.0
+			for r := 0; r < n; r++ {
+				s += q[r*b+i] * z[r*b+j]
+			}
+			t[i*b+j] = s
+		}
+	}
+	tvals, tvecs := SymEigen(t, b)
+	vecs = make([]float64, n*k)
+	for r := 0; r < n; r++ {
+		for c := 0; c < k; c++ {
+			s := 0.0
+			for j := 0; j < b; j++ {
+				s += q[r*b+j] * tvecs[j*b+c]
+			}
+			vecs[r*k+c] = s
+		}
+	}
+	return tvals[:k], vecs
+}
+
+// MatMul computes c = a b for n x m matrix a and m x k matrix b, into n x k c
+func MatMul(a []float64, n, m int, b []float64, k int, c []float64) {
+	for i := 0; i < n; i++ {
+		for j := 0; j < k; j++ {
+			s := 0.0
+			for l := 0; l < m; l++ {
+				s += a[i*m+l] * b[l*k+j]
+			}
+			c[i*k+j] = s
+		}
+	}
+}
+
+// Orthonormalize makes the k columns of n x k matrix q orthonormal,
+// using modified Gram-Schmidt with reorthogonalization.  Columns that
+// are linearly dependent on the previous ones are set to zero.
+func Orthonormalize(q []float64, n, k int) {
+	for c := 0; c < k; c++ {
+		onrm := 0.0
+		for r := 0; r < n; r++ {
+			onrm += q[r*k+c] * q[r*k+c]
+		}
+		for pass := 0; pass < 2; pass++ {
+			for p := 0; p < c; p++ {
+				dp := 0.0
+				for r := 0; r < n; r++ {
+					dp += q[r*k+c] * q[r*k+p]
+				}
+				for r := 0; r < n; r++ {
+					q[r*k+c] -= dp * q[r*k+p]
+				}
+			}
+		}
+		nrm := 0.0
+		for r := 0; r < n; r++ {
+			nrm += q[r*k+c] * q[r*k+c]
+		}
+		if nrm <= 1.0e-20*onrm || nrm == 0 {
+			for r := 0; r < n; r++ {
+				q[r*k+c] = 0
+			}
+			continue
+		}
+		nrm = 1 / math.Sqrt(nrm)
+		for r := 0; r < n; r++ {
+			q[r*k+c] *= nrm
+		}
+	}
+}
@@ -0,0 +1,230 @@
+// Copyright (c) 2020, The CCNLab Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"runtime/debug"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/emer/emergent/params"
+)
+
+// GitCommit is the git commit of the source the sim was built from, which can
+// be set at build time with: go build -ldflags "-X main.GitCommit=$(git rev-parse --short HEAD)"
+// -- otherwise the commit of the current directory is used, if it is in a git repository.
+var GitCommit = ""
+
+// Manifest records the provenance of a run, saved as JSON alongside its logs:
+// the command line and note, the params applied, the seeds, the code and module
+// versions, a content hash of the dataset files, the number of procs, and the
+// wall-clock timing of each run.
+type Manifest struct {
+	Name        string          `desc:"name of the network and run, as in the log file names"`
+	Cmd         string          `desc:"subcommand run, e.g., train"`
+	Args        []string        `desc:"full command line"`
+	Note        string          `desc:"user note, from -note"`
+	ParamSet    string          `desc:"ParamSet applied after Base"`
+	XParamSets  []string        `desc:"additional param sets applied after the ParamSet"`
+	Tag         string          `desc:"extra tag in the file names"`
+	Params      []ManifestParam `desc:"the param values in effect on each layer, projection and the sim after Config: the last value applied to each param path by SetParamsSet -- see ParamsRec"`
+	SchedParams []ManifestParam `desc:"the param values applied by the Sched and Conv actions during training, in order, with their Act and Epoch"`
+	GitCommit   string          `desc:"git commit of the sim source -- see GitCommit"`
+	GoVersion   string          `desc:"Go version the sim was built with"`
+	Modules     []string        `desc:"module path@version of the main module and all its dependencies"`
+	Data        []ManifestFile  `desc:"dataset files, with their size and content hash"`
+	NProcs      int             `desc:"number of MPI procs or in-process workers"`
+	Host        string          `desc:"host name of rank 0"`
+	Start       time.Time       `desc:"time the sim started"`
+	End         time.Time       `desc:"time the manifest was last saved"`
+	WallSecs    float64         `desc:"wall-clock seconds from Start to End"`
+	Runs        []ManifestRun   `desc:"seed and timing of each run"`
+}
+
+// ManifestParam is a param value applied to an object, as printed with LogSetParams
+type ManifestParam struct {
+	Set   string `desc:"param set name"`
+	Sel   string `desc:"selector of the param sheet"`
+	Obj   string `desc:"name of the object the param was applied to"`
+	Path  string `desc:"param path"`
+	Val   string `desc:"param value"`
+	Act   string `desc:"for SchedParams, the Sched or Conv action that applied the param, as in the epoch log SchedActs"`
+	Epoch int    `desc:"for SchedParams, the training epoch at the start of which the param was applied"`
+}
+
+// ParamsRec records the params applied to each object, keeping only the value in
+// effect for each param path of each object (the last applied), in the order the
+// paths were first applied
+type ParamsRec struct {
+	Params []ManifestParam `desc:"the params in effect"`
+	idx    map[string]int
+}
+
+// Add adds a param applied to an object, replacing any previous value of its path
+func (pr *ParamsRec) Add(mp ManifestParam) {
+	if pr.idx == nil {
+		pr.idx = make(map[string]int)
+	}
+	key := mp.Obj + " " + mp.Path
+	if i, has := pr.idx[key]; has {
+		pr.Params[i] = mp
+		return
+	}
+	pr.idx[key] = len(pr.Params)
+	pr.Params = append(pr.Params, mp)
+}
+
+// RecParams calls rec with each of given params, in path order, as applied to
+// object obj by selector sel of param set setNm
+func RecParams(rec func(mp ManifestParam), setNm, sel, obj string, pars params.Params) {
+	pts := make([]string, 0, len(pars))
+	for pt := range pars {
+		pts = append(pts, pt)
+	}
+	sort.Strings(pts)
+	for _, pt := range pts {
+		rec(ManifestParam{Set: setNm, Sel: sel, Obj: obj, Path: pt, Val: pars[pt]})
+	}
+}
+
+// SplitSel splits the params of given selector into a sheet with those for the
+// projections (Prjn. paths), and one with the others, for the layers -- nil if none
+func SplitSel(sl *params.Sel) (lay, prj *params.Sheet) {
+	lp, pp := params.Params{}, params.Params{}
+	for pt, v := range sl.Params {
+		if strings.HasPrefix(pt, "Prjn.") {
+			pp[pt] = v
+		} else {
+			lp[pt] = v
+		}
+	}
+	if len(lp) > 0 {
+		lay = &params.Sheet{{Sel: sl.Sel, Desc: sl.Desc, Params: lp}}
+	}
+	if len(pp) > 0 {
+		prj = &params.Sheet{{Sel: sl.Sel, Desc: sl.Desc, Params: pp}}
+	}
+	return
+}
+
+// ManifestFile is a file with its size and the SHA256 hash of its contents
+type ManifestFile struct {
+	Path   string `desc:"path to the file"`
+	Size   int64  `desc:"size of the file in bytes"`
+	SHA256 string `desc:"hex SHA256 hash of the file contents"`
+	Err    string `desc:"error opening or reading the file, if any"`
+}
+
+// ManifestRun is the seed and timing of a run
+type ManifestRun struct {
+	Run      int       `desc:"run number"`
+	Seed     int64     `desc:"random seed of the run"`
+	Start    time.Time `desc:"time the run started"`
+	End      time.Time `desc:"time the run ended -- zero if not ended"`
+	WallSecs float64   `desc:"wall-clock seconds of the run so far"`
+	Epochs   int       `desc:"number of epochs trained"`
+	MSecTrl  float64   `desc:"wall-clock milliseconds per trial in the last epoch"`
+}
+
+// InitBuild sets the build info of the manifest: GitCommit, GoVersion and Modules,
+// the NProcs and Host, and the Start time
+func (mf *Manifest) InitBuild(nprocs int) {
+	mf.GitCommit = GitCommit
+	if mf.GitCommit == "" {
+		if out, err := exec.Command("git", "rev-parse", "--short", "HEAD").Output(); err == nil {
+			mf.GitCommit = strings.TrimSpace(string(out))
+		}
+	}
+	mf.GoVersion = runtime.Version()
+	mf.Modules = nil
+	if bi, ok := debug.ReadBuildInfo(); ok {
+		mf.Modules = append(mf.Modules, bi.Main.Path+"@"+bi.Main.Version)
+		for _, dp := range bi.Deps {
+			if dp.Replace != nil {
+				dp = dp.Replace
+			}
+			mf.Modules = append(mf.Modules, dp.Path+"@"+dp.Version)
+		}
+	}
+	mf.NProcs = nprocs
+	mf.Host, _ = os.Hostname()
+	mf.Start = time.Now()
+}
+
+// AddData adds the given files of a dataset directory, with their content hash
+func (mf *Manifest) AddData(dir string, fnms ...string) {
+	for _, fn := range fnms {
+		mf.Data = append(mf.Data, HashFile(filepath.Join(dir, fn)))
+	}
+}
+
+// StartRun records the start of given run with given seed
+func (mf *Manifest) StartRun(run int, seed int64) {
+	mf.Runs = append(mf.Runs, ManifestRun{Run: run, Seed: seed, Start: time.Now()})
+}
+
+// UpdtRun updates the timing of the current run, with the number of epochs
+// trained and the msec per trial
+func (mf *Manifest) UpdtRun(epcs int, msecTrl float64) {
+	if len(mf.Runs) == 0 {
+		return
+	}
+	mr := &mf.Runs[len(mf.Runs)-1]
+	mr.WallSecs = time.Since(mr.Start).Seconds()
+	mr.Epochs = epcs
+	mr.MSecTrl = msecTrl
+}
+
+// EndRun records the end of the current run
+func (mf *Manifest) EndRun() {
+	if len(mf.Runs) == 0 {
+		return
+	}
+	mr := &mf.Runs[len(mf.Runs)-1]
+	mr.End = time.Now()
+	mr.WallSecs = mr.End.Sub(mr.Start).Seconds()
+}
+
+// Save saves the manifest as indented JSON to given file, updating the End time
+func (mf *Manifest) Save(fname string) error {
+	mf.End = time.Now()
+	mf.WallSecs = mf.End.Sub(mf.Start).Seconds()
+	b, err := json.MarshalIndent(mf, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(fname, append(b, '\n'), 0644)
+}
+
+// HashFile returns the size and SHA256 hash of the contents of given file,
+// with the error in Err if it cannot be read
+func HashFile(fname string) ManifestFile {
+	mf := ManifestFile{Path: fname}
+	fp, err := os.Open(fname)
+	if err != nil {
+		mf.Err = err.Error()
+		return mf
+	}
+	defer fp.Close()
+	h := sha256.New()
+	n, err := io.Copy(h, fp)
+	if err != nil {
+		mf.Err = err.Error()
+		return mf
+	}
+	mf.Size = n
+	mf.SHA256 = hex.EncodeToString(h.Sum(nil))
+	return mf
+}
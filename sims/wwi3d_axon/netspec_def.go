@@ -0,0 +1,311 @@
+// Copyright (c) 2020, The CCNLab Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import "github.com/emer/emergent/relpos"
+
+// DefNetSpec is the default network architecture: the V1 and LIP dorsal path,
+// which is all that is built with LIPOnly, and the rest of the visual hierarchy.
+// The order of layers and projections determines the order of the network.
+var DefNetSpec = NetSpec{
+	Name: "WWI3D",
+	Parts: []NetPart{
+		{Name: "LIP", Desc: "V1 and LIP dorsal path",
+			Lays: []LaySpec{
+				{Name: "V1m", Type: "Input", Shape: []int{8, 8, 5, 4}, Class: "V1"},
+				{Name: "V1h", Type: "Input", Shape: []int{16, 16, 5, 4}, Class: "V1"},
+				{Name: "LIP", Type: "Deep", Shape: []int{16, 16, 1, 1}, PulvName: "LIPP", Drivers: []string{"MTPos"}, Class: "LIP"}, // 4, 4 tiny bit better than 2,2
+				{Name: "MTPos", Type: "Hidden", Shape: []int{16, 16, 1, 1}, Class: "LIP"},
+				{Name: "EyePos", Type: "Input", Shape: []int{21, 21}},
+				{Name: "SacPlan", Type: "Input", Shape: []int{11, 11}, Class: "PopIn"},
+				{Name: "Saccade", Type: "Input", Shape: []int{11, 11}, Class: "PopIn"},
+				{Name: "ObjVel", Type: "Input", Shape: []int{11, 11}, Class: "PopIn"},
+			},
+			Prjns: []PrjnSpec{
+				{Send: "V1h", Recv: "MTPos", Pat: "PoolOneToOne", Type: "Forward", Class: "V1MT"},
+				{Send: "V1m", Recv: "MTPos", Pat: "Prjn4x4Skp2Recip", Type: "Forward", Class: "V1MT"},
+				{Send: "MTPos", Recv: "LIP", Pat: "Prjn3x3Skp1", Type: "Forward", Class: "MTLIP"}, // was PoolOneToOne
+				// {Send: "LIPCT", Recv: "LIPCT", Pat: "Full", Type: "CTCtxt", Class: "CTSelfLIP"}, // only helpful with rel = 2
+
+				{Send: "LIP", Recv: "LIPCT", Pat: "Full", Type: "Set"}, // critical to have full input
+
+				// topo CT <-> P is critical
+				{Send: "LIPCT", Recv: "LIPP", Pat: "Prjn3x3Skp1", Type: "Forward", Class: "CTToPulv"},
+				{Send: "LIPP", Recv: "LIPCT", Pat: "Prjn3x3Skp1", Type: "Forward", Class: "FmPulv2"}, //  FmLIP
+				{Send: "LIPP", Recv: "LIP", Pat: "Full", Type: "Forward", Class: "FmPulv2"},          //  FmLIP
+
+				// lip can really be a pure position rep
+				// {Send: "EyePos", Recv: "LIP", Pat: "Full", Type: "Forward"},  // InitWts sets ss.PrjnGaussTopo
+				// {Send: "SacPlan", Recv: "LIP", Pat: "Full", Type: "Forward"}, // InitWts sets ss.PrjnSigTopo
+				// {Send: "ObjVel", Recv: "LIP", Pat: "Full", Type: "Forward"},  // InitWts sets ss.PrjnSigTopo
+
+				{Send: "Saccade", Recv: "LIPCT", Pat: "Full", Type: "Forward"}, // essential -- InitWts sets ss.PrjnSigTopo
+				{Send: "EyePos", Recv: "LIPCT", Pat: "Full", Type: "Forward"},  // beneficial -- InitWts sets ss.PrjnGaussTopo
+				{Send: "ObjVel", Recv: "LIPCT", Pat: "Full", Type: "Forward"},  // beneficial -- InitWts sets ss.PrjnSigTopo
+			},
+			Pos: []LayPos{
+				{"V1h", relpos.Rel{Rel: relpos.RightOf, Other: "V1m", YAlign: relpos.Front, Space: 2}},
+				{"LIP", relpos.Rel{Rel: relpos.Above, Other: "V1m", XAlign: relpos.Left, YAlign: relpos.Front}},
+				{"LIPCT", relpos.Rel{Rel: relpos.Behind, Other: "LIP", XAlign: relpos.Left, Space: 10}},
+				{"LIPP", relpos.Rel{Rel: relpos.Behind, Other: "LIPCT", XAlign: relpos.Left, Space: 10}},
+				{"MTPos", relpos.Rel{Rel: relpos.Behind, Other: "LIPP", XAlign: relpos.Left, Space: 10}},
+				{"EyePos", relpos.Rel{Rel: relpos.RightOf, Other: "LIP", YAlign: relpos.Front, Space: 2}},
+				{"SacPlan", relpos.Rel{Rel: relpos.Behind, Other: "EyePos", XAlign: relpos.Left, Space: 10}},
+				{"Saccade", relpos.Rel{Rel: relpos.Behind, Other: "SacPlan", XAlign: relpos.Left, Space: 10}},
+				{"ObjVel", relpos.Rel{Rel: relpos.Behind, Other: "Saccade", XAlign: relpos.Left, Space: 10}},
+			},
+		},
+		{Name: "Rest", Desc: "ventral what pathway and dorsal V3, DP, integrated with LIP",
+			Lays: []LaySpec{
+				// note: important for pulvinar to be created first, for weight symmetry init
+				{Name: "V1hP", Type: "TRC", Shape: []int{16, 16, 5, 4}, Drivers: []string{"V1h"}, Class: "V1"},
+				{Name: "V1mP", Type: "TRC", Shape: []int{8, 8, 5, 4}, Drivers: []string{"V1m"}, Class: "V1"},
+				{Name: "V2", Type: "SuperCT", Shape: []int{8, 8, 10, 10}, Class: "V2"}, // v2p largely redundant with v1
+				{Name: "V3", Type: "SuperCT", Shape: []int{4, 4, 10, 10}, Class: "V3"}, // v3p is not really useful for training
+				{Name: "DP", Type: "SuperCT", Shape: []int{1, 1, 10, 10}, Class: "DP"},
+				{Name: "V4", Type: "Deep", Shape: []int{4, 4, 10, 10}, Class: "V4"},
+				{Name: "TEO", Type: "SuperCT", Shape: []int{4, 4, 10, 10}, Class: "TEO"}, // teop not so obviously important
+				{Name: "TE", Type: "SuperCT", Shape: []int{2, 2, 10, 10}, Class: "TE"},
+			},
+			Prjns: []PrjnSpec{
+				// basic super cons
+				{Send: "V1m", Recv: "V2", Pat: "Prjn3x3Skp1", Type: "Forward", Class: "V1V2"},
+				{Send: "V1h", Recv: "V2", Pat: "Prjn4x4Skp2", Type: "Forward", Class: "V1V2"},
+
+				{Send: "V2", Recv: "V4", Pat: "Prjn4x4Skp2", Type: "Forward"},
+				{Send: "V4", Recv: "V2", Pat: "Prjn4x4Skp2Recip", Type: "Back"},
+
+				{Send: "V2", Recv: "V3", Pat: "Prjn4x4Skp2", Type: "Forward"},
+				{Send: "V3", Recv: "V2", Pat: "Prjn4x4Skp2Recip", Type: "Back", Class: "BackStrong"}, // was "BackMax" -- this is critical!
+
+				{Send: "V3", Recv: "DP", Pat: "Full", Type: "Forward"},
+				{Send: "DP", Recv: "V3", Pat: "Full", Type: "Back", Class: "BackStrong"}, // likely key (in 233) -- retest
+
+				{Send: "V4", Recv: "TEO", Pat: "Prjn3x3Skp1", Type: "Forward"}, // 3x3 > full
+				{Send: "TEO", Recv: "V4", Pat: "Prjn3x3Skp1", Type: "Back"},    // todo: test as BackStrong
+
+				{Send: "TEO", Recv: "TE", Pat: "Prjn4x4Skp2", Type: "Forward"}, // 4x4 > full
+				{Send: "TE", Recv: "TEO", Pat: "Prjn4x4Skp2Recip", Type: "Back", Class: "BackWeak"},
+
+				// to LIP -- weak from v2, v3
+
+				{Send: "V2", Recv: "LIP", Pat: "Prjn2x2Skp2Recip", Type: "Forward", Class: "FwdWeak"},
+				{Send: "V3", Recv: "LIP", Pat: "Prjn4x4Skp4Recip", Type: "Forward", Class: "FwdWeak"},
+
+				{Send: "V2CT", Recv: "LIPCT", Pat: "Prjn2x2Skp2Recip", Type: "Forward", Class: "FwdWeak"},
+				{Send: "V3CT", Recv: "LIPCT", Pat: "Prjn4x4Skp4Recip", Type: "Forward", Class: "FwdWeak"},
+
+				// Pulvinar connections
+				{Send: "V2CT", Recv: "V1mP", Pat: "Prjn3x3Skp1", Type: "Back", Class: "ToPulv10"}, // was PoolOneToOne
+				{Send: "V3CT", Recv: "V1mP", Pat: "Prjn4x4Skp2Recip", Type: "Back", Class: "ToPulv2"},
+				{Send: "V4CT", Recv: "V1mP", Pat: "Prjn4x4Skp2Recip", Type: "Back", Class: "ToPulv2"},
+				{Send: "TEOCT", Recv: "V1mP", Pat: "Full", Type: "Back", Class: "ToPulv1"}, // orig is scheduled
+
+				{Send: "V2CT", Recv: "V1hP", Pat: "Prjn4x4Skp2Recip", Type: "Back", Class: "ToPulv10"}, // was 2x2
+				{Send: "V3CT", Recv: "V1hP", Pat: "Prjn8x8Skp4Recip", Type: "Back", Class: "ToPulv2"},
+				{Send: "V4CT", Recv: "V1hP", Pat: "Prjn8x8Skp4Recip", Type: "Back", Class: "ToPulv2"},
+				{Send: "TEOCT", Recv: "V1hP", Pat: "Full", Type: "Back", Class: "ToPulv1"}, // orig is scheduled
+
+				// todo: explore pulv cons here:
+				{Send: "V2CT", Recv: "V4P", Pat: "Prjn4x4Skp2", Type: "Back", Class: "ToPulv5"},  // actually FF
+				{Send: "V3CT", Recv: "V4P", Pat: "Prjn3x3Skp1", Type: "Back", Class: "ToPulv5"},  // actually FF
+				{Send: "V4CT", Recv: "V4P", Pat: "PoolOneToOne", Type: "Back", Class: "ToPulv2"}, // self ct -> p -- cemer -- useful
+				{Send: "TEOCT", Recv: "V4P", Pat: "Full", Type: "Back", Class: "ToPulv2"},
+				// {Send: "TECT", Recv: "V4P", Pat: "Full", Type: "Back", Class: "ToPulv2"}, // todo: test
+
+				// to V2
+
+				// {Send: "V2", Recv: "V2", Pat: "PoolSameUnit", Type: "Lateral"},
+
+				{Send: "V1mP", Recv: "V2", Pat: "PoolOneToOne", Type: "Forward", Class: "FmPulv02"},
+				{Send: "V1hP", Recv: "V2", Pat: "Prjn2x2Skp2", Type: "Forward", Class: "FmPulv02"},
+
+				// cemer: no v2 self
+				// {Send: "V2CT", Recv: "V2CT", Pat: "Prjn3x3Skp1", Type: "CTCtxt", Class: "CTSelfLower"}, // was PoolOneToOne
+				{Send: "V2", Recv: "V2CT", Pat: "Prjn3x3Skp1", Type: "Set", Class: "CTFmSuperLower"},
+
+				{Send: "LIP", Recv: "V2", Pat: "Prjn2x2Skp2", Type: "Back", Class: "BackStrong FmLIP"}, // was Max
+				{Send: "TEOCT", Recv: "V2", Pat: "Prjn4x4Skp2Recip", Type: "Back"},                     // key! .1 def
+
+				// {Send: "TEO", Recv: "V2", Pat: "Prjn4x4Skp2Recip", Type: "Back"}, // too strong of top-down
+
+				// v2ct
+				{Send: "V1mP", Recv: "V2CT", Pat: "Prjn3x3Skp1", Type: "Forward", Class: "FmPulv2"},
+				{Send: "V1hP", Recv: "V2CT", Pat: "Prjn4x4Skp2", Type: "Forward", Class: "FmPulv2"},
+
+				{Send: "LIPCT", Recv: "V2CT", Pat: "Prjn2x2Skp2", Type: "Back", Class: "CTBackMax1 FmLIP"},
+				{Send: "LIPP", Recv: "V2CT", Pat: "Prjn2x2Skp2", Type: "Back", Class: "CTBack FmLIP"},
+				{Send: "V3CT", Recv: "V2CT", Pat: "Prjn4x4Skp2Recip", Type: "Back", Class: "CTBackMax"},
+				{Send: "V4CT", Recv: "V2CT", Pat: "Prjn4x4Skp2Recip", Type: "Back", Class: "CTBackMax"},
+
+				// {Send: "TEOCT", Recv: "V2CT", Pat: "Prjn4x4Skp2Recip", Type: "Back", Class: "CTBackMax"}, // not beneficial
+
+				{Send: "V3", Recv: "V2CT", Pat: "Prjn2x2Skp2Recip", Type: "Back", Class: "SToCTMax"},  // s -> ct leak
+				{Send: "TEO", Recv: "V2CT", Pat: "Prjn4x4Skp2Recip", Type: "Back", Class: "SToCTMax"}, // s -> ct leak -- key @ max
+
+				// to V3
+
+				// {Send: "V3", Recv: "V3", Pat: "PoolSameUnit", Type: "Lateral"},
+
+				{Send: "V1mP", Recv: "V3", Pat: "Prjn4x4Skp2", Type: "Back", Class: "FmPulv2"},
+				{Send: "V1hP", Recv: "V3", Pat: "Prjn8x8Skp4", Type: "Back", Class: "FmPulv2"},
+
+				{Send: "V4", Recv: "V3", Pat: "Prjn3x3Skp1", Type: "Back", Class: "BackStrong"},
+				{Send: "LIP", Recv: "V3", Pat: "Prjn4x4Skp4", Type: "Back", Class: "FmLIP"},
+
+				{Send: "TEO", Recv: "V3", Pat: "Prjn3x3Skp1", Type: "Back"},
+				{Send: "TEOCT", Recv: "V3", Pat: "Prjn3x3Skp1", Type: "Back"},
+
+				// v3ct
+
+				// {Send: "V3CT", Recv: "V3CT", Pat: "Prjn3x3Skp1", Type: "CTCtxt", Class: "CTSelfLower"}, // was PoolOneToOne
+				{Send: "V3", Recv: "V3CT", Pat: "Prjn3x3Skp1", Type: "Set", Class: "CTFmSuperLower"},
+
+				{Send: "V1mP", Recv: "V3CT", Pat: "Prjn4x4Skp2", Type: "Back", Class: "FmPulv2"},
+				{Send: "V1hP", Recv: "V3CT", Pat: "Prjn8x8Skp4", Type: "Back", Class: "FmPulv2"},
+
+				{Send: "LIPCT", Recv: "V3CT", Pat: "Prjn4x4Skp4", Type: "Back", Class: "CTBack FmLIP"},
+				{Send: "DPCT", Recv: "V3CT", Pat: "Full", Type: "Back", Class: "CTBack"},
+				{Send: "V4CT", Recv: "V3CT", Pat: "Prjn3x3Skp1", Type: "Back", Class: "CTBack"},
+				{Send: "TEO", Recv: "V3CT", Pat: "Prjn3x3Skp1", Type: "Back", Class: "BackStrong"}, // was BackMax s -> ct
+
+				// todo: retest again:
+				{Send: "DP", Recv: "V3CT", Pat: "Full", Type: "Back", Class: "SToCT"},
+				{Send: "V4", Recv: "V3CT", Pat: "Prjn3x3Skp1", Type: "Back", Class: "SToCT"}, // s -> ct, 3x3 ok
+
+				// to DP
+
+				{Send: "V1mP", Recv: "DP", Pat: "Full", Type: "Back", Class: "FmPulv2"},
+				{Send: "V1hP", Recv: "DP", Pat: "Full", Type: "Back", Class: "FmPulv2"},
+
+				// {Send: "V2", Recv: "DP", Pat: "Full", Type: "Forward"}, // in cemer; no effect?, expensive
+
+				{Send: "TEO", Recv: "DP", Pat: "Full", Type: "Back"}, // todo: test again
+
+				// dpct
+				{Send: "V1mP", Recv: "DPCT", Pat: "Full", Type: "Back", Class: "FmPulv2"},
+				{Send: "V1hP", Recv: "DPCT", Pat: "Full", Type: "Back", Class: "FmPulv2"},
+
+				{Send: "DPCT", Recv: "DPCT", Pat: "Full", Type: "CTCtxt", Class: "CTSelfLower"}, // not much effect, but consistent
+				{Send: "TEOCT", Recv: "DPCT", Pat: "Full", Type: "Back", Class: "CTBack"},
+
+				// to V4
+
+				// {Send: "V4", Recv: "V4", Pat: "PoolSameUnit", Type: "Lateral"},
+
+				{Send: "V1mP", Recv: "V4", Pat: "Prjn4x4Skp2", Type: "Back", Class: "FmPulv2"},
+				{Send: "V1hP", Recv: "V4", Pat: "Prjn8x8Skp4", Type: "Back", Class: "FmPulv2"},
+
+				// {Send: "TEOCT", Recv: "V4", Pat: "Prjn3x3Skp1", Type: "Back", Class: "CTBack"}, // very not beneficial
+
+				// v4ct
+				{Send: "V4", Recv: "V4CT", Pat: "PoolOneToOne", Type: "Set", Class: "CTFmSuper"},
+				// {Send: "V4CT", Recv: "V4CT", Pat: "PoolOneToOne", Type: "CTCtxt", Class: "CTSelfLower"},
+
+				{Send: "V1mP", Recv: "V4CT", Pat: "Prjn4x4Skp2", Type: "Back", Class: "FmPulv2"},
+				{Send: "V1hP", Recv: "V4CT", Pat: "Prjn8x8Skp4", Type: "Back", Class: "FmPulv2"},
+				{Send: "V4P", Recv: "V4CT", Pat: "PoolOneToOne", Type: "Back", Class: "FmPulv05"}, // todo: 3x3?  useful
+
+				{Send: "TEOCT", Recv: "V4CT", Pat: "Prjn3x3Skp1", Type: "Back", Class: "CTBack"},
+				{Send: "TEO", Recv: "V4CT", Pat: "Prjn3x3Skp1", Type: "Back", Class: "SToCT"}, // s -> ct -- important
+
+				// Prjn4x4Skp2Recip is same as full, but has topo scales -- better
+				{Send: "TECT", Recv: "V4CT", Pat: "Prjn4x4Skp2Recip", Type: "Back", Class: "CTBack"},
+
+				// to TEO
+
+				// {Send: "TEO", Recv: "TEO", Pat: "PoolSameUnit", Type: "Lateral"},
+
+				{Send: "V1mP", Recv: "TEO", Pat: "Full", Type: "Back", Class: "FmPulv1"},
+				{Send: "V1hP", Recv: "TEO", Pat: "Full", Type: "Back", Class: "FmPulv1"},
+
+				// teoct
+				{Send: "V1mP", Recv: "TEOCT", Pat: "Full", Type: "Back", Class: "FmPulv"},
+				{Send: "V1hP", Recv: "TEOCT", Pat: "Full", Type: "Back", Class: "FmPulv"},
+				{Send: "V4P", Recv: "TEOCT", Pat: "Full", Type: "Back", Class: "FmPulv2"}, // recip
+
+				{Send: "TEO", Recv: "TEOCT", Pat: "PoolOneToOne", Type: "Set", Class: "CTFmSuper"},         // pone2one or one2one?
+				{Send: "TEOCT", Recv: "TEOCT", Pat: "PoolOneToOne", Type: "CTCtxt", Class: "CTSelfHigher"}, // pone2one similar to 3x3 -- bit better
+				{Send: "TECT", Recv: "TEOCT", Pat: "Prjn4x4Skp2Recip", Type: "Back", Class: "CTBack"},      // CTBack > not
+
+				// to TE
+
+				// {Send: "TE", Recv: "TE", Pat: "PoolSameUnit", Type: "Lateral"},
+
+				{Send: "V1mP", Recv: "TE", Pat: "Full", Type: "Back", Class: "FmPulv"},
+				{Send: "V1hP", Recv: "TE", Pat: "Full", Type: "Back", Class: "FmPulv"},
+
+				{Send: "TE", Recv: "TECT", Pat: "PoolOneToOne", Type: "Set", Class: "CTFmSuper"},         // pone2one or reg?
+				{Send: "TECT", Recv: "TECT", Pat: "PoolOneToOne", Type: "CTCtxt", Class: "CTSelfHigher"}, // pone2one > full
+
+				{Send: "V1mP", Recv: "TECT", Pat: "Full", Type: "Back", Class: "FmPulv"},
+				{Send: "V1hP", Recv: "TECT", Pat: "Full", Type: "Back", Class: "FmPulv"},
+				{Send: "V4P", Recv: "TECT", Pat: "Full", Type: "Back", Class: "FmPulv2"}, // recip
+
+				{Send: "TEOCT", Recv: "TECT", Pat: "Prjn4x4Skp2", Type: "Forward", Class: "CTBack"}, // was FwdWeak
+
+				// Latinhib: this extra inhibition drives decorrelation, produces significant learning benefits
+				{Send: "V2", Recv: "V2", Pat: "PoolOneToOne", Type: "Inhib"},
+				{Send: "V2CT", Recv: "V2CT", Pat: "PoolOneToOne", Type: "Inhib"},
+				{Send: "V3", Recv: "V3", Pat: "PoolOneToOne", Type: "Inhib"},
+				{Send: "V3CT", Recv: "V3CT", Pat: "PoolOneToOne", Type: "Inhib"},
+				{Send: "DP", Recv: "DP", Pat: "Full", Type: "Inhib"},
+				{Send: "DPCT", Recv: "DPCT", Pat: "Full", Type: "Inhib"},
+				{Send: "V4", Recv: "V4", Pat: "PoolOneToOne", Type: "Inhib"},
+				{Send: "V4CT", Recv: "V4CT", Pat: "PoolOneToOne", Type: "Inhib"},
+				{Send: "TEO", Recv: "TEO", Pat: "PoolOneToOne", Type: "Inhib"},
+				{Send: "TEOCT", Recv: "TEOCT", Pat: "PoolOneToOne", Type: "Inhib"},
+				{Send: "TE", Recv: "TE", Pat: "PoolOneToOne", Type: "Inhib"},
+				{Send: "TECT", Recv: "TECT", Pat: "PoolOneToOne", Type: "Inhib"},
+
+				// Shortcuts: V1 shortcuts best for syncing all layers -- like the pulvinar basically
+				{Send: "V1m", Recv: "V3", Pat: "UnifRnd:0.1", Type: "Forward", Class: "V1SC"},
+				{Send: "V1m", Recv: "DP", Pat: "UnifRnd:0.1", Type: "Forward", Class: "V1SC"},
+				{Send: "V1m", Recv: "V4", Pat: "UnifRnd:0.1", Type: "Forward", Class: "V1SC"},
+				{Send: "V1m", Recv: "TEO", Pat: "UnifRnd:0.1", Type: "Forward", Class: "V1SC"},
+				{Send: "V1m", Recv: "TE", Pat: "UnifRnd:0.1", Type: "Forward", Class: "V1SC"},
+
+				{Send: "V1m", Recv: "V3CT", Pat: "UnifRnd:0.1", Type: "Forward", Class: "V1SC"},
+				{Send: "V1m", Recv: "DPCT", Pat: "UnifRnd:0.1", Type: "Forward", Class: "V1SC"},
+				{Send: "V1m", Recv: "V4CT", Pat: "UnifRnd:0.1", Type: "Forward", Class: "V1SC"},
+				{Send: "V1m", Recv: "TEOCT", Pat: "UnifRnd:0.1", Type: "Forward", Class: "V1SC"},
+				{Send: "V1m", Recv: "TECT", Pat: "UnifRnd:0.1", Type: "Forward", Class: "V1SC"},
+			},
+			Pos: []LayPos{
+				{"V1mP", relpos.Rel{Rel: relpos.Behind, Other: "V1m", XAlign: relpos.Left, Space: 10}},
+				{"V1hP", relpos.Rel{Rel: relpos.RightOf, Other: "V1h", YAlign: relpos.Front, Space: 2}},
+
+				{"V2", relpos.Rel{Rel: relpos.Above, Other: "V1m", XAlign: relpos.Left, YAlign: relpos.Front}},
+				{"LIP", relpos.Rel{Rel: relpos.Above, Other: "V2", XAlign: relpos.Left, YAlign: relpos.Front}},
+				{"V2CT", relpos.Rel{Rel: relpos.Behind, Other: "V2", XAlign: relpos.Left, Space: 10}},
+
+				{"V3", relpos.Rel{Rel: relpos.RightOf, Other: "V2", YAlign: relpos.Front, Space: 2}},
+				{"V3CT", relpos.Rel{Rel: relpos.Behind, Other: "V3", XAlign: relpos.Left, Space: 10}},
+
+				{"DP", relpos.Rel{Rel: relpos.RightOf, Other: "V3", YAlign: relpos.Front, Space: 2}},
+				{"DPCT", relpos.Rel{Rel: relpos.Behind, Other: "DP", XAlign: relpos.Left, Space: 10}},
+
+				{"V4", relpos.Rel{Rel: relpos.Behind, Other: "V3CT", XAlign: relpos.Left, Space: 10}},
+				{"V4CT", relpos.Rel{Rel: relpos.Behind, Other: "V4", XAlign: relpos.Left, Space: 10}},
+				{"V4P", relpos.Rel{Rel: relpos.RightOf, Other: "V4CT", YAlign: relpos.Back, Space: 2}},
+
+				{"TEO", relpos.Rel{Rel: relpos.RightOf, Other: "EyePos", YAlign: relpos.Front, Space: 2}},
+				{"TEOCT", relpos.Rel{Rel: relpos.Behind, Other: "TEO", XAlign: relpos.Left, Space: 10}},
+
+				{"TE", relpos.Rel{Rel: relpos.RightOf, Other: "TEO", YAlign: relpos.Front, Space: 2}},
+				{"TECT", relpos.Rel{Rel: relpos.Behind, Other: "TE", XAlign: relpos.Left, Space: 10}},
+			},
+			// 2 threads = only slight advantage over 1 thread
+			Threads: map[string]int{
+				"V2": 0, "V2CT": 0,
+				"DP": 0, "DPCT": 0,
+				"V3": 1, "V3CT": 0,
+				"V4": 1, "V4CT": 1, "V4P": 1,
+				"TEO": 1, "TEOCT": 0, // TEO 23 M -- by far biggest, TEOCT 19 M
+				"TE": 1, "TECT": 0,
+			},
+		},
+	},
+}
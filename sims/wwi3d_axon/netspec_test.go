@@ -0,0 +1,552 @@
+// Copyright (c) 2020, The CCNLab Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/emer/axon/axon"
+	"github.com/emer/axon/deep"
+	"github.com/emer/emergent/emer"
+	"github.com/emer/emergent/prjn"
+	"github.com/emer/emergent/relpos"
+)
+
+// netDesc returns a description of the layers and projections of the network
+// that matter for params and running: classes, patterns, positions and threads
+func netDesc(net *deep.Network) string {
+	var b strings.Builder
+	for _, ly := range net.Layers {
+		fmt.Fprintf(&b, "%s: %s %s %v thr: %d\n", ly.Name(), ly.Type(), ly.Class(), ly.RelPos(), ly.Thread())
+		for _, pj := range *ly.RecvPrjns() {
+			fmt.Fprintf(&b, "\t%s: %s %s %s\n", pj.SendLay().Name(), pj.Type(), pj.Class(), pj.Pattern().Name())
+		}
+	}
+	return b.String()
+}
+
+// TestNetSpecSizeReport tests that DefNetSpec builds the same network as the
+// hardcoded ConfigNetLIP and ConfigNetRest that it replaced
+func TestNetSpecSizeReport(t *testing.T) {
+	for _, lipOnly := range []bool{true, false} {
+		ss := &Sim{}
+		ss.New()
+		ss.LIPOnly = lipOnly
+
+		ref := &deep.Network{}
+		ref.InitName(ref, "WWI3D")
+		configNetLIPCode(ss, ref)
+		if !lipOnly {
+			configNetRestCode(ss, ref)
+		}
+		if err := ref.Build(); err != nil {
+			t.Fatal(err)
+		}
+
+		net := &deep.Network{}
+		net.InitName(net, DefNetSpec.Name)
+		if err := ss.ConfigNetSpec(net, &DefNetSpec); err != nil {
+			t.Fatal(err)
+		}
+		if err := net.Build(); err != nil {
+			t.Fatal(err)
+		}
+
+		if sr, rsr := net.SizeReport(), ref.SizeReport(); sr != rsr {
+			t.Errorf("LIPOnly: %v: NetSpec SizeReport:\n%s\nhardcoded:\n%s", lipOnly, sr, rsr)
+		}
+		if nd, rnd := netDesc(net), netDesc(ref); nd != rnd {
+			t.Errorf("LIPOnly: %v: NetSpec network:\n%s\nhardcoded:\n%s", lipOnly, nd, rnd)
+		}
+	}
+}
+
+func TestNetSpecJSON(t *testing.T) {
+	fnm := filepath.Join(t.TempDir(), "netspec.json")
+	if err := DefNetSpec.SaveJSON(fnm); err != nil {
+		t.Fatal(err)
+	}
+	ns := NetSpec{}
+	if err := ns.OpenJSON(fnm); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(ns, DefNetSpec) {
+		t.Errorf("NetSpec JSON round trip differs from DefNetSpec")
+	}
+}
+
+////////////////////////////////////////////////////////////////////////////////
+//  Hardcoded network config, as it was before NetSpec
+
+// configNetLIPCode configures the V1 and LIP dorsal path part as ConfigNetLIP did
+func configNetLIPCode(ss *Sim, net *deep.Network) {
+	v1m := net.AddLayer4D("V1m", 8, 8, 5, 4, emer.Input)
+	v1h := net.AddLayer4D("V1h", 16, 16, 5, 4, emer.Input)
+
+	lip, lipct, lipp := net.AddSuperCTTRC4D("LIP", 16, 16, 1, 1) // 4, 4 tiny bit better than 2,2
+	lipp.SetName("LIPP")
+	// lipct.Shape().SetShape([]int{16, 16, 1, 1}, nil, nil)
+	// lipp.Shape().SetShape([]int{16, 16, 1, 1}, nil, nil)
+
+	mtpos := net.AddLayer4D("MTPos", 16, 16, 1, 1, emer.Hidden)
+
+	lipp.(*deep.TRCLayer).Driver = "MTPos"
+
+	eyepos := net.AddLayer2D("EyePos", 21, 21, emer.Input)
+	sacplan := net.AddLayer2D("SacPlan", 11, 11, emer.Input)
+	sac := net.AddLayer2D("Saccade", 11, 11, emer.Input)
+	objvel := net.AddLayer2D("ObjVel", 11, 11, emer.Input)
+
+	v1m.SetClass("V1")
+	v1h.SetClass("V1")
+
+	mtpos.SetClass("LIP")
+	lip.SetClass("LIP")
+	lipct.SetClass("LIP")
+	lipp.SetClass("LIP")
+	sacplan.SetClass("PopIn")
+	sac.SetClass("PopIn")
+	objvel.SetClass("PopIn")
+
+	full := prjn.NewFull()
+	pone2one := prjn.NewPoolOneToOne()
+
+	net.ConnectLayers(v1h, mtpos, pone2one, emer.Forward).SetClass("V1MT")
+	net.ConnectLayers(v1m, mtpos, ss.Prjn4x4Skp2Recip, emer.Forward).SetClass("V1MT")
+	net.ConnectLayers(mtpos, lip, ss.Prjn3x3Skp1, emer.Forward).SetClass("MTLIP") // was pone2one
+	// net.ConnectCtxtToCT(lipct, lipct, full).SetClass("CTSelfLIP")           // only helpful with rel = 2
+
+	lipct.RecvPrjns().SendName("LIP").SetPattern(full) // critical to have full input
+
+	// topo CT <-> P is critical
+	net.ConnectLayers(lipct, lipp, ss.Prjn3x3Skp1, emer.Forward).SetClass("CTToPulv")
+	net.ConnectLayers(lipp, lipct, ss.Prjn3x3Skp1, emer.Forward).SetClass("FmPulv2") //  FmLIP
+	net.ConnectLayers(lipp, lip, full, emer.Forward).SetClass("FmPulv2")             //  FmLIP
+
+	// lip.RecvPrjns().SendName("LIPP").SetClass("FmPulv FmLIP")
+	// lipct.RecvPrjns().SendName("LIPP").SetClass("FmPulv FmLIP")
+	// lipct.RecvPrjns().SendName("LIP").SetClass("CTCtxtStd")
+
+	// lip can really be a pure position rep
+	// net.ConnectLayers(eyepos, lip, full, emer.Forward)  // InitWts sets ss.PrjnGaussTopo
+	// net.ConnectLayers(sacplan, lip, full, emer.Forward) // InitWts sets ss.PrjnSigTopo
+	// net.ConnectLayers(objvel, lip, full, emer.Forward)  // InitWts sets ss.PrjnSigTopo
+
+	net.ConnectLayers(sac, lipct, full, emer.Forward)    // essential -- InitWts sets ss.PrjnSigTopo
+	net.ConnectLayers(eyepos, lipct, full, emer.Forward) // beneficial -- InitWts sets ss.PrjnGaussTopo
+	net.ConnectLayers(objvel, lipct, full, emer.Forward) // beneficial -- InitWts sets ss.PrjnSigTopo
+
+	//	Position
+	v1h.SetRelPos(relpos.Rel{Rel: relpos.RightOf, Other: v1m.Name(), YAlign: relpos.Front, Space: 2})
+
+	lip.SetRelPos(relpos.Rel{Rel: relpos.Above, Other: v1m.Name(), XAlign: relpos.Left, YAlign: relpos.Front})
+	lipct.SetRelPos(relpos.Rel{Rel: relpos.Behind, Other: lip.Name(), XAlign: relpos.Left, Space: 10})
+	lipp.SetRelPos(relpos.Rel{Rel: relpos.Behind, Other: lipct.Name(), XAlign: relpos.Left, Space: 10})
+	mtpos.SetRelPos(relpos.Rel{Rel: relpos.Behind, Other: lipp.Name(), XAlign: relpos.Left, Space: 10})
+
+	eyepos.SetRelPos(relpos.Rel{Rel: relpos.RightOf, Other: lip.Name(), YAlign: relpos.Front, Space: 2})
+	sacplan.SetRelPos(relpos.Rel{Rel: relpos.Behind, Other: eyepos.Name(), XAlign: relpos.Left, Space: 10})
+	sac.SetRelPos(relpos.Rel{Rel: relpos.Behind, Other: sacplan.Name(), XAlign: relpos.Left, Space: 10})
+	objvel.SetRelPos(relpos.Rel{Rel: relpos.Behind, Other: sac.Name(), XAlign: relpos.Left, Space: 10})
+
+}
+
+// configNetRestCode configures the rest of the network as ConfigNetRest did
+func configNetRestCode(ss *Sim, net *deep.Network) {
+	// note: important for pulvinar to be created first, for weight symmetry init
+	v1hp := deep.AddTRCLayer4D(net.AsAxon(), "V1hP", 16, 16, 5, 4)
+	v1hp.SetClass("V1")
+	v1hp.Driver = "V1h"
+
+	v1mp := deep.AddTRCLayer4D(net.AsAxon(), "V1mP", 8, 8, 5, 4)
+	v1mp.SetClass("V1")
+	v1mp.Driver = "V1m"
+
+	v2, v2ct := net.AddSuperCT4D("V2", 8, 8, 10, 10) // v2p largely redundant with v1
+	v3, v3ct := net.AddSuperCT4D("V3", 4, 4, 10, 10) // v3p is not really useful for training
+	dp, dpct := net.AddSuperCT4D("DP", 1, 1, 10, 10)
+	v4, v4ct, v4p := net.AddSuperCTTRC4D("V4", 4, 4, 10, 10)
+	// v4, v4ct := net.AddSuperCT4D("V4", 4, 4, 10, 10)
+	// teo, teoct, teop := net.AddSuperCTTRC4D("TEO", 4, 4, 10, 10)  // teop not so obviously important
+	teo, teoct := net.AddSuperCT4D("TEO", 4, 4, 10, 10)
+	te, tect := net.AddSuperCT4D("TE", 2, 2, 10, 10)
+
+	v2.SetClass("V2")
+	v2ct.SetClass("V2")
+	// v2p.SetClass("V2")
+
+	v3.SetClass("V3")
+	v3ct.SetClass("V3")
+	// v3p.SetClass("V3")
+
+	v4.SetClass("V4")
+	v4ct.SetClass("V4")
+	v4p.SetClass("V4")
+
+	dp.SetClass("DP")
+	dpct.SetClass("DP")
+	// dpp.SetClass("DP")
+
+	teo.SetClass("TEO")
+	teoct.SetClass("TEO")
+	// teop.SetClass("TEO")
+
+	te.SetClass("TE")
+	tect.SetClass("TE")
+	// tep.SetClass("TE")
+
+	v1m := net.LayerByName("V1m")
+	v1h := net.LayerByName("V1h")
+	lip := net.LayerByName("LIP")
+	lipct := net.LayerByName("LIPCT")
+	lipp := net.LayerByName("LIPP")
+	eyepos := net.LayerByName("EyePos")
+
+	full := prjn.NewFull()
+	pone2one := prjn.NewPoolOneToOne()
+	one2one := prjn.NewOneToOne()
+	sameu := prjn.NewPoolSameUnit()
+	sameu.SelfCon = false
+	_ = one2one
+	rndcut := prjn.NewUnifRnd()
+	rndcut.PCon = 0.1
+	_ = rndcut
+
+	// basic super cons
+	net.ConnectLayers(v1m, v2, ss.Prjn3x3Skp1, emer.Forward).SetClass("V1V2")
+	net.ConnectLayers(v1h, v2, ss.Prjn4x4Skp2, emer.Forward).SetClass("V1V2")
+
+	_, v4v2 := net.BidirConnectLayers(v2, v4, ss.Prjn4x4Skp2)
+	v4v2.SetPattern(ss.Prjn4x4Skp2Recip)
+
+	_, v3v2 := net.BidirConnectLayers(v2, v3, ss.Prjn4x4Skp2)
+	v3v2.SetClass("BackStrong") // was "BackMax") // this is critical!
+	v3v2.SetPattern(ss.Prjn4x4Skp2Recip)
+
+	_, dpv3 := net.BidirConnectLayers(v3, dp, full)
+	dpv3.SetClass("BackStrong") // likely key (in 233) -- retest
+
+	_, teov4 := net.BidirConnectLayers(v4, teo, ss.Prjn3x3Skp1) // 3x3 > full
+	_ = teov4                                                   // .SetClass("BackStrong")                                // todo: test as strong
+
+	_, teteo := net.BidirConnectLayers(teo, te, ss.Prjn4x4Skp2) // 4x4 > full
+	teteo.SetPattern(ss.Prjn4x4Skp2Recip).SetClass("BackWeak")
+
+	// non-basic cons
+
+	////////////////////
+	// to LIP -- weak from v2, v3
+
+	net.ConnectLayers(v2, lip, ss.Prjn2x2Skp2Recip, emer.Forward).SetClass("FwdWeak")
+	net.ConnectLayers(v3, lip, ss.Prjn4x4Skp4Recip, emer.Forward).SetClass("FwdWeak")
+
+	net.ConnectLayers(v2ct, lipct, ss.Prjn2x2Skp2Recip, emer.Forward).SetClass("FwdWeak")
+	net.ConnectLayers(v3ct, lipct, ss.Prjn4x4Skp4Recip, emer.Forward).SetClass("FwdWeak")
+
+	// Pulvinar connections
+	net.ConnectLayers(v2ct, v1mp, ss.Prjn3x3Skp1, emer.Back).SetClass("ToPulv10") // was p1to1
+	net.ConnectLayers(v3ct, v1mp, ss.Prjn4x4Skp2Recip, emer.Back).SetClass("ToPulv2")
+	net.ConnectLayers(v4ct, v1mp, ss.Prjn4x4Skp2Recip, emer.Back).SetClass("ToPulv2")
+	net.ConnectLayers(teoct, v1mp, full, emer.Back).SetClass("ToPulv1") // orig is scheduled
+
+	net.ConnectLayers(v2ct, v1hp, ss.Prjn4x4Skp2Recip, emer.Back).SetClass("ToPulv10") // was 2x2
+	net.ConnectLayers(v3ct, v1hp, ss.Prjn8x8Skp4Recip, emer.Back).SetClass("ToPulv2")
+	net.ConnectLayers(v4ct, v1hp, ss.Prjn8x8Skp4Recip, emer.Back).SetClass("ToPulv2")
+	net.ConnectLayers(teoct, v1hp, full, emer.Back).SetClass("ToPulv1") // orig is scheduled
+
+	// net.ConnectLayers(v2ct, v3p, ss.Prjn4x4Skp2, emer.Back).SetClass("ToPulv5") // actually FF
+	// net.ConnectLayers(dpct, v3p, full, emer.Back).SetClass("ToPulv2")
+	// net.ConnectLayers(teoct, v3p, full, emer.Back).SetClass("ToPulv1") // was uniform rnd but p = 1
+
+	// dpp.RecvPrjns().SendName(dpct.Name()).SetPattern(full)
+	// dpp.RecvPrjns().SendName(dpct.Name()).SetClass("ToPulv2")
+	// net.ConnectLayers(v2ct, dpp, full, emer.Back).SetClass("ToPulv2")  // actually FF
+	// net.ConnectLayers(v3ct, dpp, full, emer.Back).SetClass("ToPulv5")  // actually FF
+	// net.ConnectLayers(teoct, dpp, full, emer.Back).SetClass("ToPulv2") // was uniform rnd but p = 1
+
+	// todo: explore pulv cons here:
+	net.ConnectLayers(v2ct, v4p, ss.Prjn4x4Skp2, emer.Back).SetClass("ToPulv5") // actually FF
+	net.ConnectLayers(v3ct, v4p, ss.Prjn3x3Skp1, emer.Back).SetClass("ToPulv5") // actually FF
+	net.ConnectLayers(v4ct, v4p, pone2one, emer.Back).SetClass("ToPulv2")       // self ct -> p -- cemer -- useful
+	net.ConnectLayers(teoct, v4p, full, emer.Back).SetClass("ToPulv2")
+	// net.ConnectLayers(tect, v4p, full, emer.Back).SetClass("ToPulv2") // todo: test
+
+	// when teop is on, used all of these prjns:
+	// net.ConnectLayers(v3ct, teop, full, emer.Back).SetClass("FwdToPulv2") // actually FF
+	// net.ConnectLayers(v4ct, teop, full, emer.Back).SetClass("FwdToPulv5") // actually FF
+	// net.ConnectLayers(tect, teop, full, emer.Back).SetClass("ToPulv5")
+	// net.ConnectLayers(teoct, teop, pone2one, emer.Back).SetClass("ToPulv2") // self ct -> p -- cemer -- useful?
+
+	// tep.RecvPrjns().SendName(tect.Name()).SetClass("ToPulv2")          // gp1to1
+	// net.ConnectLayers(v4ct, tep, full, emer.Back).SetClass("ToPulv5")  // actually FF
+	// net.ConnectLayers(teoct, tep, full, emer.Back).SetClass("ToPulv2") // was uniform rnd but p = 1
+
+	////////////////////
+	// to V2
+
+	// net.ConnectLayers(v2, v2, sameu, emer.Lateral)
+
+	net.ConnectLayers(v1mp, v2, pone2one, emer.Forward).SetClass("FmPulv02")
+	net.ConnectLayers(v1hp, v2, ss.Prjn2x2Skp2, emer.Forward).SetClass("FmPulv02")
+
+	// cemer: no v2 self
+	// net.ConnectCtxtToCT(v2ct, v2ct, ss.Prjn3x3Skp1).SetClass("CTSelfLower") // was pone2one
+	v2ct.RecvPrjns().SendName(v2.Name()).SetPattern(ss.Prjn3x3Skp1).SetClass("CTFmSuperLower")
+
+	net.ConnectLayers(lip, v2, ss.Prjn2x2Skp2, emer.Back).SetClass("BackStrong FmLIP") // was Max
+	net.ConnectLayers(teoct, v2, ss.Prjn4x4Skp2Recip, emer.Back)                       // key! .1 def
+
+	// net.ConnectLayers(teo, v2, ss.Prjn4x4Skp2Recip, emer.Back) // too strong of top-down
+
+	// v2ct
+	net.ConnectLayers(v1mp, v2ct, ss.Prjn3x3Skp1, emer.Forward).SetClass("FmPulv2")
+	net.ConnectLayers(v1hp, v2ct, ss.Prjn4x4Skp2, emer.Forward).SetClass("FmPulv2")
+
+	net.ConnectLayers(lipct, v2ct, ss.Prjn2x2Skp2, emer.Back).SetClass("CTBackMax1 FmLIP")
+	net.ConnectLayers(lipp, v2ct, ss.Prjn2x2Skp2, emer.Back).SetClass("CTBack FmLIP")
+	net.ConnectLayers(v3ct, v2ct, ss.Prjn4x4Skp2Recip, emer.Back).SetClass("CTBackMax")
+	net.ConnectLayers(v4ct, v2ct, ss.Prjn4x4Skp2Recip, emer.Back).SetClass("CTBackMax")
+
+	// net.ConnectLayers(teoct, v2ct, ss.Prjn4x4Skp2Recip, emer.Back).SetClass("CTBackMax") // not beneficial
+
+	net.ConnectLayers(v3, v2ct, ss.Prjn2x2Skp2Recip, emer.Back).SetClass("SToCTMax")  // s -> ct leak
+	net.ConnectLayers(teo, v2ct, ss.Prjn4x4Skp2Recip, emer.Back).SetClass("SToCTMax") // s -> ct leak -- key @ max
+
+	// CTBack generically worse, generally important for cosdiff
+	// net.ConnectLayers(v3ct, v2p, ss.Prjn4x4Skp2Recip, emer.Back).SetClass("ToPulv1")
+	// net.ConnectLayers(v4ct, v2p, ss.Prjn4x4Skp2Recip, emer.Back).SetClass("ToPulv1") // better without?  not clear
+
+	////////////////////
+	// to V3
+
+	// net.ConnectLayers(v3, v3, sameu, emer.Lateral)
+
+	net.ConnectLayers(v1mp, v3, ss.Prjn4x4Skp2, emer.Back).SetClass("FmPulv2")
+	net.ConnectLayers(v1hp, v3, ss.Prjn8x8Skp4, emer.Back).SetClass("FmPulv2")
+	// net.ConnectLayers(dpp, v3, full, emer.Back).SetClass("FmPulv05") // todo: remove?
+
+	net.ConnectLayers(v4, v3, ss.Prjn3x3Skp1, emer.Back).SetClass("BackStrong")
+	net.ConnectLayers(lip, v3, ss.Prjn4x4Skp4, emer.Back).SetClass("FmLIP")
+
+	net.ConnectLayers(teo, v3, ss.Prjn3x3Skp1, emer.Back)
+	net.ConnectLayers(teoct, v3, ss.Prjn3x3Skp1, emer.Back)
+
+	// v3ct
+
+	// net.ConnectCtxtToCT(v3ct, v3ct, ss.Prjn3x3Skp1).SetClass("CTSelfLower") // was pone2one
+	v3ct.RecvPrjns().SendName(v3.Name()).SetPattern(ss.Prjn3x3Skp1).SetClass("CTFmSuperLower")
+
+	net.ConnectLayers(v1mp, v3ct, ss.Prjn4x4Skp2, emer.Back).SetClass("FmPulv2")
+	net.ConnectLayers(v1hp, v3ct, ss.Prjn8x8Skp4, emer.Back).SetClass("FmPulv2")
+	// net.ConnectLayers(dpp, v3ct, full, emer.Back).SetClass("FmPulv2")
+
+	net.ConnectLayers(lipct, v3ct, ss.Prjn4x4Skp4, emer.Back).SetClass("CTBack FmLIP")
+	net.ConnectLayers(dpct, v3ct, full, emer.Back).SetClass("CTBack")
+	net.ConnectLayers(v4ct, v3ct, ss.Prjn3x3Skp1, emer.Back).SetClass("CTBack")
+	net.ConnectLayers(teo, v3ct, ss.Prjn3x3Skp1, emer.Back).SetClass("BackStrong") // was BackMax s -> ct
+
+	// todo: retest again:
+	net.ConnectLayers(dp, v3ct, full, emer.Back).SetClass("SToCT")
+	net.ConnectLayers(v4, v3ct, ss.Prjn3x3Skp1, emer.Back).SetClass("SToCT") // s -> ct, 3x3 ok
+
+	// net.ConnectLayers(dpct, v3p, full, emer.Back).SetClass("ToPulv1") // not much effect on cosdiff
+	// net.ConnectLayers(v2ct, v3p, ss.Prjn4x4Skp2, emer.Forward).SetClass("FwdToPulv") // has major effect on cosdiff
+
+	////////////////////
+	// to DP
+
+	net.ConnectLayers(v1mp, dp, full, emer.Back).SetClass("FmPulv2")
+	net.ConnectLayers(v1hp, dp, full, emer.Back).SetClass("FmPulv2")
+	// net.ConnectLayers(v3p, dp, full, emer.Back).SetClass("FmPulv")
+	// net.ConnectLayers(teop, dp, full, emer.Back).SetClass("FmPulv") // todo: test (not used in prior teop runs)
+
+	// net.ConnectLayers(v2, dp, full, emer.Forward) // in cemer; no effect?, expensive
+
+	net.ConnectLayers(teo, dp, full, emer.Back) // todo: test again
+
+	// dpct
+	net.ConnectLayers(v1mp, dpct, full, emer.Back).SetClass("FmPulv2")
+	net.ConnectLayers(v1hp, dpct, full, emer.Back).SetClass("FmPulv2")
+
+	net.ConnectCtxtToCT(dpct, dpct, full).SetClass("CTSelfLower") // not much effect, but consistent
+	net.ConnectLayers(teoct, dpct, full, emer.Back).SetClass("CTBack")
+
+	////////////////////
+	// to V4
+
+	// net.ConnectLayers(v4, v4, sameu, emer.Lateral)
+
+	net.ConnectLayers(v1mp, v4, ss.Prjn4x4Skp2, emer.Back).SetClass("FmPulv2")
+	net.ConnectLayers(v1hp, v4, ss.Prjn8x8Skp4, emer.Back).SetClass("FmPulv2")
+
+	// net.ConnectLayers(teoct, v4, ss.Prjn3x3Skp1, emer.Back).SetClass("CTBack") // very not beneficial
+
+	// Prjn4x4Skp2Recip is same as full, but has topo scales -- better than full
+	// note: not in cemer version:
+	// net.ConnectLayers(te, v4, full, emer.Back).SetClass("BackStrong")
+
+	// v4ct
+	v4ct.RecvPrjns().SendName(v4.Name()).SetPattern(pone2one).SetClass("CTFmSuper")
+	// net.ConnectCtxtToCT(v4ct, v4ct, pone2one).SetClass("CTSelfLower") // was pone2one
+
+	net.ConnectLayers(v1mp, v4ct, ss.Prjn4x4Skp2, emer.Back).SetClass("FmPulv2")
+	net.ConnectLayers(v1hp, v4ct, ss.Prjn8x8Skp4, emer.Back).SetClass("FmPulv2")
+	net.ConnectLayers(v4p, v4ct, pone2one, emer.Back).SetClass("FmPulv05") // todo: 3x3?  useful
+
+	net.ConnectLayers(teoct, v4ct, ss.Prjn3x3Skp1, emer.Back).SetClass("CTBack")
+	net.ConnectLayers(teo, v4ct, ss.Prjn3x3Skp1, emer.Back).SetClass("SToCT") // s -> ct -- important
+
+	// Prjn4x4Skp2Recip is same as full, but has topo scales -- better
+	net.ConnectLayers(tect, v4ct, ss.Prjn4x4Skp2Recip, emer.Back).SetClass("CTBack")
+
+	// net.ConnectLayers(v2ct, v4ct, ss.Prjn4x4Skp2, emer.Forward).SetClass("CTBack") // instead of direct to v2p -- not helpful
+
+	// todo:
+	// net.ConnectLayers(v2ct, v4p, ss.Prjn4x4Skp2, emer.Forward).SetClass("FwdToPulv") // has major effect on cosdiff
+
+	////////////////////
+	// to TEO
+
+	// net.ConnectLayers(teo, teo, sameu, emer.Lateral)
+
+	net.ConnectLayers(v1mp, teo, full, emer.Back).SetClass("FmPulv1")
+	net.ConnectLayers(v1hp, teo, full, emer.Back).SetClass("FmPulv1")
+
+	// teoct
+	net.ConnectLayers(v1mp, teoct, full, emer.Back).SetClass("FmPulv")
+	net.ConnectLayers(v1hp, teoct, full, emer.Back).SetClass("FmPulv")
+	net.ConnectLayers(v4p, teoct, full, emer.Back).SetClass("FmPulv2") // recip
+	// net.ConnectLayers(teop, teoct, pone2one, emer.Back).SetClass("BackWeak") // self p->ct? (not used)
+
+	teoct.RecvPrjns().SendName(teo.Name()).SetPattern(pone2one).SetClass("CTFmSuper") // pone2one or one2one?
+	net.ConnectCtxtToCT(teoct, teoct, pone2one).SetClass("CTSelfHigher")              // pone2one similar to 3x3 -- bit better
+
+	net.ConnectLayers(tect, teoct, ss.Prjn4x4Skp2Recip, emer.Back).SetClass("CTBack") // CTBack > not
+
+	// not in cemer model:
+	// net.ConnectLayers(v4ct, teoct, full, emer.Forward).SetClass("CTBack") // instead of direct to v2p
+
+	// todo: test topo on both -- not used in prior teop tests
+	// net.ConnectLayers(v4ct, teop, full, emer.Forward).SetClass("FwdToPulv") // sig effect on TEOP cosdiff, but improves TEP
+	// net.ConnectLayers(tect, teop, full, emer.Back).SetClass("ToPulv1") // no effect on cosdiff, but better Cat without
+
+	////////////////////
+	// to TE
+
+	// net.ConnectLayers(te, te, sameu, emer.Lateral)
+
+	net.ConnectLayers(v1mp, te, full, emer.Back).SetClass("FmPulv")
+	net.ConnectLayers(v1hp, te, full, emer.Back).SetClass("FmPulv")
+
+	tect.RecvPrjns().SendName(te.Name()).SetPattern(pone2one).SetClass("CTFmSuper") // pone2one or reg?
+	net.ConnectCtxtToCT(tect, tect, pone2one).SetClass("CTSelfHigher")              // pone2one > full
+
+	net.ConnectLayers(v1mp, tect, full, emer.Back).SetClass("FmPulv")
+	net.ConnectLayers(v1hp, tect, full, emer.Back).SetClass("FmPulv")
+	net.ConnectLayers(v4p, tect, full, emer.Back).SetClass("FmPulv2") // recip
+	// net.ConnectLayers(teop, tect, full, emer.Back).SetClass("FmPulv2") // recip -- only real output of teop -- not big deal
+
+	net.ConnectLayers(teoct, tect, ss.Prjn4x4Skp2, emer.Forward).SetClass("CTBack") // was FwdWeak
+
+	// net.ConnectLayers(teoct, tep, full, emer.Back).SetClass("FwdToPulv") // sig effect on cosdiff, not much other eff
+
+	////////////////////
+	// Latinhib
+
+	// this extra inhibition drives decorrelation, produces significant learning benefits
+	net.LateralConnectLayerPrjn(v2, pone2one, &axon.HebbPrjn{}).SetType(emer.Inhib)
+	net.LateralConnectLayerPrjn(v2ct, pone2one, &axon.HebbPrjn{}).SetType(emer.Inhib)
+	net.LateralConnectLayerPrjn(v3, pone2one, &axon.HebbPrjn{}).SetType(emer.Inhib)
+	net.LateralConnectLayerPrjn(v3ct, pone2one, &axon.HebbPrjn{}).SetType(emer.Inhib)
+	net.LateralConnectLayerPrjn(dp, full, &axon.HebbPrjn{}).SetType(emer.Inhib)
+	net.LateralConnectLayerPrjn(dpct, full, &axon.HebbPrjn{}).SetType(emer.Inhib)
+	net.LateralConnectLayerPrjn(v4, pone2one, &axon.HebbPrjn{}).SetType(emer.Inhib)
+	net.LateralConnectLayerPrjn(v4ct, pone2one, &axon.HebbPrjn{}).SetType(emer.Inhib)
+	net.LateralConnectLayerPrjn(teo, pone2one, &axon.HebbPrjn{}).SetType(emer.Inhib)
+	net.LateralConnectLayerPrjn(teoct, pone2one, &axon.HebbPrjn{}).SetType(emer.Inhib)
+	net.LateralConnectLayerPrjn(te, pone2one, &axon.HebbPrjn{}).SetType(emer.Inhib)
+	net.LateralConnectLayerPrjn(tect, pone2one, &axon.HebbPrjn{}).SetType(emer.Inhib)
+
+	////////////////////
+	// Shortcuts
+
+	// V1 shortcuts best for syncing all layers -- like the pulvinar basically
+	net.ConnectLayers(v1m, v3, rndcut, emer.Forward).SetClass("V1SC")
+	net.ConnectLayers(v1m, dp, rndcut, emer.Forward).SetClass("V1SC")
+	net.ConnectLayers(v1m, v4, rndcut, emer.Forward).SetClass("V1SC")
+	net.ConnectLayers(v1m, teo, rndcut, emer.Forward).SetClass("V1SC")
+	net.ConnectLayers(v1m, te, rndcut, emer.Forward).SetClass("V1SC")
+
+	net.ConnectLayers(v1m, v3ct, rndcut, emer.Forward).SetClass("V1SC")
+	net.ConnectLayers(v1m, dpct, rndcut, emer.Forward).SetClass("V1SC")
+	net.ConnectLayers(v1m, v4ct, rndcut, emer.Forward).SetClass("V1SC")
+	net.ConnectLayers(v1m, teoct, rndcut, emer.Forward).SetClass("V1SC")
+	net.ConnectLayers(v1m, tect, rndcut, emer.Forward).SetClass("V1SC")
+
+	////////////////////
+	// Position
+
+	v1mp.SetRelPos(relpos.Rel{Rel: relpos.Behind, Other: v1m.Name(), XAlign: relpos.Left, Space: 10})
+	v1hp.SetRelPos(relpos.Rel{Rel: relpos.RightOf, Other: v1h.Name(), YAlign: relpos.Front, Space: 2})
+
+	v2.SetRelPos(relpos.Rel{Rel: relpos.Above, Other: v1m.Name(), XAlign: relpos.Left, YAlign: relpos.Front})
+	lip.SetRelPos(relpos.Rel{Rel: relpos.Above, Other: v2.Name(), XAlign: relpos.Left, YAlign: relpos.Front})
+	// v2p.SetRelPos(relpos.Rel{Rel: relpos.Behind, Other: v1m.Name(), XAlign: relpos.Left, Space: 10})
+	v2ct.SetRelPos(relpos.Rel{Rel: relpos.Behind, Other: v2.Name(), XAlign: relpos.Left, Space: 10})
+
+	v3.SetRelPos(relpos.Rel{Rel: relpos.RightOf, Other: v2.Name(), YAlign: relpos.Front, Space: 2})
+	v3ct.SetRelPos(relpos.Rel{Rel: relpos.Behind, Other: v3.Name(), XAlign: relpos.Left, Space: 10})
+	// v3p.SetRelPos(relpos.Rel{Rel: relpos.RightOf, Other: v3ct.Name(), YAlign: relpos.Front, Space: 2})
+
+	dp.SetRelPos(relpos.Rel{Rel: relpos.RightOf, Other: v3.Name(), YAlign: relpos.Front, Space: 2})
+	dpct.SetRelPos(relpos.Rel{Rel: relpos.Behind, Other: dp.Name(), XAlign: relpos.Left, Space: 10})
+	// dpp.SetRelPos(relpos.Rel{Rel: relpos.RightOf, Other: dpct.Name(), YAlign: relpos.Front, Space: 2})
+
+	v4.SetRelPos(relpos.Rel{Rel: relpos.Behind, Other: v3ct.Name(), XAlign: relpos.Left, Space: 10})
+	v4ct.SetRelPos(relpos.Rel{Rel: relpos.Behind, Other: v4.Name(), XAlign: relpos.Left, Space: 10})
+	v4p.SetRelPos(relpos.Rel{Rel: relpos.RightOf, Other: v4ct.Name(), YAlign: relpos.Back, Space: 2})
+
+	teo.SetRelPos(relpos.Rel{Rel: relpos.RightOf, Other: eyepos.Name(), YAlign: relpos.Front, Space: 2})
+	teoct.SetRelPos(relpos.Rel{Rel: relpos.Behind, Other: teo.Name(), XAlign: relpos.Left, Space: 10})
+	// teop.SetRelPos(relpos.Rel{Rel: relpos.Behind, Other: teoct.Name(), XAlign: relpos.Left, Space: 10})
+
+	te.SetRelPos(relpos.Rel{Rel: relpos.RightOf, Other: teo.Name(), YAlign: relpos.Front, Space: 2})
+	tect.SetRelPos(relpos.Rel{Rel: relpos.Behind, Other: te.Name(), XAlign: relpos.Left, Space: 10})
+	// tep.SetRelPos(relpos.Rel{Rel: relpos.Behind, Other: tect.Name(), XAlign: relpos.Left, Space: 10})
+
+	////////////////////
+
+	//	2 threads = only slight advantage over 1 thread
+	v2.SetThread(0)
+	v2ct.SetThread(0)
+	// v2p.SetThread(0)
+
+	dp.SetThread(0)
+	dpct.SetThread(0)
+	// dpp.SetThread(0)
+
+	v3ct.SetThread(0)
+
+	// v3p.SetThread(1)
+	v3.SetThread(1)
+
+	v4.SetThread(1)
+	v4ct.SetThread(1)
+	v4p.SetThread(1)
+
+	teo.SetThread(1) // 23 M -- by far biggest
+
+	teoct.SetThread(0) // 19 M
+	// teop.SetThread(0)
+
+	te.SetThread(1)
+
+	tect.SetThread(0)
+	// tep.SetThread(0)
+}
@@ -0,0 +1,231 @@
+// Copyright (c) 2020, The CCNLab Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// numpy .npy file format support, for exchanging matricies with
+// python-based models and analyses.
+
+// NpyMagic is the magic string at the start of every .npy file
+const NpyMagic = "\x93NUMPY"
+
+// ReadNpy reads a numeric numpy .npy array from given reader, returning the values
+// as float64 in row-major (C) order, and the shape.  Supports little and big endian
+// float32, float64, int8..int64 and uint8..uint64 data, in C or Fortran order
+// (Fortran order data is transposed into C order).
+func ReadNpy(r io.Reader) ([]float64, []int, error) {
+	pre := make([]byte, 8)
+	if _, err := io.ReadFull(r, pre); err != nil {
+		return nil, nil, err
+	}
+	if string(pre[:6]) != NpyMagic {
+		return nil, nil, errors.New("ReadNpy: not a numpy .npy file")
+	}
+	var hlen int
+	switch pre[6] {
+	case 1:
+		hl := make([]byte, 2)
+		if _, err := io.ReadFull(r, hl); err != nil {
+			return nil, nil, err
+		}
+		hlen = int(binary.LittleEndian.Uint16(hl))
+	case 2, 3:
+		hl := make([]byte, 4)
+		if _, err := io.ReadFull(r, hl); err != nil {
+			return nil, nil, err
+		}
+		hlen = int(binary.LittleEndian.Uint32(hl))
+	default:
+		return nil, nil, fmt.Errorf("ReadNpy: unsupported .npy version: %d", pre[6])
+	}
+	hdr := make([]byte, hlen)
+	if _, err := io.ReadFull(r, hdr); err != nil {
+		return nil, nil, err
+	}
+	descr, fort, shape, err := NpyParseHeader(string(hdr))
+	if err != nil {
+		return nil, nil, err
+	}
+	var bo binary.ByteOrder = binary.LittleEndian
+	switch descr[0] {
+	case '>':
+		bo = binary.BigEndian
+	case '<', '|', '=':
+	default:
+		return nil, nil, fmt.Errorf("ReadNpy: unsupported dtype: %s", descr)
+	}
+	kind := descr[1]
+	sz, err := strconv.Atoi(descr[2:])
+	if err != nil {
+		return nil, nil, fmt.Errorf("ReadNpy: unsupported dtype: %s", descr)
+	}
+	n := 1
+	for _, d := range shape {
+		n *= d
+	}
+	data := make([]byte, n*sz)
+	if _, err := io.ReadFull(r, data); err != nil {
+		return nil, nil, err
+	}
+	vals := make([]float64, n)
+	for i := range vals {
+		b := data[i*sz : (i+1)*sz]
+		switch {
+		case kind == 'f' && sz == 4:
+			vals[i] = float64(math.Float32frombits(bo.Uint32(b)))
+		case kind == 'f' && sz == 8:
+			vals[i] = math.Float64frombits(bo.Uint64(b))
+		case kind == 'i' && sz == 1:
+			vals[i] = float64(int8(b[0]))
+		case kind == 'i' && sz == 2:
+			vals[i] = float64(int16(bo.Uint16(b)))
+		case kind == 'i' && sz == 4:
+			vals[i] = float64(int32(bo.Uint32(b)))
+		case kind == 'i' && sz == 8:
+			vals[i] = float64(int64(bo.Uint64(b)))
+		case (kind == 'u' || kind == 'b') && sz == 1:
+			vals[i] = float64(b[0])
+		case kind == 'u' && sz == 2:
+			vals[i] = float64(bo.Uint16(b))
+		case kind == 'u' && sz == 4:
+			vals[i] = float64(bo.Uint32(b))
+		case kind == 'u' && sz == 8:
+			vals[i] = float64(bo.Uint64(b))
+		default:
+			return nil, nil, fmt.Errorf("ReadNpy: unsupported dtype: %s", descr)
+		}
+	}
+	if fort && len(shape) > 1 {
+		vals = NpyFortranToC(vals, shape)
+	}
+	return vals, shape, nil
+}
+
+// OpenNpy opens a numeric numpy .npy file -- see ReadNpy
+func OpenNpy(fname string) ([]float64, []int, error) {
+	fp, err := os.Open(fname)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer fp.Close()
+	return ReadNpy(fp)
+}
+
+// NpyParseHeader parses the python dict literal header of a .npy file,
+// e.g.: {'descr': '<f4', 'fortran_order': False, 'shape': (156, 156), }
+func NpyParseHeader(hdr string) (descr string, fort bool, shape []int, err error) {
+	hdr = strings.TrimSpace(hdr)
+	field := func(key string) string {
+		ki := strings.Index(hdr, "'"+key+"'")
+		if ki < 0 {
+			return ""
+		}
+		rest := hdr[ki+len(key)+2:]
+		ci := strings.Index(rest, ":")
+		if ci < 0 {
+			return ""
+		}
+		return strings.TrimSpace(rest[ci+1:])
+	}
+	dv := field("descr")
+	if len(dv) < 2 || dv[0] != '\'' {
+		err = fmt.Errorf("NpyParseHeader: no descr in header: %s", hdr)
+		return
+	}
+	descr = dv[1 : 1+strings.Index(dv[1:], "'")]
+	if len(descr) < 3 {
+		err = fmt.Errorf("NpyParseHeader: unsupported dtype: %s", descr)
+		return
+	}
+	fort = strings.HasPrefix(field("fortran_order"), "True")
+	sv := field("shape")
+	if len(sv) == 0 || sv[0] != '(' {
+		err = fmt.Errorf("NpyParseHeader: no shape in header: %s", hdr)
+		return
+	}
+	sv = sv[1:strings.Index(sv, ")")]
+	for _, ds := range strings.Split(sv, ",") {
+		ds = strings.TrimSpace(ds)
+		if ds == "" {
+			continue
+		}
+		d, perr := strconv.Atoi(strings.TrimSuffix(ds, "L"))
+		if perr != nil {
+			err = fmt.Errorf("NpyParseHeader: bad shape: %s", sv)
+			return
+		}
+		shape = append(shape, d)
+	}
+	return
+}
+
+// NpyFortranToC returns the Fortran (column-major) ordered values reordered
+// into C (row-major) order, for given shape
+func NpyFortranToC(vals []float64, shape []int) []float64 {
+	nd := len(shape)
+	cv := make([]float64, len(vals))
+	idx := make([]int, nd)
+	for ci := range cv {
+		rem := ci
+		for d := nd - 1; d >= 0; d-- {
+			idx[d] = rem % shape[d]
+			rem /= shape[d]
+		}
+		fi := 0
+		for d := nd - 1; d >= 0; d-- {
+			fi = fi*shape[d] + idx[d]
+		}
+		cv[ci] = vals[fi]
+	}
+	return cv
+}
+
+// WriteNpyHeader writes a version 1.0 .npy header for given numpy dtype descr
+// (e.g., <f4, <f8) and C-ordered shape to given writer -- the data must follow.
+// The header is padded so the data starts on a 64 byte boundary.
+func WriteNpyHeader(w io.Writer, descr string, shape []int) error {
+	ss := make([]string, len(shape))
+	for i, d := range shape {
+		ss[i] = strconv.Itoa(d)
+	}
+	shp := strings.Join(ss, ", ")
+	if len(shape) == 1 {
+		shp += ","
+	}
+	hdr := fmt.Sprintf("{'descr': '%s', 'fortran_order': False, 'shape': (%s), }", descr, shp)
+	pad := 64 - (len(NpyMagic)+4+len(hdr)+1)%64
+	if pad == 64 {
+		pad = 0
+	}
+	hdr += strings.Repeat(" ", pad) + "\n"
+	if len(hdr) > math.MaxUint16 {
+		return fmt.Errorf("WriteNpyHeader: header too long: %d", len(hdr))
+	}
+	pre := make([]byte, 0, len(NpyMagic)+4)
+	pre = append(pre, NpyMagic...)
+	pre = append(pre, 1, 0)
+	pre = append(pre, byte(len(hdr)), byte(len(hdr)>>8))
+	if _, err := w.Write(pre); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, hdr)
+	return err
+}
+
+// IsNpy returns true if given bytes start with the .npy magic string
+func IsNpy(b []byte) bool {
+	return bytes.HasPrefix(b, []byte(NpyMagic))
+}
@@ -458,3 +458,14 @@ var ParamSets = params.Sets{
 		},
 	}},
 }
+
+// Scheds are the default epoch schedules for each ParamSet -- Base is used for any
+// ParamSet without its own schedule.  Use -sched to load a schedule from a JSON file instead.
+var Scheds = EpochScheds{
+	"Base": {Name: "Base", Desc: "turn on the InitOffNms layers at 100, save weights at 100, 250, 500", Acts: []SchedAct{
+		{Epoch: 100, Act: "LaysOn"},
+		{Epoch: 100, Act: "SaveWts"},
+		{Epoch: 250, Act: "SaveWts"},
+		{Epoch: 500, Act: "SaveWts"},
+	}},
+}
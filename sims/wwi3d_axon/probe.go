@@ -0,0 +1,391 @@
+// Copyright (c) 2020, The CCNLab Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"math"
+	"math/rand"
+	"sort"
+
+	"github.com/emer/etable/etable"
+	"github.com/emer/etable/etensor"
+)
+
+// Probe trains cross-validated linear decoding probes on layer activations,
+// to determine what information each layer linearly encodes: class labels
+// (category, object, tick) or continuous values (eye position, saccade, etc).
+// All fitting is done in the dual (kernel) form, which is efficient when
+// there are fewer patterns than units.
+type Probe struct {
+	Folds    int           `desc:"number of cross-validation folds"`
+	Lambda   float64       `desc:"L2 regularization strength, relative to the average squared length of the (centered) activation patterns"`
+	Logistic bool          `desc:"use multinomial logistic regression for class targets, instead of ridge regression onto one-hot targets -- is much slower"`
+	LrnRate  float64       `viewif:"Logistic" desc:"learning rate for logistic regression gradient descent"`
+	Iters    int           `viewif:"Logistic" desc:"number of full-batch gradient descent iterations for logistic regression"`
+	Seed     int64         `desc:"random seed for assigning patterns to folds"`
+	Results  *etable.Table `view:"no-inline" desc:"results of last Run: a row per layer, and a column per target with cross-validated classification accuracy or regression R^2"`
+}
+
+func (pr *Probe) Defaults() {
+	pr.Folds = 5
+	pr.Lambda = 1
+	pr.Logistic = false
+	pr.LrnRate = 1
+	pr.Iters = 100
+	pr.Seed = 1
+}
+
+// ConfigResults configures the Results table for given layers and targets
+func (pr *Probe) ConfigResults(lays, clss, regs []string) {
+	if pr.Results == nil {
+		pr.Results = &etable.Table{}
+	}
+	dt := pr.Results
+	dt.SetMetaData("name", "Probe")
+	dt.SetMetaData("desc", "Cross-validated decoding accuracy (class targets) or R^2 (continuous targets) for each layer")
+	dt.SetMetaData("read-only", "true")
+	sch := etable.Schema{
+		{"Lay", etensor.STRING, nil, nil},
+	}
+	for _, cn := range clss {
+		sch = append(sch, etable.Column{cn, etensor.FLOAT64, nil, nil})
+	}
+	for _, cn := range regs {
+		sch = append(sch, etable.Column{cn, etensor.FLOAT64, nil, nil})
+	}
+	dt.SetFromSchema(sch, len(lays))
+	for li, lnm := range lays {
+		dt.SetCellString("Lay", li, lnm)
+	}
+}
+
+// Val returns the Results value for given layer and target, 0 if not found
+func (pr *Probe) Val(lay, targ string) float64 {
+	if pr.Results == nil || pr.Results.ColIdx(targ) < 0 {
+		return 0
+	}
+	for row := 0; row < pr.Results.Rows; row++ {
+		if pr.Results.CellString("Lay", row) == lay {
+			return pr.Results.CellFloat(targ, row)
+		}
+	}
+	return 0
+}
+
+// Run runs probes from each of given layer columns in ix onto each of the
+// class (clss) and continuous regression (regs) target columns, recording
+// results in the Results table.  Class targets can be any type of column
+// (values are converted to strings), and regression targets can have any
+// number of values per row.
+func (pr *Probe) Run(ix *etable.IdxView, lays, clss, regs []string) {
+	pr.ConfigResults(lays, clss, regs)
+	n := ix.Len()
+	if pr.Folds < 2 || n < pr.Folds {
+		return
+	}
+	folds := pr.FoldIdxs(n)
+	dt := ix.Table
+	for li, lnm := range lays {
+		vecs := make([][]float64, n)
+		for i, row := range ix.Idxs {
+			tsr := dt.CellTensor(lnm, row)
+			v := make([]float64, tsr.Len())
+			for j := range v {
+				v[j] = tsr.FloatVal1D(j)
+			}
+			vecs[i] = v
+		}
+		k := GramMatrix(vecs)
+		for _, cn := range clss {
+			col := dt.ColByName(cn)
+			lbls := make([]string, n)
+			for i, row := range ix.Idxs {
+				lbls[i] = col.StringVal1D(row)
+			}
+			pr.Results.SetCellFloat(cn, li, pr.ClassAcc(k, n, folds, lbls))
+		}
+		for _, cn := range regs {
+			d := dt.CellTensor(cn, ix.Idxs[0]).Len()
+			ys := make([]float64, n*d)
+			for i, row := range ix.Idxs {
+				tsr := dt.CellTensor(cn, row)
+				for j := 0; j < d; j++ {
+					ys[i*d+j] = tsr.FloatVal1D(j)
+				}
+			}
+			pr.Results.SetCellFloat(cn, li, pr.RegR2(k, n, folds, ys, d))
+		}
+	}
+}
+
+// FoldIdxs returns a random assignment of n patterns to Folds folds
+func (pr *Probe) FoldIdxs(n int) []int {
+	rnd := rand.New(rand.NewSource(pr.Seed))
+	perm := rnd.Perm(n)
+	folds := make([]int, n)
+	for i, pi := range perm {
+		folds[pi] = i % pr.Folds
+	}
+	return folds
+}
+
+// FoldSplit returns the training and testing pattern indexes for given fold
+func FoldSplit(folds []int, fold int) (tr, te []int) {
+	for i, f := range folds {
+		if f == fold {
+			te = append(te, i)
+		} else {
+			tr = append(tr, i)
+		}
+	}
+	return
+}
+
+// FoldKernels returns the train x train and test x train kernel matricies from
+// full n x n kernel k, centered on the mean of the training patterns, along with
+// the mean of the diagonal of the training kernel (average squared length).
+func FoldKernels(k []float64, n int, tr, te []int) (ktr, kte []float64, dmean float64) {
+	ntr := len(tr)
+	rm := make([]float64, n)
+	for i := 0; i < n; i++ {
+		s := 0.0
+		for _, t := range tr {
+			s += k[i*n+t]
+		}
+		rm[i] = s / float64(ntr)
+	}
+	c := 0.0
+	for _, t := range tr {
+		c += rm[t]
+	}
+	c /= float64(ntr)
+	ktr = make([]float64, ntr*ntr)
+	for a, ta := range tr {
+		for b, tb := range tr {
+			ktr[a*ntr+b] = k[ta*n+tb] - rm[ta] - rm[tb] + c
+		}
+		dmean += ktr[a*ntr+a]
+	}
+	dmean /= float64(ntr)
+	kte = make([]float64, len(te)*ntr)
+	for a, ta := range te {
+		for b, tb := range tr {
+			kte[a*ntr+b] = k[ta*n+tb] - rm[ta] - rm[tb] + c
+		}
+	}
+	return
+}
+
+// Ridge fits kernel ridge regression from ktr onto ntr x d training targets ytr,
+// returning the predictions for the test patterns in kte (nte x d)
+func (pr *Probe) Ridge(ktr, kte []float64, ntr int, ytr []float64, d int, dmean float64) []float64 {
+	nte := len(kte) / ntr
+	lam := pr.Lambda * dmean
+	if lam <= 0 {
+		lam = pr.Lambda
+	}
+	a := make([]float64, len(ktr))
+	copy(a, ktr)
+	for i := 0; i < ntr; i++ {
+		a[i*ntr+i] += lam
+	}
+	ym := make([]float64, d)
+	for i := 0; i < ntr; i++ {
+		for j := 0; j < d; j++ {
+			ym[j] += ytr[i*d+j]
+		}
+	}
+	for j := range ym {
+		ym[j] /= float64(ntr)
+	}
+	b := make([]float64, ntr*d)
+	for i := 0; i < ntr; i++ {
+		for j := 0; j < d; j++ {
+			b[i*d+j] = ytr[i*d+j] - ym[j]
+		}
+	}
+	pred := make([]float64, nte*d)
+	if !CholSolve(a, ntr, b, d) {
+		for i := 0; i < nte; i++ {
+			copy(pred[i*d:], ym)
+		}
+		return pred
+	}
+	for i := 0; i < nte; i++ {
+		for j := 0; j < d; j++ {
+			s := ym[j]
+			for t := 0; t < ntr; t++ {
+				s += kte[i*ntr+t] * b[t*d+j]
+			}
+			pred[i*d+j] = s
+		}
+	}
+	return pred
+}
+
+// LogReg fits multinomial logistic regression from ktr onto ntr x nc one-hot
+// training targets ytr, returning the class scores for the test patterns in kte
+// (nte x nc).  Uses full-batch gradient descent on the dual weights.
+func (pr *Probe) LogReg(ktr, kte []float64, ntr int, ytr []float64, nc int, dmean float64) []float64 {
+	nte := len(kte) / ntr
+	if dmean <= 0 {
+		dmean = 1
+	}
+	lr := pr.LrnRate / dmean
+	lam := pr.Lambda * dmean / float64(ntr)
+	a := make([]float64, ntr*nc)
+	bias := make([]float64, nc)
+	sc := make([]float64, ntr*nc)
+	g := make([]float64, ntr*nc)
+	for it := 0; it < pr.Iters; it++ {
+		KernScores(ktr, ntr, a, bias, nc, sc)
+		for i := 0; i < ntr; i++ {
+			SoftMax(sc[i*nc : (i+1)*nc])
+			for c := 0; c < nc; c++ {
+				g[i*nc+c] = (sc[i*nc+c] - ytr[i*nc+c]) / float64(ntr)
+			}
+		}
+		for c := 0; c < nc; c++ {
+			bg := 0.0
+			for i := 0; i < ntr; i++ {
+				bg += g[i*nc+c]
+			}
+			bias[c] -= pr.LrnRate * bg
+		}
+		for i, gv := range g {
+			a[i] -= lr * (gv + lam*a[i])
+		}
+	}
+	pred := make([]float64, nte*nc)
+	KernScores(kte, ntr, a, bias, nc, pred)
+	return pred
+}
+
+// KernScores computes scores = k a + bias for kernel k (n x ntr),
+// dual weights a (ntr x nc), into sc (n x nc)
+func KernScores(k []float64, ntr int, a, bias []float64, nc int, sc []float64) {
+	n := len(k) / ntr
+	for i := 0; i < n; i++ {
+		for c := 0; c < nc; c++ {
+			s := bias[c]
+			for t := 0; t < ntr; t++ {
+				s += k[i*ntr+t] * a[t*nc+c]
+			}
+			sc[i*nc+c] = s
+		}
+	}
+}
+
+// SoftMax replaces given scores with their softmax probabilities
+func SoftMax(sc []float64) {
+	mx := math.Inf(-1)
+	for _, s := range sc {
+		mx = math.Max(mx, s)
+	}
+	sum := 0.0
+	for i, s := range sc {
+		sc[i] = math.Exp(s - mx)
+		sum += sc[i]
+	}
+	for i := range sc {
+		sc[i] /= sum
+	}
+}
+
+// ClassAcc returns the cross-validated classification accuracy for predicting
+// given labels from the patterns with n x n kernel k
+func (pr *Probe) ClassAcc(k []float64, n int, folds []int, lbls []string) float64 {
+	var cls []string
+	cidx := make(map[string]int)
+	for _, l := range lbls {
+		if _, has := cidx[l]; !has {
+			cidx[l] = 0
+			cls = append(cls, l)
+		}
+	}
+	sort.Strings(cls)
+	for i, c := range cls {
+		cidx[c] = i
+	}
+	nc := len(cls)
+	if nc < 2 {
+		return 1
+	}
+	ncor := 0
+	for f := 0; f < pr.Folds; f++ {
+		tr, te := FoldSplit(folds, f)
+		if len(tr) == 0 || len(te) == 0 {
+			continue
+		}
+		ktr, kte, dmean := FoldKernels(k, n, tr, te)
+		ytr := make([]float64, len(tr)*nc)
+		for i, t := range tr {
+			ytr[i*nc+cidx[lbls[t]]] = 1
+		}
+		var pred []float64
+		if pr.Logistic {
+			pred = pr.LogReg(ktr, kte, len(tr), ytr, nc, dmean)
+		} else {
+			pred = pr.Ridge(ktr, kte, len(tr), ytr, nc, dmean)
+		}
+		for i, t := range te {
+			mx := 0
+			for c := 1; c < nc; c++ {
+				if pred[i*nc+c] > pred[i*nc+mx] {
+					mx = c
+				}
+			}
+			if mx == cidx[lbls[t]] {
+				ncor++
+			}
+		}
+	}
+	return float64(ncor) / float64(n)
+}
+
+// RegR2 returns the cross-validated R^2 (proportion of variance explained, pooled
+// over the d dimensions) for predicting the n x d values ys from the patterns with
+// n x n kernel k
+func (pr *Probe) RegR2(k []float64, n int, folds []int, ys []float64, d int) float64 {
+	pred := make([]float64, n*d)
+	for f := 0; f < pr.Folds; f++ {
+		tr, te := FoldSplit(folds, f)
+		if len(tr) == 0 || len(te) == 0 {
+			continue
+		}
+		ktr, kte, dmean := FoldKernels(k, n, tr, te)
+		ytr := make([]float64, len(tr)*d)
+		for i, t := range tr {
+			copy(ytr[i*d:(i+1)*d], ys[t*d:(t+1)*d])
+		}
+		fp := pr.Ridge(ktr, kte, len(tr), ytr, d, dmean)
+		for i, t := range te {
+			copy(pred[t*d:(t+1)*d], fp[i*d:(i+1)*d])
+		}
+	}
+	ym := make([]float64, d)
+	for i := 0; i < n; i++ {
+		for j := 0; j < d; j++ {
+			ym[j] += ys[i*d+j]
+		}
+	}
+	for j := range ym {
+		ym[j] /= float64(n)
+	}
+	sse := 0.0
+	sst := 0.0
+	for i := 0; i < n; i++ {
+		for j := 0; j < d; j++ {
+			e := ys[i*d+j] - pred[i*d+j]
+			sse += e * e
+			v := ys[i*d+j] - ym[j]
+			sst += v * v
+		}
+	}
+	if sst == 0 {
+		return 0
+	}
+	return 1 - sse/sst
+}
@@ -0,0 +1,86 @@
+// Copyright (c) 2020, The CCNLab Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+
+	"github.com/emer/etable/etable"
+	"github.com/emer/etable/etensor"
+)
+
+// TestStatsFmActsStream tests that the RSA stats work with the StreamSim path
+// in SimMatFmActs, with the Float32 and Packed settings used for the RepRDMs
+func TestStatsFmActsStream(t *testing.T) {
+	lays := []string{"V1m", "TE"}
+	nt := 2
+	ni := 2 // instances per object
+	nu := 8
+	sch := etable.Schema{
+		{"Tick", etensor.INT64, nil, nil},
+		{"Cat", etensor.STRING, nil, nil},
+		{"Obj", etensor.STRING, nil, nil},
+		{"V1m", etensor.FLOAT32, []int{nu}, nil},
+		{"TE", etensor.FLOAT32, []int{nu}, nil},
+	}
+	nr := nt * len(Objs) * ni
+	acts := &etable.Table{}
+	acts.SetFromSchema(sch, nr)
+	rnd := rand.New(rand.NewSource(1))
+	for _, cn := range lays {
+		vals := acts.ColByName(cn).(*etensor.Float32).Values
+		for i := range vals {
+			vals[i] = rnd.Float32()
+		}
+	}
+	var cats []string
+	for row := 0; row < nr; row++ {
+		tck := row / (len(Objs) * ni)
+		oi := (row / ni) % len(Objs)
+		acts.SetCellFloat("Tick", row, float64(tck))
+		acts.SetCellString("Cat", row, Objs[oi])
+		acts.SetCellString("Obj", row, fmt.Sprintf("%s_%03d", Objs[oi], row%ni))
+		if tck == 0 {
+			cats = append(cats, Objs[oi])
+		}
+	}
+
+	rs := &RSA{}
+	rs.TickMax = -1
+	rs.Stream.Defaults()
+	rs.Stream.Float32 = true
+	rs.Stream.Packed = true
+	rs.Stream.MinRows = 1
+	rs.TreeLays = []string{"TE"}
+	rs.TreeTick = -1
+	rs.TreeNPerm = 2
+	rs.Init(lays)
+	rs.Cats = cats
+	rs.StatsFmActs(acts, lays)
+
+	for _, cn := range lays {
+		sm := rs.Sims[cn]
+		if sm == nil {
+			t.Fatalf("layer %s: no SimMat", cn)
+		}
+		if _, ok := sm.Mat.(*etensor.Float64); !ok {
+			t.Errorf("layer %s: SimMat is %T, want *etensor.Float64", cn, sm.Mat)
+		}
+		if n := len(cats); sm.Mat.Len() != n*n {
+			t.Errorf("layer %s: SimMat len %d, want %d", cn, sm.Mat.Len(), n*n)
+		}
+	}
+	if rs.V1Sims[0] != 1 {
+		t.Errorf("V1m V1Sims: %g, want 1", rs.V1Sims[0])
+	}
+	if dt := rs.Trees["TE"]; dt == nil || dt.Rows == 0 {
+		t.Errorf("no TE Tree stats")
+	}
+	if !rs.Stream.Float32 {
+		t.Errorf("Stream.Float32 was changed")
+	}
+}
@@ -0,0 +1,61 @@
+// Copyright (c) 2020, The CCNLab Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// RSALay specifies a layer variable that is recorded in CatLayActs and analyzed
+// by the RSA, optionally restricted to the center pools of a 4D layer.
+// The spec string format is layer[:var[:ctr]], e.g., TE, TECT:ActP, V4:ActM:ctr
+type RSALay struct {
+	Lay string `desc:"name of the layer"`
+	Var string `desc:"name of the neuron variable, e.g., ActM, ActP"`
+	Ctr bool   `desc:"only use the center pools of a 4D layer"`
+}
+
+// ParseRSALay parses a layer[:var[:ctr]] spec string -- var defaults to ActM
+func ParseRSALay(spec string) (RSALay, error) {
+	rl := RSALay{Var: "ActM"}
+	fs := strings.Split(strings.TrimSpace(spec), ":")
+	if len(fs) > 3 || fs[0] == "" {
+		return rl, fmt.Errorf("ParseRSALay: invalid spec: %q -- must be layer[:var[:ctr]]", spec)
+	}
+	rl.Lay = fs[0]
+	if len(fs) > 1 && fs[1] != "" {
+		rl.Var = fs[1]
+	}
+	if len(fs) > 2 {
+		if fs[2] != "ctr" {
+			return rl, fmt.Errorf("ParseRSALay: invalid spec: %q -- last field must be ctr", spec)
+		}
+		rl.Ctr = true
+	}
+	return rl, nil
+}
+
+// Col returns the name of the CatLayActs column (and RSA layer name) for this spec:
+// just the layer name for ActM over the whole layer, otherwise with _var and _ctr suffixes
+func (rl *RSALay) Col() string {
+	nm := rl.Lay
+	if rl.Var != "ActM" {
+		nm += "_" + rl.Var
+	}
+	if rl.Ctr {
+		nm += "_ctr"
+	}
+	return nm
+}
+
+// String returns the spec string
+func (rl *RSALay) String() string {
+	s := rl.Lay + ":" + rl.Var
+	if rl.Ctr {
+		s += ":ctr"
+	}
+	return s
+}
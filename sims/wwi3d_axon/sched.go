@@ -0,0 +1,194 @@
+// Copyright (c) 2020, The CCNLab Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"strings"
+)
+
+// SchedActTypes are the types of action that can be triggered by an EpochSched:
+// Lrate = set the learning rate multiplier to Val (relative to the params),
+// SaveWts = save the weights, with the std weights file name,
+// LaysOn = turn Lays on (the sim InitOffNms if empty, where defined -- see CheckSchedLays),
+// LaysOff = turn Lays off (likewise),
+// Sheet = apply the Network sheet of the ParamSet named Set,
+// Param = set the param at Path to Str for layers / prjns matching Sel,
+// PMD = set the pulvinar driver scale (Layer.TRC.DriveScale on all TRCLayers) to Val,
+// Test = run the full set of testing items (TestAll), restoring the training state after.
+var SchedActTypes = []string{"Lrate", "SaveWts", "LaysOn", "LaysOff", "Sheet", "Param", "PMD", "Test"}
+
+// SchedAct is one action in an EpochSched, triggered at the start of training
+// epoch Epoch (i.e., after the previous epoch has finished), and every Every
+// epochs after that if Every > 0.
+type SchedAct struct {
+	Epoch int      `desc:"training epoch at which the action is triggered"`
+	Every int      `desc:"if > 0, the action is repeated every this many epochs after Epoch"`
+	Act   string   `desc:"type of action -- one of SchedActTypes"`
+	Val   float32  `desc:"value for Lrate (multiplier) and PMD (driver scale)"`
+	Set   string   `desc:"name of the ParamSet to apply for Sheet"`
+	Sel   string   `desc:"params selector for Param, e.g., .V1 or Layer"`
+	Path  string   `desc:"param path for Param, e.g., Layer.Inhib.Layer.Gi"`
+	Str   string   `desc:"param value for Param, as a string"`
+	Lays  []string `desc:"names of layers for LaysOn, LaysOff -- if empty, the sim InitOffNms, where defined"`
+}
+
+// Fires returns true if the action is triggered at given epoch
+func (sa *SchedAct) Fires(epc int) bool {
+	if epc == sa.Epoch {
+		return true
+	}
+	return sa.Every > 0 && epc > sa.Epoch && (epc-sa.Epoch)%sa.Every == 0
+}
+
+// String returns a compact description of the action, used in the epoch log.
+// It contains no spaces, so actions can be joined with spaces.
+func (sa *SchedAct) String() string {
+	switch sa.Act {
+	case "Lrate", "PMD":
+		return fmt.Sprintf("%s=%g", sa.Act, sa.Val)
+	case "LaysOn", "LaysOff":
+		if len(sa.Lays) == 0 {
+			return sa.Act
+		}
+		return sa.Act + ":" + strings.Join(sa.Lays, ",")
+	case "Sheet":
+		return sa.Act + ":" + sa.Set
+	case "Param":
+		return fmt.Sprintf("Param:%s:%s=%s", sa.Sel, sa.Path, sa.Str)
+	}
+	return sa.Act
+}
+
+// SetsParams returns true if the action sets params (Lrate, Sheet, Param, PMD), which
+// are re-applied when resuming from a checkpoint
+func (sa *SchedAct) SetsParams() bool {
+	switch sa.Act {
+	case "Lrate", "Sheet", "Param", "PMD":
+		return true
+	}
+	return false
+}
+
+// Validate returns an error if the action is not well specified
+func (sa *SchedAct) Validate() error {
+	switch sa.Act {
+	case "Lrate", "SaveWts", "LaysOn", "LaysOff", "PMD", "Test":
+	case "Sheet":
+		if sa.Set == "" {
+			return fmt.Errorf("SchedAct at epoch %d: Sheet needs a Set name", sa.Epoch)
+		}
+	case "Param":
+		if sa.Sel == "" || sa.Path == "" || sa.Str == "" {
+			return fmt.Errorf("SchedAct at epoch %d: Param needs Sel, Path and Str", sa.Epoch)
+		}
+	default:
+		return fmt.Errorf("SchedAct at epoch %d: Act %q is not one of: %v", sa.Epoch, sa.Act, SchedActTypes)
+	}
+	if sa.Every < 0 {
+		return fmt.Errorf("SchedAct at epoch %d: Every must be >= 0", sa.Epoch)
+	}
+	return nil
+}
+
+// CheckSchedLays returns an error for the first LaysOn or LaysOff action with no Lays,
+// for a sim that has no default layers (InitOffNms) to turn on and off
+func CheckSchedLays(acts []SchedAct) error {
+	for i := range acts {
+		sa := &acts[i]
+		if (sa.Act == "LaysOn" || sa.Act == "LaysOff") && len(sa.Lays) == 0 {
+			return fmt.Errorf("SchedAct at epoch %d: %s needs Lays in this sim", sa.Epoch, sa.Act)
+		}
+	}
+	return nil
+}
+
+// EpochSched is a declarative schedule of actions triggered at given training epochs,
+// replacing hard-coded epoch switches in the sim.  Schedules are selected per ParamSet
+// from EpochScheds or loaded from a JSON file, and all triggered actions are recorded
+// in the SchedActs column of the training epoch log.
+type EpochSched struct {
+	Name string     `desc:"name of the schedule"`
+	Desc string     `desc:"description of the schedule"`
+	Acts []SchedAct `desc:"the actions, applied in this order when several are triggered at the same epoch"`
+}
+
+// ActsAt returns the actions triggered at given epoch, in order
+func (es *EpochSched) ActsAt(epc int) []*SchedAct {
+	var acts []*SchedAct
+	for i := range es.Acts {
+		sa := &es.Acts[i]
+		if sa.Fires(epc) {
+			acts = append(acts, sa)
+		}
+	}
+	return acts
+}
+
+// Validate returns an error for the first action that is not well specified
+func (es *EpochSched) Validate() error {
+	for i := range es.Acts {
+		if err := es.Acts[i].Validate(); err != nil {
+			return fmt.Errorf("EpochSched %s: %v", es.Name, err)
+		}
+	}
+	return nil
+}
+
+// CopyFrom sets this schedule to a copy of given one, so that it can be
+// edited without affecting the source
+func (es *EpochSched) CopyFrom(fm *EpochSched) {
+	es.Name = fm.Name
+	es.Desc = fm.Desc
+	es.Acts = make([]SchedAct, len(fm.Acts))
+	for i, sa := range fm.Acts {
+		sa.Lays = append([]string(nil), sa.Lays...)
+		es.Acts[i] = sa
+	}
+}
+
+// OpenJSON opens the schedule from a JSON file, and validates it
+func (es *EpochSched) OpenJSON(fname string) error {
+	b, err := ioutil.ReadFile(fname)
+	if err != nil {
+		return err
+	}
+	ns := EpochSched{}
+	if err := json.Unmarshal(b, &ns); err != nil {
+		return fmt.Errorf("EpochSched %s: %v", fname, err)
+	}
+	if ns.Name == "" {
+		ns.Name = fname
+	}
+	if err := ns.Validate(); err != nil {
+		return err
+	}
+	*es = ns
+	return nil
+}
+
+// SaveJSON saves the schedule to a JSON file
+func (es *EpochSched) SaveJSON(fname string) error {
+	b, err := json.MarshalIndent(es, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(fname, b, 0644)
+}
+
+// EpochScheds is a collection of schedules, keyed by the name of the ParamSet
+// they are used with.
+type EpochScheds map[string]*EpochSched
+
+// ForParamSet returns the schedule for given ParamSet, falling back on the
+// Base schedule if it has none (nil if no Base either)
+func (es EpochScheds) ForParamSet(set string) *EpochSched {
+	if sc, ok := es[set]; ok {
+		return sc
+	}
+	return es["Base"]
+}
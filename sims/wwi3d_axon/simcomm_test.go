@@ -0,0 +1,140 @@
+// Copyright (c) 2020, The CCNLab Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"math/rand"
+	"sync"
+	"testing"
+
+	"github.com/emer/empi/mpi"
+	"github.com/emer/etable/etable"
+	"github.com/emer/etable/etensor"
+)
+
+// TestLocalComm tests that the collective ops of N workers on their own goroutines
+// give the same results as reducing and gathering serially, in rank order
+func TestLocalComm(t *testing.T) {
+	n := 4
+	nv := 11 // not a multiple of n, so the parts differ
+	nr := 2
+	rnd := rand.New(rand.NewSource(1))
+	sch := etable.Schema{
+		{"Name", etensor.STRING, nil, nil},
+		{"Val", etensor.FLOAT32, []int{3}, nil},
+	}
+	srcs := make([][]float32, n)
+	tbls := make([]*etable.Table, n)
+	for r := 0; r < n; r++ {
+		srcs[r] = make([]float32, nv)
+		for i := range srcs[r] {
+			srcs[r][i] = rnd.Float32()
+		}
+		dt := &etable.Table{}
+		dt.SetFromSchema(sch, nr)
+		for row := 0; row < nr; row++ {
+			dt.SetCellString("Name", row, fmt.Sprintf("%d_%d", r, row))
+		}
+		vals := dt.ColByName("Val").(*etensor.Float32).Values
+		for i := range vals {
+			vals[i] = rnd.Float32()
+		}
+		tbls[r] = dt
+	}
+
+	sums := make([][]float32, n)
+	maxs := make([][]float32, n)
+	rdts := make([]*etable.Table, n)
+	gdts := make([]*etable.Table, n)
+	errs := make([]error, n)
+	lc := NewLocalComm(n)
+	var wg sync.WaitGroup
+	for r := 0; r < n; r++ {
+		wg.Add(1)
+		go func(r int) {
+			defer wg.Done()
+			lw := lc.Worker(r)
+			sums[r] = make([]float32, nv)
+			maxs[r] = make([]float32, nv)
+			rdts[r] = &etable.Table{}
+			rdts[r].SetFromSchema(sch, 0)
+			gdts[r] = &etable.Table{}
+			gdts[r].SetFromSchema(sch, 0)
+			if err := lw.AllReduceF32(mpi.OpSum, sums[r], srcs[r]); err != nil {
+				errs[r] = err
+			}
+			if err := lw.AllReduceF32(mpi.OpMax, maxs[r], srcs[r]); err != nil {
+				errs[r] = err
+			}
+			if err := lw.ReduceTable(rdts[r], tbls[r], mpi.OpSum); err != nil {
+				errs[r] = err
+			}
+			if err := lw.GatherTableRows(gdts[r], tbls[r]); err != nil {
+				errs[r] = err
+			}
+		}(r)
+	}
+	wg.Wait()
+	for r, err := range errs {
+		if err != nil {
+			t.Fatalf("rank %d: %v", r, err)
+		}
+	}
+
+	for i := 0; i < nv; i++ {
+		sum := srcs[0][i]
+		max := srcs[0][i]
+		for r := 1; r < n; r++ {
+			sum += srcs[r][i]
+			if srcs[r][i] > max {
+				max = srcs[r][i]
+			}
+		}
+		for r := 0; r < n; r++ {
+			if sums[r][i] != sum {
+				t.Errorf("rank %d AllReduceF32 sum [%d]: %g, want %g", r, i, sums[r][i], sum)
+			}
+			if maxs[r][i] != max {
+				t.Errorf("rank %d AllReduceF32 max [%d]: %g, want %g", r, i, maxs[r][i], max)
+			}
+		}
+	}
+
+	nval := nr * 3
+	for i := 0; i < nval; i++ {
+		sum := 0.0
+		for r := 0; r < n; r++ {
+			sum += float64(tbls[r].ColByName("Val").(*etensor.Float32).Values[i])
+		}
+		for r := 0; r < n; r++ {
+			if got := rdts[r].ColByName("Val").FloatVal1D(i); got != float64(float32(sum)) {
+				t.Errorf("rank %d ReduceTable [%d]: %g, want %g", r, i, got, sum)
+			}
+		}
+	}
+
+	for r := 0; r < n; r++ {
+		gdt := gdts[r]
+		if gdt.Rows != n*nr {
+			t.Fatalf("rank %d GatherTableRows: %d rows, want %d", r, gdt.Rows, n*nr)
+		}
+		for sr := 0; sr < n; sr++ {
+			for row := 0; row < nr; row++ {
+				grow := sr*nr + row
+				if nm, want := gdt.CellString("Name", grow), tbls[sr].CellString("Name", row); nm != want {
+					t.Errorf("rank %d GatherTableRows Name row %d: %s, want %s", r, grow, nm, want)
+				}
+				for i := 0; i < 3; i++ {
+					got := gdt.ColByName("Val").FloatVal1D(grow*3 + i)
+					want := tbls[sr].ColByName("Val").FloatVal1D(row*3 + i)
+					if got != want {
+						t.Errorf("rank %d GatherTableRows Val row %d [%d]: %g, want %g", r, grow, i, got, want)
+					}
+				}
+			}
+		}
+	}
+}
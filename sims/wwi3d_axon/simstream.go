@@ -0,0 +1,362 @@
+// Copyright (c) 2020, The CCNLab Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"os"
+	"runtime"
+	"sync"
+
+	"github.com/emer/etable/etable"
+	"github.com/emer/etable/etensor"
+	"github.com/emer/etable/metric"
+	"github.com/emer/etable/simat"
+)
+
+// StreamSim computes similarity / distance matricies (RDMs) over large numbers of
+// items (e.g., trial-level reps, per-instance rows), blockwise in parallel goroutines,
+// with optional float32 storage and upper-triangle-only packing, and optionally
+// streaming the results to a .npy file block-by-block, so that only one block of rows
+// needs to be in memory.  TableCol has the same args as simat.SimMat TableCol.
+type StreamSim struct {
+	Metric    metric.StdMetrics `desc:"metric to compute between items -- InvCorrelation, InvCosine, Correlation and Cosine use a fast normalized dot product"`
+	BlockSize int               `desc:"number of rows in each block processed in parallel and written to disk at once"`
+	NThreads  int               `desc:"number of parallel goroutines -- 0 = GOMAXPROCS"`
+	Float32   bool              `desc:"use float32 instead of float64 for the values, for SaveNpy and PackedTableCol -- RSA SimMatFmActs always computes float64 SimMats, which the RSA analyses require"`
+	Packed    bool              `desc:"only compute and store the upper triangle (including diagonal) of the symmetric matrix, as a 1D array of N(N+1)/2 values, for SaveNpy"`
+	MinRows   int               `desc:"if > 0, RSA SimMatFmActs uses the StreamSim TableCol for IdxViews with at least this many rows"`
+}
+
+func (st *StreamSim) Defaults() {
+	st.Metric = metric.InvCorrelation
+	st.BlockSize = 256
+}
+
+// PackedLen returns the number of values in the packed upper triangle of an n x n matrix
+func PackedLen(n int) int {
+	return n * (n + 1) / 2
+}
+
+// PackedIdx returns the index into the packed upper triangle of an n x n matrix
+// for row i, col j (the indexes are swapped if j < i)
+func PackedIdx(n, i, j int) int {
+	if j < i {
+		i, j = j, i
+	}
+	return i*n - i*(i-1)/2 + (j - i)
+}
+
+// PackedSimMat is a symmetric similarity matrix stored as the packed upper triangle
+// (including the diagonal), with the same Rows, Cols labels as simat.SimMat
+type PackedSimMat struct {
+	N      int       `desc:"number of rows (and cols)"`
+	Vals   []float32 `desc:"packed upper triangle values for float32 storage -- nil if Vals64 is used"`
+	Vals64 []float64 `desc:"packed upper triangle values for float64 storage"`
+	Rows   []string  `desc:"labels for the rows -- blank rows trigger generation of grouping lines"`
+	Cols   []string  `desc:"labels for the cols -- blank cols trigger generation of grouping lines"`
+}
+
+// Val returns the value at row i, col j
+func (pm *PackedSimMat) Val(i, j int) float64 {
+	pi := PackedIdx(pm.N, i, j)
+	if pm.Vals != nil {
+		return float64(pm.Vals[pi])
+	}
+	return pm.Vals64[pi]
+}
+
+// SimMat returns a full (unpacked) float64 simat.SimMat
+func (pm *PackedSimMat) SimMat() *simat.SimMat {
+	sm := &simat.SimMat{}
+	sm.Init()
+	mat := sm.Mat.(*etensor.Float64)
+	mat.SetShape([]int{pm.N, pm.N}, nil, []string{"Y", "X"})
+	for i := 0; i < pm.N; i++ {
+		for j := i; j < pm.N; j++ {
+			v := pm.Val(i, j)
+			mat.Values[i*pm.N+j] = v
+			mat.Values[j*pm.N+i] = v
+		}
+	}
+	sm.Rows = pm.Rows
+	sm.Cols = pm.Cols
+	return sm
+}
+
+// IdxViewVecs returns the float64 vectors for given tensor column, for each row of ix
+func IdxViewVecs(ix *etable.IdxView, colnm string) [][]float64 {
+	vecs := make([][]float64, ix.Len())
+	for i, row := range ix.Idxs {
+		tsr := ix.Table.CellTensor(colnm, row)
+		v := make([]float64, tsr.Len())
+		for j := range v {
+			v[j] = tsr.FloatVal1D(j)
+		}
+		vecs[i] = v
+	}
+	return vecs
+}
+
+// IdxViewLabels returns the labels from given column for each row of ix,
+// with repeated successive labels blank if blanks is true (as in simat TableCol)
+func IdxViewLabels(ix *etable.IdxView, labNm string, blanks bool) []string {
+	lbls := make([]string, ix.Len())
+	if labNm == "" {
+		return lbls
+	}
+	last := ""
+	for i, row := range ix.Idxs {
+		lbl := ix.Table.CellString(labNm, row)
+		if blanks && lbl == last {
+			lbls[i] = ""
+			continue
+		}
+		lbls[i] = lbl
+		last = lbl
+	}
+	return lbls
+}
+
+// dotMetric returns true if the Metric can be computed from the dot product of
+// normalized vectors, and whether the vectors need to be mean centered
+func (st *StreamSim) dotMetric() (dot, center bool) {
+	switch st.Metric {
+	case metric.InvCorrelation, metric.Correlation:
+		return true, true
+	case metric.InvCosine, metric.Cosine:
+		return true, false
+	}
+	return false, false
+}
+
+// PrepVecs prepares the vectors for the Metric: for the dot product metrics, they
+// are normalized (and mean centered for correlation) -- in place, returned for convenience
+func (st *StreamSim) PrepVecs(vecs [][]float64) [][]float64 {
+	dot, center := st.dotMetric()
+	if !dot {
+		return vecs
+	}
+	if center {
+		return NormVecs(vecs)
+	}
+	for _, v := range vecs {
+		ss := 0.0
+		for _, a := range v {
+			ss += a * a
+		}
+		if ss == 0 {
+			continue
+		}
+		nrm := 1 / math.Sqrt(ss)
+		for i := range v {
+			v[i] *= nrm
+		}
+	}
+	return vecs
+}
+
+// pairFunc returns the function computing the metric between two prepared vectors
+func (st *StreamSim) pairFunc() func(a, b []float64) float64 {
+	dot, _ := st.dotMetric()
+	if !dot {
+		return metric.StdFunc64(st.Metric)
+	}
+	inv := st.Metric == metric.InvCorrelation || st.Metric == metric.InvCosine
+	return func(a, b []float64) float64 {
+		dp := 0.0
+		for k, av := range a {
+			dp += av * b[k]
+		}
+		if inv {
+			return 1 - dp
+		}
+		return dp
+	}
+}
+
+// Blocks computes the matrix over given prepared vectors (see PrepVecs) blockwise:
+// for each block of BlockSize rows, in order, the rows are computed in parallel and then
+// passed to fun with the starting row and the row values -- either the full rows
+// (n values each) or, if Packed, the upper triangle part of each row (cols i..n-1).
+// The vals slice is reused for the next block.
+func (st *StreamSim) Blocks(vecs [][]float64, fun func(strow int, vals [][]float64)) {
+	n := len(vecs)
+	bs := st.BlockSize
+	if bs <= 0 {
+		bs = 256
+	}
+	nt := st.NThreads
+	if nt <= 0 {
+		nt = runtime.GOMAXPROCS(0)
+	}
+	pf := st.pairFunc()
+	blk := make([][]float64, bs)
+	for sr := 0; sr < n; sr += bs {
+		er := sr + bs
+		if er > n {
+			er = n
+		}
+		rows := make(chan int, er-sr)
+		for i := sr; i < er; i++ {
+			rows <- i
+		}
+		close(rows)
+		var wg sync.WaitGroup
+		for t := 0; t < nt; t++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for i := range rows {
+					stc := 0
+					if st.Packed {
+						stc = i
+					}
+					rv := blk[i-sr]
+					if cap(rv) < n-stc {
+						rv = make([]float64, n-stc)
+					}
+					rv = rv[:n-stc]
+					for j := stc; j < n; j++ {
+						rv[j-stc] = pf(vecs[i], vecs[j])
+					}
+					blk[i-sr] = rv
+				}
+			}()
+		}
+		wg.Wait()
+		fun(sr, blk[:er-sr])
+	}
+}
+
+// TableCol computes the similarity matrix in sm for given tensor column in ix,
+// with row and col labels from labNm column (repeated labels blank if blanks),
+// as in simat.SimMat TableCol, but blockwise in parallel.  If Float32, sm.Mat is
+// an etensor.Float32, otherwise etensor.Float64.  The full matrix is always stored.
+func (st *StreamSim) TableCol(sm *simat.SimMat, ix *etable.IdxView, colnm, labNm string, blanks bool) error {
+	if ix.Table.ColIdx(colnm) < 0 {
+		return fmt.Errorf("StreamSim TableCol: column not found: %s", colnm)
+	}
+	vecs := st.PrepVecs(IdxViewVecs(ix, colnm))
+	n := len(vecs)
+	packed := st.Packed
+	st.Packed = false
+	defer func() { st.Packed = packed }()
+	if st.Float32 {
+		mat := etensor.NewFloat32([]int{n, n}, nil, []string{"Y", "X"})
+		st.Blocks(vecs, func(sr int, vals [][]float64) {
+			for ri, rv := range vals {
+				off := (sr + ri) * n
+				for j, v := range rv {
+					mat.Values[off+j] = float32(v)
+				}
+			}
+		})
+		sm.Mat = mat
+	} else {
+		mat := etensor.NewFloat64([]int{n, n}, nil, []string{"Y", "X"})
+		st.Blocks(vecs, func(sr int, vals [][]float64) {
+			for ri, rv := range vals {
+				copy(mat.Values[(sr+ri)*n:], rv)
+			}
+		})
+		sm.Mat = mat
+	}
+	sm.Rows = IdxViewLabels(ix, labNm, blanks)
+	sm.Cols = sm.Rows
+	return nil
+}
+
+// PackedTableCol computes the packed upper triangle similarity matrix for given
+// tensor column in ix, with labels from labNm column -- see TableCol
+func (st *StreamSim) PackedTableCol(ix *etable.IdxView, colnm, labNm string, blanks bool) (*PackedSimMat, error) {
+	if ix.Table.ColIdx(colnm) < 0 {
+		return nil, fmt.Errorf("StreamSim PackedTableCol: column not found: %s", colnm)
+	}
+	vecs := st.PrepVecs(IdxViewVecs(ix, colnm))
+	n := len(vecs)
+	pm := &PackedSimMat{N: n}
+	if st.Float32 {
+		pm.Vals = make([]float32, PackedLen(n))
+	} else {
+		pm.Vals64 = make([]float64, PackedLen(n))
+	}
+	packed := st.Packed
+	st.Packed = true
+	defer func() { st.Packed = packed }()
+	st.Blocks(vecs, func(sr int, vals [][]float64) {
+		for ri, rv := range vals {
+			off := PackedIdx(n, sr+ri, sr+ri)
+			if pm.Vals != nil {
+				for j, v := range rv {
+					pm.Vals[off+j] = float32(v)
+				}
+			} else {
+				copy(pm.Vals64[off:], rv)
+			}
+		}
+	})
+	pm.Rows = IdxViewLabels(ix, labNm, blanks)
+	pm.Cols = pm.Rows
+	return pm, nil
+}
+
+// SaveNpy computes the similarity matrix for given tensor column in ix and writes it
+// incrementally, block by block, to given .npy file, so that only one block of rows
+// is in memory at a time.  If Packed, the file holds the 1D packed upper triangle
+// (see PackedIdx), otherwise the full n x n matrix.  Values are float32 if Float32.
+func (st *StreamSim) SaveNpy(fname string, ix *etable.IdxView, colnm string) error {
+	if ix.Table.ColIdx(colnm) < 0 {
+		return fmt.Errorf("StreamSim SaveNpy: column not found: %s", colnm)
+	}
+	vecs := st.PrepVecs(IdxViewVecs(ix, colnm))
+	n := len(vecs)
+	fp, err := os.Create(fname)
+	if err != nil {
+		return err
+	}
+	defer fp.Close()
+	bw := bufio.NewWriter(fp)
+	descr := "<f8"
+	if st.Float32 {
+		descr = "<f4"
+	}
+	shape := []int{n, n}
+	if st.Packed {
+		shape = []int{PackedLen(n)}
+	}
+	if err = WriteNpyHeader(bw, descr, shape); err != nil {
+		return err
+	}
+	vsz := 8
+	if st.Float32 {
+		vsz = 4
+	}
+	buf := make([]byte, n*vsz)
+	st.Blocks(vecs, func(sr int, vals [][]float64) {
+		if err != nil {
+			return
+		}
+		for _, rv := range vals {
+			for j, v := range rv {
+				if st.Float32 {
+					binary.LittleEndian.PutUint32(buf[j*4:], math.Float32bits(float32(v)))
+				} else {
+					binary.LittleEndian.PutUint64(buf[j*8:], math.Float64bits(v))
+				}
+			}
+			if _, err = bw.Write(buf[:len(rv)*vsz]); err != nil {
+				return
+			}
+		}
+	})
+	if err != nil {
+		return err
+	}
+	return bw.Flush()
+}
@@ -0,0 +1,280 @@
+// Copyright (c) 2020, The CCNLab Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"image"
+	"image/png"
+	"math"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/emer/etable/etable"
+	"github.com/emer/etable/etensor"
+)
+
+// StatusServer is an optional HTTP server, bound to localhost, for monitoring
+// long headless runs with curl or through an ssh tunnel.  The sim pushes its
+// status to the server as it runs, so the handlers never touch the sim state.
+// The endpoints are: /status = Status as JSON (counters, per-trial timing, etc),
+// /epochs = the latest epoch log rows as JSON (all kept, or the last n=N),
+// /simat.png = the latest similarity matrix heatmap (e.g., TE), and the POST-only
+// controls /stop = stop training and /savewts = save the weights, at the end of
+// the current trial.  Requests must have a localhost Host header, so other sites
+// can not reach the server by DNS rebinding, and the controls must also have the
+// random Token in a StatusTokenHeader, which a web page can not send cross-origin.
+type StatusServer struct {
+	Addr    string `desc:"address the server listens on, host:port, where the host must be a loopback address (localhost, 127.0.0.1, ::1)"`
+	MaxEpcs int    `def:"100" desc:"maximum number of the latest epoch log rows kept"`
+	Token   string `desc:"random token required by the controls, made by Start -- printed at startup"`
+
+	mu     sync.Mutex
+	stat   Status
+	epcs   []map[string]interface{}
+	simPNG []byte
+	stop   bool
+	save   bool
+	srv    *http.Server
+}
+
+// Status is the current status of a running sim, as served by /status
+type Status struct {
+	Name          string    `desc:"name of the network and run"`
+	Counters      string    `desc:"current counters, as displayed in the NetView (Counters)"`
+	Run           int       `desc:"current run"`
+	Epoch         int       `desc:"current epoch"`
+	Trial         int       `desc:"current trial"`
+	EpcPerTrlMSec float64   `desc:"wall-clock milliseconds per trial in the last epoch"`
+	Running       bool      `desc:"true while training"`
+	StopReq       bool      `desc:"stop has been requested and not yet acted on"`
+	SaveReq       bool      `desc:"saving the weights has been requested and not yet acted on"`
+	SavedWts      string    `desc:"file name of the last weights saved through /savewts"`
+	Updated       time.Time `desc:"time of the last update from the sim"`
+}
+
+// StatusTokenHeader is the HTTP header with the Token of the StatusServer controls
+const StatusTokenHeader = "X-Status-Token"
+
+// LoopbackHost returns true if given host (without port) is localhost or a loopback address
+func LoopbackHost(host string) bool {
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// StatusLoopback returns an error if the host of given host:port address is not a loopback address
+func StatusLoopback(addr string) error {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return err
+	}
+	if !LoopbackHost(host) {
+		return fmt.Errorf("StatusServer: address %s must be on localhost, e.g., localhost:8080", addr)
+	}
+	return nil
+}
+
+// Start starts serving on given address, which must be on localhost, in a goroutine
+func (sv *StatusServer) Start(addr string) error {
+	if err := StatusLoopback(addr); err != nil {
+		return err
+	}
+	if sv.MaxEpcs <= 0 {
+		sv.MaxEpcs = 100
+	}
+	tok := make([]byte, 16)
+	if _, err := rand.Read(tok); err != nil {
+		return err
+	}
+	sv.Token = hex.EncodeToString(tok)
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	sv.Addr = ln.Addr().String()
+	mux := http.NewServeMux()
+	mux.HandleFunc("/status", sv.ServeStatus)
+	mux.HandleFunc("/epochs", sv.ServeEpochs)
+	mux.HandleFunc("/simat.png", sv.ServeSimMat)
+	mux.HandleFunc("/stop", sv.ServeCtrl)
+	mux.HandleFunc("/savewts", sv.ServeCtrl)
+	sv.srv = &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !LoopbackHost(ReqHost(r.Host)) {
+			http.Error(w, "Host must be localhost", http.StatusForbidden)
+			return
+		}
+		mux.ServeHTTP(w, r)
+	})}
+	go sv.srv.Serve(ln)
+	return nil
+}
+
+// ReqHost returns the host of given request Host header or Origin URL host, without the port
+func ReqHost(hp string) string {
+	if host, _, err := net.SplitHostPort(hp); err == nil {
+		return host
+	}
+	return strings.Trim(hp, "[]")
+}
+
+// Close stops the server
+func (sv *StatusServer) Close() error {
+	if sv.srv == nil {
+		return nil
+	}
+	return sv.srv.Close()
+}
+
+// Update calls fun to update the Status, with the lock held
+func (sv *StatusServer) Update(fun func(st *Status)) {
+	sv.mu.Lock()
+	fun(&sv.stat)
+	sv.stat.Updated = time.Now()
+	sv.mu.Unlock()
+}
+
+// AddEpoch adds the scalar columns of given row of the epoch log, keeping the last MaxEpcs
+func (sv *StatusServer) AddEpoch(dt *etable.Table, row int) {
+	rec := make(map[string]interface{}, len(dt.Cols))
+	for ci, cl := range dt.Cols {
+		if cl.NumDims() > 1 {
+			continue
+		}
+		if cl.DataType() == etensor.STRING {
+			rec[dt.ColNames[ci]] = cl.StringVal1D(row)
+			continue
+		}
+		v := cl.FloatVal1D(row)
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			rec[dt.ColNames[ci]] = nil // not representable in JSON
+		} else {
+			rec[dt.ColNames[ci]] = v
+		}
+	}
+	sv.mu.Lock()
+	sv.epcs = append(sv.epcs, rec)
+	if len(sv.epcs) > sv.MaxEpcs {
+		sv.epcs = sv.epcs[len(sv.epcs)-sv.MaxEpcs:]
+	}
+	sv.mu.Unlock()
+}
+
+// SetSimMat sets the similarity matrix image served by /simat.png
+func (sv *StatusServer) SetSimMat(img image.Image) error {
+	var b bytes.Buffer
+	if err := png.Encode(&b, img); err != nil {
+		return err
+	}
+	sv.mu.Lock()
+	sv.simPNG = b.Bytes()
+	sv.mu.Unlock()
+	return nil
+}
+
+// Ctrls returns the pending stop and save weights requests, and clears them
+func (sv *StatusServer) Ctrls() (stop, save bool) {
+	sv.mu.Lock()
+	stop, save = sv.stop, sv.save
+	sv.stop, sv.save = false, false
+	sv.stat.StopReq, sv.stat.SaveReq = false, false
+	sv.mu.Unlock()
+	return
+}
+
+// ServeJSON writes given value as indented JSON
+func ServeJSON(w http.ResponseWriter, v interface{}) {
+	b, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(append(b, '\n'))
+}
+
+// ServeStatus serves the Status
+func (sv *StatusServer) ServeStatus(w http.ResponseWriter, r *http.Request) {
+	sv.mu.Lock()
+	st := sv.stat
+	sv.mu.Unlock()
+	ServeJSON(w, &st)
+}
+
+// ServeEpochs serves the epoch log rows: the last n=N if specified, else all kept
+func (sv *StatusServer) ServeEpochs(w http.ResponseWriter, r *http.Request) {
+	n := -1
+	if ns := r.URL.Query().Get("n"); ns != "" {
+		var err error
+		if n, err = strconv.Atoi(ns); err != nil || n < 0 {
+			http.Error(w, "n must be a number >= 0", http.StatusBadRequest)
+			return
+		}
+	}
+	sv.mu.Lock()
+	epcs := sv.epcs
+	if n >= 0 && n < len(epcs) {
+		epcs = epcs[len(epcs)-n:]
+	}
+	epcs = append([]map[string]interface{}{}, epcs...)
+	sv.mu.Unlock()
+	ServeJSON(w, epcs)
+}
+
+// ServeSimMat serves the similarity matrix png
+func (sv *StatusServer) ServeSimMat(w http.ResponseWriter, r *http.Request) {
+	sv.mu.Lock()
+	b := sv.simPNG
+	sv.mu.Unlock()
+	if b == nil {
+		http.Error(w, "no similarity matrix computed yet", http.StatusNotFound)
+		return
+	}
+	w.Header().Set("Content-Type", "image/png")
+	w.Write(b)
+}
+
+// ServeCtrl records a /stop or /savewts request, which must be a POST with the
+// Token, and not from a page of another origin
+func (sv *StatusServer) ServeCtrl(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "use POST, e.g., curl -X POST", http.StatusMethodNotAllowed)
+		return
+	}
+	if org := r.Header.Get("Origin"); org != "" {
+		if u, err := url.Parse(org); err != nil || !LoopbackHost(ReqHost(u.Host)) {
+			http.Error(w, "cross-origin requests are not allowed", http.StatusForbidden)
+			return
+		}
+	}
+	if subtle.ConstantTimeCompare([]byte(r.Header.Get(StatusTokenHeader)), []byte(sv.Token)) != 1 {
+		http.Error(w, "missing or wrong "+StatusTokenHeader+" -- printed at startup", http.StatusForbidden)
+		return
+	}
+	sv.mu.Lock()
+	if r.URL.Path == "/stop" {
+		sv.stop = true
+		sv.stat.StopReq = true
+	} else {
+		sv.save = true
+		sv.stat.SaveReq = true
+	}
+	st := sv.stat
+	sv.mu.Unlock()
+	ServeJSON(w, &st)
+}